@@ -2,8 +2,15 @@ package configurations
 
 import (
 	"github.com/ecumenos-social/network-warden/services/adminauth"
+	"github.com/ecumenos-social/network-warden/services/auth"
+	"github.com/ecumenos-social/network-warden/services/emailer"
+	"github.com/ecumenos-social/network-warden/services/holders"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
 	"github.com/ecumenos-social/network-warden/services/jwt"
+	loginthrottles "github.com/ecumenos-social/network-warden/services/login-throttles"
+	passwordresets "github.com/ecumenos-social/network-warden/services/password-resets"
+	"github.com/ecumenos-social/network-warden/services/passwords"
+	sessionbinding "github.com/ecumenos-social/network-warden/services/session-binding"
 	"github.com/ecumenos-social/toolkit/types"
 	"github.com/ecumenos-social/toolkitfx"
 	"github.com/ecumenos-social/toolkitfx/fxgrpc"
@@ -26,13 +33,36 @@ type fxConfig struct {
 	PersonalDataNodesIDGenerator *idgenerators.PersonalDataNodesIDGeneratorConfig
 	NetworkNodesIDGenerator      *idgenerators.NetworkNodesIDGeneratorConfig
 	NetworkWardensIDGenerator    *idgenerators.NetworkWardensIDGeneratorConfig
+	LoginThrottlesIDGenerator    *idgenerators.LoginThrottlesIDGeneratorConfig
+	HoldersIDGenerator           *idgenerators.HoldersIDGeneratorConfig
+	HolderSessionsIDGenerator    *idgenerators.HolderSessionsIDGeneratorConfig
+	PasswordResetsIDGenerator    *idgenerators.HolderPasswordResetsIDGeneratorConfig
+	SentEmailsIDGenerator        *idgenerators.SentEmailsIDGeneratorConfig
+	EmailSuppressionsIDGenerator *idgenerators.EmailSuppressionsIDGeneratorConfig
 	JWT                          *jwt.Config
 	Auth                         *adminauth.Config
+	LoginThrottles               *loginthrottles.Config
+	SessionBinding               *sessionbinding.Config
+	PasswordHasher               *passwords.HasherConfig
+	PasswordPolicy               *passwords.PolicyConfig
+	Holders                      *holders.Config
+	HolderAuth                   *auth.Config
+	PasswordResets               *passwordresets.Config
+	Emailer                      *emailer.Config
 }
 
 var Module = func(cctx *cli.Context) fx.Option {
 	return fx.Options(
-		fx.Provide(func() fxConfig {
+		fx.Provide(func() (fxConfig, error) {
+			jwtKeys := make([]*jwt.Key, 0, len(cctx.StringSlice("nw-jwt-keys")))
+			for _, value := range cctx.StringSlice("nw-jwt-keys") {
+				key, err := jwt.ParseKey(value)
+				if err != nil {
+					return fxConfig{}, err
+				}
+				jwtKeys = append(jwtKeys, key)
+			}
+
 			return fxConfig{
 				App: &toolkitfx.GenericAppConfig{
 					ID:          cctx.Int64("nw-app-id"),
@@ -96,15 +126,113 @@ var Module = func(cctx *cli.Context) fx.Option {
 					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
 					LowNodeID: 0,
 				},
+				LoginThrottlesIDGenerator: &idgenerators.LoginThrottlesIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				HoldersIDGenerator: &idgenerators.HoldersIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				HolderSessionsIDGenerator: &idgenerators.HolderSessionsIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				PasswordResetsIDGenerator: &idgenerators.HolderPasswordResetsIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				SentEmailsIDGenerator: &idgenerators.SentEmailsIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				EmailSuppressionsIDGenerator: &idgenerators.EmailSuppressionsIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
 				JWT: &jwt.Config{
 					SigningKey:      cctx.String("nw-jwt-signing-key"),
+					SigningKeyID:    cctx.String("nw-jwt-signing-key-id"),
+					Keys:            jwtKeys,
+					Algorithm:       cctx.String("nw-jwt-algorithm"),
 					TokenAge:        cctx.Duration("nw-jwt-token-age"),
 					RefreshTokenAge: cctx.Duration("nw-jwt-refresh-token-age"),
 				},
 				Auth: &adminauth.Config{
 					SessionAge: cctx.Duration("nw-auth-session-age"),
 				},
-			}
+				LoginThrottles: &loginthrottles.Config{
+					DelayThreshold:  cctx.Int64("nw-login-throttles-delay-threshold"),
+					BaseDelay:       cctx.Duration("nw-login-throttles-base-delay"),
+					MaxAttempts:     cctx.Int64("nw-login-throttles-max-attempts"),
+					LockoutDuration: cctx.Duration("nw-login-throttles-lockout-duration"),
+					AttemptsWindow:  cctx.Duration("nw-login-throttles-attempts-window"),
+				},
+				SessionBinding: &sessionbinding.Config{
+					Policy:           sessionbinding.Policy(cctx.String("nw-session-binding-policy")),
+					IPv4PrefixLength: cctx.Int("nw-session-binding-ipv4-prefix-length"),
+					IPv6PrefixLength: cctx.Int("nw-session-binding-ipv6-prefix-length"),
+					ProofAge:         cctx.Duration("nw-session-binding-proof-age"),
+					MismatchAction:   sessionbinding.MismatchAction(cctx.String("nw-session-binding-mismatch-action")),
+				},
+				PasswordHasher: &passwords.HasherConfig{
+					Algorithm:           cctx.String("nw-password-hashing-algorithm"),
+					Argon2idMemory:      uint32(cctx.Uint("nw-password-hashing-argon2id-memory")),
+					Argon2idIterations:  uint32(cctx.Uint("nw-password-hashing-argon2id-iterations")),
+					Argon2idParallelism: uint8(cctx.Uint("nw-password-hashing-argon2id-parallelism")),
+					BcryptCost:          cctx.Int("nw-password-hashing-bcrypt-cost"),
+				},
+				PasswordPolicy: &passwords.PolicyConfig{
+					MinLength:             cctx.Int("nw-password-policy-min-length"),
+					MinCharacterClasses:   cctx.Int("nw-password-policy-min-character-classes"),
+					BreachedPasswordsFile: cctx.String("nw-password-policy-breached-passwords-file"),
+				},
+				Holders: &holders.Config{
+					ConfirmationCodeAge:     cctx.Duration("nw-holders-confirmation-code-age"),
+					MaxConfirmationAttempts: cctx.Int64("nw-holders-max-confirmation-attempts"),
+					DeletionGracePeriod:     cctx.Duration("nw-holders-deletion-grace-period"),
+				},
+				HolderAuth: &auth.Config{
+					SessionAge: cctx.Duration("nw-holder-auth-session-age"),
+				},
+				PasswordResets: &passwordresets.Config{
+					CodeAge: cctx.Duration("nw-password-resets-code-age"),
+				},
+				// admin app queues password reset emails only, so other
+				// templates are limited by sender rate limit only. Queued
+				// emails are delivered by network-warden app.
+				Emailer: &emailer.Config{
+					Transport:          cctx.String("nw-emailer-transport"),
+					SenderEmailAddress: cctx.String("nw-emailer-sender-email-address"),
+					SMTP: &emailer.SMTPTransportConfig{
+						Host:     cctx.String("nw-emailer-smtp-host"),
+						Port:     cctx.String("nw-emailer-smtp-port"),
+						Username: cctx.String("nw-emailer-sender-username"),
+						Password: cctx.String("nw-emailer-sender-password"),
+						Timeout:  cctx.Duration("nw-emailer-smtp-timeout"),
+					},
+					HTTP: &emailer.HTTPTransportConfig{
+						URL:           cctx.String("nw-emailer-http-url"),
+						Authorization: cctx.String("nw-emailer-http-authorization"),
+						ContentType:   cctx.String("nw-emailer-http-content-type"),
+						BodyTemplate:  cctx.String("nw-emailer-http-body-template"),
+						Timeout:       cctx.Duration("nw-emailer-http-timeout"),
+					},
+					Maildir: &emailer.MaildirTransportConfig{
+						Path: cctx.String("nw-emailer-maildir-path"),
+					},
+					RateLimits: map[emailer.TemplateName]*emailer.RateLimit{
+						emailer.TemplateNameResetHolderPassword: {
+							MaxRequests: cctx.Int64("nw-emailer-reset-holder-password-max-requests"),
+							Interval:    cctx.Duration("nw-emailer-reset-holder-password-interval"),
+						},
+					},
+					SenderRateLimit: &emailer.RateLimit{
+						MaxRequests: cctx.Int64("nw-emailer-sender-max-requests"),
+						Interval:    cctx.Duration("nw-emailer-sender-interval"),
+					},
+				},
+			}, nil
 		}),
 	)
 }
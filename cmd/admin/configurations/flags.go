@@ -139,6 +139,23 @@ var Flags = []cli.Flag{
 		Value:   "alDFsk1d2!j@G$4%5^B&f*6(7)h_-g+=",
 		EnvVars: []string{"NETWORK_WARDEN_ADMIN_JWT_SIGNING_KEY"},
 	},
+	&cli.StringFlag{
+		Name:    "nw-jwt-signing-key-id",
+		Usage:   "it is key ID (kid) of JWT secret",
+		Value:   "default",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_JWT_SIGNING_KEY_ID"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-jwt-algorithm",
+		Usage:   "it is JWT signing algorithm (HS256, EdDSA or ES256), for EdDSA and ES256 keys are base64 encoded PKCS #8 private keys",
+		Value:   "HS256",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_JWT_ALGORITHM"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "nw-jwt-keys",
+		Usage:   "it is JWT key set formatted as <kid>;<activated-at>;<secret>, the latest activated key signs tokens and all of them verify tokens. If it is set, nw-jwt-signing-key is ignored",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_JWT_KEYS"},
+	},
 	&cli.DurationFlag{
 		Name:    "nw-jwt-token-age",
 		Usage:   "it is age of token",
@@ -157,4 +174,243 @@ var Flags = []cli.Flag{
 		Value:   90 * time.Minute,
 		EnvVars: []string{"NETWORK_WARDEN_ADMIN_AUTH_SESSION_AGE"},
 	},
+	&cli.Int64Flag{
+		Name:    "nw-login-throttles-delay-threshold",
+		Usage:   "it is amount of failed login attempts after which next attempts are delayed",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_LOGIN_THROTTLES_DELAY_THRESHOLD"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-login-throttles-base-delay",
+		Usage:   "it is delay after failed login attempt, it is doubled for every next failed attempt",
+		Value:   time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_LOGIN_THROTTLES_BASE_DELAY"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-login-throttles-max-attempts",
+		Usage:   "it is amount of failed login attempts after which login is locked",
+		Value:   10,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_LOGIN_THROTTLES_MAX_ATTEMPTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-login-throttles-lockout-duration",
+		Usage:   "it is duration of login lockout",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_LOGIN_THROTTLES_LOCKOUT_DURATION"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-login-throttles-attempts-window",
+		Usage:   "it is duration after the latest failed login attempt when failed attempts are forgotten",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_LOGIN_THROTTLES_ATTEMPTS_WINDOW"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-session-binding-policy",
+		Usage:   "it is what sessions are bound to (none, ip-subnet, strict-ip, device-key)",
+		Value:   "strict-ip",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_SESSION_BINDING_POLICY"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-session-binding-ipv4-prefix-length",
+		Usage:   "it is length of IPv4 subnet prefix which is compared by ip-subnet session binding policy",
+		Value:   24,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_SESSION_BINDING_IPV4_PREFIX_LENGTH"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-session-binding-ipv6-prefix-length",
+		Usage:   "it is length of IPv6 subnet prefix which is compared by ip-subnet session binding policy",
+		Value:   64,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_SESSION_BINDING_IPV6_PREFIX_LENGTH"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-session-binding-proof-age",
+		Usage:   "it is how long device key proof is accepted after it was made",
+		Value:   time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_SESSION_BINDING_PROOF_AGE"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-session-binding-mismatch-action",
+		Usage:   "it is what happens if request doesn't match session binding (deny, reauthenticate, step-up)",
+		Value:   "reauthenticate",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_SESSION_BINDING_MISMATCH_ACTION"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-password-hashing-algorithm",
+		Usage:   "it is algorithm of new password hashes (argon2id, bcrypt), hashes of other algorithms are upgraded on successful login",
+		Value:   "argon2id",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_HASHING_ALGORITHM"},
+	},
+	&cli.UintFlag{
+		Name:    "nw-password-hashing-argon2id-memory",
+		Usage:   "it is argon2id memory cost in KiB",
+		Value:   64 * 1024,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_HASHING_ARGON2ID_MEMORY"},
+	},
+	&cli.UintFlag{
+		Name:    "nw-password-hashing-argon2id-iterations",
+		Usage:   "it is argon2id time cost",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_HASHING_ARGON2ID_ITERATIONS"},
+	},
+	&cli.UintFlag{
+		Name:    "nw-password-hashing-argon2id-parallelism",
+		Usage:   "it is argon2id degree of parallelism",
+		Value:   2,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_HASHING_ARGON2ID_PARALLELISM"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-password-hashing-bcrypt-cost",
+		Usage:   "it is bcrypt cost",
+		Value:   14,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_HASHING_BCRYPT_COST"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-holder-auth-session-age",
+		Usage:   "it is age of holder session",
+		Value:   90 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_HOLDER_AUTH_SESSION_AGE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-holders-confirmation-code-age",
+		Usage:   "it is age of confirmation code of holder registration",
+		Value:   24 * time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_HOLDERS_CONFIRMATION_CODE_AGE"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-holders-max-confirmation-attempts",
+		Usage:   "it is maximal amount of wrong confirmation codes after which confirmation code is invalidated",
+		Value:   5,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_HOLDERS_MAX_CONFIRMATION_ATTEMPTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-holders-deletion-grace-period",
+		Usage:   "it is period after holder deletion request when holder can cancel deletion",
+		Value:   30 * 24 * time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_HOLDERS_DELETION_GRACE_PERIOD"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-password-policy-min-length",
+		Usage:   "it is minimal length of holder password",
+		Value:   10,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_POLICY_MIN_LENGTH"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-password-policy-min-character-classes",
+		Usage:   "it is minimal amount of character classes (lowercase letters, uppercase letters, digits, other symbols) in holder password",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_POLICY_MIN_CHARACTER_CLASSES"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-password-policy-breached-passwords-file",
+		Usage:   "it is path to file with SHA-1 hashes of breached passwords in k-anonymity range format sorted by hash, screening is disabled if it is empty",
+		Value:   "",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_POLICY_BREACHED_PASSWORDS_FILE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-password-resets-code-age",
+		Usage:   "it is age of password reset code",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_PASSWORD_RESETS_CODE_AGE"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-transport",
+		Usage:   "it is transport which delivers emails (smtp, smtps, http, maildir)",
+		Value:   "smtps",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_TRANSPORT"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-smtp-host",
+		Usage:   "it is SMTP server host",
+		Value:   "smtp.sendgrid.net",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_SMTP_HOST"},
+	},
+	&cli.StringFlag{
+		Name:  "nw-emailer-smtp-port",
+		Usage: "it is SMTP server port",
+		Value: "465",
+		// 25, 587	(for smtp transport, connection is upgraded with STARTTLS)
+		// 465	(for smtps transport, connection is TLS from the start)
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_SMTP_PORT"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-sender-username",
+		Usage:   "it is emailer sender's username. It is needed for authentication",
+		Value:   "apikey",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_SENDER_USERNAME"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-sender-password",
+		Usage:   "it is emailer sender's password. It is needed for authentication",
+		Value:   "secret-apikey",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_SENDER_PASSWORD"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-sender-email-address",
+		Usage:   "it is emailer sender's email address. It is needed for putting it to message",
+		Value:   "example@mail.com",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_SENDER_EMAIL_ADDRESS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-smtp-timeout",
+		Usage:   "it is timeout of SMTP session which is used by smtp and smtps transports",
+		Value:   30 * time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_SMTP_TIMEOUT"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-http-url",
+		Usage:   "it is URL of email provider API which is used by http transport",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_HTTP_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-http-authorization",
+		Usage:   "it is value of Authorization header of requests to email provider API",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_HTTP_AUTHORIZATION"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-http-content-type",
+		Usage:   "it is content type of requests to email provider API",
+		Value:   "application/json",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_HTTP_CONTENT_TYPE"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-http-body-template",
+		Usage:   "it is text/template of request body to email provider API, it is executed with message fields From, To, Cc, Subject, HTMLBody and TextBody",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_HTTP_BODY_TEMPLATE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-http-timeout",
+		Usage:   "it is timeout of requests to email provider API",
+		Value:   10 * time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_HTTP_TIMEOUT"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-maildir-path",
+		Usage:   "it is path of maildir where maildir transport delivers emails",
+		Value:   "sent_emails",
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_MAILDIR_PATH"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-emailer-reset-holder-password-max-requests",
+		Usage:   "it is rate limit value for maximal amount of password reset emails for some interval",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_RESET_HOLDER_PASSWORD_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-reset-holder-password-interval",
+		Usage:   "it is rate limit value for interval when we measure password reset emails",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_RESET_HOLDER_PASSWORD_INTERVAL"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-emailer-sender-max-requests",
+		Usage:   "it is rate limit value for maximal amount of emails which are sent by sender email address for some interval, zero disables the limit",
+		Value:   1000,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_SENDER_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-sender-interval",
+		Usage:   "it is rate limit value for interval when we measure all emails of sender email address",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_ADMIN_EMAILER_SENDER_INTERVAL"},
+	},
 }
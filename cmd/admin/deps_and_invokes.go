@@ -7,11 +7,18 @@ import (
 	"github.com/ecumenos-social/network-warden/repository"
 	"github.com/ecumenos-social/network-warden/services/adminauth"
 	"github.com/ecumenos-social/network-warden/services/admins"
+	"github.com/ecumenos-social/network-warden/services/auth"
+	"github.com/ecumenos-social/network-warden/services/emailer"
+	"github.com/ecumenos-social/network-warden/services/holders"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
 	"github.com/ecumenos-social/network-warden/services/jwt"
+	loginthrottles "github.com/ecumenos-social/network-warden/services/login-throttles"
 	networknodes "github.com/ecumenos-social/network-warden/services/network-nodes"
 	networkwardens "github.com/ecumenos-social/network-warden/services/network-wardens"
+	passwordresets "github.com/ecumenos-social/network-warden/services/password-resets"
+	"github.com/ecumenos-social/network-warden/services/passwords"
 	personaldatanodes "github.com/ecumenos-social/network-warden/services/personal-data-nodes"
+	sessionbinding "github.com/ecumenos-social/network-warden/services/session-binding"
 	"github.com/ecumenos-social/toolkitfx"
 	"github.com/ecumenos-social/toolkitfx/fxgrpc"
 	"github.com/ecumenos-social/toolkitfx/fxlogger"
@@ -32,14 +39,28 @@ var Dependencies = fx.Options(
 		admins.New,
 		adminauth.New,
 		jwt.New,
+		loginthrottles.New,
+		sessionbinding.New,
+		passwords.NewHasher,
 		personaldatanodes.New,
 		networkwardens.New,
 		networknodes.New,
+		holders.New,
+		auth.New,
+		passwordresets.New,
+		emailer.New,
+		passwords.NewPolicy,
 		idgenerators.NewAdminsIDGenerator,
 		idgenerators.NewAdminSessionsIDGenerator,
 		idgenerators.NewPersonalDataNodesIDGenerator,
 		idgenerators.NewNetworkNodesIDGenerator,
 		idgenerators.NewNetworkWardensIDGenerator,
+		idgenerators.NewLoginThrottlesIDGenerator,
+		idgenerators.NewHoldersIDGenerator,
+		idgenerators.NewHolderSessionsIDGenerator,
+		idgenerators.NewHolderPasswordResetsIDGenerator,
+		idgenerators.NewSentEmailsIDGenerator,
+		idgenerators.NewEmailSuppressionsIDGenerator,
 		pgseeds.New,
 	),
 )
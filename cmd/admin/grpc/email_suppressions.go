@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ecumenos-social/network-warden/converters"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/jwt"
+	"github.com/ecumenos-social/schemas/formats"
+	commonv1 "github.com/ecumenos-social/schemas/proto/gen/common/v1"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Email suppression request and response messages stand for the RPC messages
+// until they are published in schemas, the methods are served by HTTP gateway
+// only.
+type EmailSuppression struct {
+	Id             string  `json:"id"`
+	CreatedAt      string  `json:"createdAt"`
+	LastModifiedAt string  `json:"lastModifiedAt"`
+	Email          string  `json:"email"`
+	Reason         string  `json:"reason"`
+	Provider       string  `json:"provider"`
+	Details        *string `json:"details,omitempty"`
+}
+
+type GetEmailSuppressionsListRequest struct {
+	Token            string               `json:"token"`
+	RemoteMacAddress *string              `json:"remoteMacAddress,omitempty"`
+	Pagination       *commonv1.Pagination `json:"pagination,omitempty"`
+}
+
+type GetEmailSuppressionsListResponse struct {
+	Data []*EmailSuppression `json:"data"`
+}
+
+type RemoveEmailSuppressionRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Email            string  `json:"email"`
+}
+
+type RemoveEmailSuppressionResponse struct {
+	Success bool `json:"success"`
+}
+
+func convertEmailSuppression(es *models.EmailSuppression) *EmailSuppression {
+	out := &EmailSuppression{
+		Id:             fmt.Sprint(es.ID),
+		CreatedAt:      formats.FormatDateTime(es.CreatedAt),
+		LastModifiedAt: formats.FormatDateTime(es.LastModifiedAt),
+		Email:          es.Email,
+		Reason:         string(es.Reason),
+		Provider:       es.Provider,
+	}
+	if es.Details.Valid {
+		out.Details = lo.ToPtr(es.Details.String)
+	}
+
+	return out
+}
+
+func (h *Handler) GetEmailSuppressionsList(ctx context.Context, req *GetEmailSuppressionsListRequest) (*GetEmailSuppressionsListResponse, error) {
+	logger := h.customizeLogger(ctx, "GetEmailSuppressionsList")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	ess, err := h.emailer.GetSuppressions(ctx, logger, converters.ConvertProtoPaginationToPagination(req.Pagination))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get email suppressions list, err=%v", err.Error())
+	}
+	data := make([]*EmailSuppression, 0, len(ess))
+	for _, es := range ess {
+		data = append(data, convertEmailSuppression(es))
+	}
+
+	return &GetEmailSuppressionsListResponse{
+		Data: data,
+	}, nil
+}
+
+// RemoveEmailSuppression lets emails be sent to address again, e.g. after
+// holder fixed their mailbox.
+func (h *Handler) RemoveEmailSuppression(ctx context.Context, req *RemoveEmailSuppressionRequest) (*RemoveEmailSuppressionResponse, error) {
+	logger := h.customizeLogger(ctx, "RemoveEmailSuppression")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	if strings.TrimSpace(req.Email) == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+	if err := h.emailer.Unsuppress(ctx, logger, req.Email); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed remove email suppression, err=%v", err.Error())
+	}
+
+	return &RemoveEmailSuppressionResponse{Success: true}, nil
+}
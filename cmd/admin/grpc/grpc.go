@@ -2,11 +2,13 @@ package grpc
 
 import (
 	"context"
+	"encoding/json"
 	"net"
 	"net/http"
 	"time"
 
 	grpcutils "github.com/ecumenos-social/grpc-utils"
+	sessionbinding "github.com/ecumenos-social/network-warden/services/session-binding"
 	pbv1 "github.com/ecumenos-social/schemas/proto/gen/networkwarden/v1"
 	"github.com/ecumenos-social/toolkitfx"
 	"github.com/ecumenos-social/toolkitfx/fxgrpc"
@@ -15,7 +17,11 @@ import (
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 type grpcServerParams struct {
@@ -125,6 +131,7 @@ func RunHTTPGateway(
 	logger *zap.Logger,
 	cfg *fxgrpc.Config,
 	g *fxgrpc.HTTPGatewayHandler,
+	handler *Handler,
 ) error {
 	httpAddr := net.JoinHostPort(cfg.HTTPGateway.Host, cfg.HTTPGateway.Port)
 	mux := runtime.NewServeMux()
@@ -141,6 +148,51 @@ func RunHTTPGateway(
 	if err := g.Handler(context.Background(), mux, conn.Connection); err != nil {
 		logger.Error("failed to register mapping service handler", zap.Error(err))
 	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/ListAdminSessions", httpMethodHandler(mux, handler.ListAdminSessions)); err != nil {
+		logger.Error("failed to register ListAdminSessions handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/RevokeAdminSession", httpMethodHandler(mux, handler.RevokeAdminSession)); err != nil {
+		logger.Error("failed to register RevokeAdminSession handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/RevokeAllOtherSessions", httpMethodHandler(mux, handler.RevokeAllOtherSessions)); err != nil {
+		logger.Error("failed to register RevokeAllOtherSessions handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/GetHoldersList", httpMethodHandler(mux, handler.GetHoldersList)); err != nil {
+		logger.Error("failed to register GetHoldersList handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/GetHolderByID", httpMethodHandler(mux, handler.GetHolderByID)); err != nil {
+		logger.Error("failed to register GetHolderByID handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/ConfirmHolder", httpMethodHandler(mux, handler.ConfirmHolder)); err != nil {
+		logger.Error("failed to register ConfirmHolder handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/SuspendHolder", httpMethodHandler(mux, handler.SuspendHolder)); err != nil {
+		logger.Error("failed to register SuspendHolder handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/UnsuspendHolder", httpMethodHandler(mux, handler.UnsuspendHolder)); err != nil {
+		logger.Error("failed to register UnsuspendHolder handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/RevokeHolderSessions", httpMethodHandler(mux, handler.RevokeHolderSessions)); err != nil {
+		logger.Error("failed to register RevokeHolderSessions handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/ResetHolderPassword", httpMethodHandler(mux, handler.ResetHolderPassword)); err != nil {
+		logger.Error("failed to register ResetHolderPassword handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/GetHolderNodes", httpMethodHandler(mux, handler.GetHolderNodes)); err != nil {
+		logger.Error("failed to register GetHolderNodes handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/GetLoginThrottlesList", httpMethodHandler(mux, handler.GetLoginThrottlesList)); err != nil {
+		logger.Error("failed to register GetLoginThrottlesList handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/ClearLoginThrottle", httpMethodHandler(mux, handler.ClearLoginThrottle)); err != nil {
+		logger.Error("failed to register ClearLoginThrottle handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/GetEmailSuppressionsList", httpMethodHandler(mux, handler.GetEmailSuppressionsList)); err != nil {
+		logger.Error("failed to register GetEmailSuppressionsList handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.AdminService/RemoveEmailSuppression", httpMethodHandler(mux, handler.RemoveEmailSuppression)); err != nil {
+		logger.Error("failed to register RemoveEmailSuppression handler", zap.Error(err))
+	}
 
 	var httpServer *http.Server
 	lc.Append(fx.Hook{
@@ -171,3 +223,46 @@ func RunHTTPGateway(
 
 	return nil
 }
+
+// httpMethodContext passes remote address, correlation ID and device key
+// metadata of HTTP request the same way gRPC does.
+func httpMethodContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+	}
+	md := metadata.MD{}
+	if corrID := r.Header.Get("Correlation-Id"); corrID != "" {
+		md.Set("correlation-id", corrID)
+	}
+	for _, key := range []string{sessionbinding.DeviceKeyMetadataKey, sessionbinding.DeviceKeyProofMetadataKey} {
+		if value := r.Header.Get(runtime.MetadataHeaderPrefix + key); value != "" {
+			md.Set(key, value)
+		}
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	return runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{})
+}
+
+// httpMethodHandler serves handler method which has no RPC in schemas yet. It
+// decodes JSON request body and encodes errors the same way HTTP gateway does.
+func httpMethodHandler[Req, Resp any](mux *runtime.ServeMux, method func(context.Context, *Req) (*Resp, error)) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := httpMethodContext(r)
+		marshaler := &runtime.JSONPb{}
+
+		var req Req
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		resp, err := method(ctx, &req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -2,26 +2,35 @@ package grpc
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	grpcutils "github.com/ecumenos-social/grpc-utils"
 	"github.com/ecumenos-social/network-warden/converters"
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/network-warden/services/adminauth"
 	"github.com/ecumenos-social/network-warden/services/admins"
+	"github.com/ecumenos-social/network-warden/services/auth"
+	"github.com/ecumenos-social/network-warden/services/emailer"
+	"github.com/ecumenos-social/network-warden/services/holders"
 	"github.com/ecumenos-social/network-warden/services/jwt"
+	loginthrottles "github.com/ecumenos-social/network-warden/services/login-throttles"
 	networknodes "github.com/ecumenos-social/network-warden/services/network-nodes"
 	networkwardens "github.com/ecumenos-social/network-warden/services/network-wardens"
+	passwordresets "github.com/ecumenos-social/network-warden/services/password-resets"
 	personaldatanodes "github.com/ecumenos-social/network-warden/services/personal-data-nodes"
+	sessionbinding "github.com/ecumenos-social/network-warden/services/session-binding"
 	pbv1 "github.com/ecumenos-social/schemas/proto/gen/networkwarden/v1"
 	"github.com/samber/lo"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 type Handler struct {
@@ -34,6 +43,12 @@ type Handler struct {
 	personalDataNodesService personaldatanodes.Service
 	networkNodesService      networknodes.Service
 	networkWardenService     networkwardens.Service
+	loginThrottles           loginthrottles.Service
+	sessionBinding           sessionbinding.Service
+	holders                  holders.Service
+	holderSessions           auth.Service
+	passwordResets           passwordresets.Service
+	emailer                  emailer.Service
 }
 
 var _ pbv1.AdminServiceServer = (*Handler)(nil)
@@ -48,6 +63,12 @@ type handlerParams struct {
 	PersonalDataNodesService personaldatanodes.Service
 	NetworkNodesService      networknodes.Service
 	NetworkWardenService     networkwardens.Service
+	LoginThrottlesService    loginthrottles.Service
+	SessionBindingService    sessionbinding.Service
+	HoldersService           holders.Service
+	HolderSessionsService    auth.Service
+	PasswordResetsService    passwordresets.Service
+	EmailerService           emailer.Service
 }
 
 func NewHandler(params handlerParams) *Handler {
@@ -58,6 +79,12 @@ func NewHandler(params handlerParams) *Handler {
 		personalDataNodesService: params.PersonalDataNodesService,
 		networkNodesService:      params.NetworkNodesService,
 		networkWardenService:     params.NetworkWardenService,
+		loginThrottles:           params.LoginThrottlesService,
+		sessionBinding:           params.SessionBindingService,
+		holders:                  params.HoldersService,
+		holderSessions:           params.HolderSessionsService,
+		passwordResets:           params.PasswordResetsService,
+		emailer:                  params.EmailerService,
 
 		logger: params.Logger,
 	}
@@ -83,6 +110,10 @@ func (h *Handler) customizeLogger(ctx context.Context, operationName string) *za
 }
 
 func (h *Handler) createSession(ctx context.Context, logger *zap.Logger, adminID int64, ip string, mac *string) (string, string, error) {
+	deviceKey, err := h.sessionBinding.DeviceKey(logger, incomingMetadataValue(ctx, sessionbinding.DeviceKeyMetadataKey))
+	if err != nil {
+		return "", "", status.Errorf(codes.InvalidArgument, "invalid device key (error = %v)", err.Error())
+	}
 	token, refreshToken, err := h.jwt.CreateTokens(ctx, logger, fmt.Sprint(adminID))
 	if err != nil {
 		return "", "", status.Errorf(codes.Internal, "failed create tokens (error = %v)", err.Error())
@@ -94,6 +125,7 @@ func (h *Handler) createSession(ctx context.Context, logger *zap.Logger, adminID
 		RefreshToken:     refreshToken,
 		RemoteIPAddress:  ip,
 		RemoteMACAddress: mac,
+		DeviceKey:        deviceKey,
 	})
 	if err != nil {
 		return "", "", status.Errorf(codes.Internal, "failed create session (error = %v)", err.Error())
@@ -120,37 +152,107 @@ func (h *Handler) parseToken(ctx context.Context, logger *zap.Logger, token stri
 	if err != nil {
 		return nil, status.Error(codes.Unauthenticated, err.Error())
 	}
-	if as.RemoteIPAddress.Valid && as.RemoteIPAddress.String != grpcutils.ExtractRemoteIPAddress(ctx) {
-		logger.Error("remote IP address doesn't match with session's remote IP address", zap.String("session-remote-ip-address", as.RemoteIPAddress.String), zap.String("incoming-remote-ip-address", grpcutils.ExtractRemoteIPAddress(ctx)))
-		return nil, status.Error(codes.PermissionDenied, "no permissions")
-	}
+	// remote MAC address is supplied by client, so it is only logged and
+	// session binding policy decides whether request may use the session
 	if remoteMacAddress != nil {
 		logger = logger.With(zap.String("incoming-remote-mac-address", *remoteMacAddress))
-		if as.RemoteMACAddress.Valid && as.RemoteMACAddress.String != *remoteMacAddress {
-			logger.Error("remote MAC address doesn't match with session's remote MAC address", zap.String("session-remote-mac-address", as.RemoteMACAddress.String))
-			return nil, status.Error(codes.PermissionDenied, "no permissions")
-		}
+	}
+	err = h.sessionBinding.Check(logger, &sessionbinding.Binding{
+		RemoteIPAddress: as.RemoteIPAddress,
+		DeviceKey:       as.DeviceKey,
+	}, &sessionbinding.Request{
+		RemoteIPAddress: grpcutils.ExtractRemoteIPAddress(ctx),
+		Token:           token,
+		DeviceKeyProof:  incomingMetadataValue(ctx, sessionbinding.DeviceKeyProofMetadataKey),
+	})
+	if err != nil {
+		return nil, h.sessionBindingMismatchError(ctx, logger, as, err)
+	}
+	if err := h.auth.MarkAdminSessionUsed(ctx, logger, as); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed modify session (error = %v)", err.Error())
 	}
 
 	return as, nil
 }
 
+// sessionBindingMismatchError handles request which doesn't match session
+// binding according to mismatch action. Admins have no second factor, so
+// step-up works as reauthentication, both expire the session.
+func (h *Handler) sessionBindingMismatchError(ctx context.Context, logger *zap.Logger, as *models.AdminSession, err error) error {
+	var mismatchErr *sessionbinding.MismatchError
+	if !errors.As(err, &mismatchErr) {
+		return status.Errorf(codes.Internal, "failed check session binding (error = %v)", err.Error())
+	}
+	if mismatchErr.Action == sessionbinding.MismatchActionDeny {
+		return sessionBindingError(codes.PermissionDenied, "no permissions", "SESSION_BINDING_MISMATCH")
+	}
+
+	if err := h.auth.MakeAdminSessionExpired(ctx, logger, as.ID, as); err != nil {
+		return status.Errorf(codes.Internal, "failed expire session (error = %v)", err.Error())
+	}
+
+	return sessionBindingError(codes.Unauthenticated, "reauthentication required", "REAUTHENTICATION_REQUIRED")
+}
+
+func sessionBindingError(code codes.Code, message, reason string) error {
+	st, err := status.New(code, message).WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: "networkwarden.v1",
+	})
+	if err != nil {
+		return status.Error(code, message)
+	}
+
+	return st.Err()
+}
+
+// incomingMetadataValue returns the first value of incoming metadata key.
+func incomingMetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
 func (h *Handler) LoginAdmin(ctx context.Context, req *pbv1.AdminServiceLoginAdminRequest) (*pbv1.AdminServiceLoginAdminResponse, error) {
 	logger := h.customizeLogger(ctx, "LoginAdmin")
 	defer logger.Info("request processed")
 
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+
 	admin, err := h.admins.GetAdminByEmailOrPhoneNumber(ctx, logger, req.Email, req.PhoneNumber)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed get admin, err=%v", err.Error())
 	}
 	if admin == nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
 		return nil, status.Error(codes.InvalidArgument, "admin not found")
 	}
 	logger = logger.With(zap.Int64("admin-id", admin.ID))
 
+	accountKey := loginthrottles.AdminKey(admin.ID)
+	if err := h.loginThrottles.Check(ctx, logger, accountKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
 	if err := h.admins.ValidatePassword(ctx, logger, admin, req.Password); err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey, accountKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
 		return nil, status.Error(codes.InvalidArgument, "invalid password")
 	}
+	if err := h.loginThrottles.RegisterSuccess(ctx, logger, accountKey); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed register successful login attempt (error = %v)", err.Error())
+	}
 	token, refreshToken, err := h.createSession(ctx, logger, admin.ID, grpcutils.ExtractRemoteIPAddress(ctx), lo.ToPtr(req.RemoteMacAddress))
 	if err != nil {
 		return nil, err
@@ -177,13 +279,10 @@ func (h *Handler) RefreshAdminToken(ctx context.Context, req *pbv1.AdminServiceR
 		return nil, status.Errorf(codes.Internal, "failed create tokens (error = %v)", err.Error())
 	}
 
-	as.Token = token
-	as.RefreshToken = refreshToken
-	as.ExpiredAt = sql.NullTime{
-		Time:  h.auth.GetExpiredAtForAdminSession(),
-		Valid: true,
-	}
-	if err := h.auth.ModifyAdminSession(ctx, logger, as.AdminID, as); err != nil {
+	if err := h.auth.RotateAdminSessionTokens(ctx, logger, as, token, refreshToken); err != nil {
+		if errors.Is(err, adminauth.ErrRefreshTokenReused) {
+			return nil, status.Error(codes.Unauthenticated, "refresh token was already used")
+		}
 		return nil, status.Errorf(codes.Internal, "failed modify session (error = %v)", err.Error())
 	}
 
@@ -435,3 +534,41 @@ func (h *Handler) GetNetworkWardenByID(ctx context.Context, req *pbv1.AdminServi
 		Data: converters.ConvertNetworkWardenToProtoNetworkWarden(nw),
 	}, nil
 }
+
+// loginThrottledError converts error of login throttles check to status,
+// throttled logins get ResourceExhausted with retry delay in details.
+func loginThrottledError(err error) error {
+	var te *loginthrottles.ThrottledError
+	if !errors.As(err, &te) {
+		return status.Errorf(codes.Internal, "failed check login attempts (error = %v)", err.Error())
+	}
+
+	return retryAfterError(te.Error(), te.RetryAfter)
+}
+
+// retryAfterError is ResourceExhausted status with retry delay in details.
+func retryAfterError(msg string, retryAfter time.Duration) error {
+	st, detailsErr := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryAfter),
+	})
+	if detailsErr != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+
+	return st.Err()
+}
+
+// sendEmailError converts error of emailer to status. Exceeded rate limit
+// gets ResourceExhausted with retry delay in details, suppressed email
+// address gets FailedPrecondition, other errors are internal.
+func sendEmailError(err error, msg string) error {
+	var rle *emailer.RateLimitedError
+	switch {
+	case errors.As(err, &rle):
+		return retryAfterError(rle.Error(), rle.RetryAfter)
+	case errors.Is(err, emailer.ErrSuppressedEmail):
+		return status.Error(codes.FailedPrecondition, "holder's email address is in the suppression list")
+	}
+
+	return status.Errorf(codes.Internal, "%s, err=%v", msg, err.Error())
+}
@@ -0,0 +1,373 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ecumenos-social/network-warden/converters"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/emailer"
+	"github.com/ecumenos-social/network-warden/services/jwt"
+	"github.com/ecumenos-social/schemas/formats"
+	commonv1 "github.com/ecumenos-social/schemas/proto/gen/common/v1"
+	pbv1 "github.com/ecumenos-social/schemas/proto/gen/networkwarden/v1"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Holder management request and response messages stand for the RPC messages
+// until they are published in schemas, the methods are served by HTTP gateway
+// only.
+type AdminHolder struct {
+	Id                string   `json:"id"`
+	CreatedAt         string   `json:"createdAt"`
+	LastModifiedAt    string   `json:"lastModifiedAt"`
+	Emails            []string `json:"emails"`
+	PhoneNumbers      []string `json:"phoneNumbers"`
+	AvatarImageUrl    *string  `json:"avatarImageUrl,omitempty"`
+	Countries         []string `json:"countries"`
+	Languages         []string `json:"languages"`
+	Confirmed         bool     `json:"confirmed"`
+	DisplayName       string   `json:"displayName"`
+	LegalName         *string  `json:"legalName,omitempty"`
+	PreferredLanguage *string  `json:"preferredLanguage,omitempty"`
+	Timezone          *string  `json:"timezone,omitempty"`
+	SuspendedAt       *string  `json:"suspendedAt,omitempty"`
+	DeletionDueAt     *string  `json:"deletionDueAt,omitempty"`
+}
+
+type GetHoldersListRequest struct {
+	Token            string               `json:"token"`
+	RemoteMacAddress *string              `json:"remoteMacAddress,omitempty"`
+	Pagination       *commonv1.Pagination `json:"pagination,omitempty"`
+	Confirmed        *bool                `json:"confirmed,omitempty"`
+	Suspended        *bool                `json:"suspended,omitempty"`
+	Country          string               `json:"country,omitempty"`
+	Language         string               `json:"language,omitempty"`
+	// CreatedAfter and CreatedBefore are RFC 3339 timestamps.
+	CreatedAfter  string `json:"createdAfter,omitempty"`
+	CreatedBefore string `json:"createdBefore,omitempty"`
+	// Contact is substring of email or phone number.
+	Contact string `json:"contact,omitempty"`
+}
+
+type GetHoldersListResponse struct {
+	Data []*AdminHolder `json:"data"`
+}
+
+type HolderRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Id               string  `json:"id"`
+}
+
+type GetHolderByIDResponse struct {
+	Data *AdminHolder `json:"data"`
+}
+
+type HolderActionResponse struct {
+	Success bool `json:"success"`
+}
+
+type GetHolderNodesRequest struct {
+	Token            string               `json:"token"`
+	RemoteMacAddress *string              `json:"remoteMacAddress,omitempty"`
+	Id               string               `json:"id"`
+	Pagination       *commonv1.Pagination `json:"pagination,omitempty"`
+}
+
+type GetHolderNodesResponse struct {
+	NetworkNodes      []*pbv1.NetworkNode      `json:"networkNodes"`
+	PersonalDataNodes []*pbv1.PersonalDataNode `json:"personalDataNodes"`
+}
+
+func convertHolderToAdminHolder(holder *models.Holder) *AdminHolder {
+	out := &AdminHolder{
+		Id:             fmt.Sprint(holder.ID),
+		CreatedAt:      formats.FormatDateTime(holder.CreatedAt),
+		LastModifiedAt: formats.FormatDateTime(holder.LastModifiedAt),
+		Emails:         holder.Emails,
+		PhoneNumbers:   holder.PhoneNumbers,
+		Countries:      holder.Countries,
+		Languages:      holder.Languages,
+		Confirmed:      holder.Confirmed,
+		DisplayName:    holder.DisplayName,
+	}
+	if holder.AvatarImageURL.Valid {
+		out.AvatarImageUrl = lo.ToPtr(holder.AvatarImageURL.String)
+	}
+	if holder.LegalName.Valid {
+		out.LegalName = lo.ToPtr(holder.LegalName.String)
+	}
+	if holder.PreferredLanguage.Valid {
+		out.PreferredLanguage = lo.ToPtr(holder.PreferredLanguage.String)
+	}
+	if holder.Timezone.Valid {
+		out.Timezone = lo.ToPtr(holder.Timezone.String)
+	}
+	if holder.SuspendedAt.Valid {
+		out.SuspendedAt = lo.ToPtr(formats.FormatDateTime(holder.SuspendedAt.Time))
+	}
+	if holder.DeletionDueAt.Valid {
+		out.DeletionDueAt = lo.ToPtr(formats.FormatDateTime(holder.DeletionDueAt.Time))
+	}
+
+	return out
+}
+
+func parseTimeFilter(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// getHolder returns holder by incoming ID, it fails if there is no such
+// holder.
+func (h *Handler) getHolder(ctx context.Context, logger *zap.Logger, id string) (*models.Holder, error) {
+	holderID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		logger.Error("invalid ID", zap.Error(err), zap.String("incoming-holder-id", id))
+		return nil, status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	holder, err := h.holders.GetHolderByID(ctx, logger, holderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		logger.Error("holder not found", zap.Int64("holder-id", holderID))
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
+	}
+
+	return holder, nil
+}
+
+func (h *Handler) GetHoldersList(ctx context.Context, req *GetHoldersListRequest) (*GetHoldersListResponse, error) {
+	logger := h.customizeLogger(ctx, "GetHoldersList")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	createdAfter, err := parseTimeFilter(req.CreatedAfter)
+	if err != nil {
+		logger.Error("invalid created after", zap.Error(err), zap.String("created-after", req.CreatedAfter))
+		return nil, status.Error(codes.InvalidArgument, "invalid created after")
+	}
+	createdBefore, err := parseTimeFilter(req.CreatedBefore)
+	if err != nil {
+		logger.Error("invalid created before", zap.Error(err), zap.String("created-before", req.CreatedBefore))
+		return nil, status.Error(codes.InvalidArgument, "invalid created before")
+	}
+	filter := &models.HoldersFilter{
+		Confirmed:     req.Confirmed,
+		Suspended:     req.Suspended,
+		Country:       req.Country,
+		Language:      req.Language,
+		CreatedAfter:  createdAfter,
+		CreatedBefore: createdBefore,
+		Contact:       req.Contact,
+	}
+	hs, err := h.holders.GetList(ctx, logger, filter, converters.ConvertProtoPaginationToPagination(req.Pagination))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holders list, err=%v", err.Error())
+	}
+	data := make([]*AdminHolder, 0, len(hs))
+	for _, holder := range hs {
+		data = append(data, convertHolderToAdminHolder(holder))
+	}
+
+	return &GetHoldersListResponse{
+		Data: data,
+	}, nil
+}
+
+func (h *Handler) GetHolderByID(ctx context.Context, req *HolderRequest) (*GetHolderByIDResponse, error) {
+	logger := h.customizeLogger(ctx, "GetHolderByID")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	holder, err := h.getHolder(ctx, logger, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetHolderByIDResponse{
+		Data: convertHolderToAdminHolder(holder),
+	}, nil
+}
+
+func (h *Handler) ConfirmHolder(ctx context.Context, req *HolderRequest) (*HolderActionResponse, error) {
+	logger := h.customizeLogger(ctx, "ConfirmHolder")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	holder, err := h.getHolder(ctx, logger, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+	if _, err := h.holders.ForceConfirm(ctx, logger, holder); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, "failed to confirm holder")
+	}
+
+	return &HolderActionResponse{Success: true}, nil
+}
+
+func (h *Handler) SuspendHolder(ctx context.Context, req *HolderRequest) (*HolderActionResponse, error) {
+	logger := h.customizeLogger(ctx, "SuspendHolder")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	holder, err := h.getHolder(ctx, logger, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+	if _, err := h.holders.Suspend(ctx, logger, holder); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, "failed to suspend holder")
+	}
+	// suspended holder must not keep sessions which were created before.
+	if err := h.holderSessions.MakeHolderSessionsExpired(ctx, logger, holder.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed revoke holder sessions, err=%v", err.Error())
+	}
+
+	return &HolderActionResponse{Success: true}, nil
+}
+
+func (h *Handler) UnsuspendHolder(ctx context.Context, req *HolderRequest) (*HolderActionResponse, error) {
+	logger := h.customizeLogger(ctx, "UnsuspendHolder")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	holder, err := h.getHolder(ctx, logger, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+	if _, err := h.holders.Unsuspend(ctx, logger, holder); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, "failed to unsuspend holder")
+	}
+
+	return &HolderActionResponse{Success: true}, nil
+}
+
+func (h *Handler) RevokeHolderSessions(ctx context.Context, req *HolderRequest) (*HolderActionResponse, error) {
+	logger := h.customizeLogger(ctx, "RevokeHolderSessions")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	holder, err := h.getHolder(ctx, logger, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+	if err := h.holderSessions.MakeHolderSessionsExpired(ctx, logger, holder.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed revoke holder sessions, err=%v", err.Error())
+	}
+
+	return &HolderActionResponse{Success: true}, nil
+}
+
+// ResetHolderPassword issues password reset code and emails it to the first
+// email of holder.
+func (h *Handler) ResetHolderPassword(ctx context.Context, req *HolderRequest) (*HolderActionResponse, error) {
+	logger := h.customizeLogger(ctx, "ResetHolderPassword")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	holder, err := h.getHolder(ctx, logger, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+	if len(holder.Emails) == 0 {
+		logger.Error("holder has no email")
+		return nil, status.Error(codes.FailedPrecondition, "holder has no email")
+	}
+	email := holder.Emails[0]
+	if err := h.emailer.CanSendResetHolderPassword(ctx, logger, email); err != nil {
+		return nil, sendEmailError(err, "failed check password reset email rate limit")
+	}
+	pr, code, err := h.passwordResets.Issue(ctx, logger, holder.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed issue password reset, err=%v", err.Error())
+	}
+	if err := h.emailer.SendResetHolderPassword(ctx, logger, emailer.HolderRecipient(holder, email), code, time.Until(pr.ExpiredAt)); err != nil {
+		return nil, sendEmailError(err, "failed send password reset email")
+	}
+
+	return &HolderActionResponse{Success: true}, nil
+}
+
+func (h *Handler) GetHolderNodes(ctx context.Context, req *GetHolderNodesRequest) (*GetHolderNodesResponse, error) {
+	logger := h.customizeLogger(ctx, "GetHolderNodes")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	holder, err := h.getHolder(ctx, logger, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+	pagination := converters.ConvertProtoPaginationToPagination(req.Pagination)
+	nns, err := h.networkNodesService.GetList(ctx, logger, holder.ID, pagination, true)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get NNs list, err=%v", err.Error())
+	}
+	pdns, err := h.personalDataNodesService.GetList(ctx, logger, holder.ID, pagination, true)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get PDNs list, err=%v", err.Error())
+	}
+	resp := &GetHolderNodesResponse{
+		NetworkNodes:      make([]*pbv1.NetworkNode, 0, len(nns)),
+		PersonalDataNodes: make([]*pbv1.PersonalDataNode, 0, len(pdns)),
+	}
+	for _, nn := range nns {
+		resp.NetworkNodes = append(resp.NetworkNodes, converters.ConvertNetworkNodeToProtoNetworkNode(nn))
+	}
+	for _, pdn := range pdns {
+		resp.PersonalDataNodes = append(resp.PersonalDataNodes, converters.ConvertPersonalDataNodeToProtoPersonalDataNode(pdn))
+	}
+
+	return resp, nil
+}
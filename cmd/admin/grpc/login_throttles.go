@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/ecumenos-social/network-warden/converters"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/jwt"
+	"github.com/ecumenos-social/schemas/formats"
+	commonv1 "github.com/ecumenos-social/schemas/proto/gen/common/v1"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Login throttle request and response messages stand for the RPC messages
+// until they are published in schemas, the methods are served by HTTP gateway
+// only.
+type LoginThrottle struct {
+	Id             string  `json:"id"`
+	CreatedAt      string  `json:"createdAt"`
+	LastModifiedAt string  `json:"lastModifiedAt"`
+	Kind           string  `json:"kind"`
+	Subject        string  `json:"subject"`
+	FailedAttempts int64   `json:"failedAttempts"`
+	LastFailedAt   string  `json:"lastFailedAt"`
+	LockedUntil    *string `json:"lockedUntil,omitempty"`
+}
+
+type GetLoginThrottlesListRequest struct {
+	Token            string               `json:"token"`
+	RemoteMacAddress *string              `json:"remoteMacAddress,omitempty"`
+	Pagination       *commonv1.Pagination `json:"pagination,omitempty"`
+}
+
+type GetLoginThrottlesListResponse struct {
+	Data []*LoginThrottle `json:"data"`
+}
+
+type ClearLoginThrottleRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Id               string  `json:"id"`
+}
+
+type ClearLoginThrottleResponse struct {
+	Success bool `json:"success"`
+}
+
+func convertLoginThrottle(lt *models.LoginThrottle) *LoginThrottle {
+	out := &LoginThrottle{
+		Id:             fmt.Sprint(lt.ID),
+		CreatedAt:      formats.FormatDateTime(lt.CreatedAt),
+		LastModifiedAt: formats.FormatDateTime(lt.LastModifiedAt),
+		Kind:           lt.Kind,
+		Subject:        lt.Subject,
+		FailedAttempts: lt.FailedAttempts,
+		LastFailedAt:   formats.FormatDateTime(lt.LastFailedAt),
+	}
+	if lt.LockedUntil.Valid {
+		out.LockedUntil = lo.ToPtr(formats.FormatDateTime(lt.LockedUntil.Time))
+	}
+
+	return out
+}
+
+func (h *Handler) GetLoginThrottlesList(ctx context.Context, req *GetLoginThrottlesListRequest) (*GetLoginThrottlesListResponse, error) {
+	logger := h.customizeLogger(ctx, "GetLoginThrottlesList")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	lts, err := h.loginThrottles.GetList(ctx, logger, converters.ConvertProtoPaginationToPagination(req.Pagination))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get login throttles list, err=%v", err.Error())
+	}
+	data := make([]*LoginThrottle, 0, len(lts))
+	for _, lt := range lts {
+		data = append(data, convertLoginThrottle(lt))
+	}
+
+	return &GetLoginThrottlesListResponse{
+		Data: data,
+	}, nil
+}
+
+// ClearLoginThrottle removes failed login attempts and lockout of holder or
+// remote IP address, e.g. after support verified the holder.
+func (h *Handler) ClearLoginThrottle(ctx context.Context, req *ClearLoginThrottleRequest) (*ClearLoginThrottleResponse, error) {
+	logger := h.customizeLogger(ctx, "ClearLoginThrottle")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	id, err := strconv.ParseInt(req.Id, 10, 64)
+	if err != nil {
+		logger.Error("invalid login throttle ID", zap.Error(err), zap.String("incoming-login-throttle-id", req.Id))
+		return nil, status.Error(codes.InvalidArgument, "invalid login throttle ID")
+	}
+	if err := h.loginThrottles.Clear(ctx, logger, id); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed clear login throttle, err=%v", err.Error())
+	}
+
+	return &ClearLoginThrottleResponse{Success: true}, nil
+}
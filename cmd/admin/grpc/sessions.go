@@ -0,0 +1,144 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/jwt"
+	"github.com/ecumenos-social/schemas/formats"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Session request and response messages stand for the RPC messages until
+// they are published in schemas, the methods are served by HTTP gateway only.
+type AdminSession struct {
+	Id               string  `json:"id"`
+	CreatedAt        string  `json:"createdAt"`
+	ExpiredAt        *string `json:"expiredAt,omitempty"`
+	LastUsedAt       *string `json:"lastUsedAt,omitempty"`
+	RemoteIpAddress  *string `json:"remoteIpAddress,omitempty"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	DeviceBound      bool    `json:"deviceBound"`
+	Current          bool    `json:"current"`
+}
+
+type ListAdminSessionsRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type ListAdminSessionsResponse struct {
+	Data []*AdminSession `json:"data"`
+}
+
+type RevokeAdminSessionRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Id               string  `json:"id"`
+}
+
+type RevokeAdminSessionResponse struct {
+	Success bool `json:"success"`
+}
+
+type RevokeAllOtherSessionsRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type RevokeAllOtherSessionsResponse struct {
+	Success bool `json:"success"`
+}
+
+// convertAdminSession leaves out tokens, they must not leave the session
+// they belong to.
+func convertAdminSession(s *models.AdminSession, currentID int64) *AdminSession {
+	out := &AdminSession{
+		Id:          fmt.Sprint(s.ID),
+		CreatedAt:   formats.FormatDateTime(s.CreatedAt),
+		DeviceBound: s.DeviceKey.Valid,
+		Current:     s.ID == currentID,
+	}
+	if s.ExpiredAt.Valid {
+		out.ExpiredAt = lo.ToPtr(formats.FormatDateTime(s.ExpiredAt.Time))
+	}
+	if s.LastUsedAt.Valid {
+		out.LastUsedAt = lo.ToPtr(formats.FormatDateTime(s.LastUsedAt.Time))
+	}
+	if s.RemoteIPAddress.Valid {
+		out.RemoteIpAddress = lo.ToPtr(s.RemoteIPAddress.String)
+	}
+	if s.RemoteMACAddress.Valid {
+		out.RemoteMacAddress = lo.ToPtr(s.RemoteMACAddress.String)
+	}
+
+	return out
+}
+
+func (h *Handler) ListAdminSessions(ctx context.Context, req *ListAdminSessionsRequest) (*ListAdminSessionsResponse, error) {
+	logger := h.customizeLogger(ctx, "ListAdminSessions")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+
+	sessions, err := h.auth.ListAdminSessions(ctx, logger, as.AdminID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get admin sessions, err=%v", err.Error())
+	}
+	data := make([]*AdminSession, 0, len(sessions))
+	for _, s := range sessions {
+		data = append(data, convertAdminSession(s, as.ID))
+	}
+
+	return &ListAdminSessionsResponse{Data: data}, nil
+}
+
+func (h *Handler) RevokeAdminSession(ctx context.Context, req *RevokeAdminSessionRequest) (*RevokeAdminSessionResponse, error) {
+	logger := h.customizeLogger(ctx, "RevokeAdminSession")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+	id, err := strconv.ParseInt(req.Id, 10, 64)
+	if err != nil {
+		logger.Error("invalid session ID", zap.Error(err), zap.String("incoming-admin-session-id", req.Id))
+		return nil, status.Error(codes.InvalidArgument, "invalid session ID")
+	}
+
+	if err := h.auth.RevokeAdminSession(ctx, logger, as.AdminID, id); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to revoke session, err=%v", err.Error())
+	}
+
+	return &RevokeAdminSessionResponse{Success: true}, nil
+}
+
+// RevokeAllOtherSessions expires every session of admin except the one
+// which made the request.
+func (h *Handler) RevokeAllOtherSessions(ctx context.Context, req *RevokeAllOtherSessionsRequest) (*RevokeAllOtherSessionsResponse, error) {
+	logger := h.customizeLogger(ctx, "RevokeAllOtherSessions")
+	defer logger.Info("request processed")
+
+	as, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("admin-id", as.AdminID))
+
+	if err := h.auth.RevokeOtherAdminSessions(ctx, logger, as.AdminID, as.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke other sessions, err=%v", err.Error())
+	}
+
+	return &RevokeAllOtherSessionsResponse{Success: true}, nil
+}
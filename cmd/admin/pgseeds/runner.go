@@ -7,6 +7,7 @@ import (
 	"github.com/ecumenos-social/network-warden/cmd/admin/pgseeds/data"
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/network-warden/services/admins"
+	"github.com/ecumenos-social/network-warden/services/passwords"
 	"go.uber.org/zap"
 )
 
@@ -15,14 +16,16 @@ type Runner interface {
 }
 
 type runner struct {
-	logger     *zap.Logger
-	adminsRepo admins.Repository
+	logger         *zap.Logger
+	adminsRepo     admins.Repository
+	passwordHasher passwords.Hasher
 }
 
-func New(logger *zap.Logger, adminsRepo admins.Repository) Runner {
+func New(logger *zap.Logger, adminsRepo admins.Repository, passwordHasher passwords.Hasher) Runner {
 	return &runner{
-		logger:     logger,
-		adminsRepo: adminsRepo,
+		logger:         logger,
+		adminsRepo:     adminsRepo,
+		passwordHasher: passwordHasher,
 	}
 }
 
@@ -47,7 +50,7 @@ func (r *runner) seedHolders(ctx context.Context) error {
 			avatarImageURL.Valid = true
 			avatarImageURL.String = *a.AvatarImageURL
 		}
-		passwordHash, err := admins.HashPassword(a.Password)
+		passwordHash, err := r.passwordHasher.Hash(a.Password)
 		if err != nil {
 			return err
 		}
@@ -1,11 +1,22 @@
 package configurations
 
 import (
+	"github.com/ecumenos-social/network-warden/cmd/network-warden/jobs"
 	"github.com/ecumenos-social/network-warden/services/auth"
 	"github.com/ecumenos-social/network-warden/services/emailer"
+	holdercontacts "github.com/ecumenos-social/network-warden/services/holder-contacts"
+	"github.com/ecumenos-social/network-warden/services/holders"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
 	"github.com/ecumenos-social/network-warden/services/jwt"
+	loginlinks "github.com/ecumenos-social/network-warden/services/login-links"
+	loginthrottles "github.com/ecumenos-social/network-warden/services/login-throttles"
+	"github.com/ecumenos-social/network-warden/services/oidc"
+	"github.com/ecumenos-social/network-warden/services/passkeys"
+	passwordresets "github.com/ecumenos-social/network-warden/services/password-resets"
+	"github.com/ecumenos-social/network-warden/services/passwords"
+	sessionbinding "github.com/ecumenos-social/network-warden/services/session-binding"
 	smssender "github.com/ecumenos-social/network-warden/services/sms-sender"
+	twofactor "github.com/ecumenos-social/network-warden/services/two-factor"
 	"github.com/ecumenos-social/toolkit/types"
 	"github.com/ecumenos-social/toolkitfx"
 	"github.com/ecumenos-social/toolkitfx/fxgrpc"
@@ -18,26 +29,60 @@ import (
 type fxConfig struct {
 	fx.Out
 
-	App                          *toolkitfx.GenericAppConfig
-	AppSpecific                  *toolkitfx.NetworkWardenAppConfig
-	Logger                       *fxlogger.Config
-	GRPC                         *fxgrpc.Config
-	Postgres                     *fxpostgres.Config
-	HolderSessionsIDGenerator    *idgenerators.HolderSessionsIDGeneratorConfig
-	HoldersIDGenerator           *idgenerators.HoldersIDGeneratorConfig
-	SentEmailsIDGenerator        *idgenerators.SentEmailsIDGeneratorConfig
-	NetworkNodesIDGenerator      *idgenerators.NetworkNodesIDGeneratorConfig
-	PersonalDataNodesIDGenerator *idgenerators.PersonalDataNodesIDGeneratorConfig
-	NetworkWardensIDGenerator    *idgenerators.NetworkWardensIDGeneratorConfig
-	JWT                          *jwt.Config
-	Auth                         *auth.Config
-	Emailer                      *emailer.Config
-	SMSSender                    *smssender.Config
+	App                               *toolkitfx.GenericAppConfig
+	AppSpecific                       *toolkitfx.NetworkWardenAppConfig
+	Logger                            *fxlogger.Config
+	GRPC                              *fxgrpc.Config
+	Postgres                          *fxpostgres.Config
+	HolderSessionsIDGenerator         *idgenerators.HolderSessionsIDGeneratorConfig
+	HoldersIDGenerator                *idgenerators.HoldersIDGeneratorConfig
+	SentEmailsIDGenerator             *idgenerators.SentEmailsIDGeneratorConfig
+	EmailSuppressionsIDGenerator      *idgenerators.EmailSuppressionsIDGeneratorConfig
+	NetworkNodesIDGenerator           *idgenerators.NetworkNodesIDGeneratorConfig
+	PersonalDataNodesIDGenerator      *idgenerators.PersonalDataNodesIDGeneratorConfig
+	NetworkWardensIDGenerator         *idgenerators.NetworkWardensIDGeneratorConfig
+	LoginThrottlesIDGenerator         *idgenerators.LoginThrottlesIDGeneratorConfig
+	HolderPasswordResetsIDGenerator   *idgenerators.HolderPasswordResetsIDGeneratorConfig
+	HolderTOTPSecretsIDGenerator      *idgenerators.HolderTOTPSecretsIDGeneratorConfig
+	HolderRecoveryCodesIDGenerator    *idgenerators.HolderRecoveryCodesIDGeneratorConfig
+	HolderLoginChallengesIDGenerator  *idgenerators.HolderLoginChallengesIDGeneratorConfig
+	HolderContactsIDGenerator         *idgenerators.HolderContactsIDGeneratorConfig
+	OIDCAuthorizationCodesIDGenerator *idgenerators.OIDCAuthorizationCodesIDGeneratorConfig
+	OIDCAccessTokensIDGenerator       *idgenerators.OIDCAccessTokensIDGeneratorConfig
+	HolderPasskeysIDGenerator         *idgenerators.HolderPasskeysIDGeneratorConfig
+	WebAuthnChallengesIDGenerator     *idgenerators.WebAuthnChallengesIDGeneratorConfig
+	HolderLoginLinksIDGenerator       *idgenerators.HolderLoginLinksIDGeneratorConfig
+	SentSMSIDGenerator                *idgenerators.SentSMSIDGeneratorConfig
+	JWT                               *jwt.Config
+	Auth                              *auth.Config
+	Emailer                           *emailer.Config
+	SMSSender                         *smssender.Config
+	PasswordResets                    *passwordresets.Config
+	LoginLinks                        *loginlinks.Config
+	TwoFactor                         *twofactor.Config
+	LoginThrottles                    *loginthrottles.Config
+	SessionBinding                    *sessionbinding.Config
+	Holders                           *holders.Config
+	PasswordPolicy                    *passwords.PolicyConfig
+	PasswordHasher                    *passwords.HasherConfig
+	HolderContacts                    *holdercontacts.Config
+	OIDC                              *oidc.Config
+	Passkeys                          *passkeys.Config
+	Jobs                              *jobs.Config
 }
 
 var Module = func(cctx *cli.Context) fx.Option {
 	return fx.Options(
-		fx.Provide(func() fxConfig {
+		fx.Provide(func() (fxConfig, error) {
+			jwtKeys := make([]*jwt.Key, 0, len(cctx.StringSlice("nw-jwt-keys")))
+			for _, value := range cctx.StringSlice("nw-jwt-keys") {
+				key, err := jwt.ParseKey(value)
+				if err != nil {
+					return fxConfig{}, err
+				}
+				jwtKeys = append(jwtKeys, key)
+			}
+
 			return fxConfig{
 				App: &toolkitfx.GenericAppConfig{
 					ID:          cctx.Int64("nw-app-id"),
@@ -93,6 +138,10 @@ var Module = func(cctx *cli.Context) fx.Option {
 					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
 					LowNodeID: 0,
 				},
+				EmailSuppressionsIDGenerator: &idgenerators.EmailSuppressionsIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
 				NetworkNodesIDGenerator: &idgenerators.NetworkNodesIDGeneratorConfig{
 					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
 					LowNodeID: 0,
@@ -105,8 +154,59 @@ var Module = func(cctx *cli.Context) fx.Option {
 					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
 					LowNodeID: 0,
 				},
+				HolderPasswordResetsIDGenerator: &idgenerators.HolderPasswordResetsIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				HolderTOTPSecretsIDGenerator: &idgenerators.HolderTOTPSecretsIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				HolderRecoveryCodesIDGenerator: &idgenerators.HolderRecoveryCodesIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				HolderLoginChallengesIDGenerator: &idgenerators.HolderLoginChallengesIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				HolderContactsIDGenerator: &idgenerators.HolderContactsIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				OIDCAuthorizationCodesIDGenerator: &idgenerators.OIDCAuthorizationCodesIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				OIDCAccessTokensIDGenerator: &idgenerators.OIDCAccessTokensIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				HolderPasskeysIDGenerator: &idgenerators.HolderPasskeysIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				WebAuthnChallengesIDGenerator: &idgenerators.WebAuthnChallengesIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				HolderLoginLinksIDGenerator: &idgenerators.HolderLoginLinksIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				SentSMSIDGenerator: &idgenerators.SentSMSIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
+				LoginThrottlesIDGenerator: &idgenerators.LoginThrottlesIDGeneratorConfig{
+					TopNodeID: cctx.Int64("nw-app-id-gen-node"),
+					LowNodeID: 0,
+				},
 				JWT: &jwt.Config{
 					SigningKey:      cctx.String("nw-jwt-signing-key"),
+					SigningKeyID:    cctx.String("nw-jwt-signing-key-id"),
+					Keys:            jwtKeys,
+					Algorithm:       cctx.String("nw-jwt-algorithm"),
 					TokenAge:        cctx.Duration("nw-jwt-token-age"),
 					RefreshTokenAge: cctx.Duration("nw-jwt-refresh-token-age"),
 				},
@@ -114,18 +214,158 @@ var Module = func(cctx *cli.Context) fx.Option {
 					SessionAge: cctx.Duration("nw-auth-session-age"),
 				},
 				Emailer: &emailer.Config{
-					SMTPHost:           cctx.String("nw-emailer-smtp-host"),
-					SMTPPort:           cctx.String("nw-emailer-smtp-port"),
-					SenderUsername:     cctx.String("nw-emailer-sender-username"),
+					Transport:          cctx.String("nw-emailer-transport"),
 					SenderEmailAddress: cctx.String("nw-emailer-sender-email-address"),
-					SenderPassword:     cctx.String("nw-emailer-sender-password"),
-					ConfirmationOfRegistration: &emailer.RateLimit{
-						MaxRequests: cctx.Int64("nw-emailer-confirmation-of-registration-max-requests"),
-						Interval:    cctx.Duration("nw-emailer-confirmation-of-registration-interval"),
+					SMTP: &emailer.SMTPTransportConfig{
+						Host:     cctx.String("nw-emailer-smtp-host"),
+						Port:     cctx.String("nw-emailer-smtp-port"),
+						Username: cctx.String("nw-emailer-sender-username"),
+						Password: cctx.String("nw-emailer-sender-password"),
+						Timeout:  cctx.Duration("nw-emailer-smtp-timeout"),
+					},
+					HTTP: &emailer.HTTPTransportConfig{
+						URL:           cctx.String("nw-emailer-http-url"),
+						Authorization: cctx.String("nw-emailer-http-authorization"),
+						ContentType:   cctx.String("nw-emailer-http-content-type"),
+						BodyTemplate:  cctx.String("nw-emailer-http-body-template"),
+						Timeout:       cctx.Duration("nw-emailer-http-timeout"),
+					},
+					Maildir: &emailer.MaildirTransportConfig{
+						Path: cctx.String("nw-emailer-maildir-path"),
+					},
+					MaxDeliveryAttempts: cctx.Int64("nw-emailer-max-delivery-attempts"),
+					RetryBaseDelay:      cctx.Duration("nw-emailer-retry-base-delay"),
+					RetryMaxDelay:       cctx.Duration("nw-emailer-retry-max-delay"),
+					WebhookSecret:       cctx.String("nw-emailer-webhook-secret"),
+					RateLimits: map[emailer.TemplateName]*emailer.RateLimit{
+						emailer.TemplateNameConfirmHolderRegistration: {
+							MaxRequests: cctx.Int64("nw-emailer-confirmation-of-registration-max-requests"),
+							Interval:    cctx.Duration("nw-emailer-confirmation-of-registration-interval"),
+						},
+						emailer.TemplateNameResetHolderPassword: {
+							MaxRequests: cctx.Int64("nw-emailer-reset-holder-password-max-requests"),
+							Interval:    cctx.Duration("nw-emailer-reset-holder-password-interval"),
+						},
+						emailer.TemplateNameConfirmHolderContact: {
+							MaxRequests: cctx.Int64("nw-emailer-confirmation-of-contact-max-requests"),
+							Interval:    cctx.Duration("nw-emailer-confirmation-of-contact-interval"),
+						},
+						emailer.TemplateNameHolderDeleted: {
+							MaxRequests: cctx.Int64("nw-emailer-holder-deleted-max-requests"),
+							Interval:    cctx.Duration("nw-emailer-holder-deleted-interval"),
+						},
+						emailer.TemplateNameHolderLoginLink: {
+							MaxRequests: cctx.Int64("nw-emailer-holder-login-link-max-requests"),
+							Interval:    cctx.Duration("nw-emailer-holder-login-link-interval"),
+						},
+					},
+					SenderRateLimit: &emailer.RateLimit{
+						MaxRequests: cctx.Int64("nw-emailer-sender-max-requests"),
+						Interval:    cctx.Duration("nw-emailer-sender-interval"),
 					},
 				},
-				SMSSender: &smssender.Config{},
-			}
+				Holders: &holders.Config{
+					ConfirmationCodeAge:     cctx.Duration("nw-holders-confirmation-code-age"),
+					MaxConfirmationAttempts: cctx.Int64("nw-holders-max-confirmation-attempts"),
+					DeletionGracePeriod:     cctx.Duration("nw-holders-deletion-grace-period"),
+				},
+				PasswordHasher: &passwords.HasherConfig{
+					Algorithm:           cctx.String("nw-password-hashing-algorithm"),
+					Argon2idMemory:      uint32(cctx.Uint("nw-password-hashing-argon2id-memory")),
+					Argon2idIterations:  uint32(cctx.Uint("nw-password-hashing-argon2id-iterations")),
+					Argon2idParallelism: uint8(cctx.Uint("nw-password-hashing-argon2id-parallelism")),
+					BcryptCost:          cctx.Int("nw-password-hashing-bcrypt-cost"),
+				},
+				PasswordPolicy: &passwords.PolicyConfig{
+					MinLength:             cctx.Int("nw-password-policy-min-length"),
+					MinCharacterClasses:   cctx.Int("nw-password-policy-min-character-classes"),
+					BreachedPasswordsFile: cctx.String("nw-password-policy-breached-passwords-file"),
+				},
+				HolderContacts: &holdercontacts.Config{
+					CodeAge:     cctx.Duration("nw-holder-contacts-code-age"),
+					MaxAttempts: cctx.Int64("nw-holder-contacts-max-attempts"),
+				},
+				OIDC: &oidc.Config{
+					Issuer:         cctx.String("nw-oidc-issuer"),
+					CodeAge:        cctx.Duration("nw-oidc-code-age"),
+					AccessTokenAge: cctx.Duration("nw-oidc-access-token-age"),
+					IDTokenAge:     cctx.Duration("nw-oidc-id-token-age"),
+				},
+				Passkeys: &passkeys.Config{
+					RPID:         cctx.String("nw-passkeys-rp-id"),
+					RPName:       cctx.String("nw-passkeys-rp-name"),
+					Origins:      cctx.StringSlice("nw-passkeys-origins"),
+					ChallengeAge: cctx.Duration("nw-passkeys-challenge-age"),
+				},
+				SMSSender: &smssender.Config{
+					Provider: cctx.String("nw-sms-sender-provider"),
+					Sender:   cctx.String("nw-sms-sender-sender"),
+					HTTP: &smssender.HTTPProviderConfig{
+						URL:           cctx.String("nw-sms-sender-http-url"),
+						Authorization: cctx.String("nw-sms-sender-http-authorization"),
+						ContentType:   cctx.String("nw-sms-sender-http-content-type"),
+						BodyTemplate:  cctx.String("nw-sms-sender-http-body-template"),
+						Timeout:       cctx.Duration("nw-sms-sender-http-timeout"),
+					},
+					SMPP: &smssender.SMPPProviderConfig{
+						Addr:       cctx.String("nw-sms-sender-smpp-addr"),
+						SystemID:   cctx.String("nw-sms-sender-smpp-system-id"),
+						Password:   cctx.String("nw-sms-sender-smpp-password"),
+						SystemType: cctx.String("nw-sms-sender-smpp-system-type"),
+						TLS:        cctx.Bool("nw-sms-sender-smpp-tls"),
+						Timeout:    cctx.Duration("nw-sms-sender-smpp-timeout"),
+					},
+					File: &smssender.FileProviderConfig{
+						Path: cctx.String("nw-sms-sender-file-path"),
+					},
+					ConfirmationOfRegistration: &smssender.RateLimit{
+						MaxRequests: cctx.Int64("nw-sms-sender-confirmation-of-registration-max-requests"),
+						Interval:    cctx.Duration("nw-sms-sender-confirmation-of-registration-interval"),
+					},
+					ResetHolderPassword: &smssender.RateLimit{
+						MaxRequests: cctx.Int64("nw-sms-sender-reset-holder-password-max-requests"),
+						Interval:    cctx.Duration("nw-sms-sender-reset-holder-password-interval"),
+					},
+					ConfirmationOfContact: &smssender.RateLimit{
+						MaxRequests: cctx.Int64("nw-sms-sender-confirmation-of-contact-max-requests"),
+						Interval:    cctx.Duration("nw-sms-sender-confirmation-of-contact-interval"),
+					},
+				},
+				PasswordResets: &passwordresets.Config{
+					CodeAge: cctx.Duration("nw-password-resets-code-age"),
+				},
+				LoginLinks: &loginlinks.Config{
+					Enabled:  cctx.Bool("nw-login-links-enabled"),
+					LinkURL:  cctx.String("nw-login-links-url"),
+					TokenAge: cctx.Duration("nw-login-links-token-age"),
+				},
+				TwoFactor: &twofactor.Config{
+					Issuer:               cctx.String("nw-two-factor-issuer"),
+					Skew:                 cctx.Int64("nw-two-factor-skew"),
+					RecoveryCodesCount:   cctx.Int("nw-two-factor-recovery-codes-count"),
+					ChallengeAge:         cctx.Duration("nw-two-factor-challenge-age"),
+					MaxChallengeAttempts: cctx.Int64("nw-two-factor-max-challenge-attempts"),
+					SecretKey:            cctx.String("nw-two-factor-secret-key"),
+				},
+				LoginThrottles: &loginthrottles.Config{
+					DelayThreshold:  cctx.Int64("nw-login-throttles-delay-threshold"),
+					BaseDelay:       cctx.Duration("nw-login-throttles-base-delay"),
+					MaxAttempts:     cctx.Int64("nw-login-throttles-max-attempts"),
+					LockoutDuration: cctx.Duration("nw-login-throttles-lockout-duration"),
+					AttemptsWindow:  cctx.Duration("nw-login-throttles-attempts-window"),
+				},
+				SessionBinding: &sessionbinding.Config{
+					Policy:           sessionbinding.Policy(cctx.String("nw-session-binding-policy")),
+					IPv4PrefixLength: cctx.Int("nw-session-binding-ipv4-prefix-length"),
+					IPv6PrefixLength: cctx.Int("nw-session-binding-ipv6-prefix-length"),
+					ProofAge:         cctx.Duration("nw-session-binding-proof-age"),
+					MismatchAction:   sessionbinding.MismatchAction(cctx.String("nw-session-binding-mismatch-action")),
+				},
+				Jobs: &jobs.Config{
+					HolderDeletionsInterval: cctx.Duration("nw-jobs-holder-deletions-interval"),
+					EmailQueueInterval:      cctx.Duration("nw-jobs-email-queue-interval"),
+				},
+			}, nil
 		}),
 	)
 }
@@ -145,6 +145,23 @@ var Flags = []cli.Flag{
 		Value:   "alDFsk1d2!j@G$4%5^B&f*6(7)h_-g+=",
 		EnvVars: []string{"NETWORK_WARDEN_JWT_SIGNING_KEY"},
 	},
+	&cli.StringFlag{
+		Name:    "nw-jwt-signing-key-id",
+		Usage:   "it is key ID (kid) of JWT secret",
+		Value:   "default",
+		EnvVars: []string{"NETWORK_WARDEN_JWT_SIGNING_KEY_ID"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-jwt-algorithm",
+		Usage:   "it is JWT signing algorithm (HS256, EdDSA or ES256), for EdDSA and ES256 keys are base64 encoded PKCS #8 private keys",
+		Value:   "HS256",
+		EnvVars: []string{"NETWORK_WARDEN_JWT_ALGORITHM"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "nw-jwt-keys",
+		Usage:   "it is JWT key set formatted as <kid>;<activated-at>;<secret>, the latest activated key signs tokens and all of them verify tokens. If it is set, nw-jwt-signing-key is ignored",
+		EnvVars: []string{"NETWORK_WARDEN_JWT_KEYS"},
+	},
 	&cli.DurationFlag{
 		Name:    "nw-jwt-token-age",
 		Usage:   "it is age of token",
@@ -163,6 +180,12 @@ var Flags = []cli.Flag{
 		Value:   90 * time.Minute,
 		EnvVars: []string{"NETWORK_WARDEN_AUTH_SESSION_AGE"},
 	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-transport",
+		Usage:   "it is transport which delivers emails (smtp, smtps, http, maildir)",
+		Value:   "smtps",
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_TRANSPORT"},
+	},
 	&cli.StringFlag{
 		Name:    "nw-emailer-smtp-host",
 		Usage:   "it is SMTP server host",
@@ -173,8 +196,8 @@ var Flags = []cli.Flag{
 		Name:  "nw-emailer-smtp-port",
 		Usage: "it is SMTP server port",
 		Value: "465",
-		// 25, 587	(for unencrypted/TLS connections)
-		// 465	(for SSL connections)
+		// 25, 587	(for smtp transport, connection is upgraded with STARTTLS)
+		// 465	(for smtps transport, connection is TLS from the start)
 		EnvVars: []string{"NETWORK_WARDEN_EMAILER_SMTP_PORT"},
 	},
 	&cli.StringFlag{
@@ -195,6 +218,68 @@ var Flags = []cli.Flag{
 		Value:   "example@mail.com",
 		EnvVars: []string{"NETWORK_WARDEN_EMAILER_SENDER_EMAIL_ADDRESS"},
 	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-smtp-timeout",
+		Usage:   "it is timeout of SMTP session which is used by smtp and smtps transports",
+		Value:   30 * time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_SMTP_TIMEOUT"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-http-url",
+		Usage:   "it is URL of email provider API which is used by http transport",
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HTTP_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-http-authorization",
+		Usage:   "it is value of Authorization header of requests to email provider API",
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HTTP_AUTHORIZATION"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-http-content-type",
+		Usage:   "it is content type of requests to email provider API",
+		Value:   "application/json",
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HTTP_CONTENT_TYPE"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-http-body-template",
+		Usage:   "it is text/template of request body to email provider API, it is executed with message fields From, To, Cc, Subject, HTMLBody and TextBody",
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HTTP_BODY_TEMPLATE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-http-timeout",
+		Usage:   "it is timeout of requests to email provider API",
+		Value:   10 * time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HTTP_TIMEOUT"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-maildir-path",
+		Usage:   "it is path of maildir where maildir transport delivers emails",
+		Value:   "sent_emails",
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_MAILDIR_PATH"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-emailer-max-delivery-attempts",
+		Usage:   "it is maximal amount of failed delivery attempts after which email is given up",
+		Value:   8,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_MAX_DELIVERY_ATTEMPTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-retry-base-delay",
+		Usage:   "it is delay after the first failed delivery attempt, it doubles after every next one",
+		Value:   30 * time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_RETRY_BASE_DELAY"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-retry-max-delay",
+		Usage:   "it is maximal delay between delivery attempts",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_RETRY_MAX_DELAY"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-emailer-webhook-secret",
+		Usage:   "it is bearer token which email provider sends to bounce and complaint webhook, the webhook is disabled if it is empty",
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_WEBHOOK_SECRET"},
+	},
 	&cli.Int64Flag{
 		Name:    "nw-emailer-confirmation-of-registration-max-requests",
 		Usage:   "it is rate limit value for maximal amount of requests for some interval",
@@ -207,4 +292,434 @@ var Flags = []cli.Flag{
 		Value:   5 * time.Minute,
 		EnvVars: []string{"NETWORK_WARDEN_EMAILER_CONFIRMATION_OF_REGISTRATION_INTERVAL"},
 	},
+	&cli.Int64Flag{
+		Name:    "nw-emailer-reset-holder-password-max-requests",
+		Usage:   "it is rate limit value for maximal amount of password reset emails for some interval",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_RESET_HOLDER_PASSWORD_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-reset-holder-password-interval",
+		Usage:   "it is rate limit value for interval when we measure password reset emails",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_RESET_HOLDER_PASSWORD_INTERVAL"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-emailer-confirmation-of-contact-max-requests",
+		Usage:   "it is rate limit value for maximal amount of contact confirmation emails for some interval",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_CONFIRMATION_OF_CONTACT_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-confirmation-of-contact-interval",
+		Usage:   "it is rate limit value for interval when we measure contact confirmation emails",
+		Value:   5 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_CONFIRMATION_OF_CONTACT_INTERVAL"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-emailer-holder-deleted-max-requests",
+		Usage:   "it is rate limit value for maximal amount of account deletion emails for some interval",
+		Value:   1,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HOLDER_DELETED_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-holder-deleted-interval",
+		Usage:   "it is rate limit value for interval when we measure account deletion emails",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HOLDER_DELETED_INTERVAL"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-emailer-holder-login-link-max-requests",
+		Usage:   "it is rate limit value for maximal amount of login link emails for some interval",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HOLDER_LOGIN_LINK_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-holder-login-link-interval",
+		Usage:   "it is rate limit value for interval when we measure login link emails",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_HOLDER_LOGIN_LINK_INTERVAL"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-emailer-sender-max-requests",
+		Usage:   "it is rate limit value for maximal amount of emails which are sent by sender email address for some interval, zero disables the limit",
+		Value:   1000,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_SENDER_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-emailer-sender-interval",
+		Usage:   "it is rate limit value for interval when we measure all emails of sender email address",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_EMAILER_SENDER_INTERVAL"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-provider",
+		Usage:   "it is provider which delivers SMS messages (http, smpp, file, log), it must be set explicitly",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_PROVIDER"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-sender",
+		Usage:   "it is phone number or alphanumeric sender ID of SMS messages",
+		Value:   "Ecumenos",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_SENDER"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-http-url",
+		Usage:   "it is URL of SMS gateway API which is used by http provider",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_HTTP_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-http-authorization",
+		Usage:   "it is value of Authorization header of requests to SMS gateway API",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_HTTP_AUTHORIZATION"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-http-content-type",
+		Usage:   "it is content type of requests to SMS gateway API",
+		Value:   "application/json",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_HTTP_CONTENT_TYPE"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-http-body-template",
+		Usage:   "it is text/template of request body to SMS gateway API, it is executed with message fields From, To and Text",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_HTTP_BODY_TEMPLATE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-sms-sender-http-timeout",
+		Usage:   "it is timeout of requests to SMS gateway API",
+		Value:   10 * time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_HTTP_TIMEOUT"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-smpp-addr",
+		Usage:   "it is address of SMSC which is used by smpp provider",
+		Value:   "localhost:2775",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_SMPP_ADDR"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-smpp-system-id",
+		Usage:   "it is system ID which is used for binding to SMSC",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_SMPP_SYSTEM_ID"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-smpp-password",
+		Usage:   "it is password which is used for binding to SMSC",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_SMPP_PASSWORD"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-smpp-system-type",
+		Usage:   "it is system type which is used for binding to SMSC",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_SMPP_SYSTEM_TYPE"},
+	},
+	&cli.BoolFlag{
+		Name:    "nw-sms-sender-smpp-tls",
+		Usage:   "it is flag which enables TLS for connections to SMSC",
+		Value:   false,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_SMPP_TLS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-sms-sender-smpp-timeout",
+		Usage:   "it is timeout of SMPP session with SMSC",
+		Value:   10 * time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_SMPP_TIMEOUT"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-sms-sender-file-path",
+		Usage:   "it is path of file where file provider appends SMS messages",
+		Value:   "sent_sms.jsonl",
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_FILE_PATH"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-sms-sender-confirmation-of-registration-max-requests",
+		Usage:   "it is rate limit value for maximal amount of registration confirmation SMS for some interval",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_CONFIRMATION_OF_REGISTRATION_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-sms-sender-confirmation-of-registration-interval",
+		Usage:   "it is rate limit value for interval when we measure registration confirmation SMS",
+		Value:   5 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_CONFIRMATION_OF_REGISTRATION_INTERVAL"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-sms-sender-reset-holder-password-max-requests",
+		Usage:   "it is rate limit value for maximal amount of password reset SMS for some interval",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_RESET_HOLDER_PASSWORD_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-sms-sender-reset-holder-password-interval",
+		Usage:   "it is rate limit value for interval when we measure password reset SMS",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_RESET_HOLDER_PASSWORD_INTERVAL"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-sms-sender-confirmation-of-contact-max-requests",
+		Usage:   "it is rate limit value for maximal amount of contact confirmation SMS for some interval",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_CONFIRMATION_OF_CONTACT_MAX_REQUESTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-sms-sender-confirmation-of-contact-interval",
+		Usage:   "it is rate limit value for interval when we measure contact confirmation SMS",
+		Value:   5 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_SMS_SENDER_CONFIRMATION_OF_CONTACT_INTERVAL"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-holders-confirmation-code-age",
+		Usage:   "it is age of confirmation code of holder registration",
+		Value:   24 * time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_HOLDERS_CONFIRMATION_CODE_AGE"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-holders-max-confirmation-attempts",
+		Usage:   "it is maximal amount of wrong confirmation codes after which confirmation code is invalidated",
+		Value:   5,
+		EnvVars: []string{"NETWORK_WARDEN_HOLDERS_MAX_CONFIRMATION_ATTEMPTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-holders-deletion-grace-period",
+		Usage:   "it is period after holder deletion request when holder can cancel deletion",
+		Value:   30 * 24 * time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_HOLDERS_DELETION_GRACE_PERIOD"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-password-hashing-algorithm",
+		Usage:   "it is algorithm of new password hashes (argon2id, bcrypt), hashes of other algorithms are upgraded on successful login",
+		Value:   "argon2id",
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_HASHING_ALGORITHM"},
+	},
+	&cli.UintFlag{
+		Name:    "nw-password-hashing-argon2id-memory",
+		Usage:   "it is argon2id memory cost in KiB",
+		Value:   64 * 1024,
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_HASHING_ARGON2ID_MEMORY"},
+	},
+	&cli.UintFlag{
+		Name:    "nw-password-hashing-argon2id-iterations",
+		Usage:   "it is argon2id time cost",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_HASHING_ARGON2ID_ITERATIONS"},
+	},
+	&cli.UintFlag{
+		Name:    "nw-password-hashing-argon2id-parallelism",
+		Usage:   "it is argon2id degree of parallelism",
+		Value:   2,
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_HASHING_ARGON2ID_PARALLELISM"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-password-hashing-bcrypt-cost",
+		Usage:   "it is bcrypt cost",
+		Value:   14,
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_HASHING_BCRYPT_COST"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-password-policy-min-length",
+		Usage:   "it is minimal length of holder password",
+		Value:   10,
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_POLICY_MIN_LENGTH"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-password-policy-min-character-classes",
+		Usage:   "it is minimal amount of character classes (lowercase letters, uppercase letters, digits, other symbols) in holder password",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_POLICY_MIN_CHARACTER_CLASSES"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-password-policy-breached-passwords-file",
+		Usage:   "it is path to file with SHA-1 hashes of breached passwords in k-anonymity range format sorted by hash, screening is disabled if it is empty",
+		Value:   "",
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_POLICY_BREACHED_PASSWORDS_FILE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-holder-contacts-code-age",
+		Usage:   "it is age of verification code of holder contact",
+		Value:   24 * time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_HOLDER_CONTACTS_CODE_AGE"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-holder-contacts-max-attempts",
+		Usage:   "it is maximal amount of wrong verification codes after which holder contact has to request a new code",
+		Value:   5,
+		EnvVars: []string{"NETWORK_WARDEN_HOLDER_CONTACTS_MAX_ATTEMPTS"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-oidc-issuer",
+		Usage:   "it is public URL of HTTP gateway which is OpenID Connect issuer",
+		Value:   "http://localhost:9090",
+		EnvVars: []string{"NETWORK_WARDEN_OIDC_ISSUER"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-oidc-code-age",
+		Usage:   "it is age of OpenID Connect authorization code",
+		Value:   time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_OIDC_CODE_AGE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-oidc-access-token-age",
+		Usage:   "it is age of OpenID Connect access token",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_OIDC_ACCESS_TOKEN_AGE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-oidc-id-token-age",
+		Usage:   "it is age of OpenID Connect ID token",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_OIDC_ID_TOKEN_AGE"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-passkeys-rp-id",
+		Usage:   "it is WebAuthn relying party ID of passkeys, it is domain of holders' client",
+		Value:   "localhost",
+		EnvVars: []string{"NETWORK_WARDEN_PASSKEYS_RP_ID"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-passkeys-rp-name",
+		Usage:   "it is WebAuthn relying party name of passkeys",
+		Value:   "Ecumenos",
+		EnvVars: []string{"NETWORK_WARDEN_PASSKEYS_RP_NAME"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "nw-passkeys-origins",
+		Usage:   "it is origins of holders' clients which are allowed to register and use passkeys",
+		Value:   cli.NewStringSlice("http://localhost:9090"),
+		EnvVars: []string{"NETWORK_WARDEN_PASSKEYS_ORIGINS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-passkeys-challenge-age",
+		Usage:   "it is age of passkey registration and login challenge",
+		Value:   5 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_PASSKEYS_CHALLENGE_AGE"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-password-resets-code-age",
+		Usage:   "it is age of password reset code",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_PASSWORD_RESETS_CODE_AGE"},
+	},
+	&cli.BoolFlag{
+		Name:    "nw-login-links-enabled",
+		Usage:   "it is flag which enables passwordless login with links sent to holders' emails",
+		Value:   false,
+		EnvVars: []string{"NETWORK_WARDEN_LOGIN_LINKS_ENABLED"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-login-links-url",
+		Usage:   "it is URL of holders' client page which redeems login link, the token is passed in token query parameter",
+		Value:   "http://localhost:9090/login-link",
+		EnvVars: []string{"NETWORK_WARDEN_LOGIN_LINKS_URL"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-login-links-token-age",
+		Usage:   "it is age of login link",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_LOGIN_LINKS_TOKEN_AGE"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-two-factor-issuer",
+		Usage:   "it is issuer name which is shown in authenticator applications",
+		Value:   "Ecumenos",
+		EnvVars: []string{"NETWORK_WARDEN_TWO_FACTOR_ISSUER"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-two-factor-skew",
+		Usage:   "it is amount of TOTP time steps accepted before and after current one",
+		Value:   1,
+		EnvVars: []string{"NETWORK_WARDEN_TWO_FACTOR_SKEW"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-two-factor-recovery-codes-count",
+		Usage:   "it is amount of recovery codes generated at two-factor enrollment",
+		Value:   10,
+		EnvVars: []string{"NETWORK_WARDEN_TWO_FACTOR_RECOVERY_CODES_COUNT"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-two-factor-challenge-age",
+		Usage:   "it is age of login challenge for two-factor authentication",
+		Value:   5 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_TWO_FACTOR_CHALLENGE_AGE"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-two-factor-max-challenge-attempts",
+		Usage:   "it is maximal amount of failed attempts to complete login challenge",
+		Value:   5,
+		EnvVars: []string{"NETWORK_WARDEN_TWO_FACTOR_MAX_CHALLENGE_ATTEMPTS"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-two-factor-secret-key",
+		Usage:   "it is base64 encoded 32 bytes key which TOTP secrets are encrypted with",
+		EnvVars: []string{"NETWORK_WARDEN_TWO_FACTOR_SECRET_KEY"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-login-throttles-delay-threshold",
+		Usage:   "it is amount of failed login attempts after which next attempts are delayed",
+		Value:   3,
+		EnvVars: []string{"NETWORK_WARDEN_LOGIN_THROTTLES_DELAY_THRESHOLD"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-login-throttles-base-delay",
+		Usage:   "it is delay after failed login attempt, it is doubled for every next failed attempt",
+		Value:   time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_LOGIN_THROTTLES_BASE_DELAY"},
+	},
+	&cli.Int64Flag{
+		Name:    "nw-login-throttles-max-attempts",
+		Usage:   "it is amount of failed login attempts after which login is locked",
+		Value:   10,
+		EnvVars: []string{"NETWORK_WARDEN_LOGIN_THROTTLES_MAX_ATTEMPTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-login-throttles-lockout-duration",
+		Usage:   "it is duration of login lockout",
+		Value:   15 * time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_LOGIN_THROTTLES_LOCKOUT_DURATION"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-login-throttles-attempts-window",
+		Usage:   "it is duration after the latest failed login attempt when failed attempts are forgotten",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_LOGIN_THROTTLES_ATTEMPTS_WINDOW"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-session-binding-policy",
+		Usage:   "it is what sessions are bound to (none, ip-subnet, strict-ip, device-key)",
+		Value:   "ip-subnet",
+		EnvVars: []string{"NETWORK_WARDEN_SESSION_BINDING_POLICY"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-session-binding-ipv4-prefix-length",
+		Usage:   "it is length of IPv4 subnet prefix which is compared by ip-subnet session binding policy",
+		Value:   24,
+		EnvVars: []string{"NETWORK_WARDEN_SESSION_BINDING_IPV4_PREFIX_LENGTH"},
+	},
+	&cli.IntFlag{
+		Name:    "nw-session-binding-ipv6-prefix-length",
+		Usage:   "it is length of IPv6 subnet prefix which is compared by ip-subnet session binding policy",
+		Value:   64,
+		EnvVars: []string{"NETWORK_WARDEN_SESSION_BINDING_IPV6_PREFIX_LENGTH"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-session-binding-proof-age",
+		Usage:   "it is how long device key proof is accepted after it was made",
+		Value:   time.Minute,
+		EnvVars: []string{"NETWORK_WARDEN_SESSION_BINDING_PROOF_AGE"},
+	},
+	&cli.StringFlag{
+		Name:    "nw-session-binding-mismatch-action",
+		Usage:   "it is what happens if request doesn't match session binding (deny, reauthenticate, step-up)",
+		Value:   "reauthenticate",
+		EnvVars: []string{"NETWORK_WARDEN_SESSION_BINDING_MISMATCH_ACTION"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-jobs-holder-deletions-interval",
+		Usage:   "it is interval of purging holders whose deletion grace period is over",
+		Value:   time.Hour,
+		EnvVars: []string{"NETWORK_WARDEN_JOBS_HOLDER_DELETIONS_INTERVAL"},
+	},
+	&cli.DurationFlag{
+		Name:    "nw-jobs-email-queue-interval",
+		Usage:   "it is interval of delivering queued emails",
+		Value:   10 * time.Second,
+		EnvVars: []string{"NETWORK_WARDEN_JOBS_EMAIL_QUEUE_INTERVAL"},
+	},
 }
@@ -3,17 +3,28 @@ package main
 import (
 	"github.com/ecumenos-social/network-warden/cmd/network-warden/configurations"
 	"github.com/ecumenos-social/network-warden/cmd/network-warden/grpc"
+	"github.com/ecumenos-social/network-warden/cmd/network-warden/jobs"
 	"github.com/ecumenos-social/network-warden/cmd/network-warden/pgseeds"
 	"github.com/ecumenos-social/network-warden/repository"
 	"github.com/ecumenos-social/network-warden/services/auth"
 	"github.com/ecumenos-social/network-warden/services/emailer"
+	holdercontacts "github.com/ecumenos-social/network-warden/services/holder-contacts"
+	holderexports "github.com/ecumenos-social/network-warden/services/holder-exports"
 	"github.com/ecumenos-social/network-warden/services/holders"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
 	"github.com/ecumenos-social/network-warden/services/jwt"
+	loginlinks "github.com/ecumenos-social/network-warden/services/login-links"
+	loginthrottles "github.com/ecumenos-social/network-warden/services/login-throttles"
 	networknodes "github.com/ecumenos-social/network-warden/services/network-nodes"
 	networkwardens "github.com/ecumenos-social/network-warden/services/network-wardens"
+	"github.com/ecumenos-social/network-warden/services/oidc"
+	"github.com/ecumenos-social/network-warden/services/passkeys"
+	passwordresets "github.com/ecumenos-social/network-warden/services/password-resets"
+	"github.com/ecumenos-social/network-warden/services/passwords"
 	personaldatanodes "github.com/ecumenos-social/network-warden/services/personal-data-nodes"
+	sessionbinding "github.com/ecumenos-social/network-warden/services/session-binding"
 	smssender "github.com/ecumenos-social/network-warden/services/sms-sender"
+	twofactor "github.com/ecumenos-social/network-warden/services/two-factor"
 	"github.com/ecumenos-social/toolkitfx"
 	"github.com/ecumenos-social/toolkitfx/fxgrpc"
 	"github.com/ecumenos-social/toolkitfx/fxlogger"
@@ -32,19 +43,43 @@ var Dependencies = fx.Options(
 		grpc.NewLivenessGateway,
 		grpc.NewHandler,
 		holders.New,
+		passwords.NewPolicy,
+		passwords.NewHasher,
+		holdercontacts.New,
+		holderexports.New,
 		auth.New,
 		jwt.New,
+		loginthrottles.New,
+		sessionbinding.New,
 		emailer.New,
 		smssender.New,
 		networknodes.New,
 		personaldatanodes.New,
 		networkwardens.New,
+		passwordresets.New,
+		loginlinks.New,
+		twofactor.New,
+		oidc.New,
+		passkeys.New,
 		idgenerators.NewHolderSessionsIDGenerator,
 		idgenerators.NewHoldersIDGenerator,
 		idgenerators.NewNetworkNodesIDGenerator,
 		idgenerators.NewPersonalDataNodesIDGenerator,
 		idgenerators.NewNetworkWardensIDGenerator,
+		idgenerators.NewLoginThrottlesIDGenerator,
 		idgenerators.NewSentEmailsIDGenerator,
+		idgenerators.NewEmailSuppressionsIDGenerator,
+		idgenerators.NewHolderPasswordResetsIDGenerator,
+		idgenerators.NewHolderTOTPSecretsIDGenerator,
+		idgenerators.NewHolderRecoveryCodesIDGenerator,
+		idgenerators.NewHolderLoginChallengesIDGenerator,
+		idgenerators.NewHolderContactsIDGenerator,
+		idgenerators.NewOIDCAuthorizationCodesIDGenerator,
+		idgenerators.NewOIDCAccessTokensIDGenerator,
+		idgenerators.NewHolderPasskeysIDGenerator,
+		idgenerators.NewWebAuthnChallengesIDGenerator,
+		idgenerators.NewHolderLoginLinksIDGenerator,
+		idgenerators.NewSentSMSIDGenerator,
 		pgseeds.New,
 	),
 )
@@ -54,4 +89,6 @@ var Invokes = fx.Invoke(
 	grpc.RunHTTPGateway,
 	fxgrpc.RunHealthServer,
 	fxgrpc.RunLivenessGateway,
+	jobs.RunHolderDeletionsPurger,
+	jobs.RunEmailQueueWorker,
 )
@@ -0,0 +1,259 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/emailer"
+	holdercontacts "github.com/ecumenos-social/network-warden/services/holder-contacts"
+	"github.com/ecumenos-social/network-warden/services/jwt"
+	smssender "github.com/ecumenos-social/network-warden/services/sms-sender"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Holder contact request and response messages stand for the RPC messages
+// until they are published in schemas, the methods are served by HTTP gateway
+// only.
+type HolderContact struct {
+	Id            *string `json:"id,omitempty"`
+	Kind          string  `json:"kind"`
+	Value         string  `json:"value"`
+	Verified      bool    `json:"verified"`
+	Primary       bool    `json:"primary"`
+	Undeliverable bool    `json:"undeliverable"`
+}
+
+type GetHolderContactsRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type GetHolderContactsResponse struct {
+	Data []*HolderContact `json:"data"`
+}
+
+type AddHolderContactRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Kind             string  `json:"kind"`
+	Value            string  `json:"value"`
+}
+
+type AddHolderContactResponse struct {
+	Data *HolderContact `json:"data"`
+}
+
+type VerifyHolderContactRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Id               string  `json:"id"`
+	Code             string  `json:"code"`
+}
+
+type VerifyHolderContactResponse struct {
+	Success bool `json:"success"`
+}
+
+// HolderContactRequest addresses verified or pending contact by its kind and
+// value, it is used by RemoveHolderContact and MakeHolderContactPrimary.
+type HolderContactRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Kind             string  `json:"kind"`
+	Value            string  `json:"value"`
+}
+
+type HolderContactResponse struct {
+	Success bool `json:"success"`
+}
+
+func convertHolderContact(c *holdercontacts.Contact) *HolderContact {
+	out := &HolderContact{
+		Kind:          c.Kind.String(),
+		Value:         c.Value,
+		Verified:      c.Verified,
+		Primary:       c.Primary,
+		Undeliverable: c.Undeliverable,
+	}
+	if c.ID != nil {
+		out.Id = lo.ToPtr(fmt.Sprint(*c.ID))
+	}
+
+	return out
+}
+
+func (h *Handler) getContactsHolder(ctx context.Context, logger *zap.Logger, hs *models.HolderSession) (*models.Holder, error) {
+	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		return nil, status.Error(codes.InvalidArgument, "can not found holder by token's information")
+	}
+
+	return holder, nil
+}
+
+func (h *Handler) GetHolderContacts(ctx context.Context, req *GetHolderContactsRequest) (*GetHolderContactsResponse, error) {
+	logger := h.customizeLogger(ctx, "GetHolderContacts")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+	holder, err := h.getContactsHolder(ctx, logger, hs)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts, err := h.holderContacts.GetList(ctx, logger, holder)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder contacts, err=%v", err.Error())
+	}
+	data := make([]*HolderContact, 0, len(contacts))
+	for _, c := range contacts {
+		data = append(data, convertHolderContact(c))
+	}
+
+	return &GetHolderContactsResponse{Data: data}, nil
+}
+
+// canSendContactCode checks rate limits before code is issued, so code of
+// contact which can't receive it is not regenerated.
+func (h *Handler) canSendContactCode(ctx context.Context, logger *zap.Logger, kind holdercontacts.Kind, value string) error {
+	switch kind {
+	case holdercontacts.KindEmail:
+		if err := h.emailer.CanSendConfirmationOfContact(ctx, logger, value); err != nil {
+			return sendEmailError(err, "failed to verify if service can send verification code")
+		}
+	case holdercontacts.KindPhoneNumber:
+		canSend, err := h.smsSender.CanSendConfirmationOfContact(ctx, logger, value)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to verify if service can send verification code, err = %v", err.Error())
+		}
+		if !canSend {
+			return status.Error(codes.ResourceExhausted, "rate limit of sent verification messages was exceeded. please, try next time")
+		}
+	default:
+		return status.Error(codes.InvalidArgument, kind.Validate().Error())
+	}
+
+	return nil
+}
+
+// AddHolderContact adds pending contact and sends verification code to it by
+// email or SMS. Adding the same pending contact again sends a new code.
+func (h *Handler) AddHolderContact(ctx context.Context, req *AddHolderContactRequest) (*AddHolderContactResponse, error) {
+	logger := h.customizeLogger(ctx, "AddHolderContact")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+	holder, err := h.getContactsHolder(ctx, logger, hs)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := holdercontacts.Kind(req.Kind)
+	if err := h.canSendContactCode(ctx, logger, kind, req.Value); err != nil {
+		return nil, err
+	}
+	hc, code, err := h.holderContacts.Add(ctx, logger, holder, kind, req.Value)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to add holder contact, err=%v", err.Error())
+	}
+	switch kind {
+	case holdercontacts.KindEmail:
+		if err := h.emailer.SendConfirmationOfContact(ctx, logger, emailer.HolderRecipient(holder, hc.Value), code, h.holderContacts.CodeAge()); err != nil {
+			return nil, sendEmailError(err, "failed to send verification code")
+		}
+	case holdercontacts.KindPhoneNumber:
+		if err := h.smsSender.SendConfirmationOfContact(ctx, logger, smssender.HolderRecipient(holder, hc.Value), code); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to send verification code, err = %v", err.Error())
+		}
+	}
+
+	return &AddHolderContactResponse{Data: &HolderContact{
+		Id:    lo.ToPtr(fmt.Sprint(hc.ID)),
+		Kind:  hc.Kind,
+		Value: hc.Value,
+	}}, nil
+}
+
+func (h *Handler) VerifyHolderContact(ctx context.Context, req *VerifyHolderContactRequest) (*VerifyHolderContactResponse, error) {
+	logger := h.customizeLogger(ctx, "VerifyHolderContact")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+	id, err := strconv.ParseInt(req.Id, 10, 64)
+	if err != nil {
+		logger.Error("invalid holder contact ID", zap.Error(err), zap.String("incoming-holder-contact-id", req.Id))
+		return nil, status.Error(codes.InvalidArgument, "invalid holder contact ID")
+	}
+	holder, err := h.getContactsHolder(ctx, logger, hs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := h.holderContacts.Verify(ctx, logger, holder, id, req.Code); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to verify holder contact, err=%v", err.Error())
+	}
+
+	return &VerifyHolderContactResponse{Success: true}, nil
+}
+
+func (h *Handler) RemoveHolderContact(ctx context.Context, req *HolderContactRequest) (*HolderContactResponse, error) {
+	logger := h.customizeLogger(ctx, "RemoveHolderContact")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+	holder, err := h.getContactsHolder(ctx, logger, hs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := h.holderContacts.Remove(ctx, logger, holder, holdercontacts.Kind(req.Kind), req.Value); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to remove holder contact, err=%v", err.Error())
+	}
+
+	return &HolderContactResponse{Success: true}, nil
+}
+
+func (h *Handler) MakeHolderContactPrimary(ctx context.Context, req *HolderContactRequest) (*HolderContactResponse, error) {
+	logger := h.customizeLogger(ctx, "MakeHolderContactPrimary")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+	holder, err := h.getContactsHolder(ctx, logger, hs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := h.holderContacts.MakePrimary(ctx, logger, holder, holdercontacts.Kind(req.Kind), req.Value); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to make holder contact primary, err=%v", err.Error())
+	}
+
+	return &HolderContactResponse{Success: true}, nil
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ecumenos-social/network-warden/services/emailer"
+	"go.uber.org/zap"
+)
+
+// emailFeedbackPath is webhook which email providers post bounce and
+// complaint notifications to, provider is name of adapter which parses them.
+const emailFeedbackPath = "/webhooks/emails/{provider}"
+
+// maxEmailFeedbackSize limits notification body, providers batch events in
+// much smaller requests.
+const maxEmailFeedbackSize = 1 << 20
+
+type emailFeedbackResponse struct {
+	Suppressed int    `json:"suppressed"`
+	Error      string `json:"error,omitempty"`
+}
+
+// EmailFeedback suppresses email addresses which bounced or complained
+// according to provider's notification. Provider authenticates with the
+// webhook secret as bearer token.
+func (h *Handler) EmailFeedback(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	ctx := httpMethodContext(r)
+	logger := h.customizeLogger(ctx, "EmailFeedback").With(zap.String("provider", pathParams["provider"]))
+	defer logger.Info("request processed")
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEmailFeedbackSize))
+	if err != nil {
+		writeEmailFeedbackResponse(w, http.StatusBadRequest, &emailFeedbackResponse{Error: "invalid request body"})
+		return
+	}
+	secret, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	suppressed, err := h.emailer.HandleFeedback(ctx, logger, pathParams["provider"], secret, r.Header, body)
+	switch {
+	case err == nil:
+		writeEmailFeedbackResponse(w, http.StatusOK, &emailFeedbackResponse{Suppressed: suppressed})
+	case errors.Is(err, emailer.ErrFeedbackDisabled), errors.Is(err, emailer.ErrUnknownFeedbackProvider):
+		writeEmailFeedbackResponse(w, http.StatusNotFound, &emailFeedbackResponse{Error: err.Error()})
+	case errors.Is(err, emailer.ErrFeedbackUnauthorized):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeEmailFeedbackResponse(w, http.StatusUnauthorized, &emailFeedbackResponse{Error: err.Error()})
+	case errors.Is(err, emailer.ErrInvalidFeedback):
+		writeEmailFeedbackResponse(w, http.StatusBadRequest, &emailFeedbackResponse{Error: err.Error()})
+	default:
+		// provider retries the whole notification, suppressing is idempotent
+		writeEmailFeedbackResponse(w, http.StatusInternalServerError, &emailFeedbackResponse{Suppressed: suppressed, Error: "internal error"})
+	}
+}
+
+func writeEmailFeedbackResponse(w http.ResponseWriter, statusCode int, resp *emailFeedbackResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -2,11 +2,16 @@ package grpc
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"mime"
 	"net"
 	"net/http"
 	"time"
 
 	grpcutils "github.com/ecumenos-social/grpc-utils"
+	"github.com/ecumenos-social/network-warden/services/jwt"
+	sessionbinding "github.com/ecumenos-social/network-warden/services/session-binding"
 	pbv1 "github.com/ecumenos-social/schemas/proto/gen/networkwarden/v1"
 	"github.com/ecumenos-social/toolkitfx"
 	"github.com/ecumenos-social/toolkitfx/fxgrpc"
@@ -15,7 +20,11 @@ import (
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 type grpcServerParams struct {
@@ -72,6 +81,8 @@ func RunHTTPGateway(
 	logger *zap.Logger,
 	cfg *fxgrpc.Config,
 	g *fxgrpc.HTTPGatewayHandler,
+	jwtService jwt.Service,
+	handler *Handler,
 ) error {
 	httpAddr := net.JoinHostPort(cfg.HTTPGateway.Host, cfg.HTTPGateway.Port)
 	mux := runtime.NewServeMux()
@@ -88,6 +99,109 @@ func RunHTTPGateway(
 	if err := g.Handler(context.Background(), mux, conn.Connection); err != nil {
 		logger.Error("failed to register mapping service handler", zap.Error(err))
 	}
+	if err := mux.HandlePath(http.MethodGet, jwksPath, jwksHandler(logger, jwtService)); err != nil {
+		logger.Error("failed to register JWKS handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RequestHolderLoginLink", httpMethodHandler(mux, handler.RequestHolderLoginLink)); err != nil {
+		logger.Error("failed to register RequestHolderLoginLink handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RedeemHolderLoginLink", httpMethodHandler(mux, handler.RedeemHolderLoginLink)); err != nil {
+		logger.Error("failed to register RedeemHolderLoginLink handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/ListHolderSessions", httpMethodHandler(mux, handler.ListHolderSessions)); err != nil {
+		logger.Error("failed to register ListHolderSessions handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RevokeHolderSession", httpMethodHandler(mux, handler.RevokeHolderSession)); err != nil {
+		logger.Error("failed to register RevokeHolderSession handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RevokeAllOtherSessions", httpMethodHandler(mux, handler.RevokeAllOtherSessions)); err != nil {
+		logger.Error("failed to register RevokeAllOtherSessions handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/EnrollTwoFactor", httpMethodHandler(mux, handler.EnrollTwoFactor)); err != nil {
+		logger.Error("failed to register EnrollTwoFactor handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/VerifyTwoFactor", httpMethodHandler(mux, handler.VerifyTwoFactor)); err != nil {
+		logger.Error("failed to register VerifyTwoFactor handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/DisableTwoFactor", httpMethodHandler(mux, handler.DisableTwoFactor)); err != nil {
+		logger.Error("failed to register DisableTwoFactor handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RedeemTwoFactorChallenge", httpMethodHandler(mux, handler.RedeemTwoFactorChallenge)); err != nil {
+		logger.Error("failed to register RedeemTwoFactorChallenge handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RequestHolderPasswordReset", httpMethodHandler(mux, handler.RequestHolderPasswordReset)); err != nil {
+		logger.Error("failed to register RequestHolderPasswordReset handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/ResetHolderPassword", httpMethodHandler(mux, handler.ResetHolderPassword)); err != nil {
+		logger.Error("failed to register ResetHolderPassword handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/GetHolderContacts", httpMethodHandler(mux, handler.GetHolderContacts)); err != nil {
+		logger.Error("failed to register GetHolderContacts handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/AddHolderContact", httpMethodHandler(mux, handler.AddHolderContact)); err != nil {
+		logger.Error("failed to register AddHolderContact handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/VerifyHolderContact", httpMethodHandler(mux, handler.VerifyHolderContact)); err != nil {
+		logger.Error("failed to register VerifyHolderContact handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RemoveHolderContact", httpMethodHandler(mux, handler.RemoveHolderContact)); err != nil {
+		logger.Error("failed to register RemoveHolderContact handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/MakeHolderContactPrimary", httpMethodHandler(mux, handler.MakeHolderContactPrimary)); err != nil {
+		logger.Error("failed to register MakeHolderContactPrimary handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/BeginPasskeyRegistration", httpMethodHandler(mux, handler.BeginPasskeyRegistration)); err != nil {
+		logger.Error("failed to register BeginPasskeyRegistration handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/FinishPasskeyRegistration", httpMethodHandler(mux, handler.FinishPasskeyRegistration)); err != nil {
+		logger.Error("failed to register FinishPasskeyRegistration handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/BeginPasskeyLogin", httpMethodHandler(mux, handler.BeginPasskeyLogin)); err != nil {
+		logger.Error("failed to register BeginPasskeyLogin handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/FinishPasskeyLogin", httpMethodHandler(mux, handler.FinishPasskeyLogin)); err != nil {
+		logger.Error("failed to register FinishPasskeyLogin handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/GetPasskeys", httpMethodHandler(mux, handler.GetPasskeys)); err != nil {
+		logger.Error("failed to register GetPasskeys handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RemovePasskey", httpMethodHandler(mux, handler.RemovePasskey)); err != nil {
+		logger.Error("failed to register RemovePasskey handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodGet, oidcDiscoveryPath, handler.OIDCDiscovery); err != nil {
+		logger.Error("failed to register OIDC discovery handler", zap.Error(err))
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if err := mux.HandlePath(method, oidcAuthorizationPath, handler.OIDCAuthorize); err != nil {
+			logger.Error("failed to register OIDC authorization handler", zap.Error(err))
+		}
+		if err := mux.HandlePath(method, oidcUserInfoPath, handler.OIDCUserInfo); err != nil {
+			logger.Error("failed to register OIDC userinfo handler", zap.Error(err))
+		}
+	}
+	if err := mux.HandlePath(http.MethodPost, oidcTokenPath, handler.OIDCToken); err != nil {
+		logger.Error("failed to register OIDC token handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/RegisterHolderWithProfile", httpMethodHandler(mux, handler.RegisterHolderWithProfile)); err != nil {
+		logger.Error("failed to register RegisterHolderWithProfile handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/ModifyHolderProfile", httpMethodHandler(mux, handler.ModifyHolderProfile)); err != nil {
+		logger.Error("failed to register ModifyHolderProfile handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/GetHolderProfile", httpMethodHandler(mux, handler.GetHolderProfile)); err != nil {
+		logger.Error("failed to register GetHolderProfile handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/CancelHolderDeletion", httpMethodHandler(mux, handler.CancelHolderDeletion)); err != nil {
+		logger.Error("failed to register CancelHolderDeletion handler", zap.Error(err))
+	}
+	// gRPC streaming of the export waits for the RPC in schemas, see
+	// ExportHolderDataRequest
+	if err := mux.HandlePath(http.MethodPost, "/networkwarden.v1.NetworkWardenService/ExportHolderData", httpDownloadHandler(mux, logger, "application/zip", "holder-data.zip", handler.ExportHolderData)); err != nil {
+		logger.Error("failed to register ExportHolderData handler", zap.Error(err))
+	}
+	if err := mux.HandlePath(http.MethodPost, emailFeedbackPath, handler.EmailFeedback); err != nil {
+		logger.Error("failed to register email feedback handler", zap.Error(err))
+	}
 
 	var httpServer *http.Server
 	lc.Append(fx.Hook{
@@ -118,3 +232,104 @@ func RunHTTPGateway(
 
 	return nil
 }
+
+// jwksHandler publishes public keys of JWT key set, so network nodes and
+// personal data nodes can verify holder tokens themselves.
+func jwksHandler(logger *zap.Logger, jwtService jwt.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		body, err := json.Marshal(jwtService.PublicKeySet())
+		if err != nil {
+			logger.Error("failed to marshal JWKS", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_, _ = w.Write(body)
+	}
+}
+
+// httpMethodContext passes remote address, correlation ID and device key
+// metadata of HTTP request the same way gRPC does.
+func httpMethodContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+	}
+	md := metadata.MD{}
+	if corrID := r.Header.Get("Correlation-Id"); corrID != "" {
+		md.Set("correlation-id", corrID)
+	}
+	for _, key := range []string{sessionbinding.DeviceKeyMetadataKey, sessionbinding.DeviceKeyProofMetadataKey} {
+		if value := r.Header.Get(runtime.MetadataHeaderPrefix + key); value != "" {
+			md.Set(key, value)
+		}
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	return runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{})
+}
+
+// httpMethodHandler serves handler method which has no RPC in schemas yet. It
+// decodes JSON request body and encodes errors the same way HTTP gateway does.
+func httpMethodHandler[Req, Resp any](mux *runtime.ServeMux, method func(context.Context, *Req) (*Resp, error)) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := httpMethodContext(r)
+		marshaler := &runtime.JSONPb{}
+
+		var req Req
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		resp, err := method(ctx, &req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// downloadWriter sends download headers right before the first write, so
+// method can still fail with regular error response until then.
+type downloadWriter struct {
+	w                     http.ResponseWriter
+	contentType, fileName string
+	written               bool
+}
+
+func (dw *downloadWriter) Write(p []byte) (int, error) {
+	if !dw.written {
+		dw.written = true
+		dw.w.Header().Set("Content-Type", dw.contentType)
+		dw.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": dw.fileName}))
+		dw.w.WriteHeader(http.StatusOK)
+	}
+	return dw.w.Write(p)
+}
+
+// httpDownloadHandler serves handler method which streams file which has no
+// RPC in schemas yet.
+func httpDownloadHandler[Req any](mux *runtime.ServeMux, logger *zap.Logger, contentType, fileName string, method func(context.Context, *Req, io.Writer) error) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := httpMethodContext(r)
+		marshaler := &runtime.JSONPb{}
+
+		var req Req
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		dw := &downloadWriter{w: w, contentType: contentType, fileName: fileName}
+		if err := method(ctx, &req, dw); err != nil {
+			if dw.written {
+				// headers are already sent, the client gets truncated file
+				logger.Error("failed to stream file", zap.Error(err), zap.String("file-name", fileName))
+				return
+			}
+			runtime.HTTPError(ctx, mux, marshaler, w, r, err)
+		}
+	}
+}
@@ -2,9 +2,14 @@ package grpc
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	errorwrapper "github.com/ecumenos-social/error-wrapper"
 	grpcutils "github.com/ecumenos-social/grpc-utils"
@@ -12,20 +17,34 @@ import (
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/network-warden/services/auth"
 	"github.com/ecumenos-social/network-warden/services/emailer"
+	holdercontacts "github.com/ecumenos-social/network-warden/services/holder-contacts"
+	holderexports "github.com/ecumenos-social/network-warden/services/holder-exports"
 	"github.com/ecumenos-social/network-warden/services/holders"
 	"github.com/ecumenos-social/network-warden/services/jwt"
+	loginlinks "github.com/ecumenos-social/network-warden/services/login-links"
+	loginthrottles "github.com/ecumenos-social/network-warden/services/login-throttles"
 	networknodes "github.com/ecumenos-social/network-warden/services/network-nodes"
 	networkwardens "github.com/ecumenos-social/network-warden/services/network-wardens"
+	"github.com/ecumenos-social/network-warden/services/oidc"
+	"github.com/ecumenos-social/network-warden/services/passkeys"
+	passwordresets "github.com/ecumenos-social/network-warden/services/password-resets"
+	"github.com/ecumenos-social/network-warden/services/passwords"
 	personaldatanodes "github.com/ecumenos-social/network-warden/services/personal-data-nodes"
+	sessionbinding "github.com/ecumenos-social/network-warden/services/session-binding"
 	smssender "github.com/ecumenos-social/network-warden/services/sms-sender"
+	twofactor "github.com/ecumenos-social/network-warden/services/two-factor"
+	"github.com/ecumenos-social/schemas/formats"
 	pbv1 "github.com/ecumenos-social/schemas/proto/gen/networkwarden/v1"
 	"github.com/ecumenos-social/toolkit/validators"
 	"github.com/ecumenos-social/toolkitfx"
+	"github.com/samber/lo"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 type Handler struct {
@@ -39,6 +58,15 @@ type Handler struct {
 	networkNodesService      networknodes.Service
 	personalDataNodesService personaldatanodes.Service
 	networkWardensService    networkwardens.Service
+	twoFactor                twofactor.Service
+	loginThrottles           loginthrottles.Service
+	holderExports            holderexports.Service
+	oidc                     oidc.Service
+	passkeys                 passkeys.Service
+	sessionBinding           sessionbinding.Service
+	loginLinks               loginlinks.Service
+	passwordResets           passwordresets.Service
+	holderContacts           holdercontacts.Service
 	logger                   *zap.Logger
 
 	networkWardenID int64
@@ -58,6 +86,15 @@ type handlerParams struct {
 	NetworkNodesService      networknodes.Service
 	PersonalDataNodesService personaldatanodes.Service
 	NetworkWardensService    networkwardens.Service
+	TwoFactorService         twofactor.Service
+	LoginThrottlesService    loginthrottles.Service
+	HolderExportsService     holderexports.Service
+	OIDCService              oidc.Service
+	PasskeysService          passkeys.Service
+	SessionBindingService    sessionbinding.Service
+	LoginLinksService        loginlinks.Service
+	PasswordResetsService    passwordresets.Service
+	HolderContactsService    holdercontacts.Service
 	Logger                   *zap.Logger
 }
 
@@ -71,6 +108,15 @@ func NewHandler(params handlerParams) *Handler {
 		networkNodesService:      params.NetworkNodesService,
 		personalDataNodesService: params.PersonalDataNodesService,
 		networkWardensService:    params.NetworkWardensService,
+		twoFactor:                params.TwoFactorService,
+		loginThrottles:           params.LoginThrottlesService,
+		holderExports:            params.HolderExportsService,
+		oidc:                     params.OIDCService,
+		passkeys:                 params.PasskeysService,
+		sessionBinding:           params.SessionBindingService,
+		loginLinks:               params.LoginLinksService,
+		passwordResets:           params.PasswordResetsService,
+		holderContacts:           params.HolderContactsService,
 		logger:                   params.Logger,
 
 		networkWardenID: params.AppConfig.ID,
@@ -141,22 +187,86 @@ func (h *Handler) RegisterHolder(ctx context.Context, req *pbv1.NetworkWardenSer
 	logger := h.customizeLogger(ctx, "RegisterHolder")
 	defer logger.Info("request processed")
 
+	return h.registerHolder(ctx, logger, req, &holderProfile{})
+}
+
+// holderProfile is profile of holder which is not part of RPC messages yet.
+type holderProfile struct {
+	DisplayName       *string `json:"displayName,omitempty"`
+	LegalName         *string `json:"legalName,omitempty"`
+	PreferredLanguage *string `json:"preferredLanguage,omitempty"`
+	Timezone          *string `json:"timezone,omitempty"`
+}
+
+const (
+	maxHolderDisplayNameLength = 64
+	maxHolderLegalNameLength   = 256
+)
+
+func (p *holderProfile) validate(ctx context.Context) error {
+	names := []struct {
+		field     string
+		value     *string
+		maxLength int
+	}{
+		{field: "display_name", value: p.DisplayName, maxLength: maxHolderDisplayNameLength},
+		{field: "legal_name", value: p.LegalName, maxLength: maxHolderLegalNameLength},
+	}
+	for _, n := range names {
+		if n.value == nil {
+			continue
+		}
+		if utf8.RuneCountInString(*n.value) > n.maxLength {
+			return status.Errorf(codes.InvalidArgument, "invalid request, %s is longer than %d characters", n.field, n.maxLength)
+		}
+		if !utf8.ValidString(*n.value) || strings.IndexFunc(*n.value, unicode.IsControl) >= 0 {
+			return status.Errorf(codes.InvalidArgument, "invalid request, %s contains invalid characters", n.field)
+		}
+	}
+	if p.PreferredLanguage != nil && *p.PreferredLanguage != "" {
+		if err := validators.ValidateLanguageCode(ctx, *p.PreferredLanguage); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid request, invalid preferred language (language_code: %v, error = %v)", *p.PreferredLanguage, err.Error())
+		}
+	}
+	if p.Timezone != nil && *p.Timezone != "" {
+		// Local would mean time zone of the server
+		if _, err := time.LoadLocation(*p.Timezone); err != nil || *p.Timezone == "Local" {
+			return status.Errorf(codes.InvalidArgument, "invalid request, unknown timezone (timezone: %v)", *p.Timezone)
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) registerHolder(ctx context.Context, logger *zap.Logger, req *pbv1.NetworkWardenServiceRegisterHolderRequest, profile *holderProfile) (*pbv1.NetworkWardenServiceRegisterHolderResponse, error) {
 	if err := h.validateRegisterHolderRequest(ctx, logger, req); err != nil {
 		return nil, err
 	}
+	if err := profile.validate(ctx); err != nil {
+		return nil, err
+	}
 
 	params := &holders.InsertParams{
-		Emails:       req.Emails,
-		PhoneNumbers: req.PhoneNumbers,
-		Countries:    req.Countries,
-		Languages:    req.Languages,
-		Password:     req.Password,
+		Emails:            req.Emails,
+		PhoneNumbers:      req.PhoneNumbers,
+		Countries:         req.Countries,
+		Languages:         req.Languages,
+		Password:          req.Password,
+		LegalName:         profile.LegalName,
+		PreferredLanguage: profile.PreferredLanguage,
+		Timezone:          profile.Timezone,
 	}
 	if req.AvatarImageUrl != nil {
 		params.AvatarImageURL = req.AvatarImageUrl
 	}
+	if profile.DisplayName != nil {
+		params.DisplayName = *profile.DisplayName
+	}
 	holder, err := h.hs.Insert(ctx, logger, params)
 	if err != nil {
+		if st := passwordPolicyError("password", err); st != nil {
+			return nil, st
+		}
 		return nil, status.Errorf(codes.Internal, "failed create holder entity (error = %v)", err.Error())
 	}
 
@@ -170,7 +280,7 @@ func (h *Handler) RegisterHolder(ctx context.Context, req *pbv1.NetworkWardenSer
 		approach = pbv1.NetworkWardenServiceConfirmationApproach_NETWORK_WARDEN_SERVICE_CONFIRMATION_APPROACH_EMAIL
 	}
 	if err := h.sendConfirmationMessage(ctx, logger, approach, holder); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed send confirmation (error = %v)", err.Error())
+		return nil, sendEmailError(err, "failed send confirmation")
 	}
 
 	return &pbv1.NetworkWardenServiceRegisterHolderResponse{
@@ -180,7 +290,21 @@ func (h *Handler) RegisterHolder(ctx context.Context, req *pbv1.NetworkWardenSer
 	}, nil
 }
 
+// createSession creates session of holder after successful login. Every
+// login ends here, so suspended holders are rejected here.
 func (h *Handler) createSession(ctx context.Context, logger *zap.Logger, holderID int64, ip string, mac *string) (string, string, error) {
+	holder, err := h.hs.GetHolderByID(ctx, logger, holderID)
+	if err != nil {
+		return "", "", status.Errorf(codes.Internal, "failed get holder (error = %v)", err.Error())
+	}
+	if holder != nil && holder.SuspendedAt.Valid {
+		logger.Error("holder is suspended", zap.Int64("holder-id", holderID))
+		return "", "", status.Error(codes.PermissionDenied, "holder is suspended")
+	}
+	deviceKey, err := h.sessionBinding.DeviceKey(logger, incomingMetadataValue(ctx, sessionbinding.DeviceKeyMetadataKey))
+	if err != nil {
+		return "", "", status.Errorf(codes.InvalidArgument, "invalid device key (error = %v)", err.Error())
+	}
 	token, refreshToken, err := h.jwt.CreateTokens(ctx, logger, fmt.Sprint(holderID))
 	if err != nil {
 		return "", "", status.Errorf(codes.Internal, "failed create tokens (error = %v)", err.Error())
@@ -192,6 +316,7 @@ func (h *Handler) createSession(ctx context.Context, logger *zap.Logger, holderI
 		RefreshToken:     refreshToken,
 		RemoteIPAddress:  ip,
 		RemoteMACAddress: mac,
+		DeviceKey:        deviceKey,
 	})
 	if err != nil {
 		return "", "", status.Errorf(codes.Internal, "failed create session (error = %v)", err.Error())
@@ -243,20 +368,71 @@ func (h *Handler) validateRegisterHolderRequest(ctx context.Context, logger *zap
 
 func (h *Handler) sendConfirmationMessage(ctx context.Context, logger *zap.Logger, approach pbv1.NetworkWardenServiceConfirmationApproach, holder *models.Holder) error {
 	if approach == pbv1.NetworkWardenServiceConfirmationApproach_NETWORK_WARDEN_SERVICE_CONFIRMATION_APPROACH_EMAIL {
-		if err := h.emailer.SendConfirmationOfRegistration(ctx, logger, holder.Emails[0], holder.Emails[0], holder.ConfirmationCode); err != nil {
+		if err := h.emailer.SendConfirmationOfRegistration(ctx, logger, emailer.HolderRecipient(holder, holder.Emails[0]), holder.ConfirmationCode); err != nil {
 			return err
 		}
 		return nil
 	}
 	if approach == pbv1.NetworkWardenServiceConfirmationApproach_NETWORK_WARDEN_SERVICE_CONFIRMATION_APPROACH_PHONE_NUMBER {
-		if err := h.smsSender.Send(ctx); err != nil {
+		if err := h.smsSender.SendConfirmationOfRegistration(ctx, logger, smssender.HolderRecipient(holder, holder.PhoneNumbers[0]), holder.ConfirmationCode); err != nil {
 			return err
 		}
+		return nil
 	}
 
 	return errorwrapper.New("unknown approach for sending confirmation of registration code")
 }
 
+// sendEmailError converts error of emailer to status. Exceeded rate limit
+// gets ResourceExhausted with retry delay in details, suppressed email
+// address gets FailedPrecondition, other errors are internal.
+func sendEmailError(err error, msg string) error {
+	var rle *emailer.RateLimitedError
+	switch {
+	case errors.As(err, &rle):
+		return retryAfterError(rle.Error(), rle.RetryAfter)
+	case errors.Is(err, emailer.ErrSuppressedEmail):
+		return status.Error(codes.FailedPrecondition, "email address doesn't accept emails, it bounced or complained about them")
+	}
+
+	return status.Errorf(codes.Internal, "%s (error = %v)", msg, err.Error())
+}
+
+// isUndeliverableEmailError reports whether email is not sent because of rate
+// limit or suppressed address. Methods which must not reveal whether holder
+// exists answer them with success, the same way they answer unknown holders.
+func isUndeliverableEmailError(err error) bool {
+	var rle *emailer.RateLimitedError
+	return errors.As(err, &rle) || errors.Is(err, emailer.ErrSuppressedEmail)
+}
+
+func (h *Handler) canSendConfirmationMessage(ctx context.Context, logger *zap.Logger, approach pbv1.NetworkWardenServiceConfirmationApproach, holder *models.Holder) error {
+	switch approach {
+	case pbv1.NetworkWardenServiceConfirmationApproach_NETWORK_WARDEN_SERVICE_CONFIRMATION_APPROACH_EMAIL:
+		if len(holder.Emails) == 0 {
+			return status.Error(codes.InvalidArgument, "holder doesn't have email for confirmation")
+		}
+		if err := h.emailer.CanSendConfirmationOfRegistration(ctx, logger, holder.Emails[0]); err != nil {
+			return sendEmailError(err, "failed to verify if service can send confirmation code")
+		}
+	case pbv1.NetworkWardenServiceConfirmationApproach_NETWORK_WARDEN_SERVICE_CONFIRMATION_APPROACH_PHONE_NUMBER:
+		if len(holder.PhoneNumbers) == 0 {
+			return status.Error(codes.InvalidArgument, "holder doesn't have phone number for confirmation")
+		}
+		canSend, err := h.smsSender.CanSendConfirmationOfRegistration(ctx, logger, holder.PhoneNumbers[0])
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to verify if service can send confirmation code, err = %v", err.Error())
+		}
+		if !canSend {
+			return status.Error(codes.InvalidArgument, "rate limit of sent confirmation messages was exceeded. please, try next time")
+		}
+	default:
+		return status.Error(codes.InvalidArgument, "unknown confirmation approach")
+	}
+
+	return nil
+}
+
 func (h *Handler) parseToken(ctx context.Context, logger *zap.Logger, token string, remoteMacAddress *string, scope jwt.TokenScope) (*models.HolderSession, error) {
 	t, err := h.jwt.DecodeToken(logger, token)
 	if err != nil {
@@ -275,23 +451,846 @@ func (h *Handler) parseToken(ctx context.Context, logger *zap.Logger, token stri
 	if err != nil {
 		return nil, status.Error(codes.Unauthenticated, err.Error())
 	}
-	if hs.RemoteIPAddress.Valid && hs.RemoteIPAddress.String != grpcutils.ExtractRemoteIPAddress(ctx) {
-		logger.Error("remote IP address doesn't match with session's remote IP address", zap.String("session-remote-ip-address", hs.RemoteIPAddress.String), zap.String("incoming-remote-ip-address", grpcutils.ExtractRemoteIPAddress(ctx)))
-		return nil, status.Error(codes.PermissionDenied, "no permissions")
+	// remote MAC address is supplied by client, so it is only logged and
+	// session binding policy decides whether request may use the session
+	if remoteMacAddress != nil {
+		logger = logger.With(zap.String("incoming-remote-mac-address", *remoteMacAddress))
+	}
+	err = h.sessionBinding.Check(logger, &sessionbinding.Binding{
+		RemoteIPAddress: hs.RemoteIPAddress,
+		DeviceKey:       hs.DeviceKey,
+	}, &sessionbinding.Request{
+		RemoteIPAddress: grpcutils.ExtractRemoteIPAddress(ctx),
+		Token:           token,
+		DeviceKeyProof:  incomingMetadataValue(ctx, sessionbinding.DeviceKeyProofMetadataKey),
+	})
+	if err != nil {
+		return nil, h.sessionBindingMismatchError(ctx, logger, hs, remoteMacAddress, err)
+	}
+	if err := h.auth.MarkHolderSessionUsed(ctx, logger, hs); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed modify session (error = %v)", err.Error())
+	}
+
+	return hs, nil
+}
+
+// sessionBindingMismatchError handles request which doesn't match session
+// binding according to mismatch action. Reauthentication and step-up expire
+// the session, step-up passes two-factor login challenge in the error details
+// if the holder has two-factor authentication enabled. Client redeems it with
+// RedeemTwoFactorChallenge from the new address and with its device key, so
+// the new session is bound to them. Step-up challenge is redeemed with
+// holder's password too, so a stolen token and a second factor are not
+// enough to get a new session.
+func (h *Handler) sessionBindingMismatchError(ctx context.Context, logger *zap.Logger, hs *models.HolderSession, mac *string, err error) error {
+	var mismatchErr *sessionbinding.MismatchError
+	if !errors.As(err, &mismatchErr) {
+		return status.Errorf(codes.Internal, "failed check session binding (error = %v)", err.Error())
+	}
+	if mismatchErr.Action == sessionbinding.MismatchActionDeny {
+		return sessionBindingError(codes.PermissionDenied, "no permissions", "SESSION_BINDING_MISMATCH")
+	}
+
+	if err := h.auth.MakeHolderSessionExpired(ctx, logger, hs.ID, hs); err != nil {
+		return status.Errorf(codes.Internal, "failed expire session (error = %v)", err.Error())
+	}
+	if mismatchErr.Action == sessionbinding.MismatchActionStepUp {
+		twoFactorEnabled, err := h.twoFactor.IsEnabled(ctx, logger, hs.HolderID)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed check two-factor authentication, err=%v", err.Error())
+		}
+		if twoFactorEnabled {
+			return h.twoFactorChallengeError(ctx, logger, hs.HolderID, mac, true)
+		}
+	}
+
+	return sessionBindingError(codes.Unauthenticated, "reauthentication required", "REAUTHENTICATION_REQUIRED")
+}
+
+func sessionBindingError(code codes.Code, message, reason string) error {
+	st, err := status.New(code, message).WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: "networkwarden.v1",
+	})
+	if err != nil {
+		return status.Error(code, message)
+	}
+
+	return st.Err()
+}
+
+// incomingMetadataValue returns the first value of incoming metadata key.
+func incomingMetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func (h *Handler) ConfirmHolderRegistration(ctx context.Context, req *pbv1.NetworkWardenServiceConfirmHolderRegistrationRequest) (*pbv1.NetworkWardenServiceConfirmHolderRegistrationResponse, error) {
+	logger := h.customizeLogger(ctx, "ConfirmHolderRegistration")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	logger = logger.With(zap.Int64("session-holder-id", hs.HolderID))
+	if _, err := h.hs.Confirm(ctx, logger, hs.HolderID, req.ConfirmationCode); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to confirm holder registration (err = %v)", err.Error())
+	}
+
+	return &pbv1.NetworkWardenServiceConfirmHolderRegistrationResponse{Success: true}, nil
+}
+
+func (h *Handler) ResendConfirmationCode(ctx context.Context, req *pbv1.NetworkWardenServiceResendConfirmationCodeRequest) (*pbv1.NetworkWardenServiceResendConfirmationCodeResponse, error) {
+	logger := h.customizeLogger(ctx, "ResendConfirmationCode")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get holder for resending confirmation code, err = %v", err.Error())
+	}
+	if holder == nil {
+		return nil, status.Error(codes.InvalidArgument, "can not found holder by token's information")
+	}
+	if err := h.canSendConfirmationMessage(ctx, logger, req.ConfirmationApproach, holder); err != nil {
+		return nil, err
+	}
+
+	holder, err = h.hs.RegenerateConfirmationCode(ctx, logger, hs.HolderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to regenerate confirmation code, err = %v", err.Error())
+	}
+	if err := h.sendConfirmationMessage(ctx, logger, req.ConfirmationApproach, holder); err != nil {
+		return nil, sendEmailError(err, "failed send confirmation")
+	}
+
+	return &pbv1.NetworkWardenServiceResendConfirmationCodeResponse{Success: true}, nil
+}
+
+func (h *Handler) LoginHolder(ctx context.Context, req *pbv1.NetworkWardenServiceLoginHolderRequest) (*pbv1.NetworkWardenServiceLoginHolderResponse, error) {
+	logger := h.customizeLogger(ctx, "LoginHolder")
+	defer logger.Info("request processed")
+
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+
+	holder, err := h.hs.GetHolderByEmailOrPhoneNumber(ctx, logger, req.Email, req.PhoneNumber)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+
+	accountKey := loginthrottles.HolderKey(holder.ID)
+	if err := h.loginThrottles.Check(ctx, logger, accountKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	if err := h.hs.ValidatePassword(ctx, logger, holder, req.Password); err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey, accountKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, "invalid password")
+	}
+	if err := h.loginThrottles.RegisterSuccess(ctx, logger, accountKey); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed register successful login attempt (error = %v)", err.Error())
+	}
+	twoFactorEnabled, err := h.twoFactor.IsEnabled(ctx, logger, holder.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed check two-factor authentication, err=%v", err.Error())
+	}
+	if twoFactorEnabled {
+		return nil, h.twoFactorChallengeError(ctx, logger, holder.ID, req.RemoteMacAddress, false)
+	}
+	token, refreshToken, err := h.createSession(ctx, logger, holder.ID, grpcutils.ExtractRemoteIPAddress(ctx), req.RemoteMacAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pbv1.NetworkWardenServiceLoginHolderResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// RequestHolderLoginLinkRequest and RequestHolderLoginLinkResponse stand for
+// the RPC messages until they are published in schemas, the method is served
+// by HTTP gateway only.
+type RequestHolderLoginLinkRequest struct {
+	Email string `json:"email"`
+}
+
+type RequestHolderLoginLinkResponse struct {
+	Success bool `json:"success"`
+}
+
+// RequestHolderLoginLink emails single-use login link to holder. Login links
+// are rate limited the same way as other emails to the address. Response
+// doesn't tell whether the holder exists or the link was sent, so the method
+// can't be used to enumerate holders.
+func (h *Handler) RequestHolderLoginLink(ctx context.Context, req *RequestHolderLoginLinkRequest) (*RequestHolderLoginLinkResponse, error) {
+	logger := h.customizeLogger(ctx, "RequestHolderLoginLink")
+	defer logger.Info("request processed")
+
+	if !h.loginLinks.Enabled() {
+		return nil, status.Error(codes.Unimplemented, "login links are disabled")
+	}
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	if req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "invalid request (email is required)")
+	}
+	if err := validators.ValidateEmail(ctx, req.Email); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request, invalid email (error = %v)", err.Error())
+	}
+
+	holder, err := h.hs.GetHolderByEmailOrPhoneNumber(ctx, logger, req.Email, "")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		logger.Info("holder not found, login link is not sent")
+		return &RequestHolderLoginLinkResponse{Success: true}, nil
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+	// the link is sent to the address which is stored for holder, not to the
+	// one which came in the request
+	email, ok := lo.Find(holder.Emails, func(e string) bool { return strings.EqualFold(e, req.Email) })
+	if !ok {
+		logger.Error("requested email is not email of holder, login link is not sent")
+		return &RequestHolderLoginLinkResponse{Success: true}, nil
+	}
+
+	if err := h.emailer.CanSendHolderLoginLink(ctx, logger, email); err != nil {
+		if isUndeliverableEmailError(err) {
+			logger.Warn("login link is not sent", zap.Error(err))
+			return &RequestHolderLoginLinkResponse{Success: true}, nil
+		}
+		return nil, sendEmailError(err, "failed check login link emails")
+	}
+	link, token, err := h.loginLinks.Issue(ctx, logger, holder.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to issue login link, err=%v", err.Error())
+	}
+	if err := h.emailer.SendHolderLoginLink(ctx, logger, emailer.HolderRecipient(holder, email), link, token, h.loginLinks.TokenAge()); err != nil {
+		if isUndeliverableEmailError(err) {
+			logger.Warn("login link is not sent", zap.Error(err))
+			return &RequestHolderLoginLinkResponse{Success: true}, nil
+		}
+		return nil, sendEmailError(err, "failed to send login link")
+	}
+
+	return &RequestHolderLoginLinkResponse{Success: true}, nil
+}
+
+// RedeemHolderLoginLinkRequest and RedeemHolderLoginLinkResponse stand for the
+// RPC messages until they are published in schemas, the method is served by
+// HTTP gateway only.
+type RedeemHolderLoginLinkRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type RedeemHolderLoginLinkResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RedeemHolderLoginLink creates session the same way LoginHolder does. Login
+// link replaces password only, holders with two-factor authentication get
+// login challenge.
+func (h *Handler) RedeemHolderLoginLink(ctx context.Context, req *RedeemHolderLoginLinkRequest) (*RedeemHolderLoginLinkResponse, error) {
+	logger := h.customizeLogger(ctx, "RedeemHolderLoginLink")
+	defer logger.Info("request processed")
+
+	if !h.loginLinks.Enabled() {
+		return nil, status.Error(codes.Unimplemented, "login links are disabled")
+	}
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+
+	holderID, err := h.loginLinks.Redeem(ctx, logger, req.Token)
+	if err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid login link")
+	}
+	logger = logger.With(zap.Int64("holder-id", holderID))
+
+	twoFactorEnabled, err := h.twoFactor.IsEnabled(ctx, logger, holderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed check two-factor authentication, err=%v", err.Error())
+	}
+	if twoFactorEnabled {
+		return nil, h.twoFactorChallengeError(ctx, logger, holderID, req.RemoteMacAddress, false)
+	}
+	token, refreshToken, err := h.createSession(ctx, logger, holderID, grpcutils.ExtractRemoteIPAddress(ctx), req.RemoteMacAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedeemHolderLoginLinkResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// validateEmailOrPhoneNumber checks that exactly one of email and phone number
+// is set and is valid. Methods served by HTTP gateway only are not covered by
+// the request validator of gRPC server, so they validate holder identifiers
+// themselves.
+func validateEmailOrPhoneNumber(ctx context.Context, email, phoneNumber string) error {
+	if (email == "") == (phoneNumber == "") {
+		return status.Error(codes.InvalidArgument, "invalid request (either email or phone number is required)")
+	}
+	if email != "" {
+		if err := validators.ValidateEmail(ctx, email); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid request, invalid email (error = %v)", err.Error())
+		}
+		return nil
+	}
+	if err := validators.ValidatePhoneNumber(ctx, phoneNumber); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request, invalid phone number (error = %v)", err.Error())
+	}
+
+	return nil
+}
+
+// Password reset request and response messages stand for the RPC messages
+// until they are published in schemas, the methods are served by HTTP gateway
+// only.
+type RequestHolderPasswordResetRequest struct {
+	Email       string `json:"email,omitempty"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+}
+
+type RequestHolderPasswordResetResponse struct {
+	Success bool `json:"success"`
+}
+
+// RequestHolderPasswordReset sends password reset code to email or phone
+// number of holder. Unknown email or phone number gets the same response, so
+// the method can't be used to find out registered holders.
+func (h *Handler) RequestHolderPasswordReset(ctx context.Context, req *RequestHolderPasswordResetRequest) (*RequestHolderPasswordResetResponse, error) {
+	logger := h.customizeLogger(ctx, "RequestHolderPasswordReset")
+	defer logger.Info("request processed")
+
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	if err := validateEmailOrPhoneNumber(ctx, req.Email, req.PhoneNumber); err != nil {
+		return nil, err
+	}
+
+	holder, err := h.hs.GetHolderByEmailOrPhoneNumber(ctx, logger, req.Email, req.PhoneNumber)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		logger.Info("holder not found, password reset code is not sent")
+		return &RequestHolderPasswordResetResponse{Success: true}, nil
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+
+	if req.Email != "" {
+		if err := h.emailer.CanSendResetHolderPassword(ctx, logger, req.Email); err != nil {
+			if isUndeliverableEmailError(err) {
+				logger.Warn("password reset code is not sent", zap.Error(err))
+				return &RequestHolderPasswordResetResponse{Success: true}, nil
+			}
+			return nil, sendEmailError(err, "failed check password reset emails")
+		}
+	} else {
+		canSend, err := h.smsSender.CanSendResetHolderPassword(ctx, logger, req.PhoneNumber)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed check password reset SMS, err=%v", err.Error())
+		}
+		if !canSend {
+			logger.Warn("password reset code is not sent, rate limit of SMS was exceeded")
+			return &RequestHolderPasswordResetResponse{Success: true}, nil
+		}
+	}
+	pr, code, err := h.passwordResets.Issue(ctx, logger, holder.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed issue password reset, err=%v", err.Error())
+	}
+	if req.Email != "" {
+		if err := h.emailer.SendResetHolderPassword(ctx, logger, emailer.HolderRecipient(holder, req.Email), code, time.Until(pr.ExpiredAt)); err != nil {
+			if isUndeliverableEmailError(err) {
+				logger.Warn("password reset code is not sent", zap.Error(err))
+				return &RequestHolderPasswordResetResponse{Success: true}, nil
+			}
+			return nil, sendEmailError(err, "failed send password reset email")
+		}
+	} else {
+		if err := h.smsSender.SendResetHolderPassword(ctx, logger, smssender.HolderRecipient(holder, req.PhoneNumber), code); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed send password reset SMS, err=%v", err.Error())
+		}
+	}
+
+	return &RequestHolderPasswordResetResponse{Success: true}, nil
+}
+
+type ResetHolderPasswordRequest struct {
+	Email       string `json:"email,omitempty"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+	Code        string `json:"code"`
+	NewPassword string `json:"newPassword"`
+}
+
+type ResetHolderPasswordResponse struct {
+	Success bool `json:"success"`
+}
+
+// ResetHolderPassword sets new password by password reset code which was sent
+// by RequestHolderPasswordReset or by admin. All sessions of holder are
+// expired, so whoever knew the old password is logged out.
+func (h *Handler) ResetHolderPassword(ctx context.Context, req *ResetHolderPasswordRequest) (*ResetHolderPasswordResponse, error) {
+	logger := h.customizeLogger(ctx, "ResetHolderPassword")
+	defer logger.Info("request processed")
+
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	if err := validateEmailOrPhoneNumber(ctx, req.Email, req.PhoneNumber); err != nil {
+		return nil, err
+	}
+
+	holder, err := h.hs.GetHolderByEmailOrPhoneNumber(ctx, logger, req.Email, req.PhoneNumber)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, "invalid password reset code")
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+
+	// the code is checked before the password policy, so policy violations
+	// are told only to whoever has the code and can't reveal the holder
+	if err := h.passwordResets.Verify(ctx, logger, holder.ID, req.Code); err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, "invalid password reset code")
+	}
+	if err := h.hs.ValidatePasswordPolicy(ctx, logger, holder, req.NewPassword); err != nil {
+		if st := passwordPolicyError("new_password", err); st != nil {
+			return nil, st
+		}
+		return nil, status.Error(codes.InvalidArgument, "invalid new password")
+	}
+	if err := h.passwordResets.Redeem(ctx, logger, holder.ID, req.Code); err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, "invalid password reset code")
+	}
+	if err := h.hs.ChangePassword(ctx, logger, holder, req.NewPassword); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to change holder's password, err=%v", err.Error())
+	}
+	if err := h.auth.MakeHolderSessionsExpired(ctx, logger, holder.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed expire holder sessions, err=%v", err.Error())
+	}
+	// holder proved access to email or phone number, the account lockout is
+	// not needed anymore
+	if err := h.loginThrottles.RegisterSuccess(ctx, logger, loginthrottles.HolderKey(holder.ID)); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed register successful login attempt (error = %v)", err.Error())
+	}
+
+	return &ResetHolderPasswordResponse{Success: true}, nil
+}
+
+// passwordPolicyError converts password policy violations to InvalidArgument
+// status with a field violation per broken rule. It returns nil for other
+// errors.
+func passwordPolicyError(field string, err error) error {
+	var policyErr *passwords.PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+
+	br := &errdetails.BadRequest{}
+	reasons := make([]string, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: v.Description,
+		})
+		reasons = append(reasons, v.Reason)
+	}
+	st, err := status.New(codes.InvalidArgument, "password violates policy").WithDetails(br, &errdetails.ErrorInfo{
+		Reason:   "PASSWORD_POLICY_VIOLATION",
+		Domain:   "networkwarden.v1",
+		Metadata: map[string]string{"violations": strings.Join(reasons, ",")},
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed attach password policy violations (error = %v)", err.Error())
+	}
+
+	return st.Err()
+}
+
+// twoFactorChallengeError builds the error returned by LoginHolder instead of
+// tokens when the holder has two-factor authentication enabled. The login
+// challenge is passed in the error details, the client completes the login by
+// redeeming it by RedeemTwoFactorChallenge together with TOTP or recovery code.
+// Step-up challenge is marked in the details, it takes password as well.
+func (h *Handler) twoFactorChallengeError(ctx context.Context, logger *zap.Logger, holderID int64, mac *string, stepUp bool) error {
+	challenge, err := h.twoFactor.CreateLoginChallenge(ctx, logger, &twofactor.CreateLoginChallengeParams{
+		HolderID:         holderID,
+		RemoteIPAddress:  grpcutils.ExtractRemoteIPAddress(ctx),
+		RemoteMACAddress: mac,
+		StepUp:           stepUp,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed create login challenge (error = %v)", err.Error())
+	}
+
+	st, err := status.New(codes.Unauthenticated, "two-factor authentication required").WithDetails(&errdetails.ErrorInfo{
+		Reason:   "TWO_FACTOR_REQUIRED",
+		Domain:   "networkwarden.v1",
+		Metadata: map[string]string{"challenge": challenge, "step_up": strconv.FormatBool(stepUp)},
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed attach login challenge (error = %v)", err.Error())
+	}
+
+	return st.Err()
+}
+
+// Two-factor authentication request and response messages stand for the RPC
+// messages until they are published in schemas, the methods are served by
+// HTTP gateway only.
+type EnrollTwoFactorRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type EnrollTwoFactorResponse struct {
+	Secret          string   `json:"secret"`
+	ProvisioningUri string   `json:"provisioningUri"`
+	RecoveryCodes   []string `json:"recoveryCodes"`
+}
+
+// EnrollTwoFactor generates TOTP secret and recovery codes. Two-factor
+// authentication is enabled after VerifyTwoFactor confirms that holder's
+// authenticator produces valid codes.
+func (h *Handler) EnrollTwoFactor(ctx context.Context, req *EnrollTwoFactorRequest) (*EnrollTwoFactorResponse, error) {
+	logger := h.customizeLogger(ctx, "EnrollTwoFactor")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		logger.Error("holder not found")
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
+	}
+	var account string
+	if len(holder.Emails) > 0 {
+		account = holder.Emails[0]
+	} else if len(holder.PhoneNumbers) > 0 {
+		account = holder.PhoneNumbers[0]
+	}
+	enrollment, err := h.twoFactor.Enroll(ctx, logger, holder.ID, account)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to enroll two-factor authentication, err=%v", err.Error())
+	}
+
+	return &EnrollTwoFactorResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningUri: enrollment.ProvisioningURI,
+		RecoveryCodes:   enrollment.RecoveryCodes,
+	}, nil
+}
+
+type TwoFactorCodeRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Code             string  `json:"code"`
+}
+
+type TwoFactorCodeResponse struct {
+	Success bool `json:"success"`
+}
+
+// VerifyTwoFactor enables two-factor authentication by the first TOTP code of
+// enrolled secret.
+func (h *Handler) VerifyTwoFactor(ctx context.Context, req *TwoFactorCodeRequest) (*TwoFactorCodeResponse, error) {
+	logger := h.customizeLogger(ctx, "VerifyTwoFactor")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	accountKey := loginthrottles.HolderKey(hs.HolderID)
+	if err := h.loginThrottles.Check(ctx, logger, accountKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	if err := h.twoFactor.Verify(ctx, logger, hs.HolderID, req.Code); err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, accountKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "failed to verify two-factor authentication, err=%v", err.Error())
+	}
+
+	return &TwoFactorCodeResponse{Success: true}, nil
+}
+
+// DisableTwoFactor removes TOTP secret and recovery codes, it takes TOTP code
+// or unused recovery code.
+func (h *Handler) DisableTwoFactor(ctx context.Context, req *TwoFactorCodeRequest) (*TwoFactorCodeResponse, error) {
+	logger := h.customizeLogger(ctx, "DisableTwoFactor")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	accountKey := loginthrottles.HolderKey(hs.HolderID)
+	if err := h.loginThrottles.Check(ctx, logger, accountKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	if err := h.twoFactor.Disable(ctx, logger, hs.HolderID, req.Code); err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, accountKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "failed to disable two-factor authentication, err=%v", err.Error())
+	}
+
+	return &TwoFactorCodeResponse{Success: true}, nil
+}
+
+type RedeemTwoFactorChallengeRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+	// Password is required by step-up challenges only.
+	Password         string  `json:"password,omitempty"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type RedeemTwoFactorChallengeResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RedeemTwoFactorChallenge completes login which was answered with
+// TWO_FACTOR_REQUIRED error. It takes the challenge from error details
+// together with TOTP code or unused recovery code, step-up challenge takes
+// holder's password too.
+func (h *Handler) RedeemTwoFactorChallenge(ctx context.Context, req *RedeemTwoFactorChallengeRequest) (*RedeemTwoFactorChallengeResponse, error) {
+	logger := h.customizeLogger(ctx, "RedeemTwoFactorChallenge")
+	defer logger.Info("request processed")
+
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	if req.Challenge == "" || req.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "invalid request (challenge and code are required)")
+	}
+
+	lc, err := h.twoFactor.RedeemLoginChallenge(ctx, logger, req.Challenge, req.Code)
+	if err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid two-factor authentication code")
+	}
+	logger = logger.With(zap.Int64("holder-id", lc.HolderID))
+	if lc.StepUp {
+		// the challenge was issued to whoever presented the token, the password
+		// proves it is the holder; the challenge is used up either way
+		if err := h.validateStepUpPassword(ctx, logger, ipKey, lc.HolderID, req.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	token, refreshToken, err := h.createSession(ctx, logger, lc.HolderID, grpcutils.ExtractRemoteIPAddress(ctx), req.RemoteMacAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedeemTwoFactorChallengeResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (h *Handler) validateStepUpPassword(ctx context.Context, logger *zap.Logger, ipKey loginthrottles.Key, holderID int64, password string) error {
+	accountKey := loginthrottles.HolderKey(holderID)
+	if err := h.loginThrottles.Check(ctx, logger, accountKey); err != nil {
+		return loginThrottledError(err)
+	}
+	holder, err := h.hs.GetHolderByID(ctx, logger, holderID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		return status.Error(codes.Unauthenticated, "invalid password")
+	}
+	if err := h.hs.ValidatePassword(ctx, logger, holder, password); err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey, accountKey); err != nil {
+			return status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return status.Error(codes.Unauthenticated, "invalid password")
+	}
+
+	return nil
+}
+
+func (h *Handler) LogoutHolder(ctx context.Context, req *pbv1.NetworkWardenServiceLogoutHolderRequest) (*pbv1.NetworkWardenServiceLogoutHolderResponse, error) {
+	logger := h.customizeLogger(ctx, "LogoutHolder")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	if err := h.auth.MakeHolderSessionExpired(ctx, logger, hs.HolderID, hs); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed modify session (error = %v)", err.Error())
+	}
+
+	return &pbv1.NetworkWardenServiceLogoutHolderResponse{Success: true}, nil
+}
+
+func (h *Handler) RefreshHolderToken(ctx context.Context, req *pbv1.NetworkWardenServiceRefreshHolderTokenRequest) (*pbv1.NetworkWardenServiceRefreshHolderTokenResponse, error) {
+	logger := h.customizeLogger(ctx, "RefreshHolderToken")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.RefreshToken, req.RemoteMacAddress, jwt.TokenScopeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	token, refreshToken, err := h.jwt.CreateTokens(ctx, logger, fmt.Sprint(hs.HolderID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed create tokens (error = %v)", err.Error())
+	}
+
+	if err := h.auth.RotateHolderSessionTokens(ctx, logger, hs, token, refreshToken); err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			return nil, status.Error(codes.Unauthenticated, "refresh token was already used")
+		}
+		return nil, status.Errorf(codes.Internal, "failed modify session (error = %v)", err.Error())
+	}
+
+	return &pbv1.NetworkWardenServiceRefreshHolderTokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (h *Handler) ChangeHolderPassword(ctx context.Context, req *pbv1.NetworkWardenServiceChangeHolderPasswordRequest) (*pbv1.NetworkWardenServiceChangeHolderPasswordResponse, error) {
+	logger := h.customizeLogger(ctx, "ChangeHolderPassword")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		logger.Error("holder not found")
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
+	}
+	if err := h.hs.ValidatePassword(ctx, logger, holder, req.Password); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid password")
+	}
+	if err := h.hs.ChangePassword(ctx, logger, holder, req.NewPassword); err != nil {
+		if st := passwordPolicyError("new_password", err); st != nil {
+			return nil, st
+		}
+		return nil, status.Error(codes.InvalidArgument, "failed to change holder's password")
+	}
+
+	return &pbv1.NetworkWardenServiceChangeHolderPasswordResponse{Success: true}, nil
+}
+
+func (h *Handler) ModifyHolder(ctx context.Context, req *pbv1.NetworkWardenServiceModifyHolderRequest) (*pbv1.NetworkWardenServiceModifyHolderResponse, error) {
+	logger := h.customizeLogger(ctx, "ModifyHolder")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		logger.Error("holder not found")
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
+	}
+
+	params := &holders.ModifyParams{
+		AvatarImageURL: req.AvatarImageUrl,
+		Countries:      req.Countries,
+		Languages:      req.Languages,
 	}
-	if remoteMacAddress != nil {
-		logger = logger.With(zap.String("incoming-remote-mac-address", *remoteMacAddress))
-		if hs.RemoteMACAddress.Valid && hs.RemoteMACAddress.String != *remoteMacAddress {
-			logger.Error("remote MAC address doesn't match with session's remote MAC address", zap.String("session-remote-mac-address", hs.RemoteMACAddress.String))
-			return nil, status.Error(codes.PermissionDenied, "no permissions")
-		}
+	if _, err := h.hs.Modify(ctx, logger, holder, params); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "failed to modify holder")
 	}
 
-	return hs, nil
+	return &pbv1.NetworkWardenServiceModifyHolderResponse{Success: true}, nil
 }
 
-func (h *Handler) ConfirmHolderRegistration(ctx context.Context, req *pbv1.NetworkWardenServiceConfirmHolderRegistrationRequest) (*pbv1.NetworkWardenServiceConfirmHolderRegistrationResponse, error) {
-	logger := h.customizeLogger(ctx, "ConfirmHolderRegistration")
+func (h *Handler) GetHolder(ctx context.Context, req *pbv1.NetworkWardenServiceGetHolderRequest) (*pbv1.NetworkWardenServiceGetHolderResponse, error) {
+	logger := h.customizeLogger(ctx, "GetHolder")
 	defer logger.Info("request processed")
 
 	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
@@ -300,16 +1299,25 @@ func (h *Handler) ConfirmHolderRegistration(ctx context.Context, req *pbv1.Netwo
 	}
 	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
 
-	logger = logger.With(zap.Int64("session-holder-id", hs.HolderID))
-	if _, err := h.hs.Confirm(ctx, logger, hs.HolderID, req.ConfirmationCode); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "failed to confirm holder registration (err = %v)", err.Error())
+	holderID, err := strconv.ParseInt(req.HolderId, 10, 64)
+	if err != nil {
+		logger.Error("invalid ID", zap.Error(err), zap.String("incoming-holder-id", req.HolderId))
+		return nil, status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	holder, err := h.hs.GetHolderByID(ctx, logger, holderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		logger.Error("holder not found")
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
 	}
 
-	return &pbv1.NetworkWardenServiceConfirmHolderRegistrationResponse{Success: true}, nil
+	return &pbv1.NetworkWardenServiceGetHolderResponse{Data: converters.ConvertHolderToProtoHolder(holder)}, nil
 }
 
-func (h *Handler) ResendConfirmationCode(ctx context.Context, req *pbv1.NetworkWardenServiceResendConfirmationCodeRequest) (*pbv1.NetworkWardenServiceResendConfirmationCodeResponse, error) {
-	logger := h.customizeLogger(ctx, "ResendConfirmationCode")
+func (h *Handler) DeleteHolder(ctx context.Context, req *pbv1.NetworkWardenServiceDeleteHolderRequest) (*pbv1.NetworkWardenServiceDeleteHolderResponse, error) {
+	logger := h.customizeLogger(ctx, "DeleteHolder")
 	defer logger.Info("request processed")
 
 	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
@@ -320,59 +1328,135 @@ func (h *Handler) ResendConfirmationCode(ctx context.Context, req *pbv1.NetworkW
 
 	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get holder for resending confirmation code, err = %v", err.Error())
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
 	}
 	if holder == nil {
-		return nil, status.Error(codes.InvalidArgument, "can not found holder by token's information")
+		logger.Error("holder not found")
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
 	}
-	canSend, err := h.emailer.CanSendConfirmationOfRegistration(ctx, logger, holder.Emails[0])
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to verify if service can send confirmation code, err = %v", err.Error())
+	if err := h.hs.ValidatePassword(ctx, logger, holder, req.Password); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid password")
 	}
-	if !canSend {
-		return nil, status.Error(codes.InvalidArgument, "rate limit of sent emails was exceeded. please, try next time")
+
+	if holder.DeletionDueAt.Valid {
+		logger.Error("holder deletion is already scheduled")
+		return nil, status.Error(codes.FailedPrecondition, "holder deletion is already scheduled")
 	}
 
-	holder, err = h.hs.RegenerateConfirmationCode(ctx, logger, hs.HolderID)
+	holder, err = h.hs.ScheduleDeletion(ctx, logger, holder)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to regenerate confirmation code, err = %v", err.Error())
+		return nil, status.Errorf(codes.Internal, "failed to schedule holder deletion, err=%v", err.Error())
 	}
-	if err := h.sendConfirmationMessage(ctx, logger, req.ConfirmationApproach, holder); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed send confirmation (error = %v)", err.Error())
+	logger.Info("holder deletion is scheduled", zap.Time("deletion-due-at", holder.DeletionDueAt.Time))
+
+	return &pbv1.NetworkWardenServiceDeleteHolderResponse{Success: true}, nil
+}
+
+// Holder profile request and response messages stand for the RPC messages
+// until profile fields are published in schemas, the methods are served by
+// HTTP gateway only.
+type RegisterHolderWithProfileRequest struct {
+	RemoteMacAddress *string  `json:"remoteMacAddress,omitempty"`
+	Emails           []string `json:"emails,omitempty"`
+	PhoneNumbers     []string `json:"phoneNumbers,omitempty"`
+	AvatarImageUrl   *string  `json:"avatarImageUrl,omitempty"`
+	Countries        []string `json:"countries,omitempty"`
+	Languages        []string `json:"languages,omitempty"`
+	Password         string   `json:"password"`
+	holderProfile
+}
+
+type RegisterHolderWithProfileResponse struct {
+	Token                string `json:"token"`
+	RefreshToken         string `json:"refreshToken"`
+	ConfirmationApproach string `json:"confirmationApproach"`
+}
+
+func (h *Handler) RegisterHolderWithProfile(ctx context.Context, req *RegisterHolderWithProfileRequest) (*RegisterHolderWithProfileResponse, error) {
+	logger := h.customizeLogger(ctx, "RegisterHolderWithProfile")
+	defer logger.Info("request processed")
+
+	resp, err := h.registerHolder(ctx, logger, &pbv1.NetworkWardenServiceRegisterHolderRequest{
+		RemoteMacAddress: req.RemoteMacAddress,
+		Emails:           req.Emails,
+		PhoneNumbers:     req.PhoneNumbers,
+		AvatarImageUrl:   req.AvatarImageUrl,
+		Countries:        req.Countries,
+		Languages:        req.Languages,
+		Password:         req.Password,
+	}, &req.holderProfile)
+	if err != nil {
+		return nil, err
 	}
 
-	return &pbv1.NetworkWardenServiceResendConfirmationCodeResponse{Success: true}, nil
+	return &RegisterHolderWithProfileResponse{
+		Token:                resp.Token,
+		RefreshToken:         resp.RefreshToken,
+		ConfirmationApproach: resp.ConfirmationApproach.String(),
+	}, nil
 }
 
-func (h *Handler) LoginHolder(ctx context.Context, req *pbv1.NetworkWardenServiceLoginHolderRequest) (*pbv1.NetworkWardenServiceLoginHolderResponse, error) {
-	logger := h.customizeLogger(ctx, "LoginHolder")
+type ModifyHolderProfileRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	holderProfile
+}
+
+type ModifyHolderProfileResponse struct {
+	Success bool `json:"success"`
+}
+
+func (h *Handler) ModifyHolderProfile(ctx context.Context, req *ModifyHolderProfileRequest) (*ModifyHolderProfileResponse, error) {
+	logger := h.customizeLogger(ctx, "ModifyHolderProfile")
 	defer logger.Info("request processed")
 
-	holder, err := h.hs.GetHolderByEmailOrPhoneNumber(ctx, logger, req.Email, req.PhoneNumber)
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+
+	if err := req.holderProfile.validate(ctx); err != nil {
+		return nil, err
+	}
+	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
 	}
 	if holder == nil {
+		logger.Error("holder not found")
 		return nil, status.Error(codes.InvalidArgument, "holder not found")
 	}
-	logger = logger.With(zap.Int64("holder-id", holder.ID))
 
-	if err := h.hs.ValidatePassword(ctx, logger, holder, req.Password); err != nil {
-		return nil, status.Error(codes.InvalidArgument, "invalid password")
+	params := &holders.ModifyParams{
+		DisplayName:       req.DisplayName,
+		LegalName:         req.LegalName,
+		PreferredLanguage: req.PreferredLanguage,
+		Timezone:          req.Timezone,
 	}
-	token, refreshToken, err := h.createSession(ctx, logger, holder.ID, grpcutils.ExtractRemoteIPAddress(ctx), req.RemoteMacAddress)
-	if err != nil {
-		return nil, err
+	if _, err := h.hs.Modify(ctx, logger, holder, params); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "failed to modify holder")
 	}
 
-	return &pbv1.NetworkWardenServiceLoginHolderResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-	}, nil
+	return &ModifyHolderProfileResponse{Success: true}, nil
 }
 
-func (h *Handler) LogoutHolder(ctx context.Context, req *pbv1.NetworkWardenServiceLogoutHolderRequest) (*pbv1.NetworkWardenServiceLogoutHolderResponse, error) {
-	logger := h.customizeLogger(ctx, "LogoutHolder")
+type GetHolderProfileRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	HolderId         string  `json:"holderId"`
+}
+
+// GetHolderProfileResponse doesn't contain legal name of other holders.
+type GetHolderProfileResponse struct {
+	DisplayName       string  `json:"displayName"`
+	LegalName         *string `json:"legalName,omitempty"`
+	PreferredLanguage *string `json:"preferredLanguage,omitempty"`
+	Timezone          *string `json:"timezone,omitempty"`
+}
+
+func (h *Handler) GetHolderProfile(ctx context.Context, req *GetHolderProfileRequest) (*GetHolderProfileResponse, error) {
+	logger := h.customizeLogger(ctx, "GetHolderProfile")
 	defer logger.Info("request processed")
 
 	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
@@ -381,74 +1465,125 @@ func (h *Handler) LogoutHolder(ctx context.Context, req *pbv1.NetworkWardenServi
 	}
 	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
 
-	if err := h.auth.MakeHolderSessionExpired(ctx, logger, hs.HolderID, hs); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed modify session (error = %v)", err.Error())
+	holderID, err := strconv.ParseInt(req.HolderId, 10, 64)
+	if err != nil {
+		logger.Error("invalid ID", zap.Error(err), zap.String("incoming-holder-id", req.HolderId))
+		return nil, status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	holder, err := h.hs.GetHolderByID(ctx, logger, holderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+	}
+	if holder == nil {
+		logger.Error("holder not found")
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
 	}
 
-	return &pbv1.NetworkWardenServiceLogoutHolderResponse{Success: true}, nil
+	resp := &GetHolderProfileResponse{
+		DisplayName:       holder.DisplayName,
+		PreferredLanguage: lo.Ternary(holder.PreferredLanguage.Valid, &holder.PreferredLanguage.String, nil),
+		Timezone:          lo.Ternary(holder.Timezone.Valid, &holder.Timezone.String, nil),
+	}
+	if holder.ID == hs.HolderID && holder.LegalName.Valid {
+		resp.LegalName = &holder.LegalName.String
+	}
+
+	return resp, nil
 }
 
-func (h *Handler) RefreshHolderToken(ctx context.Context, req *pbv1.NetworkWardenServiceRefreshHolderTokenRequest) (*pbv1.NetworkWardenServiceRefreshHolderTokenResponse, error) {
-	logger := h.customizeLogger(ctx, "RefreshHolderToken")
+// CancelHolderDeletionRequest and CancelHolderDeletionResponse stand for the
+// RPC messages until they are published in schemas, the method is served by
+// HTTP gateway only.
+type CancelHolderDeletionRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type CancelHolderDeletionResponse struct {
+	Success bool `json:"success"`
+}
+
+func (h *Handler) CancelHolderDeletion(ctx context.Context, req *CancelHolderDeletionRequest) (*CancelHolderDeletionResponse, error) {
+	logger := h.customizeLogger(ctx, "CancelHolderDeletion")
 	defer logger.Info("request processed")
 
-	hs, err := h.parseToken(ctx, logger, req.RefreshToken, req.RemoteMacAddress, jwt.TokenScopeRefresh)
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
 	if err != nil {
 		return nil, err
 	}
 	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
 
-	token, refreshToken, err := h.jwt.CreateTokens(ctx, logger, fmt.Sprint(hs.HolderID))
+	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed create tokens (error = %v)", err.Error())
+		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
 	}
-
-	hs.Token = token
-	hs.RefreshToken = refreshToken
-	hs.ExpiredAt = sql.NullTime{
-		Time:  h.auth.GetExpiredAtForHolderSession(),
-		Valid: true,
+	if holder == nil {
+		logger.Error("holder not found")
+		return nil, status.Error(codes.InvalidArgument, "holder not found")
 	}
-	if err := h.auth.ModifyHolderSession(ctx, logger, hs.HolderID, hs); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed modify session (error = %v)", err.Error())
+	if !holder.DeletionDueAt.Valid {
+		logger.Error("holder deletion is not scheduled")
+		return nil, status.Error(codes.FailedPrecondition, "holder deletion is not scheduled")
 	}
 
-	return &pbv1.NetworkWardenServiceRefreshHolderTokenResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-	}, nil
+	if _, err := h.hs.CancelDeletion(ctx, logger, holder); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to cancel holder deletion, err=%v", err.Error())
+	}
+
+	return &CancelHolderDeletionResponse{Success: true}, nil
 }
 
-func (h *Handler) ChangeHolderPassword(ctx context.Context, req *pbv1.NetworkWardenServiceChangeHolderPasswordRequest) (*pbv1.NetworkWardenServiceChangeHolderPasswordResponse, error) {
-	logger := h.customizeLogger(ctx, "ChangeHolderPassword")
+// ExportHolderDataRequest stands for the RPC message until it is published
+// in schemas, the method is served by HTTP gateway only. Streaming the archive
+// over gRPC needs server-streaming ExportHolderData RPC with chunk message,
+// it is not part of ecumenos-social/schemas v0.0.22 yet; its handler will
+// pass writer which sends chunks to the stream.
+type ExportHolderDataRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+// ExportHolderData writes zip archive with holder's data to w. Errors are
+// returned as gRPC statuses as long as nothing is written to w.
+func (h *Handler) ExportHolderData(ctx context.Context, req *ExportHolderDataRequest, w io.Writer) error {
+	logger := h.customizeLogger(ctx, "ExportHolderData")
 	defer logger.Info("request processed")
 
 	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
 
 	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+		return status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
 	}
 	if holder == nil {
 		logger.Error("holder not found")
-		return nil, status.Error(codes.InvalidArgument, "holder not found")
-	}
-	if err := h.hs.ValidatePassword(ctx, logger, holder, req.Password); err != nil {
-		return nil, status.Error(codes.InvalidArgument, "invalid password")
+		return status.Error(codes.InvalidArgument, "holder not found")
 	}
-	if err := h.hs.ChangePassword(ctx, logger, holder, req.NewPassword); err != nil {
-		return nil, status.Error(codes.InvalidArgument, "failed to change holder's password")
+
+	if err := h.holderExports.Export(ctx, logger, holder, w); err != nil {
+		return status.Errorf(codes.Internal, "failed to export holder data, err=%v", err.Error())
 	}
 
-	return &pbv1.NetworkWardenServiceChangeHolderPasswordResponse{Success: true}, nil
+	return nil
 }
 
-func (h *Handler) ModifyHolder(ctx context.Context, req *pbv1.NetworkWardenServiceModifyHolderRequest) (*pbv1.NetworkWardenServiceModifyHolderResponse, error) {
-	logger := h.customizeLogger(ctx, "ModifyHolder")
+// Passkey request and response messages stand for the RPC messages until
+// they are published in schemas, the methods are served by HTTP gateway only.
+type BeginPasskeyRegistrationRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type BeginPasskeyRegistrationResponse struct {
+	Options *passkeys.CreationOptions `json:"options"`
+}
+
+func (h *Handler) BeginPasskeyRegistration(ctx context.Context, req *BeginPasskeyRegistrationRequest) (*BeginPasskeyRegistrationResponse, error) {
+	logger := h.customizeLogger(ctx, "BeginPasskeyRegistration")
 	defer logger.Info("request processed")
 
 	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
@@ -466,20 +1601,34 @@ func (h *Handler) ModifyHolder(ctx context.Context, req *pbv1.NetworkWardenServi
 		return nil, status.Error(codes.InvalidArgument, "holder not found")
 	}
 
-	params := &holders.ModifyParams{
-		AvatarImageURL: req.AvatarImageUrl,
-		Countries:      req.Countries,
-		Languages:      req.Languages,
+	var name string
+	if len(holder.Emails) > 0 {
+		name = holder.Emails[0]
+	} else if len(holder.PhoneNumbers) > 0 {
+		name = holder.PhoneNumbers[0]
 	}
-	if _, err := h.hs.Modify(ctx, logger, holder, params); err != nil {
-		return nil, status.Error(codes.InvalidArgument, "failed to modify holder")
+	options, err := h.passkeys.BeginRegistration(ctx, logger, holder.ID, name, name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to begin passkey registration, err=%v", err.Error())
 	}
 
-	return &pbv1.NetworkWardenServiceModifyHolderResponse{Success: true}, nil
+	return &BeginPasskeyRegistrationResponse{Options: options}, nil
 }
 
-func (h *Handler) GetHolder(ctx context.Context, req *pbv1.NetworkWardenServiceGetHolderRequest) (*pbv1.NetworkWardenServiceGetHolderResponse, error) {
-	logger := h.customizeLogger(ctx, "GetHolder")
+type FinishPasskeyRegistrationRequest struct {
+	Token            string                           `json:"token"`
+	RemoteMacAddress *string                          `json:"remoteMacAddress,omitempty"`
+	Name             string                           `json:"name"`
+	Credential       *passkeys.RegistrationCredential `json:"credential"`
+}
+
+type FinishPasskeyRegistrationResponse struct {
+	Success bool   `json:"success"`
+	Id      string `json:"id"`
+}
+
+func (h *Handler) FinishPasskeyRegistration(ctx context.Context, req *FinishPasskeyRegistrationRequest) (*FinishPasskeyRegistrationResponse, error) {
+	logger := h.customizeLogger(ctx, "FinishPasskeyRegistration")
 	defer logger.Info("request processed")
 
 	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
@@ -487,26 +1636,113 @@ func (h *Handler) GetHolder(ctx context.Context, req *pbv1.NetworkWardenServiceG
 		return nil, err
 	}
 	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+	if req.Credential == nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid request (credential is required)")
+	}
 
-	holderID, err := strconv.ParseInt(req.HolderId, 10, 64)
+	pk, err := h.passkeys.FinishRegistration(ctx, logger, hs.HolderID, req.Name, req.Credential)
 	if err != nil {
-		logger.Error("invalid ID", zap.Error(err), zap.String("incoming-holder-id", req.HolderId))
-		return nil, status.Error(codes.InvalidArgument, "invalid ID")
+		return nil, status.Errorf(codes.InvalidArgument, "failed to register passkey, err=%v", err.Error())
 	}
-	holder, err := h.hs.GetHolderByID(ctx, logger, holderID)
+
+	return &FinishPasskeyRegistrationResponse{
+		Success: true,
+		Id:      fmt.Sprint(pk.ID),
+	}, nil
+}
+
+// BeginPasskeyLoginRequest has no holder identifiers, assertion ceremony is
+// the same for every holder.
+type BeginPasskeyLoginRequest struct{}
+
+type BeginPasskeyLoginResponse struct {
+	Options *passkeys.RequestOptions `json:"options"`
+}
+
+// BeginPasskeyLogin starts assertion ceremony with discoverable passkeys, the
+// authenticator tells which holder signs in. The response is the same for
+// everyone, so the method can't be used to find out registered holders or
+// their passkeys.
+func (h *Handler) BeginPasskeyLogin(ctx context.Context, req *BeginPasskeyLoginRequest) (*BeginPasskeyLoginResponse, error) {
+	logger := h.customizeLogger(ctx, "BeginPasskeyLogin")
+	defer logger.Info("request processed")
+
+	// every call stores challenge, so throttled addresses can't fill the table
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	options, err := h.passkeys.BeginLogin(ctx, logger)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+		return nil, status.Errorf(codes.Internal, "failed to begin passkey login, err=%v", err.Error())
 	}
-	if holder == nil {
-		logger.Error("holder not found")
-		return nil, status.Error(codes.InvalidArgument, "holder not found")
+
+	return &BeginPasskeyLoginResponse{Options: options}, nil
+}
+
+type FinishPasskeyLoginRequest struct {
+	Credential       *passkeys.AssertionCredential `json:"credential"`
+	RemoteMacAddress *string                       `json:"remoteMacAddress,omitempty"`
+}
+
+type FinishPasskeyLoginResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// FinishPasskeyLogin verifies assertion and creates session the same way
+// LoginHolder does. Passkeys require user verification, so two-factor
+// authentication is not asked.
+func (h *Handler) FinishPasskeyLogin(ctx context.Context, req *FinishPasskeyLoginRequest) (*FinishPasskeyLoginResponse, error) {
+	logger := h.customizeLogger(ctx, "FinishPasskeyLogin")
+	defer logger.Info("request processed")
+
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, loginThrottledError(err)
+	}
+	if req.Credential == nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid request (credential is required)")
 	}
 
-	return &pbv1.NetworkWardenServiceGetHolderResponse{Data: converters.ConvertHolderToProtoHolder(holder)}, nil
+	pk, err := h.passkeys.FinishLogin(ctx, logger, req.Credential)
+	if err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed register failed login attempt (error = %v)", err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid passkey assertion")
+	}
+	logger = logger.With(zap.Int64("holder-id", pk.HolderID))
+
+	token, refreshToken, err := h.createSession(ctx, logger, pk.HolderID, grpcutils.ExtractRemoteIPAddress(ctx), req.RemoteMacAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FinishPasskeyLoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
 }
 
-func (h *Handler) DeleteHolder(ctx context.Context, req *pbv1.NetworkWardenServiceDeleteHolderRequest) (*pbv1.NetworkWardenServiceDeleteHolderResponse, error) {
-	logger := h.customizeLogger(ctx, "DeleteHolder")
+type GetPasskeysRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+}
+
+type Passkey struct {
+	Id         string  `json:"id"`
+	Name       string  `json:"name"`
+	CreatedAt  string  `json:"createdAt"`
+	LastUsedAt *string `json:"lastUsedAt,omitempty"`
+}
+
+type GetPasskeysResponse struct {
+	Data []*Passkey `json:"data"`
+}
+
+func (h *Handler) GetPasskeys(ctx context.Context, req *GetPasskeysRequest) (*GetPasskeysResponse, error) {
+	logger := h.customizeLogger(ctx, "GetPasskeys")
 	defer logger.Info("request processed")
 
 	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
@@ -515,23 +1751,56 @@ func (h *Handler) DeleteHolder(ctx context.Context, req *pbv1.NetworkWardenServi
 	}
 	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
 
-	holder, err := h.hs.GetHolderByID(ctx, logger, hs.HolderID)
+	list, err := h.passkeys.GetList(ctx, logger, hs.HolderID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed get holder, err=%v", err.Error())
+		return nil, status.Errorf(codes.Internal, "failed to get passkeys, err=%v", err.Error())
+	}
+	data := make([]*Passkey, 0, len(list))
+	for _, pk := range list {
+		p := &Passkey{
+			Id:        fmt.Sprint(pk.ID),
+			Name:      pk.Name,
+			CreatedAt: formats.FormatDateTime(pk.CreatedAt),
+		}
+		if pk.LastUsedAt.Valid {
+			p.LastUsedAt = lo.ToPtr(formats.FormatDateTime(pk.LastUsedAt.Time))
+		}
+		data = append(data, p)
 	}
-	if holder == nil {
-		logger.Error("holder not found")
-		return nil, status.Error(codes.InvalidArgument, "holder not found")
+
+	return &GetPasskeysResponse{Data: data}, nil
+}
+
+type RemovePasskeyRequest struct {
+	Token            string  `json:"token"`
+	RemoteMacAddress *string `json:"remoteMacAddress,omitempty"`
+	Id               string  `json:"id"`
+}
+
+type RemovePasskeyResponse struct {
+	Success bool `json:"success"`
+}
+
+func (h *Handler) RemovePasskey(ctx context.Context, req *RemovePasskeyRequest) (*RemovePasskeyResponse, error) {
+	logger := h.customizeLogger(ctx, "RemovePasskey")
+	defer logger.Info("request processed")
+
+	hs, err := h.parseToken(ctx, logger, req.Token, req.RemoteMacAddress, jwt.TokenScopeAccess)
+	if err != nil {
+		return nil, err
 	}
-	if err := h.hs.ValidatePassword(ctx, logger, holder, req.Password); err != nil {
-		return nil, status.Error(codes.InvalidArgument, "invalid password")
+	logger = logger.With(zap.Int64("holder-id", hs.HolderID))
+	id, err := strconv.ParseInt(req.Id, 10, 64)
+	if err != nil {
+		logger.Error("invalid passkey ID", zap.Error(err), zap.String("incoming-passkey-id", req.Id))
+		return nil, status.Error(codes.InvalidArgument, "invalid passkey ID")
 	}
 
-	if err := h.hs.Delete(ctx, logger, holder.ID); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to delete holder, err=%v", err.Error())
+	if err := h.passkeys.Remove(ctx, logger, hs.HolderID, id); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to remove passkey, err=%v", err.Error())
 	}
 
-	return &pbv1.NetworkWardenServiceDeleteHolderResponse{Success: true}, nil
+	return &RemovePasskeyResponse{Success: true}, nil
 }
 
 func (h *Handler) isHolderConfirmed(ctx context.Context, logger *zap.Logger, holderID int64) (bool, error) {
@@ -840,3 +2109,26 @@ func (h *Handler) RegisterNetworkWarden(ctx context.Context, req *pbv1.NetworkWa
 
 	return &pbv1.NetworkWardenServiceRegisterNetworkWardenResponse{Success: true}, nil
 }
+
+// loginThrottledError converts error of login throttles check to status,
+// throttled logins get ResourceExhausted with retry delay in details.
+func loginThrottledError(err error) error {
+	var te *loginthrottles.ThrottledError
+	if !errors.As(err, &te) {
+		return status.Errorf(codes.Internal, "failed check login attempts (error = %v)", err.Error())
+	}
+
+	return retryAfterError(te.Error(), te.RetryAfter)
+}
+
+// retryAfterError is ResourceExhausted status with retry delay in details.
+func retryAfterError(msg string, retryAfter time.Duration) error {
+	st, detailsErr := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryAfter),
+	})
+	if detailsErr != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+
+	return st.Err()
+}
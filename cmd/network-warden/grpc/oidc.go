@@ -0,0 +1,471 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	grpcutils "github.com/ecumenos-social/grpc-utils"
+	"github.com/ecumenos-social/network-warden/converters"
+	"github.com/ecumenos-social/network-warden/models"
+	loginthrottles "github.com/ecumenos-social/network-warden/services/login-throttles"
+	"github.com/ecumenos-social/network-warden/services/oidc"
+	pbv1 "github.com/ecumenos-social/schemas/proto/gen/networkwarden/v1"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+// OpenID Connect endpoints are served by HTTP gateway directly, they follow
+// OAuth 2.0 wire format (form requests, redirects, JSON responses) instead of
+// gRPC one.
+const (
+	oidcDiscoveryPath     = "/.well-known/openid-configuration"
+	oidcAuthorizationPath = "/oauth2/authorize"
+	oidcTokenPath         = "/oauth2/token"
+	oidcUserInfoPath      = "/oauth2/userinfo"
+	jwksPath              = "/.well-known/jwks.json"
+)
+
+type oidcDiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+func (h *Handler) OIDCDiscovery(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	issuer := h.oidc.Issuer()
+	doc := &oidcDiscoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + oidcAuthorizationPath,
+		TokenEndpoint:                     issuer + oidcTokenPath,
+		UserInfoEndpoint:                  issuer + oidcUserInfoPath,
+		JWKSURI:                           issuer + jwksPath,
+		ScopesSupported:                   oidc.SupportedScopes,
+		ResponseTypesSupported:            []string{oidc.ResponseTypeCode},
+		GrantTypesSupported:               []string{oidc.GrantTypeAuthorizationCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{h.jwt.Algorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{oidc.CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+			"picture", "locale", "languages", "countries",
+			"email", "email_verified", "phone_number", "phone_number_verified",
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_ = json.NewEncoder(w).Encode(doc)
+}
+
+var scopeDescriptions = map[string]string{
+	oidc.ScopeOpenID:  "confirm your identity",
+	oidc.ScopeProfile: "see your avatar, languages and countries",
+	oidc.ScopeEmail:   "see your primary email address",
+	oidc.ScopePhone:   "see your primary phone number",
+}
+
+var consentPageTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{if .Client}}Log in to {{.Client.Name}}{{else}}Log in error{{end}}</title>
+</head>
+<body>
+{{if .Client}}
+  <h1>Log in to {{.Client.Name}}</h1>
+  <p>{{.Client.Name}} ({{.Client.URL}}) wants to:</p>
+  <ul>
+  {{range .Scopes}}  <li>{{.}}</li>
+  {{end}}</ul>
+  {{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+  <form method="post" action="{{.Action}}">
+    <input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+    <input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+    <input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+    <input type="hidden" name="scope" value="{{.Request.Scope}}">
+    <input type="hidden" name="state" value="{{.Request.State}}">
+    <input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+    <input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+    <input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+    <p><label>Email or phone number <input type="text" name="login" autocomplete="username"></label></p>
+    <p><label>Password <input type="password" name="password" autocomplete="current-password"></label></p>
+    <p><label>Two-factor authentication code (if enabled) <input type="text" name="two_factor_code" autocomplete="one-time-code"></label></p>
+    <p>
+      <button type="submit" name="decision" value="allow">Allow</button>
+      <button type="submit" name="decision" value="deny">Deny</button>
+    </p>
+  </form>
+{{else}}
+  <h1>Log in error</h1>
+  <p>{{.Error}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+type consentPage struct {
+	Action  string
+	Client  *oidc.Client
+	Request *oidc.AuthorizationRequest
+	Scopes  []string
+	Error   string
+}
+
+func renderConsentPage(w http.ResponseWriter, logger *zap.Logger, statusCode int, page *consentPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// the page asks for password, it must not be framed by other sites
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(statusCode)
+	if err := consentPageTemplate.Execute(w, page); err != nil {
+		logger.Error("failed to render consent page", zap.Error(err))
+	}
+}
+
+func authorizationRequestFromForm(form url.Values) *oidc.AuthorizationRequest {
+	return &oidc.AuthorizationRequest{
+		ResponseType:        form.Get("response_type"),
+		ClientID:            form.Get("client_id"),
+		RedirectURI:         form.Get("redirect_uri"),
+		Scope:               form.Get("scope"),
+		State:               form.Get("state"),
+		Nonce:               form.Get("nonce"),
+		CodeChallenge:       form.Get("code_challenge"),
+		CodeChallengeMethod: form.Get("code_challenge_method"),
+	}
+}
+
+// redirectToClient sends holder back to client with params added to the query
+// of redirect URI.
+func redirectToClient(w http.ResponseWriter, r *http.Request, req *oidc.AuthorizationRequest, params url.Values) {
+	u, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func redirectErrorToClient(w http.ResponseWriter, r *http.Request, req *oidc.AuthorizationRequest, err error) {
+	oidcErr := &oidc.Error{Code: oidc.ErrorServerError, Description: "internal error"}
+	errors.As(err, &oidcErr)
+	redirectToClient(w, r, req, url.Values{
+		"error":             {oidcErr.Code},
+		"error_description": {oidcErr.Description},
+	})
+}
+
+// OIDCAuthorize shows consent screen (GET) and handles holder's decision
+// (POST). Holder authenticates on the consent screen with the same
+// credentials and login throttles as LoginHolder.
+func (h *Handler) OIDCAuthorize(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	ctx := httpMethodContext(r)
+	logger := h.customizeLogger(ctx, "OIDCAuthorize")
+	defer logger.Info("request processed")
+
+	if err := r.ParseForm(); err != nil {
+		renderConsentPage(w, logger, http.StatusBadRequest, &consentPage{Error: "invalid request"})
+		return
+	}
+	req := authorizationRequestFromForm(r.Form)
+	logger = logger.With(zap.String("client-id", req.ClientID))
+	client, err := h.oidc.ValidateAuthorizationRequest(ctx, logger, req)
+	if client == nil {
+		var oidcErr *oidc.Error
+		if errors.As(err, &oidcErr) {
+			renderConsentPage(w, logger, http.StatusBadRequest, &consentPage{Error: oidcErr.Description})
+			return
+		}
+		renderConsentPage(w, logger, http.StatusInternalServerError, &consentPage{Error: "internal error, please, try again later"})
+		return
+	}
+	if err != nil {
+		redirectErrorToClient(w, r, req, err)
+		return
+	}
+
+	page := &consentPage{
+		Action:  oidcAuthorizationPath,
+		Client:  client,
+		Request: req,
+		Scopes: lo.Map(req.Scopes(), func(scope string, _ int) string {
+			return scopeDescriptions[scope]
+		}),
+	}
+	if r.Method != http.MethodPost {
+		renderConsentPage(w, logger, http.StatusOK, page)
+		return
+	}
+	if r.PostForm.Get("decision") != "allow" {
+		logger.Info("holder denied authorization")
+		redirectErrorToClient(w, r, req, &oidc.Error{Code: oidc.ErrorAccessDenied, Description: "holder denied authorization"})
+		return
+	}
+
+	holder, err := h.authenticateOIDCHolder(ctx, logger, r.PostForm.Get("login"), r.PostForm.Get("password"), r.PostForm.Get("two_factor_code"))
+	if err != nil {
+		page.Error = oidcAuthenticationErrorMessage(err)
+		renderConsentPage(w, logger, http.StatusUnauthorized, page)
+		return
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+
+	code, err := h.oidc.IssueCode(ctx, logger, holder.ID, req)
+	if err != nil {
+		redirectErrorToClient(w, r, req, err)
+		return
+	}
+	redirectToClient(w, r, req, url.Values{"code": {code}})
+}
+
+var (
+	errOIDCInvalidCredentials   = errors.New("invalid email, phone number or password")
+	errOIDCTwoFactorRequired    = errors.New("two-factor authentication code is required")
+	errOIDCInvalidTwoFactorCode = errors.New("invalid two-factor authentication code")
+	errOIDCHolderSuspended      = errors.New("holder is suspended")
+)
+
+func oidcAuthenticationErrorMessage(err error) string {
+	var te *loginthrottles.ThrottledError
+	switch {
+	case errors.As(err, &te):
+		return te.Error()
+	case errors.Is(err, errOIDCInvalidCredentials), errors.Is(err, errOIDCTwoFactorRequired), errors.Is(err, errOIDCInvalidTwoFactorCode), errors.Is(err, errOIDCHolderSuspended):
+		return err.Error()
+	}
+	return "internal error, please, try again later"
+}
+
+func (h *Handler) authenticateOIDCHolder(ctx context.Context, logger *zap.Logger, login, password, twoFactorCode string) (*models.Holder, error) {
+	ipKey := loginthrottles.RemoteIPAddressKey(grpcutils.ExtractRemoteIPAddress(ctx))
+	if err := h.loginThrottles.Check(ctx, logger, ipKey); err != nil {
+		return nil, err
+	}
+	if login == "" || password == "" {
+		return nil, errOIDCInvalidCredentials
+	}
+
+	var email, phoneNumber string
+	if strings.Contains(login, "@") {
+		email = login
+	} else {
+		phoneNumber = login
+	}
+	var holder *models.Holder
+	// malformed login is answered the same way as unknown one
+	if validateEmailOrPhoneNumber(ctx, email, phoneNumber) == nil {
+		var err error
+		holder, err = h.hs.GetHolderByEmailOrPhoneNumber(ctx, logger, email, phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if holder == nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey); err != nil {
+			return nil, err
+		}
+		return nil, errOIDCInvalidCredentials
+	}
+	logger = logger.With(zap.Int64("holder-id", holder.ID))
+
+	accountKey := loginthrottles.HolderKey(holder.ID)
+	if err := h.loginThrottles.Check(ctx, logger, accountKey); err != nil {
+		return nil, err
+	}
+	if err := h.hs.ValidatePassword(ctx, logger, holder, password); err != nil {
+		if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey, accountKey); err != nil {
+			return nil, err
+		}
+		return nil, errOIDCInvalidCredentials
+	}
+	// suspension is told only to whoever knows the password
+	if holder.SuspendedAt.Valid {
+		logger.Error("holder is suspended")
+		return nil, errOIDCHolderSuspended
+	}
+	twoFactorEnabled, err := h.twoFactor.IsEnabled(ctx, logger, holder.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactorEnabled {
+		if twoFactorCode == "" {
+			return nil, errOIDCTwoFactorRequired
+		}
+		if err := h.twoFactor.ValidateCode(ctx, logger, holder.ID, twoFactorCode); err != nil {
+			if err := h.loginThrottles.RegisterFailure(ctx, logger, ipKey, accountKey); err != nil {
+				return nil, err
+			}
+			return nil, errOIDCInvalidTwoFactorCode
+		}
+	}
+	if err := h.loginThrottles.RegisterSuccess(ctx, logger, accountKey); err != nil {
+		return nil, err
+	}
+
+	return holder, nil
+}
+
+type oidcErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// writeOIDCError writes OAuth 2.0 error response of token and userinfo
+// endpoints.
+func writeOIDCError(w http.ResponseWriter, err error) {
+	oidcErr := &oidc.Error{Code: oidc.ErrorServerError, Description: "internal error"}
+	errors.As(err, &oidcErr)
+
+	statusCode := http.StatusBadRequest
+	switch oidcErr.Code {
+	case oidc.ErrorServerError:
+		statusCode = http.StatusInternalServerError
+	case oidc.ErrorInvalidClient:
+		statusCode = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+	case oidc.ErrorInvalidToken:
+		statusCode = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(&oidcErrorResponse{
+		Error:            oidcErr.Code,
+		ErrorDescription: oidcErr.Description,
+	})
+}
+
+// OIDCToken exchanges authorization code for access token and ID token.
+// Client authenticates with its API key as client secret.
+func (h *Handler) OIDCToken(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	ctx := httpMethodContext(r)
+	logger := h.customizeLogger(ctx, "OIDCToken")
+	defer logger.Info("request processed")
+
+	if err := r.ParseForm(); err != nil {
+		writeOIDCError(w, &oidc.Error{Code: oidc.ErrorInvalidRequest, Description: "invalid request body"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		// client credentials are form-encoded before basic encoding
+		// (RFC 6749, section 2.3.1)
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	resp, err := h.oidc.Exchange(ctx, logger, &oidc.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	})
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// OIDCUserInfo returns claims of holder who granted access token. Claims are
+// derived from the same holder representation as GetHolder returns.
+func (h *Handler) OIDCUserInfo(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	ctx := httpMethodContext(r)
+	logger := h.customizeLogger(ctx, "OIDCUserInfo")
+	defer logger.Info("request processed")
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && r.Method == http.MethodPost && r.ParseForm() == nil {
+		token = r.PostForm.Get("access_token")
+	}
+	if token == "" {
+		writeOIDCError(w, &oidc.Error{Code: oidc.ErrorInvalidToken, Description: "access token is required"})
+		return
+	}
+	at, err := h.oidc.GetAccessToken(ctx, logger, token)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+	logger = logger.With(zap.Int64("holder-id", at.HolderID), zap.String("client-id", at.ClientID))
+
+	holder, err := h.hs.GetHolderByID(ctx, logger, at.HolderID)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+	if holder == nil {
+		logger.Error("holder not found")
+		writeOIDCError(w, &oidc.Error{Code: oidc.ErrorInvalidToken, Description: "holder not found"})
+		return
+	}
+	if holder.SuspendedAt.Valid {
+		logger.Error("holder is suspended")
+		writeOIDCError(w, &oidc.Error{Code: oidc.ErrorInvalidToken, Description: "holder is suspended"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(userInfoClaims(converters.ConvertHolderToProtoHolder(holder), holder.Confirmed, at.Scopes))
+}
+
+// userInfoClaims maps holder to standard OpenID Connect claims of granted
+// scopes. Languages and countries are not standard claims, they are returned
+// as is in profile scope.
+func userInfoClaims(holder *pbv1.Holder, confirmed bool, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{"sub": holder.Id}
+	if lo.Contains(scopes, oidc.ScopeProfile) {
+		if holder.AvatarImageUrl != nil {
+			claims["picture"] = *holder.AvatarImageUrl
+		}
+		if len(holder.Languages) > 0 {
+			claims["locale"] = holder.Languages[0]
+		}
+		claims["languages"] = lo.Ternary(holder.Languages == nil, []string{}, holder.Languages)
+		claims["countries"] = lo.Ternary(holder.Countries == nil, []string{}, holder.Countries)
+	}
+	if lo.Contains(scopes, oidc.ScopeEmail) && len(holder.Emails) > 0 {
+		claims["email"] = holder.Emails[0]
+		claims["email_verified"] = confirmed
+	}
+	if lo.Contains(scopes, oidc.ScopePhone) && len(holder.PhoneNumbers) > 0 {
+		claims["phone_number"] = holder.PhoneNumbers[0]
+		claims["phone_number_verified"] = confirmed
+	}
+
+	return claims
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecumenos-social/network-warden/services/emailer"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const emailQueueBatchSize int64 = 50
+
+// RunEmailQueueWorker periodically delivers queued emails.
+func RunEmailQueueWorker(lc fx.Lifecycle, logger *zap.Logger, config *Config, es emailer.Service) {
+	logger = logger.With(zap.String("job", "email-queue-worker"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(config.EmailQueueInterval)
+				defer ticker.Stop()
+				for {
+					deliverQueuedEmails(ctx, logger, es)
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+}
+
+func deliverQueuedEmails(ctx context.Context, logger *zap.Logger, es emailer.Service) {
+	for ctx.Err() == nil {
+		n, err := es.DeliverQueued(ctx, logger, emailQueueBatchSize)
+		if err != nil || int64(n) < emailQueueBatchSize {
+			return
+		}
+	}
+}
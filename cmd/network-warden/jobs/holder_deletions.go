@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecumenos-social/network-warden/services/emailer"
+	"github.com/ecumenos-social/network-warden/services/holders"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	HolderDeletionsInterval time.Duration
+	EmailQueueInterval      time.Duration
+}
+
+const holderDeletionsBatchSize int64 = 100
+
+// RunHolderDeletionsPurger periodically deletes holders whose deletion grace
+// period is over and notifies them by email.
+func RunHolderDeletionsPurger(lc fx.Lifecycle, logger *zap.Logger, config *Config, hs holders.Service, es emailer.Service) {
+	logger = logger.With(zap.String("job", "holder-deletions-purger"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(config.HolderDeletionsInterval)
+				defer ticker.Stop()
+				for {
+					purgeHolderDeletions(ctx, logger, hs, es)
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+}
+
+func purgeHolderDeletions(ctx context.Context, logger *zap.Logger, hs holders.Service, es emailer.Service) {
+	for ctx.Err() == nil {
+		// claimed holders are leased to this purger, so the next claim takes
+		// the next batch even if some of them failed to be deleted
+		list, err := hs.ClaimHoldersDueForDeletion(ctx, logger, holderDeletionsBatchSize)
+		if err != nil || len(list) == 0 {
+			return
+		}
+		for _, holder := range list {
+			l := logger.With(zap.Int64("holder-id", holder.ID))
+			if err := hs.Delete(ctx, l, holder.ID); err != nil {
+				l.Error("failed to delete holder after deletion grace period", zap.Error(err))
+				continue
+			}
+			l.Info("holder was deleted after deletion grace period")
+			if len(holder.Emails) == 0 {
+				continue
+			}
+			if err := es.SendHolderDeleted(ctx, l, emailer.HolderRecipient(holder, holder.Emails[0])); err != nil {
+				l.Error("failed to notify holder about deletion", zap.Error(err))
+			}
+		}
+	}
+}
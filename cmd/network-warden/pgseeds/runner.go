@@ -3,10 +3,12 @@ package pgseeds
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/ecumenos-social/network-warden/cmd/network-warden/pgseeds/data"
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/network-warden/services/holders"
+	"github.com/ecumenos-social/network-warden/services/passwords"
 	"go.uber.org/zap"
 )
 
@@ -15,14 +17,16 @@ type Runner interface {
 }
 
 type runner struct {
-	logger      *zap.Logger
-	holdersRepo holders.Repository
+	logger         *zap.Logger
+	holdersRepo    holders.Repository
+	passwordHasher passwords.Hasher
 }
 
-func New(logger *zap.Logger, holdersRepo holders.Repository) Runner {
+func New(logger *zap.Logger, holdersRepo holders.Repository, passwordHasher passwords.Hasher) Runner {
 	return &runner{
-		logger:      logger,
-		holdersRepo: holdersRepo,
+		logger:         logger,
+		holdersRepo:    holdersRepo,
+		passwordHasher: passwordHasher,
 	}
 }
 
@@ -47,23 +51,24 @@ func (r *runner) seedHolders(ctx context.Context) error {
 			avatarImageURL.Valid = true
 			avatarImageURL.String = *h.AvatarImageURL
 		}
-		passwordHash, err := holders.HashPassword(h.Password)
+		passwordHash, err := r.passwordHasher.Hash(h.Password)
 		if err != nil {
 			return err
 		}
 
 		if err := r.holdersRepo.InsertHolder(ctx, &models.Holder{
-			ID:               h.ID,
-			CreatedAt:        h.CreatedAt,
-			LastModifiedAt:   h.LastModifiedAt,
-			Emails:           h.Emails,
-			PhoneNumbers:     h.PhoneNumbers,
-			AvatarImageURL:   avatarImageURL,
-			Countries:        h.Countries,
-			Languages:        h.Languages,
-			PasswordHash:     passwordHash,
-			Confirmed:        h.Confirmed,
-			ConfirmationCode: h.ConfirmationCode,
+			ID:                       h.ID,
+			CreatedAt:                h.CreatedAt,
+			LastModifiedAt:           h.LastModifiedAt,
+			Emails:                   h.Emails,
+			PhoneNumbers:             h.PhoneNumbers,
+			AvatarImageURL:           avatarImageURL,
+			Countries:                h.Countries,
+			Languages:                h.Languages,
+			PasswordHash:             passwordHash,
+			Confirmed:                h.Confirmed,
+			ConfirmationCode:         h.ConfirmationCode,
+			ConfirmationCodeIssuedAt: time.Now(),
 		}); err != nil {
 			return err
 		}
@@ -15,4 +15,6 @@ type AdminSession struct {
 	ExpiredAt        sql.NullTime   `json:"expired_at"`
 	RemoteIPAddress  sql.NullString `json:"remote_ip_address"`
 	RemoteMACAddress sql.NullString `json:"remote_mac_address"`
+	DeviceKey        sql.NullString `json:"device_key"`
+	LastUsedAt       sql.NullTime   `json:"last_used_at"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// EmailSuppression is email address which emails are not sent to because
+// it bounced or its owner complained about them.
+type EmailSuppression struct {
+	ID             int64                  `json:"id"`
+	CreatedAt      time.Time              `json:"created_at"`
+	LastModifiedAt time.Time              `json:"last_modified_at"`
+	Email          string                 `json:"email"`
+	Reason         EmailSuppressionReason `json:"reason"`
+	Provider       string                 `json:"provider"`
+	Details        sql.NullString         `json:"details"`
+}
+
+type EmailSuppressionReason string
+
+const (
+	// EmailSuppressionReasonBounce is permanent bounce, the address doesn't
+	// exist or doesn't accept emails.
+	EmailSuppressionReasonBounce    EmailSuppressionReason = "bounce"
+	EmailSuppressionReasonComplaint EmailSuppressionReason = "complaint"
+)
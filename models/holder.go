@@ -6,15 +6,36 @@ import (
 )
 
 type Holder struct {
-	ID               int64          `json:"id"`
-	CreatedAt        time.Time      `json:"created_at"`
-	LastModifiedAt   time.Time      `json:"last_modified_at"`
-	Emails           []string       `json:"emails"`
-	PhoneNumbers     []string       `json:"phone_numbers"`
-	AvatarImageURL   sql.NullString `json:"avatar_image_url"`
-	Countries        []string       `json:"countries"`
-	Languages        []string       `json:"languages"`
-	PasswordHash     string         `json:"password_hash"`
-	Confirmed        bool           `json:"confirmed"`
-	ConfirmationCode string         `json:"confirmation_code"`
+	ID                         int64          `json:"id"`
+	CreatedAt                  time.Time      `json:"created_at"`
+	LastModifiedAt             time.Time      `json:"last_modified_at"`
+	Emails                     []string       `json:"emails"`
+	PhoneNumbers               []string       `json:"phone_numbers"`
+	AvatarImageURL             sql.NullString `json:"avatar_image_url"`
+	Countries                  []string       `json:"countries"`
+	Languages                  []string       `json:"languages"`
+	PasswordHash               string         `json:"password_hash"`
+	Confirmed                  bool           `json:"confirmed"`
+	ConfirmationCode           string         `json:"confirmation_code"`
+	ConfirmationCodeIssuedAt   time.Time      `json:"confirmation_code_issued_at"`
+	ConfirmationFailedAttempts int64          `json:"confirmation_failed_attempts"`
+	DeletionDueAt              sql.NullTime   `json:"deletion_due_at"`
+	DisplayName                string         `json:"display_name"`
+	LegalName                  sql.NullString `json:"legal_name"`
+	PreferredLanguage          sql.NullString `json:"preferred_language"`
+	Timezone                   sql.NullString `json:"timezone"`
+	SuspendedAt                sql.NullTime   `json:"suspended_at"`
+}
+
+// HoldersFilter narrows list of holders, fields which are not set don't
+// filter.
+type HoldersFilter struct {
+	Confirmed     *bool
+	Suspended     *bool
+	Country       string
+	Language      string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Contact is substring of any email or phone number of holder.
+	Contact string
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// HolderContact is an email or phone number which was added by holder but is
+// not verified yet. Verified contacts are stored in Holder.Emails and
+// Holder.PhoneNumbers, the first item of each list is the primary one.
+type HolderContact struct {
+	ID                         int64     `json:"id"`
+	CreatedAt                  time.Time `json:"created_at"`
+	LastModifiedAt             time.Time `json:"last_modified_at"`
+	HolderID                   int64     `json:"holder_id"`
+	Kind                       string    `json:"kind"`
+	Value                      string    `json:"value"`
+	VerificationCodeHash       string    `json:"verification_code_hash"`
+	VerificationCodeIssuedAt   time.Time `json:"verification_code_issued_at"`
+	VerificationFailedAttempts int64     `json:"verification_failed_attempts"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// HolderPasskey is WebAuthn credential of holder. CredentialID is base64url
+// encoded, PublicKey is COSE encoded.
+type HolderPasskey struct {
+	ID             int64        `json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	LastModifiedAt time.Time    `json:"last_modified_at"`
+	HolderID       int64        `json:"holder_id"`
+	Name           string       `json:"name"`
+	CredentialID   string       `json:"credential_id"`
+	PublicKey      []byte       `json:"public_key"`
+	SignCount      int64        `json:"sign_count"`
+	LastUsedAt     sql.NullTime `json:"last_used_at"`
+}
+
+// WebAuthnChallenge is a challenge of registration or assertion ceremony.
+// HolderID is not set for assertions of discoverable credentials.
+type WebAuthnChallenge struct {
+	ID             int64         `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	LastModifiedAt time.Time     `json:"last_modified_at"`
+	HolderID       sql.NullInt64 `json:"holder_id"`
+	Ceremony       string        `json:"ceremony"`
+	ChallengeHash  string        `json:"challenge_hash"`
+	ExpiredAt      time.Time     `json:"expired_at"`
+	UsedAt         sql.NullTime  `json:"used_at"`
+}
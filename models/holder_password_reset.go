@@ -0,0 +1,16 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type HolderPasswordReset struct {
+	ID             int64        `json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	LastModifiedAt time.Time    `json:"last_modified_at"`
+	HolderID       int64        `json:"holder_id"`
+	CodeHash       string       `json:"code_hash"`
+	ExpiredAt      time.Time    `json:"expired_at"`
+	UsedAt         sql.NullTime `json:"used_at"`
+}
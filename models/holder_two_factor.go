@@ -0,0 +1,41 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type HolderTOTPSecret struct {
+	ID             int64     `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastModifiedAt time.Time `json:"last_modified_at"`
+	HolderID       int64     `json:"holder_id"`
+	Secret         string    `json:"secret"`
+	Enabled        bool      `json:"enabled"`
+	LastUsedStep   int64     `json:"last_used_step"`
+}
+
+type HolderRecoveryCode struct {
+	ID             int64        `json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	LastModifiedAt time.Time    `json:"last_modified_at"`
+	HolderID       int64        `json:"holder_id"`
+	CodeHash       string       `json:"code_hash"`
+	UsedAt         sql.NullTime `json:"used_at"`
+}
+
+type HolderLoginChallenge struct {
+	ID               int64          `json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	LastModifiedAt   time.Time      `json:"last_modified_at"`
+	HolderID         int64          `json:"holder_id"`
+	ChallengeHash    string         `json:"challenge_hash"`
+	ExpiredAt        time.Time      `json:"expired_at"`
+	UsedAt           sql.NullTime   `json:"used_at"`
+	FailedAttempts   int64          `json:"failed_attempts"`
+	RemoteIPAddress  sql.NullString `json:"remote_ip_address"`
+	RemoteMACAddress sql.NullString `json:"remote_mac_address"`
+	// StepUp marks challenge which was issued on session binding mismatch,
+	// it is redeemed with holder's password too.
+	StepUp bool `json:"step_up"`
+}
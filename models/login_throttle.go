@@ -0,0 +1,17 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type LoginThrottle struct {
+	ID             int64        `json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	LastModifiedAt time.Time    `json:"last_modified_at"`
+	Kind           string       `json:"kind"`
+	Subject        string       `json:"subject"`
+	FailedAttempts int64        `json:"failed_attempts"`
+	LastFailedAt   time.Time    `json:"last_failed_at"`
+	LockedUntil    sql.NullTime `json:"locked_until"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// OIDCAuthorizationCode is a code issued to a node (OIDC client) after holder
+// consented to log in it. It is exchanged for tokens once.
+type OIDCAuthorizationCode struct {
+	ID                  int64          `json:"id"`
+	CreatedAt           time.Time      `json:"created_at"`
+	LastModifiedAt      time.Time      `json:"last_modified_at"`
+	HolderID            int64          `json:"holder_id"`
+	ClientID            string         `json:"client_id"`
+	CodeHash            string         `json:"code_hash"`
+	RedirectURI         string         `json:"redirect_uri"`
+	Scopes              []string       `json:"scopes"`
+	Nonce               sql.NullString `json:"nonce"`
+	CodeChallenge       string         `json:"code_challenge"`
+	CodeChallengeMethod string         `json:"code_challenge_method"`
+	ExpiredAt           time.Time      `json:"expired_at"`
+	UsedAt              sql.NullTime   `json:"used_at"`
+}
+
+// OIDCAccessToken is an opaque token which lets node read holder's userinfo.
+type OIDCAccessToken struct {
+	ID                  int64        `json:"id"`
+	CreatedAt           time.Time    `json:"created_at"`
+	LastModifiedAt      time.Time    `json:"last_modified_at"`
+	HolderID            int64        `json:"holder_id"`
+	ClientID            string       `json:"client_id"`
+	AuthorizationCodeID int64        `json:"authorization_code_id"`
+	TokenHash           string       `json:"token_hash"`
+	Scopes              []string     `json:"scopes"`
+	ExpiredAt           time.Time    `json:"expired_at"`
+	RevokedAt           sql.NullTime `json:"revoked_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// HolderSessionRotatedRefreshToken is a refresh token which was already
+// exchanged for a new token pair. Every session is a refresh token family,
+// so presenting one of these tokens again means that the family leaked.
+type HolderSessionRotatedRefreshToken struct {
+	RefreshTokenHash string    `json:"refresh_token_hash"`
+	CreatedAt        time.Time `json:"created_at"`
+	HolderSessionID  int64     `json:"holder_session_id"`
+}
+
+type AdminSessionRotatedRefreshToken struct {
+	RefreshTokenHash string    `json:"refresh_token_hash"`
+	CreatedAt        time.Time `json:"created_at"`
+	AdminSessionID   int64     `json:"admin_session_id"`
+}
@@ -1,14 +1,50 @@
 package models
 
 import (
+	"database/sql"
 	"time"
 )
 
 type SentEmail struct {
-	ID             int64     `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	LastModifiedAt time.Time `json:"last_modified_at"`
-	SenderEmail    string    `json:"sender_email"`
-	ReceiverEmail  string    `json:"receiver_email"`
-	TemplateName   string    `json:"template_name"`
+	ID             int64           `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	LastModifiedAt time.Time       `json:"last_modified_at"`
+	SenderEmail    string          `json:"sender_email"`
+	ReceiverEmail  string          `json:"receiver_email"`
+	TemplateName   string          `json:"template_name"`
+	Status         SentEmailStatus `json:"status"`
+	Subject        string          `json:"subject"`
+	Body           string          `json:"body"`
+	Attempts       int64           `json:"attempts"`
+	LastError      sql.NullString  `json:"last_error"`
+	NextAttemptAt  sql.NullTime    `json:"next_attempt_at"`
+	SentAt         sql.NullTime    `json:"sent_at"`
+}
+
+type SentEmailStatus string
+
+const (
+	// SentEmailStatusPending is email which is waiting for delivery.
+	SentEmailStatusPending SentEmailStatus = "pending"
+	SentEmailStatusSent    SentEmailStatus = "sent"
+	// SentEmailStatusDead is email which delivery is given up after too
+	// many failed attempts.
+	SentEmailStatusDead SentEmailStatus = "dead"
+)
+
+// SentEmailsFilter narrows sent emails, fields which are not set don't
+// restrict them.
+type SentEmailsFilter struct {
+	SenderEmail   string
+	ReceiverEmail string
+	TemplateName  string
+	CreatedAfter  *time.Time
+}
+
+// SentEmailRateLimit allows to create email only if less than MaxCount
+// emails which are restricted by the same limit were created after
+// CreatedAfter.
+type SentEmailRateLimit struct {
+	CreatedAfter time.Time
+	MaxCount     int64
 }
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+type SentSMS struct {
+	ID                  int64     `json:"id"`
+	CreatedAt           time.Time `json:"created_at"`
+	LastModifiedAt      time.Time `json:"last_modified_at"`
+	Sender              string    `json:"sender"`
+	ReceiverPhoneNumber string    `json:"receiver_phone_number"`
+	TemplateName        string    `json:"template_name"`
+}
@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists holder_password_resets cascade;
+
+commit;
@@ -0,0 +1,15 @@
+begin;
+
+create table public.holder_password_resets
+(
+  id               bigint primary key,
+  created_at       timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at timestamp(0) with time zone default current_timestamp not null,
+  holder_id        bigint references holders (id) on delete cascade not null,
+  code_hash        text not null,
+  expired_at       timestamp(0) with time zone not null,
+  used_at          timestamp(0) with time zone
+);
+create index holder_password_resets_holder_id_index on holder_password_resets (holder_id);
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+drop table if exists holder_login_challenges cascade;
+drop table if exists holder_recovery_codes cascade;
+drop table if exists holder_totp_secrets cascade;
+
+commit;
@@ -0,0 +1,41 @@
+begin;
+
+create table public.holder_totp_secrets
+(
+  id               bigint primary key,
+  created_at       timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at timestamp(0) with time zone default current_timestamp not null,
+  holder_id        bigint references holders (id) on delete cascade not null,
+  secret           text not null,
+  enabled          boolean not null,
+  last_used_step   bigint not null
+);
+create unique index holder_totp_secrets_holder_id_uindex on holder_totp_secrets (holder_id);
+
+create table public.holder_recovery_codes
+(
+  id               bigint primary key,
+  created_at       timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at timestamp(0) with time zone default current_timestamp not null,
+  holder_id        bigint references holders (id) on delete cascade not null,
+  code_hash        text not null,
+  used_at          timestamp(0) with time zone
+);
+create index holder_recovery_codes_holder_id_index on holder_recovery_codes (holder_id);
+
+create table public.holder_login_challenges
+(
+  id                 bigint primary key,
+  created_at         timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at   timestamp(0) with time zone default current_timestamp not null,
+  holder_id          bigint references holders (id) on delete cascade not null,
+  challenge_hash     text not null,
+  expired_at         timestamp(0) with time zone not null,
+  used_at            timestamp(0) with time zone,
+  failed_attempts    bigint not null,
+  remote_ip_address  text,
+  remote_mac_address text
+);
+create unique index holder_login_challenges_challenge_hash_uindex on holder_login_challenges (challenge_hash);
+
+commit;
@@ -0,0 +1,9 @@
+begin;
+
+drop index if exists admin_sessions_admin_id_index;
+alter table public.admin_sessions drop column if exists last_used_at;
+
+drop index if exists holder_sessions_holder_id_index;
+alter table public.holder_sessions drop column if exists last_used_at;
+
+commit;
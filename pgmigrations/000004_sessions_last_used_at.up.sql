@@ -0,0 +1,9 @@
+begin;
+
+alter table public.holder_sessions add column last_used_at timestamp(0) with time zone;
+create index holder_sessions_holder_id_index on holder_sessions (holder_id);
+
+alter table public.admin_sessions add column last_used_at timestamp(0) with time zone;
+create index admin_sessions_admin_id_index on admin_sessions (admin_id);
+
+commit;
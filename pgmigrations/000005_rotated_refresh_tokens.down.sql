@@ -0,0 +1,6 @@
+begin;
+
+drop table if exists admin_session_rotated_refresh_tokens cascade;
+drop table if exists holder_session_rotated_refresh_tokens cascade;
+
+commit;
@@ -0,0 +1,19 @@
+begin;
+
+create table public.holder_session_rotated_refresh_tokens
+(
+  refresh_token_hash text primary key,
+  created_at         timestamp(0) with time zone default current_timestamp not null,
+  holder_session_id  bigint references holder_sessions (id) on delete cascade not null
+);
+create index holder_session_rotated_refresh_tokens_holder_session_id_index on holder_session_rotated_refresh_tokens (holder_session_id);
+
+create table public.admin_session_rotated_refresh_tokens
+(
+  refresh_token_hash text primary key,
+  created_at         timestamp(0) with time zone default current_timestamp not null,
+  admin_session_id   bigint references admin_sessions (id) on delete cascade not null
+);
+create index admin_session_rotated_refresh_tokens_admin_session_id_index on admin_session_rotated_refresh_tokens (admin_session_id);
+
+commit;
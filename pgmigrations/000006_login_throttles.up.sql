@@ -0,0 +1,16 @@
+begin;
+
+create table public.login_throttles
+(
+  id               bigint primary key,
+  created_at       timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at timestamp(0) with time zone default current_timestamp not null,
+  kind             text not null,
+  subject          text not null,
+  failed_attempts  bigint not null,
+  last_failed_at   timestamp(0) with time zone not null,
+  locked_until     timestamp(0) with time zone,
+  unique (kind, subject)
+);
+
+commit;
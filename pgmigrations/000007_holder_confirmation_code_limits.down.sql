@@ -0,0 +1,6 @@
+begin;
+
+alter table public.holders drop column if exists confirmation_failed_attempts;
+alter table public.holders drop column if exists confirmation_code_issued_at;
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+alter table public.holders add column confirmation_code_issued_at timestamp(0) with time zone default current_timestamp not null;
+alter table public.holders add column confirmation_failed_attempts bigint default 0 not null;
+
+commit;
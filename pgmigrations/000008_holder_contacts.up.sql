@@ -0,0 +1,17 @@
+begin;
+
+create table public.holder_contacts
+(
+  id                           bigint primary key,
+  created_at                   timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at             timestamp(0) with time zone default current_timestamp not null,
+  holder_id                    bigint references holders (id) on delete cascade not null,
+  kind                         text not null,
+  value                        text not null,
+  verification_code_hash       text not null,
+  verification_code_issued_at  timestamp(0) with time zone not null,
+  verification_failed_attempts bigint default 0 not null,
+  unique (holder_id, kind, value)
+);
+
+commit;
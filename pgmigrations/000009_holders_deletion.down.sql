@@ -0,0 +1,6 @@
+begin;
+
+drop index if exists holders_deletion_due_at_index;
+alter table public.holders drop column if exists deletion_due_at;
+
+commit;
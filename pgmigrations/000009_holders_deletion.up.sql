@@ -0,0 +1,6 @@
+begin;
+
+alter table public.holders add column deletion_due_at timestamp(0) with time zone;
+create index holders_deletion_due_at_index on holders (deletion_due_at);
+
+commit;
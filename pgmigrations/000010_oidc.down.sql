@@ -0,0 +1,6 @@
+begin;
+
+drop table if exists oidc_access_tokens cascade;
+drop table if exists oidc_authorization_codes cascade;
+
+commit;
@@ -0,0 +1,37 @@
+begin;
+
+create table public.oidc_authorization_codes
+(
+  id                    bigint primary key,
+  created_at            timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at      timestamp(0) with time zone default current_timestamp not null,
+  holder_id             bigint references holders (id) on delete cascade not null,
+  client_id             text not null,
+  code_hash             text not null,
+  redirect_uri          text not null,
+  scopes                text[] not null,
+  nonce                 text,
+  code_challenge        text not null,
+  code_challenge_method text not null,
+  expired_at            timestamp(0) with time zone not null,
+  used_at               timestamp(0) with time zone
+);
+create unique index oidc_authorization_codes_code_hash_uindex on oidc_authorization_codes (code_hash);
+
+create table public.oidc_access_tokens
+(
+  id                    bigint primary key,
+  created_at            timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at      timestamp(0) with time zone default current_timestamp not null,
+  holder_id             bigint references holders (id) on delete cascade not null,
+  client_id             text not null,
+  authorization_code_id bigint references oidc_authorization_codes (id) on delete cascade not null,
+  token_hash            text not null,
+  scopes                text[] not null,
+  expired_at            timestamp(0) with time zone not null,
+  revoked_at            timestamp(0) with time zone
+);
+create unique index oidc_access_tokens_token_hash_uindex on oidc_access_tokens (token_hash);
+create index oidc_access_tokens_authorization_code_id_index on oidc_access_tokens (authorization_code_id);
+
+commit;
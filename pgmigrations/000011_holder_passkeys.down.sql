@@ -0,0 +1,6 @@
+begin;
+
+drop table if exists webauthn_challenges cascade;
+drop table if exists holder_passkeys cascade;
+
+commit;
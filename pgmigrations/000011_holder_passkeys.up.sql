@@ -0,0 +1,31 @@
+begin;
+
+create table public.holder_passkeys
+(
+  id               bigint primary key,
+  created_at       timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at timestamp(0) with time zone default current_timestamp not null,
+  holder_id        bigint references holders (id) on delete cascade not null,
+  name             text not null,
+  credential_id    text not null,
+  public_key       bytea not null,
+  sign_count       bigint not null,
+  last_used_at     timestamp(0) with time zone
+);
+create unique index holder_passkeys_credential_id_uindex on holder_passkeys (credential_id);
+create index holder_passkeys_holder_id_index on holder_passkeys (holder_id);
+
+create table public.webauthn_challenges
+(
+  id               bigint primary key,
+  created_at       timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at timestamp(0) with time zone default current_timestamp not null,
+  holder_id        bigint references holders (id) on delete cascade,
+  ceremony         text not null,
+  challenge_hash   text not null,
+  expired_at       timestamp(0) with time zone not null,
+  used_at          timestamp(0) with time zone
+);
+create unique index webauthn_challenges_challenge_hash_uindex on webauthn_challenges (challenge_hash);
+
+commit;
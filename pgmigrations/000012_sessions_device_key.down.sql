@@ -0,0 +1,7 @@
+begin;
+
+alter table public.admin_sessions drop column if exists device_key;
+
+alter table public.holder_sessions drop column if exists device_key;
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+alter table public.holder_sessions add column device_key text;
+
+alter table public.admin_sessions add column device_key text;
+
+commit;
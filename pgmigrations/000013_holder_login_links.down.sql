@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists holder_login_links cascade;
+
+commit;
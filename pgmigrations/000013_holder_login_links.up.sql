@@ -0,0 +1,15 @@
+begin;
+
+create table public.holder_login_links
+(
+  id               bigint primary key,
+  created_at       timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at timestamp(0) with time zone default current_timestamp not null,
+  holder_id        bigint references holders (id) on delete cascade not null,
+  token_hash       text unique not null,
+  expired_at       timestamp(0) with time zone not null,
+  used_at          timestamp(0) with time zone
+);
+create index holder_login_links_holder_id_index on holder_login_links (holder_id);
+
+commit;
@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists sent_sms cascade;
+
+commit;
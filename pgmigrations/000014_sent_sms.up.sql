@@ -0,0 +1,15 @@
+begin;
+
+create table public.sent_sms
+(
+  id                    bigint primary key,
+  created_at            timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at      timestamp(0) with time zone default current_timestamp not null,
+  sender                text not null,
+  receiver_phone_number text not null,
+  template_name         text not null
+);
+create index sent_sms_receiver_phone_number_index on sent_sms (receiver_phone_number);
+create index sent_sms_template_name_index on sent_sms (template_name);
+
+commit;
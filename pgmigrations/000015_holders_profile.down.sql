@@ -0,0 +1,8 @@
+begin;
+
+alter table public.holders drop column if exists timezone;
+alter table public.holders drop column if exists preferred_language;
+alter table public.holders drop column if exists legal_name;
+alter table public.holders drop column if exists display_name;
+
+commit;
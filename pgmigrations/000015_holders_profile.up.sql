@@ -0,0 +1,8 @@
+begin;
+
+alter table public.holders add column display_name text not null default '';
+alter table public.holders add column legal_name text;
+alter table public.holders add column preferred_language text;
+alter table public.holders add column timezone text;
+
+commit;
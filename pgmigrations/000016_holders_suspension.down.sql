@@ -0,0 +1,6 @@
+begin;
+
+drop index if exists holders_created_at_index;
+alter table public.holders drop column if exists suspended_at;
+
+commit;
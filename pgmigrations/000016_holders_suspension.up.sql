@@ -0,0 +1,6 @@
+begin;
+
+alter table public.holders add column suspended_at timestamp(0) with time zone;
+create index holders_created_at_index on holders (created_at);
+
+commit;
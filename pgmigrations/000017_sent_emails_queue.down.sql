@@ -0,0 +1,12 @@
+begin;
+
+drop index if exists sent_emails_status_next_attempt_at_index;
+alter table public.sent_emails drop column if exists sent_at;
+alter table public.sent_emails drop column if exists next_attempt_at;
+alter table public.sent_emails drop column if exists last_error;
+alter table public.sent_emails drop column if exists attempts;
+alter table public.sent_emails drop column if exists body;
+alter table public.sent_emails drop column if exists subject;
+alter table public.sent_emails drop column if exists status;
+
+commit;
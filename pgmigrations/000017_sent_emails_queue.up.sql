@@ -0,0 +1,12 @@
+begin;
+
+alter table public.sent_emails add column status text not null default 'sent';
+alter table public.sent_emails add column subject text not null default '';
+alter table public.sent_emails add column body text not null default '';
+alter table public.sent_emails add column attempts bigint not null default 0;
+alter table public.sent_emails add column last_error text;
+alter table public.sent_emails add column next_attempt_at timestamp(0) with time zone;
+alter table public.sent_emails add column sent_at timestamp(0) with time zone;
+create index sent_emails_status_next_attempt_at_index on sent_emails (status, next_attempt_at);
+
+commit;
@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists email_suppressions cascade;
+
+commit;
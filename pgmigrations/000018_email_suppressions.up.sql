@@ -0,0 +1,14 @@
+begin;
+
+create table public.email_suppressions
+(
+  id               bigint primary key,
+  created_at       timestamp(0) with time zone default current_timestamp not null,
+  last_modified_at timestamp(0) with time zone default current_timestamp not null,
+  email            text not null unique,
+  reason           text not null,
+  provider         text not null,
+  details          text
+);
+
+commit;
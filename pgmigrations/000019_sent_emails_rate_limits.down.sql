@@ -0,0 +1,6 @@
+begin;
+
+drop index if exists sent_emails_sender_created_at_index;
+drop index if exists sent_emails_sender_receiver_template_created_at_index;
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+create index sent_emails_sender_receiver_template_created_at_index on sent_emails (sender_email, receiver_email, template_name, created_at);
+create index sent_emails_sender_created_at_index on sent_emails (sender_email, created_at);
+
+commit;
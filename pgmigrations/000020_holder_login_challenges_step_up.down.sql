@@ -0,0 +1,5 @@
+begin;
+
+alter table public.holder_login_challenges drop column if exists step_up;
+
+commit;
@@ -0,0 +1,5 @@
+begin;
+
+alter table public.holder_login_challenges add column step_up boolean not null default false;
+
+commit;
@@ -0,0 +1,8 @@
+begin;
+
+drop index if exists sent_emails_sender_sequence_index;
+drop index if exists sent_emails_receiver_sequence_index;
+alter table public.sent_emails drop column if exists sender_sequence;
+alter table public.sent_emails drop column if exists receiver_sequence;
+
+commit;
@@ -0,0 +1,8 @@
+begin;
+
+alter table public.sent_emails add column receiver_sequence bigint;
+alter table public.sent_emails add column sender_sequence bigint;
+create unique index sent_emails_receiver_sequence_index on sent_emails (sender_email, receiver_email, template_name, receiver_sequence);
+create unique index sent_emails_sender_sequence_index on sent_emails (sender_email, sender_sequence);
+
+commit;
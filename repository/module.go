@@ -5,10 +5,19 @@ import (
 	"github.com/ecumenos-social/network-warden/services/admins"
 	"github.com/ecumenos-social/network-warden/services/auth"
 	"github.com/ecumenos-social/network-warden/services/emailer"
+	holdercontacts "github.com/ecumenos-social/network-warden/services/holder-contacts"
+	holderexports "github.com/ecumenos-social/network-warden/services/holder-exports"
 	"github.com/ecumenos-social/network-warden/services/holders"
+	loginlinks "github.com/ecumenos-social/network-warden/services/login-links"
+	loginthrottles "github.com/ecumenos-social/network-warden/services/login-throttles"
 	networknodes "github.com/ecumenos-social/network-warden/services/network-nodes"
 	networkwardens "github.com/ecumenos-social/network-warden/services/network-wardens"
+	"github.com/ecumenos-social/network-warden/services/oidc"
+	"github.com/ecumenos-social/network-warden/services/passkeys"
+	passwordresets "github.com/ecumenos-social/network-warden/services/password-resets"
 	personaldatanodes "github.com/ecumenos-social/network-warden/services/personal-data-nodes"
+	smssender "github.com/ecumenos-social/network-warden/services/sms-sender"
+	twofactor "github.com/ecumenos-social/network-warden/services/two-factor"
 	"go.uber.org/fx"
 )
 
@@ -23,5 +32,14 @@ var Module = fx.Options(
 		func(r *Repository) networkwardens.Repository { return networkwardens.Repository(r) },
 		func(r *Repository) admins.Repository { return admins.Repository(r) },
 		func(r *Repository) adminauth.Repository { return adminauth.Repository(r) },
+		func(r *Repository) passwordresets.Repository { return passwordresets.Repository(r) },
+		func(r *Repository) twofactor.Repository { return twofactor.Repository(r) },
+		func(r *Repository) loginthrottles.Repository { return loginthrottles.Repository(r) },
+		func(r *Repository) holdercontacts.Repository { return holdercontacts.Repository(r) },
+		func(r *Repository) holderexports.Repository { return holderexports.Repository(r) },
+		func(r *Repository) oidc.Repository { return oidc.Repository(r) },
+		func(r *Repository) passkeys.Repository { return passkeys.Repository(r) },
+		func(r *Repository) loginlinks.Repository { return loginlinks.Repository(r) },
+		func(r *Repository) smssender.Repository { return smssender.Repository(r) },
 	),
 )
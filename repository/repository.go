@@ -2,13 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/toolkit/primitives"
 	"github.com/ecumenos-social/toolkit/types"
 	"github.com/ecumenos-social/toolkitfx/fxpostgres"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 )
 
@@ -38,18 +41,28 @@ func (r *Repository) scanHolder(rows scanner) (*models.Holder, error) {
 		&h.PasswordHash,
 		&h.Confirmed,
 		&h.ConfirmationCode,
+		&h.ConfirmationCodeIssuedAt,
+		&h.ConfirmationFailedAttempts,
+		&h.DeletionDueAt,
+		&h.DisplayName,
+		&h.LegalName,
+		&h.PreferredLanguage,
+		&h.Timezone,
+		&h.SuspendedAt,
 	)
 	return &h, err
 }
 
 func (r *Repository) GetHoldersByEmails(ctx context.Context, emails []string) ([]*models.Holder, error) {
-	q := fmt.Sprintf(`
+	q := `
     select
       id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
-      countries, languages, password_hash, confirmed, confirmation_code
+      countries, languages, password_hash, confirmed, confirmation_code,
+      confirmation_code_issued_at, confirmation_failed_attempts, deletion_due_at,
+      display_name, legal_name, preferred_language, timezone, suspended_at
     from public.holders
-    where emails && array[%s]::text[];`, "'"+strings.Join(emails, "', '")+"'")
-	rows, err := r.driver.QueryRows(ctx, q)
+    where emails && $1::text[];`
+	rows, err := r.driver.QueryRows(ctx, q, emails)
 	if err != nil {
 		return nil, err
 	}
@@ -70,13 +83,15 @@ func (r *Repository) GetHoldersByEmails(ctx context.Context, emails []string) ([
 }
 
 func (r *Repository) GetHoldersByPhoneNumbers(ctx context.Context, phoneNumbers []string) ([]*models.Holder, error) {
-	q := fmt.Sprintf(`
+	q := `
     select
       id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
-      countries, languages, password_hash, confirmed, confirmation_code
+      countries, languages, password_hash, confirmed, confirmation_code,
+      confirmation_code_issued_at, confirmation_failed_attempts, deletion_due_at,
+      display_name, legal_name, preferred_language, timezone, suspended_at
     from public.holders
-    where phone_numbers && array[%s]::text[];`, "'"+strings.Join(phoneNumbers, "', '")+"'")
-	rows, err := r.driver.QueryRows(ctx, q)
+    where phone_numbers && $1::text[];`
+	rows, err := r.driver.QueryRows(ctx, q, phoneNumbers)
 	if err != nil {
 		return nil, err
 	}
@@ -97,13 +112,15 @@ func (r *Repository) GetHoldersByPhoneNumbers(ctx context.Context, phoneNumbers
 }
 
 func (r *Repository) GetHolderByEmail(ctx context.Context, email string) (*models.Holder, error) {
-	q := fmt.Sprintf(`
+	q := `
     select
       id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
-      countries, languages, password_hash, confirmed, confirmation_code
+      countries, languages, password_hash, confirmed, confirmation_code,
+      confirmation_code_issued_at, confirmation_failed_attempts, deletion_due_at,
+      display_name, legal_name, preferred_language, timezone, suspended_at
     from public.holders
-    where emails && array['%s']::text[];`, email)
-	row, err := r.driver.QueryRow(ctx, q)
+    where emails && array[$1::text];`
+	row, err := r.driver.QueryRow(ctx, q, email)
 	if err != nil {
 		return nil, err
 	}
@@ -121,13 +138,15 @@ func (r *Repository) GetHolderByEmail(ctx context.Context, email string) (*model
 }
 
 func (r *Repository) GetHolderByPhoneNumber(ctx context.Context, phoneNumber string) (*models.Holder, error) {
-	q := fmt.Sprintf(`
+	q := `
     select
       id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
-      countries, languages, password_hash, confirmed, confirmation_code
+      countries, languages, password_hash, confirmed, confirmation_code,
+      confirmation_code_issued_at, confirmation_failed_attempts, deletion_due_at,
+      display_name, legal_name, preferred_language, timezone, suspended_at
     from public.holders
-    where phone_numbers && array['%s']::text[];`, phoneNumber)
-	row, err := r.driver.QueryRow(ctx, q)
+    where phone_numbers && array[$1::text];`
+	row, err := r.driver.QueryRow(ctx, q, phoneNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -148,7 +167,9 @@ func (r *Repository) GetHolderByID(ctx context.Context, id int64) (*models.Holde
 	q := `
   select
     id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
-    countries, languages, password_hash, confirmed, confirmation_code
+    countries, languages, password_hash, confirmed, confirmation_code,
+    confirmation_code_issued_at, confirmation_failed_attempts, deletion_due_at,
+    display_name, legal_name, preferred_language, timezone, suspended_at
   from public.holders
   where id=$1;`
 	row, err := r.driver.QueryRow(ctx, q, id)
@@ -170,173 +191,1831 @@ func (r *Repository) GetHolderByID(ctx context.Context, id int64) (*models.Holde
 
 func (r *Repository) InsertHolder(ctx context.Context, holder *models.Holder) error {
 	query := `insert into public.holders
-  (id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url, countries, languages, password_hash, confirmed, confirmation_code)
-  values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);`
+  (id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url, countries, languages, password_hash, confirmed, confirmation_code,
+   confirmation_code_issued_at, confirmation_failed_attempts, deletion_due_at, display_name, legal_name, preferred_language, timezone,
+   suspended_at)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19);`
 	params := []interface{}{
 		holder.ID, holder.CreatedAt, holder.LastModifiedAt,
 		holder.Emails, holder.PhoneNumbers, holder.AvatarImageURL, holder.Countries, holder.Languages,
 		holder.PasswordHash, holder.Confirmed, holder.ConfirmationCode,
+		holder.ConfirmationCodeIssuedAt, holder.ConfirmationFailedAttempts, holder.DeletionDueAt,
+		holder.DisplayName, holder.LegalName, holder.PreferredLanguage, holder.Timezone,
+		holder.SuspendedAt,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) ModifyHolder(ctx context.Context, id int64, holder *models.Holder) error {
+	query := `update public.holders
+  set created_at=$2, last_modified_at=$3, emails=$4, phone_numbers=$5, avatar_image_url=$6, countries=$7, languages=$8, password_hash=$9, confirmed=$10, confirmation_code=$11,
+  confirmation_code_issued_at=$12, confirmation_failed_attempts=$13, deletion_due_at=$14,
+  display_name=$15, legal_name=$16, preferred_language=$17, timezone=$18, suspended_at=$19
+  where id=$1;`
+	params := []interface{}{
+		holder.ID, holder.CreatedAt, holder.LastModifiedAt,
+		holder.Emails, holder.PhoneNumbers, holder.AvatarImageURL, holder.Countries,
+		holder.Languages, holder.PasswordHash, holder.Confirmed, holder.ConfirmationCode,
+		holder.ConfirmationCodeIssuedAt, holder.ConfirmationFailedAttempts, holder.DeletionDueAt,
+		holder.DisplayName, holder.LegalName, holder.PreferredLanguage, holder.Timezone,
+		holder.SuspendedAt,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+// IncrementHolderConfirmationFailedAttempts registers wrong confirmation code
+// for the holder whose confirmation code is still confirmationCode. The code
+// is invalidated when failed attempts reach maxAttempts. It returns failed
+// attempts after increment, or 0 if the code was changed meanwhile.
+func (r *Repository) IncrementHolderConfirmationFailedAttempts(ctx context.Context, id int64, confirmationCode string, maxAttempts int64, modifiedAt time.Time) (int64, error) {
+	q := `
+  update public.holders
+  set last_modified_at=$3,
+      confirmation_failed_attempts=confirmation_failed_attempts + 1,
+      confirmation_code=case when confirmation_failed_attempts + 1 >= $4 then '' else confirmation_code end
+  where id=$1 and confirmation_code=$2
+  returning confirmation_failed_attempts;`
+	row, err := r.driver.QueryRow(ctx, q, id, confirmationCode, modifiedAt, maxAttempts)
+	if err != nil {
+		return 0, err
+	}
+
+	var failedAttempts int64
+	err = row.Scan(&failedAttempts)
+	if err == nil {
+		return failedAttempts, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+
+	return 0, err
+}
+
+// ClaimHoldersDueForDeletion returns holders whose deletion is due at now and
+// moves their deletion to leaseUntil, so concurrent purgers don't delete the
+// same holder and holder which failed to be deleted doesn't block the others.
+func (r *Repository) ClaimHoldersDueForDeletion(ctx context.Context, now, leaseUntil time.Time, limit int64) ([]*models.Holder, error) {
+	q := `
+  update public.holders
+  set deletion_due_at=$2
+  where id in (
+    select id from public.holders
+    where deletion_due_at <= $1
+    order by deletion_due_at
+    limit $3
+    for update skip locked
+  )
+  returning
+    id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
+    countries, languages, password_hash, confirmed, confirmation_code,
+    confirmation_code_issued_at, confirmation_failed_attempts, deletion_due_at,
+    display_name, legal_name, preferred_language, timezone, suspended_at;`
+	rows, err := r.driver.QueryRows(ctx, q, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.Holder
+
+	for rows.Next() {
+		h, err := r.scanHolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetHolders returns holders which match filter, the newest holders go
+// first.
+func (r *Repository) GetHolders(ctx context.Context, filter *models.HoldersFilter, pagination *types.Pagination) ([]*models.Holder, error) {
+	var (
+		conditions []string
+		params     []interface{}
+	)
+	addCondition := func(condition string, param interface{}) {
+		params = append(params, param)
+		conditions = append(conditions, fmt.Sprintf(condition, len(params)))
+	}
+	if filter.Confirmed != nil {
+		addCondition("confirmed=$%d", *filter.Confirmed)
+	}
+	if filter.Suspended != nil {
+		addCondition("(suspended_at is not null)=$%d", *filter.Suspended)
+	}
+	if filter.Country != "" {
+		addCondition("$%d=any(countries)", filter.Country)
+	}
+	if filter.Language != "" {
+		addCondition("$%d=any(languages)", filter.Language)
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("created_at>=$%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		addCondition("created_at<$%d", *filter.CreatedBefore)
+	}
+	if filter.Contact != "" {
+		addCondition(`exists (
+      select 1 from unnest(array_cat(emails, phone_numbers)) as contact
+      where strpos(lower(contact), lower($%d)) > 0
+    )`, filter.Contact)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+	params = append(params, pagination.GetLimit(), pagination.GetOffset())
+
+	q := fmt.Sprintf(`
+  select
+    id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
+    countries, languages, password_hash, confirmed, confirmation_code,
+    confirmation_code_issued_at, confirmation_failed_attempts, deletion_due_at,
+    display_name, legal_name, preferred_language, timezone, suspended_at
+  from public.holders
+  %s
+  order by created_at desc, id desc
+  limit $%d offset $%d;`, where, len(params)-1, len(params))
+	rows, err := r.driver.QueryRows(ctx, q, params...)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.Holder
+
+	for rows.Next() {
+		h, err := r.scanHolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *Repository) DeleteHolder(ctx context.Context, id int64) error {
+	query := "delete from public.holders cascade where id=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, id)
+	return err
+}
+
+func (r *Repository) InsertHolderSession(ctx context.Context, holderSession *models.HolderSession) error {
+	query := `insert into public.holder_sessions
+  (id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);`
+	params := []interface{}{
+		holderSession.ID, holderSession.CreatedAt, holderSession.LastModifiedAt,
+		holderSession.HolderID, holderSession.Token, holderSession.RefreshToken, holderSession.ExpiredAt,
+		holderSession.RemoteIPAddress, holderSession.RemoteMACAddress, holderSession.LastUsedAt, holderSession.DeviceKey,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) scanHolderSession(rows scanner) (*models.HolderSession, error) {
+	var hs models.HolderSession
+	err := rows.Scan(
+		&hs.ID,
+		&hs.CreatedAt,
+		&hs.LastModifiedAt,
+		&hs.HolderID,
+		&hs.Token,
+		&hs.RefreshToken,
+		&hs.ExpiredAt,
+		&hs.RemoteIPAddress,
+		&hs.RemoteMACAddress,
+		&hs.LastUsedAt,
+		&hs.DeviceKey,
+	)
+	return &hs, err
+}
+
+func (r *Repository) GetHolderSessionByRefreshToken(ctx context.Context, refToken string) (*models.HolderSession, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
+  from public.holder_sessions
+  where refresh_token=$1;`
+	row, err := r.driver.QueryRow(ctx, q, refToken)
+	if err != nil {
+		return nil, err
+	}
+
+	hs, err := r.scanHolderSession(row)
+	if err == nil {
+		return hs, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) GetHolderSessionByToken(ctx context.Context, token string) (*models.HolderSession, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
+  from public.holder_sessions
+  where token=$1;`
+	row, err := r.driver.QueryRow(ctx, q, token)
+	if err != nil {
+		return nil, err
+	}
+
+	hs, err := r.scanHolderSession(row)
+	if err == nil {
+		return hs, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) ModifyHolderSession(ctx context.Context, id int64, holderSession *models.HolderSession) error {
+	query := `update public.holder_sessions
+  set created_at=$2, last_modified_at=$3, holder_id=$4, token=$5, refresh_token=$6, expired_at=$7, remote_ip_address=$8, remote_mac_address=$9, last_used_at=$10, device_key=$11
+  where id=$1;`
+	params := []interface{}{
+		holderSession.ID, holderSession.CreatedAt, holderSession.LastModifiedAt,
+		holderSession.HolderID, holderSession.Token, holderSession.RefreshToken, holderSession.ExpiredAt,
+		holderSession.RemoteIPAddress, holderSession.RemoteMACAddress, holderSession.LastUsedAt, holderSession.DeviceKey,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+// SwapHolderSessionTokens replaces the token pair of the session only if its
+// refresh token is still oldRefreshToken. It returns false if another request
+// rotated the refresh token first.
+func (r *Repository) SwapHolderSessionTokens(ctx context.Context, id int64, oldRefreshToken string, holderSession *models.HolderSession) (bool, error) {
+	q := `
+  update public.holder_sessions
+  set last_modified_at=$3, token=$4, refresh_token=$5, expired_at=$6
+  where id=$1 and refresh_token=$2
+  returning id;`
+	row, err := r.driver.QueryRow(ctx, q, id, oldRefreshToken, holderSession.LastModifiedAt, holderSession.Token, holderSession.RefreshToken, holderSession.ExpiredAt)
+	if err != nil {
+		return false, err
+	}
+
+	var swappedID int64
+	err = row.Scan(&swappedID)
+	if err == nil {
+		return true, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+func (r *Repository) ExpireHolderSessionsByHolderID(ctx context.Context, holderID int64, expiredAt time.Time) error {
+	query := `update public.holder_sessions
+  set last_modified_at=$2, expired_at=$2
+  where holder_id=$1 and (expired_at is null or expired_at > $2);`
+	err := r.driver.ExecuteQuery(ctx, query, holderID, expiredAt)
+	return err
+}
+
+func (r *Repository) GetHolderSessionByID(ctx context.Context, id int64) (*models.HolderSession, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
+  from public.holder_sessions
+  where id=$1;`
+	row, err := r.driver.QueryRow(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hs, err := r.scanHolderSession(row)
+	if err == nil {
+		return hs, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) GetActiveHolderSessionsByHolderID(ctx context.Context, holderID int64, now time.Time) ([]*models.HolderSession, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
+  from public.holder_sessions
+  where holder_id=$1 and (expired_at is null or expired_at > $2)
+  order by created_at desc;`
+	rows, err := r.driver.QueryRows(ctx, q, holderID, now)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.HolderSession
+
+	for rows.Next() {
+		hs, err := r.scanHolderSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, hs)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *Repository) GetHolderSessionsByHolderID(ctx context.Context, holderID int64) ([]*models.HolderSession, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
+  from public.holder_sessions
+  where holder_id=$1
+  order by created_at desc;`
+	rows, err := r.driver.QueryRows(ctx, q, holderID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.HolderSession
+
+	for rows.Next() {
+		hs, err := r.scanHolderSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, hs)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *Repository) ExpireHolderSessionsByHolderIDExcept(ctx context.Context, holderID, exceptID int64, expiredAt time.Time) error {
+	query := `update public.holder_sessions
+  set last_modified_at=$3, expired_at=$3
+  where holder_id=$1 and id<>$2 and (expired_at is null or expired_at > $3);`
+	err := r.driver.ExecuteQuery(ctx, query, holderID, exceptID, expiredAt)
+	return err
+}
+
+func (r *Repository) MarkHolderSessionUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	query := "update public.holder_sessions set last_used_at=$2 where id=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, id, usedAt)
+	return err
+}
+
+func (r *Repository) InsertHolderSessionRotatedRefreshToken(ctx context.Context, rt *models.HolderSessionRotatedRefreshToken) error {
+	query := `insert into public.holder_session_rotated_refresh_tokens
+  (refresh_token_hash, created_at, holder_session_id)
+  values ($1, $2, $3);`
+	err := r.driver.ExecuteQuery(ctx, query, rt.RefreshTokenHash, rt.CreatedAt, rt.HolderSessionID)
+	return err
+}
+
+func (r *Repository) GetHolderSessionRotatedRefreshToken(ctx context.Context, refreshTokenHash string) (*models.HolderSessionRotatedRefreshToken, error) {
+	q := `
+  select refresh_token_hash, created_at, holder_session_id
+  from public.holder_session_rotated_refresh_tokens
+  where refresh_token_hash=$1;`
+	row, err := r.driver.QueryRow(ctx, q, refreshTokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var rt models.HolderSessionRotatedRefreshToken
+	err = row.Scan(&rt.RefreshTokenHash, &rt.CreatedAt, &rt.HolderSessionID)
+	if err == nil {
+		return &rt, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) InsertHolderPasswordReset(ctx context.Context, pr *models.HolderPasswordReset) error {
+	query := `insert into public.holder_password_resets
+  (id, created_at, last_modified_at, holder_id, code_hash, expired_at, used_at)
+  values ($1, $2, $3, $4, $5, $6, $7);`
+	params := []interface{}{pr.ID, pr.CreatedAt, pr.LastModifiedAt, pr.HolderID, pr.CodeHash, pr.ExpiredAt, pr.UsedAt}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) RedeemHolderPasswordReset(ctx context.Context, holderID int64, codeHash string, usedAt time.Time) (*models.HolderPasswordReset, error) {
+	q := `
+  update public.holder_password_resets
+  set last_modified_at=$3, used_at=$3
+  where holder_id=$1 and code_hash=$2 and used_at is null and expired_at > $3
+  returning id, created_at, last_modified_at, holder_id, code_hash, expired_at, used_at;`
+	row, err := r.driver.QueryRow(ctx, q, holderID, codeHash, usedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := r.scanHolderPasswordReset(row)
+	if err == nil {
+		return pr, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) scanHolderPasswordReset(rows scanner) (*models.HolderPasswordReset, error) {
+	var pr models.HolderPasswordReset
+	err := rows.Scan(
+		&pr.ID,
+		&pr.CreatedAt,
+		&pr.LastModifiedAt,
+		&pr.HolderID,
+		&pr.CodeHash,
+		&pr.ExpiredAt,
+		&pr.UsedAt,
+	)
+	return &pr, err
+}
+
+func (r *Repository) GetUnusedHolderPasswordReset(ctx context.Context, holderID int64, codeHash string) (*models.HolderPasswordReset, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, code_hash, expired_at, used_at
+  from public.holder_password_resets
+  where holder_id=$1 and code_hash=$2 and used_at is null
+  order by created_at desc
+  limit 1;`
+	row, err := r.driver.QueryRow(ctx, q, holderID, codeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := r.scanHolderPasswordReset(row)
+	if err == nil {
+		return pr, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) InsertHolderLoginLink(ctx context.Context, ll *models.HolderLoginLink) error {
+	query := `insert into public.holder_login_links
+  (id, created_at, last_modified_at, holder_id, token_hash, expired_at, used_at)
+  values ($1, $2, $3, $4, $5, $6, $7);`
+	params := []interface{}{ll.ID, ll.CreatedAt, ll.LastModifiedAt, ll.HolderID, ll.TokenHash, ll.ExpiredAt, ll.UsedAt}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) RedeemHolderLoginLink(ctx context.Context, tokenHash string, usedAt time.Time) (*models.HolderLoginLink, error) {
+	q := `
+  update public.holder_login_links
+  set last_modified_at=$2, used_at=$2
+  where token_hash=$1 and used_at is null and expired_at > $2
+  returning id, created_at, last_modified_at, holder_id, token_hash, expired_at, used_at;`
+	row, err := r.driver.QueryRow(ctx, q, tokenHash, usedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	ll, err := r.scanHolderLoginLink(row)
+	if err == nil {
+		return ll, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) scanHolderLoginLink(rows scanner) (*models.HolderLoginLink, error) {
+	var ll models.HolderLoginLink
+	err := rows.Scan(
+		&ll.ID,
+		&ll.CreatedAt,
+		&ll.LastModifiedAt,
+		&ll.HolderID,
+		&ll.TokenHash,
+		&ll.ExpiredAt,
+		&ll.UsedAt,
+	)
+	return &ll, err
+}
+
+func (r *Repository) GetHolderLoginLinkByTokenHash(ctx context.Context, tokenHash string) (*models.HolderLoginLink, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, token_hash, expired_at, used_at
+  from public.holder_login_links
+  where token_hash=$1;`
+	row, err := r.driver.QueryRow(ctx, q, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	ll, err := r.scanHolderLoginLink(row)
+	if err == nil {
+		return ll, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) scanHolderTOTPSecret(rows scanner) (*models.HolderTOTPSecret, error) {
+	var ts models.HolderTOTPSecret
+	err := rows.Scan(
+		&ts.ID,
+		&ts.CreatedAt,
+		&ts.LastModifiedAt,
+		&ts.HolderID,
+		&ts.Secret,
+		&ts.Enabled,
+		&ts.LastUsedStep,
+	)
+	return &ts, err
+}
+
+func (r *Repository) GetHolderTOTPSecretByHolderID(ctx context.Context, holderID int64) (*models.HolderTOTPSecret, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, secret, enabled, last_used_step
+  from public.holder_totp_secrets
+  where holder_id=$1;`
+	row, err := r.driver.QueryRow(ctx, q, holderID)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := r.scanHolderTOTPSecret(row)
+	if err == nil {
+		return ts, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) InsertHolderTOTPSecret(ctx context.Context, ts *models.HolderTOTPSecret) error {
+	query := `insert into public.holder_totp_secrets
+  (id, created_at, last_modified_at, holder_id, secret, enabled, last_used_step)
+  values ($1, $2, $3, $4, $5, $6, $7);`
+	params := []interface{}{ts.ID, ts.CreatedAt, ts.LastModifiedAt, ts.HolderID, ts.Secret, ts.Enabled, ts.LastUsedStep}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) ModifyHolderTOTPSecret(ctx context.Context, id int64, ts *models.HolderTOTPSecret) error {
+	query := `update public.holder_totp_secrets
+  set created_at=$2, last_modified_at=$3, holder_id=$4, secret=$5, enabled=$6, last_used_step=$7
+  where id=$1;`
+	params := []interface{}{ts.ID, ts.CreatedAt, ts.LastModifiedAt, ts.HolderID, ts.Secret, ts.Enabled, ts.LastUsedStep}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) UseHolderTOTPStep(ctx context.Context, id, step int64, modifiedAt time.Time) (bool, error) {
+	q := `
+  update public.holder_totp_secrets
+  set last_modified_at=$3, last_used_step=$2
+  where id=$1 and enabled and last_used_step < $2
+  returning id;`
+	row, err := r.driver.QueryRow(ctx, q, id, step, modifiedAt)
+	if err != nil {
+		return false, err
+	}
+
+	var updatedID int64
+	err = row.Scan(&updatedID)
+	if err == nil {
+		return true, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+func (r *Repository) DeleteHolderTOTPSecretByHolderID(ctx context.Context, holderID int64) error {
+	query := "delete from public.holder_totp_secrets where holder_id=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, holderID)
+	return err
+}
+
+func (r *Repository) InsertHolderRecoveryCode(ctx context.Context, rc *models.HolderRecoveryCode) error {
+	query := `insert into public.holder_recovery_codes
+  (id, created_at, last_modified_at, holder_id, code_hash, used_at)
+  values ($1, $2, $3, $4, $5, $6);`
+	params := []interface{}{rc.ID, rc.CreatedAt, rc.LastModifiedAt, rc.HolderID, rc.CodeHash, rc.UsedAt}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) DeleteHolderRecoveryCodesByHolderID(ctx context.Context, holderID int64) error {
+	query := "delete from public.holder_recovery_codes where holder_id=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, holderID)
+	return err
+}
+
+func (r *Repository) scanHolderRecoveryCode(rows scanner) (*models.HolderRecoveryCode, error) {
+	var rc models.HolderRecoveryCode
+	err := rows.Scan(
+		&rc.ID,
+		&rc.CreatedAt,
+		&rc.LastModifiedAt,
+		&rc.HolderID,
+		&rc.CodeHash,
+		&rc.UsedAt,
+	)
+	return &rc, err
+}
+
+func (r *Repository) RedeemHolderRecoveryCode(ctx context.Context, holderID int64, codeHash string, usedAt time.Time) (*models.HolderRecoveryCode, error) {
+	q := `
+  update public.holder_recovery_codes
+  set last_modified_at=$3, used_at=$3
+  where id=(
+    select id from public.holder_recovery_codes
+    where holder_id=$1 and code_hash=$2 and used_at is null
+    limit 1
+    for update
+  ) and used_at is null
+  returning id, created_at, last_modified_at, holder_id, code_hash, used_at;`
+	row, err := r.driver.QueryRow(ctx, q, holderID, codeHash, usedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := r.scanHolderRecoveryCode(row)
+	if err == nil {
+		return rc, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) InsertHolderLoginChallenge(ctx context.Context, lc *models.HolderLoginChallenge) error {
+	query := `insert into public.holder_login_challenges
+  (id, created_at, last_modified_at, holder_id, challenge_hash, expired_at, used_at, failed_attempts, remote_ip_address, remote_mac_address, step_up)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);`
+	params := []interface{}{
+		lc.ID, lc.CreatedAt, lc.LastModifiedAt, lc.HolderID, lc.ChallengeHash, lc.ExpiredAt, lc.UsedAt,
+		lc.FailedAttempts, lc.RemoteIPAddress, lc.RemoteMACAddress, lc.StepUp,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) IncrementHolderLoginChallengeFailedAttempts(ctx context.Context, id, maxAttempts int64, modifiedAt time.Time) (int64, error) {
+	q := `
+  update public.holder_login_challenges
+  set last_modified_at=$3, failed_attempts=failed_attempts + 1
+  where id=$1 and failed_attempts < $2 and used_at is null and expired_at > $3
+  returning failed_attempts;`
+	row, err := r.driver.QueryRow(ctx, q, id, maxAttempts, modifiedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	var failedAttempts int64
+	err = row.Scan(&failedAttempts)
+	if err == nil {
+		return failedAttempts, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+
+	return 0, err
+}
+
+func (r *Repository) RedeemHolderLoginChallenge(ctx context.Context, id int64, usedAt time.Time) (*models.HolderLoginChallenge, error) {
+	q := `
+  update public.holder_login_challenges
+  set last_modified_at=$2, used_at=$2
+  where id=$1 and used_at is null
+  returning id, created_at, last_modified_at, holder_id, challenge_hash, expired_at, used_at, failed_attempts, remote_ip_address, remote_mac_address, step_up;`
+	row, err := r.driver.QueryRow(ctx, q, id, usedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	lc, err := r.scanHolderLoginChallenge(row)
+	if err == nil {
+		return lc, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) scanHolderLoginChallenge(rows scanner) (*models.HolderLoginChallenge, error) {
+	var lc models.HolderLoginChallenge
+	err := rows.Scan(
+		&lc.ID,
+		&lc.CreatedAt,
+		&lc.LastModifiedAt,
+		&lc.HolderID,
+		&lc.ChallengeHash,
+		&lc.ExpiredAt,
+		&lc.UsedAt,
+		&lc.FailedAttempts,
+		&lc.RemoteIPAddress,
+		&lc.RemoteMACAddress,
+		&lc.StepUp,
+	)
+	return &lc, err
+}
+
+func (r *Repository) GetHolderLoginChallengeByChallengeHash(ctx context.Context, challengeHash string) (*models.HolderLoginChallenge, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, challenge_hash, expired_at, used_at, failed_attempts, remote_ip_address, remote_mac_address, step_up
+  from public.holder_login_challenges
+  where challenge_hash=$1;`
+	row, err := r.driver.QueryRow(ctx, q, challengeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	lc, err := r.scanHolderLoginChallenge(row)
+	if err == nil {
+		return lc, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) InsertHolderContact(ctx context.Context, hc *models.HolderContact) error {
+	query := `insert into public.holder_contacts
+  (id, created_at, last_modified_at, holder_id, kind, value, verification_code_hash, verification_code_issued_at, verification_failed_attempts)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	params := []interface{}{
+		hc.ID, hc.CreatedAt, hc.LastModifiedAt, hc.HolderID, hc.Kind, hc.Value,
+		hc.VerificationCodeHash, hc.VerificationCodeIssuedAt, hc.VerificationFailedAttempts,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) ModifyHolderContact(ctx context.Context, id int64, hc *models.HolderContact) error {
+	query := `update public.holder_contacts
+  set created_at=$2, last_modified_at=$3, holder_id=$4, kind=$5, value=$6, verification_code_hash=$7, verification_code_issued_at=$8, verification_failed_attempts=$9
+  where id=$1;`
+	params := []interface{}{
+		hc.ID, hc.CreatedAt, hc.LastModifiedAt, hc.HolderID, hc.Kind, hc.Value,
+		hc.VerificationCodeHash, hc.VerificationCodeIssuedAt, hc.VerificationFailedAttempts,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) IncrementHolderContactVerificationFailedAttempts(ctx context.Context, id int64, verificationCodeHash string, maxAttempts int64, modifiedAt time.Time) (int64, error) {
+	q := `
+  update public.holder_contacts
+  set last_modified_at=$3,
+      verification_failed_attempts=verification_failed_attempts + 1
+  where id=$1 and verification_code_hash=$2 and verification_failed_attempts < $4
+  returning verification_failed_attempts;`
+	row, err := r.driver.QueryRow(ctx, q, id, verificationCodeHash, modifiedAt, maxAttempts)
+	if err != nil {
+		return 0, err
+	}
+
+	var failedAttempts int64
+	err = row.Scan(&failedAttempts)
+	if err == nil {
+		return failedAttempts, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+
+	return 0, err
+}
+
+func (r *Repository) DeleteHolderContact(ctx context.Context, id int64) error {
+	query := "delete from public.holder_contacts where id=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, id)
+	return err
+}
+
+func (r *Repository) scanHolderContact(rows scanner) (*models.HolderContact, error) {
+	var hc models.HolderContact
+	err := rows.Scan(
+		&hc.ID,
+		&hc.CreatedAt,
+		&hc.LastModifiedAt,
+		&hc.HolderID,
+		&hc.Kind,
+		&hc.Value,
+		&hc.VerificationCodeHash,
+		&hc.VerificationCodeIssuedAt,
+		&hc.VerificationFailedAttempts,
+	)
+	return &hc, err
+}
+
+func (r *Repository) GetHolderContactByID(ctx context.Context, id int64) (*models.HolderContact, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, kind, value, verification_code_hash, verification_code_issued_at, verification_failed_attempts
+  from public.holder_contacts
+  where id=$1;`
+	row, err := r.driver.QueryRow(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hc, err := r.scanHolderContact(row)
+	if err == nil {
+		return hc, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) GetHolderContactsByHolderID(ctx context.Context, holderID int64) ([]*models.HolderContact, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, kind, value, verification_code_hash, verification_code_issued_at, verification_failed_attempts
+  from public.holder_contacts
+  where holder_id=$1
+  order by created_at;`
+	rows, err := r.driver.QueryRows(ctx, q, holderID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.HolderContact
+
+	for rows.Next() {
+		hc, err := r.scanHolderContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, hc)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *Repository) InsertOIDCAuthorizationCode(ctx context.Context, ac *models.OIDCAuthorizationCode) error {
+	query := `insert into public.oidc_authorization_codes
+  (id, created_at, last_modified_at, holder_id, client_id, code_hash, redirect_uri, scopes, nonce, code_challenge, code_challenge_method, expired_at, used_at)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);`
+	params := []interface{}{
+		ac.ID, ac.CreatedAt, ac.LastModifiedAt, ac.HolderID, ac.ClientID, ac.CodeHash, ac.RedirectURI,
+		ac.Scopes, ac.Nonce, ac.CodeChallenge, ac.CodeChallengeMethod, ac.ExpiredAt, ac.UsedAt,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) RedeemOIDCAuthorizationCode(ctx context.Context, id int64, usedAt time.Time) (*models.OIDCAuthorizationCode, error) {
+	q := `
+  update public.oidc_authorization_codes
+  set last_modified_at=$2, used_at=$2
+  where id=$1 and used_at is null and expired_at > $2
+  returning id, created_at, last_modified_at, holder_id, client_id, code_hash, redirect_uri, scopes, nonce, code_challenge, code_challenge_method, expired_at, used_at;`
+	row, err := r.driver.QueryRow(ctx, q, id, usedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	ac, err := r.scanOIDCAuthorizationCode(row)
+	if err == nil {
+		return ac, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) scanOIDCAuthorizationCode(rows scanner) (*models.OIDCAuthorizationCode, error) {
+	var ac models.OIDCAuthorizationCode
+	err := rows.Scan(
+		&ac.ID,
+		&ac.CreatedAt,
+		&ac.LastModifiedAt,
+		&ac.HolderID,
+		&ac.ClientID,
+		&ac.CodeHash,
+		&ac.RedirectURI,
+		&ac.Scopes,
+		&ac.Nonce,
+		&ac.CodeChallenge,
+		&ac.CodeChallengeMethod,
+		&ac.ExpiredAt,
+		&ac.UsedAt,
+	)
+	return &ac, err
+}
+
+func (r *Repository) GetOIDCAuthorizationCodeByCodeHash(ctx context.Context, codeHash string) (*models.OIDCAuthorizationCode, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, client_id, code_hash, redirect_uri, scopes, nonce, code_challenge, code_challenge_method, expired_at, used_at
+  from public.oidc_authorization_codes
+  where code_hash=$1;`
+	row, err := r.driver.QueryRow(ctx, q, codeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	ac, err := r.scanOIDCAuthorizationCode(row)
+	if err == nil {
+		return ac, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) InsertOIDCAccessToken(ctx context.Context, at *models.OIDCAccessToken) error {
+	query := `insert into public.oidc_access_tokens
+  (id, created_at, last_modified_at, holder_id, client_id, authorization_code_id, token_hash, scopes, expired_at, revoked_at)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`
+	params := []interface{}{
+		at.ID, at.CreatedAt, at.LastModifiedAt, at.HolderID, at.ClientID, at.AuthorizationCodeID,
+		at.TokenHash, at.Scopes, at.ExpiredAt, at.RevokedAt,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) scanOIDCAccessToken(rows scanner) (*models.OIDCAccessToken, error) {
+	var at models.OIDCAccessToken
+	err := rows.Scan(
+		&at.ID,
+		&at.CreatedAt,
+		&at.LastModifiedAt,
+		&at.HolderID,
+		&at.ClientID,
+		&at.AuthorizationCodeID,
+		&at.TokenHash,
+		&at.Scopes,
+		&at.ExpiredAt,
+		&at.RevokedAt,
+	)
+	return &at, err
+}
+
+func (r *Repository) GetOIDCAccessTokenByTokenHash(ctx context.Context, tokenHash string) (*models.OIDCAccessToken, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, client_id, authorization_code_id, token_hash, scopes, expired_at, revoked_at
+  from public.oidc_access_tokens
+  where token_hash=$1;`
+	row, err := r.driver.QueryRow(ctx, q, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	at, err := r.scanOIDCAccessToken(row)
+	if err == nil {
+		return at, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) RevokeOIDCAccessTokensByAuthorizationCodeID(ctx context.Context, authorizationCodeID int64, revokedAt time.Time) error {
+	query := `update public.oidc_access_tokens
+  set last_modified_at=$2, revoked_at=$2
+  where authorization_code_id=$1 and revoked_at is null;`
+	err := r.driver.ExecuteQuery(ctx, query, authorizationCodeID, revokedAt)
+	return err
+}
+
+func (r *Repository) RevokeOIDCAccessTokensByHolderID(ctx context.Context, holderID int64, revokedAt time.Time) error {
+	query := `update public.oidc_access_tokens
+  set last_modified_at=$2, revoked_at=$2
+  where holder_id=$1 and revoked_at is null;`
+	err := r.driver.ExecuteQuery(ctx, query, holderID, revokedAt)
+	return err
+}
+
+func (r *Repository) InsertHolderPasskey(ctx context.Context, pk *models.HolderPasskey) error {
+	query := `insert into public.holder_passkeys
+  (id, created_at, last_modified_at, holder_id, name, credential_id, public_key, sign_count, last_used_at)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	params := []interface{}{
+		pk.ID, pk.CreatedAt, pk.LastModifiedAt, pk.HolderID, pk.Name, pk.CredentialID, pk.PublicKey, pk.SignCount, pk.LastUsedAt,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) ModifyHolderPasskey(ctx context.Context, id int64, pk *models.HolderPasskey) error {
+	query := `update public.holder_passkeys
+  set created_at=$2, last_modified_at=$3, holder_id=$4, name=$5, credential_id=$6, public_key=$7, sign_count=$8, last_used_at=$9
+  where id=$1;`
+	params := []interface{}{
+		pk.ID, pk.CreatedAt, pk.LastModifiedAt, pk.HolderID, pk.Name, pk.CredentialID, pk.PublicKey, pk.SignCount, pk.LastUsedAt,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) DeleteHolderPasskey(ctx context.Context, id int64) error {
+	query := "delete from public.holder_passkeys where id=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, id)
+	return err
+}
+
+func (r *Repository) scanHolderPasskey(rows scanner) (*models.HolderPasskey, error) {
+	var pk models.HolderPasskey
+	err := rows.Scan(
+		&pk.ID,
+		&pk.CreatedAt,
+		&pk.LastModifiedAt,
+		&pk.HolderID,
+		&pk.Name,
+		&pk.CredentialID,
+		&pk.PublicKey,
+		&pk.SignCount,
+		&pk.LastUsedAt,
+	)
+	return &pk, err
+}
+
+func (r *Repository) GetHolderPasskeyByID(ctx context.Context, id int64) (*models.HolderPasskey, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, name, credential_id, public_key, sign_count, last_used_at
+  from public.holder_passkeys
+  where id=$1;`
+	row, err := r.driver.QueryRow(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := r.scanHolderPasskey(row)
+	if err == nil {
+		return pk, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) GetHolderPasskeyByCredentialID(ctx context.Context, credentialID string) (*models.HolderPasskey, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, name, credential_id, public_key, sign_count, last_used_at
+  from public.holder_passkeys
+  where credential_id=$1;`
+	row, err := r.driver.QueryRow(ctx, q, credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := r.scanHolderPasskey(row)
+	if err == nil {
+		return pk, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) GetHolderPasskeysByHolderID(ctx context.Context, holderID int64) ([]*models.HolderPasskey, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, name, credential_id, public_key, sign_count, last_used_at
+  from public.holder_passkeys
+  where holder_id=$1
+  order by created_at;`
+	rows, err := r.driver.QueryRows(ctx, q, holderID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.HolderPasskey
+
+	for rows.Next() {
+		pk, err := r.scanHolderPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pk)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *Repository) InsertWebAuthnChallenge(ctx context.Context, wc *models.WebAuthnChallenge) error {
+	query := `insert into public.webauthn_challenges
+  (id, created_at, last_modified_at, holder_id, ceremony, challenge_hash, expired_at, used_at)
+  values ($1, $2, $3, $4, $5, $6, $7, $8);`
+	params := []interface{}{wc.ID, wc.CreatedAt, wc.LastModifiedAt, wc.HolderID, wc.Ceremony, wc.ChallengeHash, wc.ExpiredAt, wc.UsedAt}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) scanWebAuthnChallenge(rows scanner) (*models.WebAuthnChallenge, error) {
+	var wc models.WebAuthnChallenge
+	err := rows.Scan(
+		&wc.ID,
+		&wc.CreatedAt,
+		&wc.LastModifiedAt,
+		&wc.HolderID,
+		&wc.Ceremony,
+		&wc.ChallengeHash,
+		&wc.ExpiredAt,
+		&wc.UsedAt,
+	)
+	return &wc, err
+}
+
+func (r *Repository) GetWebAuthnChallengeByChallengeHash(ctx context.Context, challengeHash string) (*models.WebAuthnChallenge, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, holder_id, ceremony, challenge_hash, expired_at, used_at
+  from public.webauthn_challenges
+  where challenge_hash=$1;`
+	row, err := r.driver.QueryRow(ctx, q, challengeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	wc, err := r.scanWebAuthnChallenge(row)
+	if err == nil {
+		return wc, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+// RedeemWebAuthnChallenge marks unused and unexpired challenge of ceremony as
+// used and returns it. It returns nil if there is no such challenge, so
+// concurrent ceremonies can't redeem the same challenge twice.
+func (r *Repository) RedeemWebAuthnChallenge(ctx context.Context, challengeHash, ceremony string, usedAt time.Time) (*models.WebAuthnChallenge, error) {
+	q := `
+  update public.webauthn_challenges
+  set last_modified_at=$3, used_at=$3
+  where challenge_hash=$1 and ceremony=$2 and used_at is null and expired_at > $3
+  returning id, created_at, last_modified_at, holder_id, ceremony, challenge_hash, expired_at, used_at;`
+	row, err := r.driver.QueryRow(ctx, q, challengeHash, ceremony, usedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	wc, err := r.scanWebAuthnChallenge(row)
+	if err == nil {
+		return wc, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) scanLoginThrottle(rows scanner) (*models.LoginThrottle, error) {
+	var lt models.LoginThrottle
+	err := rows.Scan(
+		&lt.ID,
+		&lt.CreatedAt,
+		&lt.LastModifiedAt,
+		&lt.Kind,
+		&lt.Subject,
+		&lt.FailedAttempts,
+		&lt.LastFailedAt,
+		&lt.LockedUntil,
+	)
+	return &lt, err
+}
+
+func (r *Repository) GetLoginThrottle(ctx context.Context, kind, subject string) (*models.LoginThrottle, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, kind, subject, failed_attempts, last_failed_at, locked_until
+  from public.login_throttles
+  where kind=$1 and subject=$2;`
+	row, err := r.driver.QueryRow(ctx, q, kind, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	lt, err := r.scanLoginThrottle(row)
+	if err == nil {
+		return lt, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+// IncrementLoginThrottle registers failed attempt for (kind, subject). The
+// counter starts over if the previous failure happened before resetBefore.
+func (r *Repository) IncrementLoginThrottle(ctx context.Context, id int64, kind, subject string, failedAt, resetBefore time.Time) (*models.LoginThrottle, error) {
+	q := `
+  insert into public.login_throttles
+  (id, created_at, last_modified_at, kind, subject, failed_attempts, last_failed_at, locked_until)
+  values ($1, $2, $2, $3, $4, 1, $2, null)
+  on conflict (kind, subject) do update
+  set last_modified_at=$2,
+      failed_attempts=case when login_throttles.last_failed_at < $5 then 1 else login_throttles.failed_attempts + 1 end,
+      last_failed_at=$2
+  returning id, created_at, last_modified_at, kind, subject, failed_attempts, last_failed_at, locked_until;`
+	row, err := r.driver.QueryRow(ctx, q, id, failedAt, kind, subject, resetBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.scanLoginThrottle(row)
+}
+
+func (r *Repository) ModifyLoginThrottle(ctx context.Context, id int64, lt *models.LoginThrottle) error {
+	query := `update public.login_throttles
+  set created_at=$2, last_modified_at=$3, kind=$4, subject=$5, failed_attempts=$6, last_failed_at=$7, locked_until=$8
+  where id=$1;`
+	params := []interface{}{
+		lt.ID, lt.CreatedAt, lt.LastModifiedAt, lt.Kind, lt.Subject, lt.FailedAttempts, lt.LastFailedAt, lt.LockedUntil,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) DeleteLoginThrottle(ctx context.Context, kind, subject string) error {
+	query := "delete from public.login_throttles where kind=$1 and subject=$2;"
+	err := r.driver.ExecuteQuery(ctx, query, kind, subject)
+	return err
+}
+
+func (r *Repository) DeleteLoginThrottleByID(ctx context.Context, id int64) error {
+	query := "delete from public.login_throttles where id=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, id)
+	return err
+}
+
+// GetLoginThrottles returns throttles which still restrict logins at now,
+// ordered by the latest failure.
+func (r *Repository) GetLoginThrottles(ctx context.Context, now time.Time, pagination *types.Pagination) ([]*models.LoginThrottle, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, kind, subject, failed_attempts, last_failed_at, locked_until
+  from public.login_throttles
+  where locked_until > $1 or failed_attempts > 0
+  order by last_failed_at desc
+  limit $2 offset $3;`
+	rows, err := r.driver.QueryRows(ctx, q, now, pagination.GetLimit(), pagination.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.LoginThrottle
+
+	for rows.Next() {
+		lt, err := r.scanLoginThrottle(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, lt)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *Repository) InsertSentEmail(ctx context.Context, se *models.SentEmail) error {
+	query := `insert into public.sent_emails
+  (id, created_at, last_modified_at, sender_email, receiver_email, template_name, status, subject, body, attempts, last_error, next_attempt_at, sent_at)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);`
+	params := []interface{}{
+		se.ID,
+		se.CreatedAt,
+		se.LastModifiedAt,
+		se.SenderEmail,
+		se.ReceiverEmail,
+		se.TemplateName,
+		se.Status,
+		se.Subject,
+		se.Body,
+		se.Attempts,
+		se.LastError,
+		se.NextAttemptAt,
+		se.SentAt,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+const (
+	// uniqueViolationCode is SQLSTATE of unique index violation.
+	uniqueViolationCode = "23505"
+	// maxRateLimitedSentEmailInserts is how many times insert of rate limited
+	// sent email is tried when it conflicts with concurrent inserts.
+	maxRateLimitedSentEmailInserts = 10
+)
+
+// InsertSentEmailWithinRateLimits inserts sent email if neither emails of
+// the same sender, receiver and template nor emails of the same sender
+// exceed their rate limits, nil limit doesn't restrict. It returns false if
+// email is not inserted because of the limits.
+//
+// Emails restricted by limit are numbered, every email takes the next number
+// after the emails which it counted. Concurrent inserts which counted the
+// same emails take the same number and all of them but one fail on unique
+// index, so they are repeated and count the email which was inserted.
+func (r *Repository) InsertSentEmailWithinRateLimits(ctx context.Context, se *models.SentEmail, receiverLimit, senderLimit *models.SentEmailRateLimit) (bool, error) {
+	params := []interface{}{
+		se.ID,
+		se.CreatedAt,
+		se.LastModifiedAt,
+		se.SenderEmail,
+		se.ReceiverEmail,
+		se.TemplateName,
+		se.Status,
+		se.Subject,
+		se.Body,
+		se.Attempts,
+		se.LastError,
+		se.NextAttemptAt,
+		se.SentAt,
+	}
+	var conditions []string
+	receiverSequence, senderSequence := "null::bigint", "null::bigint"
+	if receiverLimit != nil {
+		params = append(params, receiverLimit.CreatedAfter, receiverLimit.MaxCount)
+		receiverSequence = `coalesce((
+      select max(receiver_sequence) from public.sent_emails
+      where sender_email=$4 and receiver_email=$5 and template_name=$6
+    ), 0) + 1`
+		conditions = append(conditions, fmt.Sprintf(`(
+      select count(*) from public.sent_emails
+      where sender_email=$4 and receiver_email=$5 and template_name=$6 and created_at>$%d
+    ) < $%d`, len(params)-1, len(params)))
+	}
+	if senderLimit != nil {
+		params = append(params, senderLimit.CreatedAfter, senderLimit.MaxCount)
+		senderSequence = `coalesce((
+      select max(sender_sequence) from public.sent_emails
+      where sender_email=$4
+    ), 0) + 1`
+		conditions = append(conditions, fmt.Sprintf(`(
+      select count(*) from public.sent_emails
+      where sender_email=$4 and created_at>$%d
+    ) < $%d`, len(params)-1, len(params)))
+	}
+	var where string
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+	// parameters of insert from select are not typed by columns
+	q := fmt.Sprintf(`
+  insert into public.sent_emails
+  (id, created_at, last_modified_at, sender_email, receiver_email, template_name, status, subject, body, attempts, last_error, next_attempt_at, sent_at, receiver_sequence, sender_sequence)
+  select
+    $1::bigint, $2::timestamptz, $3::timestamptz, $4::text, $5::text, $6::text, $7::text, $8::text, $9::text, $10::bigint, $11::text, $12::timestamptz, $13::timestamptz,
+    %s,
+    %s
+  %s
+  returning id;`, receiverSequence, senderSequence, where)
+
+	for attempt := 1; ; attempt++ {
+		row, err := r.driver.QueryRow(ctx, q, params...)
+		if err != nil {
+			return false, err
+		}
+		var id int64
+		err = row.Scan(&id)
+		if err == nil {
+			return true, nil
+		}
+		if primitives.IsSameError(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolationCode || attempt == maxRateLimitedSentEmailInserts {
+			return false, err
+		}
+	}
+}
+
+func (r *Repository) ModifySentEmail(ctx context.Context, id int64, se *models.SentEmail) error {
+	query := `update public.sent_emails
+  set created_at=$2, last_modified_at=$3, sender_email=$4, receiver_email=$5, template_name=$6, status=$7, subject=$8, body=$9, attempts=$10, last_error=$11, next_attempt_at=$12, sent_at=$13
+  where id=$1;`
+	params := []interface{}{
+		id,
+		se.CreatedAt,
+		se.LastModifiedAt,
+		se.SenderEmail,
+		se.ReceiverEmail,
+		se.TemplateName,
+		se.Status,
+		se.Subject,
+		se.Body,
+		se.Attempts,
+		se.LastError,
+		se.NextAttemptAt,
+		se.SentAt,
+	}
+	err := r.driver.ExecuteQuery(ctx, query, params...)
+	return err
+}
+
+func (r *Repository) scanSentEmail(rows scanner) (*models.SentEmail, error) {
+	var se models.SentEmail
+	err := rows.Scan(
+		&se.ID,
+		&se.CreatedAt,
+		&se.LastModifiedAt,
+		&se.SenderEmail,
+		&se.ReceiverEmail,
+		&se.TemplateName,
+		&se.Status,
+		&se.Subject,
+		&se.Body,
+		&se.Attempts,
+		&se.LastError,
+		&se.NextAttemptAt,
+		&se.SentAt,
+	)
+	return &se, err
+}
+
+// ClaimDueSentEmails returns pending emails which are due at now and moves
+// their next attempt to leaseUntil, so concurrent workers don't deliver the
+// same email twice.
+func (r *Repository) ClaimDueSentEmails(ctx context.Context, now, leaseUntil time.Time, limit int64) ([]*models.SentEmail, error) {
+	q := `
+  update public.sent_emails
+  set next_attempt_at=$3
+  where id in (
+    select id from public.sent_emails
+    where status=$1 and next_attempt_at <= $2
+    order by next_attempt_at
+    limit $4
+    for update skip locked
+  )
+  returning id, created_at, last_modified_at, sender_email, receiver_email, template_name, status, subject, body, attempts, last_error, next_attempt_at, sent_at;`
+	rows, err := r.driver.QueryRows(ctx, q, models.SentEmailStatusPending, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.SentEmail
+
+	for rows.Next() {
+		se, err := r.scanSentEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, se)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// sentEmailsConditions returns where clause of sent emails query which
+// matches filter, the query parameters start from the first one.
+func sentEmailsConditions(filter *models.SentEmailsFilter) (string, []interface{}) {
+	var (
+		conditions []string
+		params     []interface{}
+	)
+	addCondition := func(condition string, param interface{}) {
+		params = append(params, param)
+		conditions = append(conditions, fmt.Sprintf(condition, len(params)))
+	}
+	if filter.SenderEmail != "" {
+		addCondition("sender_email=$%d", filter.SenderEmail)
+	}
+	if filter.ReceiverEmail != "" {
+		addCondition("receiver_email=$%d", filter.ReceiverEmail)
+	}
+	if filter.TemplateName != "" {
+		addCondition("template_name=$%d", filter.TemplateName)
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("created_at>$%d", *filter.CreatedAfter)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return "where " + strings.Join(conditions, " and "), params
+}
+
+// CountSentEmails returns amount of sent emails which match filter.
+func (r *Repository) CountSentEmails(ctx context.Context, filter *models.SentEmailsFilter) (int64, error) {
+	where, params := sentEmailsConditions(filter)
+	q := fmt.Sprintf(`
+  select count(*)
+  from public.sent_emails
+  %s;`, where)
+	row, err := r.driver.QueryRow(ctx, q, params...)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = row.Scan(&count)
+	return count, err
+}
+
+// GetSentEmailCreatedAt returns creation time of sent email which matches
+// filter and has offset newer emails, it returns nil if there is no such
+// email.
+func (r *Repository) GetSentEmailCreatedAt(ctx context.Context, filter *models.SentEmailsFilter, offset int64) (*time.Time, error) {
+	where, params := sentEmailsConditions(filter)
+	params = append(params, offset)
+	q := fmt.Sprintf(`
+  select created_at
+  from public.sent_emails
+  %s
+  order by created_at desc
+  limit 1 offset $%d;`, where, len(params))
+	row, err := r.driver.QueryRow(ctx, q, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdAt time.Time
+	err = row.Scan(&createdAt)
+	if err == nil {
+		return &createdAt, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) GetSentEmailsByReceivers(ctx context.Context, receivers []string) ([]*models.SentEmail, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, sender_email, receiver_email, template_name, status, subject, body, attempts, last_error, next_attempt_at, sent_at
+  from public.sent_emails
+  where receiver_email=any($1)
+  order by created_at desc;`
+	rows, err := r.driver.QueryRows(ctx, q, receivers)
+	if err != nil {
+		return nil, err
 	}
-	err := r.driver.ExecuteQuery(ctx, query, params...)
-	return err
-}
+	var out []*models.SentEmail
 
-func (r *Repository) ModifyHolder(ctx context.Context, id int64, holder *models.Holder) error {
-	query := `update public.holders
-  set created_at=$2, last_modified_at=$3, emails=$4, phone_numbers=$5, avatar_image_url=$6, countries=$7, languages=$8, password_hash=$9, confirmed=$10, confirmation_code=$11
-  where id=$1;`
-	params := []interface{}{
-		holder.ID, holder.CreatedAt, holder.LastModifiedAt,
-		holder.Emails, holder.PhoneNumbers, holder.AvatarImageURL, holder.Countries,
-		holder.Languages, holder.PasswordHash, holder.Confirmed, holder.ConfirmationCode,
+	for rows.Next() {
+		se, err := r.scanSentEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, se)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
-	err := r.driver.ExecuteQuery(ctx, query, params...)
-	return err
-}
 
-func (r *Repository) DeleteHolder(ctx context.Context, id int64) error {
-	query := "delete from public.holders cascade where id=$1;"
-	err := r.driver.ExecuteQuery(ctx, query, id)
-	return err
+	return out, nil
 }
 
-func (r *Repository) InsertHolderSession(ctx context.Context, holderSession *models.HolderSession) error {
-	query := `insert into public.holder_sessions
-  (id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address)
-  values ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
-	params := []interface{}{
-		holderSession.ID, holderSession.CreatedAt, holderSession.LastModifiedAt,
-		holderSession.HolderID, holderSession.Token, holderSession.RefreshToken, holderSession.ExpiredAt,
-		holderSession.RemoteIPAddress, holderSession.RemoteMACAddress,
-	}
+// UpsertEmailSuppression inserts suppression of email or replaces reason of
+// existing one.
+func (r *Repository) UpsertEmailSuppression(ctx context.Context, es *models.EmailSuppression) error {
+	query := `insert into public.email_suppressions
+  (id, created_at, last_modified_at, email, reason, provider, details)
+  values ($1, $2, $3, $4, $5, $6, $7)
+  on conflict (email) do update
+  set last_modified_at=$3, reason=$5, provider=$6, details=$7;`
+	params := []interface{}{es.ID, es.CreatedAt, es.LastModifiedAt, es.Email, es.Reason, es.Provider, es.Details}
 	err := r.driver.ExecuteQuery(ctx, query, params...)
 	return err
 }
 
-func (r *Repository) scanHolderSession(rows scanner) (*models.HolderSession, error) {
-	var hs models.HolderSession
+func (r *Repository) scanEmailSuppression(rows scanner) (*models.EmailSuppression, error) {
+	var es models.EmailSuppression
 	err := rows.Scan(
-		&hs.ID,
-		&hs.CreatedAt,
-		&hs.LastModifiedAt,
-		&hs.HolderID,
-		&hs.Token,
-		&hs.RefreshToken,
-		&hs.ExpiredAt,
-		&hs.RemoteIPAddress,
-		&hs.RemoteMACAddress,
+		&es.ID,
+		&es.CreatedAt,
+		&es.LastModifiedAt,
+		&es.Email,
+		&es.Reason,
+		&es.Provider,
+		&es.Details,
 	)
-	return &hs, err
+	return &es, err
 }
 
-func (r *Repository) GetHolderSessionByRefreshToken(ctx context.Context, refToken string) (*models.HolderSession, error) {
+func (r *Repository) GetEmailSuppressionsByEmails(ctx context.Context, emails []string) ([]*models.EmailSuppression, error) {
 	q := `
   select
-  id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address
-  from public.holder_sessions
-  where refresh_token=$1;`
-	row, err := r.driver.QueryRow(ctx, q, refToken)
+    id, created_at, last_modified_at, email, reason, provider, details
+  from public.email_suppressions
+  where email=any($1);`
+	rows, err := r.driver.QueryRows(ctx, q, emails)
 	if err != nil {
 		return nil, err
 	}
+	var out []*models.EmailSuppression
 
-	hs, err := r.scanHolderSession(row)
-	if err == nil {
-		return hs, nil
+	for rows.Next() {
+		es, err := r.scanEmailSuppression(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, es)
 	}
-
-	if primitives.IsSameError(err, pgx.ErrNoRows) {
-		return nil, nil
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return nil, err
+	return out, nil
 }
 
-func (r *Repository) GetHolderSessionByToken(ctx context.Context, token string) (*models.HolderSession, error) {
+func (r *Repository) GetEmailSuppressions(ctx context.Context, pagination *types.Pagination) ([]*models.EmailSuppression, error) {
 	q := `
   select
-  id, created_at, last_modified_at, holder_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address
-  from public.holder_sessions
-  where token=$1;`
-	row, err := r.driver.QueryRow(ctx, q, token)
+    id, created_at, last_modified_at, email, reason, provider, details
+  from public.email_suppressions
+  order by last_modified_at desc, id desc
+  limit $1 offset $2;`
+	rows, err := r.driver.QueryRows(ctx, q, pagination.GetLimit(), pagination.GetOffset())
 	if err != nil {
 		return nil, err
 	}
+	var out []*models.EmailSuppression
 
-	hs, err := r.scanHolderSession(row)
-	if err == nil {
-		return hs, nil
+	for rows.Next() {
+		es, err := r.scanEmailSuppression(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, es)
 	}
-
-	if primitives.IsSameError(err, pgx.ErrNoRows) {
-		return nil, nil
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return nil, err
+	return out, nil
 }
 
-func (r *Repository) ModifyHolderSession(ctx context.Context, id int64, holderSession *models.HolderSession) error {
-	query := `update public.holder_sessions
-  set created_at=$2, last_modified_at=$3, holder_id=$4, token=$5, refresh_token=$6, expired_at=$7, remote_ip_address=$8, remote_mac_address=$9
-  where id=$1;`
-	params := []interface{}{
-		holderSession.ID, holderSession.CreatedAt, holderSession.LastModifiedAt,
-		holderSession.HolderID, holderSession.Token, holderSession.RefreshToken, holderSession.ExpiredAt,
-		holderSession.RemoteIPAddress, holderSession.RemoteMACAddress,
-	}
-	err := r.driver.ExecuteQuery(ctx, query, params...)
+func (r *Repository) DeleteEmailSuppression(ctx context.Context, email string) error {
+	query := "delete from public.email_suppressions where email=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, email)
 	return err
 }
 
-func (r *Repository) InsertSentEmail(ctx context.Context, se *models.SentEmail) error {
-	query := `insert into public.sent_emails
-  (id, created_at, last_modified_at, sender_email, receiver_email, template_name)
+func (r *Repository) InsertSentSMS(ctx context.Context, ss *models.SentSMS) error {
+	query := `insert into public.sent_sms
+  (id, created_at, last_modified_at, sender, receiver_phone_number, template_name)
   values ($1, $2, $3, $4, $5, $6);`
-	params := []interface{}{se.ID, se.CreatedAt, se.LastModifiedAt, se.SenderEmail, se.ReceiverEmail, se.TemplateName}
-	err := r.driver.ExecuteQuery(ctx, query, params...)
-	return err
-}
-
-func (r *Repository) ModifySentEmail(ctx context.Context, id int64, se *models.SentEmail) error {
-	query := `update public.sent_emails
-  set created_at=$2, last_modified_at=$3, sender_email=$4, receiver_email=$5, template_name=$6
-  where id=$1;`
-	params := []interface{}{se.ID, se.CreatedAt, se.LastModifiedAt, se.SenderEmail, se.ReceiverEmail, se.TemplateName}
+	params := []interface{}{ss.ID, ss.CreatedAt, ss.LastModifiedAt, ss.Sender, ss.ReceiverPhoneNumber, ss.TemplateName}
 	err := r.driver.ExecuteQuery(ctx, query, params...)
 	return err
 }
 
-func (r *Repository) scanSentEmail(rows scanner) (*models.SentEmail, error) {
-	var se models.SentEmail
+func (r *Repository) scanSentSMS(rows scanner) (*models.SentSMS, error) {
+	var ss models.SentSMS
 	err := rows.Scan(
-		&se.ID,
-		&se.CreatedAt,
-		&se.LastModifiedAt,
-		&se.SenderEmail,
-		&se.ReceiverEmail,
-		&se.TemplateName,
+		&ss.ID,
+		&ss.CreatedAt,
+		&ss.LastModifiedAt,
+		&ss.Sender,
+		&ss.ReceiverPhoneNumber,
+		&ss.TemplateName,
 	)
-	return &se, err
+	return &ss, err
 }
 
-func (r *Repository) GetSentEmails(ctx context.Context, sender, receiver, templateName string) ([]*models.SentEmail, error) {
+func (r *Repository) GetSentSMS(ctx context.Context, sender, receiver, templateName string) ([]*models.SentSMS, error) {
 	q := `
   select
-    id, created_at, last_modified_at, sender_email, receiver_email, template_name
-  from public.sent_emails
-  where sender_email=$1 and receiver_email=$2 and template_name=$3;`
+    id, created_at, last_modified_at, sender, receiver_phone_number, template_name
+  from public.sent_sms
+  where sender=$1 and receiver_phone_number=$2 and template_name=$3;`
 	rows, err := r.driver.QueryRows(ctx, q, sender, receiver, templateName)
 	if err != nil {
 		return nil, err
 	}
-	var out []*models.SentEmail
+	var out []*models.SentSMS
 
 	for rows.Next() {
-		se, err := r.scanSentEmail(rows)
+		ss, err := r.scanSentSMS(rows)
 		if err != nil {
 			return nil, err
 		}
-		out = append(out, se)
+		out = append(out, ss)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *Repository) GetSentSMSByReceivers(ctx context.Context, receivers []string) ([]*models.SentSMS, error) {
+	q := `
+  select
+    id, created_at, last_modified_at, sender, receiver_phone_number, template_name
+  from public.sent_sms
+  where receiver_phone_number=any($1)
+  order by created_at desc;`
+	rows, err := r.driver.QueryRows(ctx, q, receivers)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.SentSMS
+
+	for rows.Next() {
+		ss, err := r.scanSentSMS(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ss)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
@@ -455,6 +2134,28 @@ func (r *Repository) GetNetworkNodesList(ctx context.Context, filters map[string
 	return out, nil
 }
 
+func (r *Repository) GetNetworkNodesByHolderID(ctx context.Context, holderID int64) ([]*models.NetworkNode, error) {
+	q := fmt.Sprintf(`select %s from public.network_nodes where holder_id=$1 order by created_at;`, selectNetworkNodeStatementFieldsQuery)
+	rows, err := r.driver.QueryRows(ctx, q, holderID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.NetworkNode
+
+	for rows.Next() {
+		nn, err := r.scanNetworkNode(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, nn)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
 func (r *Repository) GetNetworkNodeByDomainName(ctx context.Context, domainName string) (*models.NetworkNode, error) {
 	q := fmt.Sprintf(`select %s from public.network_nodes where domain_name=$1;`, selectNetworkNodeStatementFieldsQuery)
 	row, err := r.driver.QueryRow(ctx, q, domainName)
@@ -623,6 +2324,28 @@ func (r *Repository) GetPersonalDataNodesList(ctx context.Context, filters map[s
 	return out, nil
 }
 
+func (r *Repository) GetPersonalDataNodesByHolderID(ctx context.Context, holderID int64) ([]*models.PersonalDataNode, error) {
+	q := fmt.Sprintf(`select %s from public.personal_data_nodes where holder_id=$1 order by created_at;`, selectPersonalDataNodeStatementFieldsQuery)
+	rows, err := r.driver.QueryRows(ctx, q, holderID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.PersonalDataNode
+
+	for rows.Next() {
+		pdn, err := r.scanPersonalDataNode(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pdn)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
 func (r *Repository) GetPersonalDataNodeByLabel(ctx context.Context, label string) (*models.PersonalDataNode, error) {
 	q := fmt.Sprintf(`select %s from public.personal_data_nodes where label=$1;`, selectPersonalDataNodeStatementFieldsQuery)
 	row, err := r.driver.QueryRow(ctx, q, label)
@@ -838,13 +2561,13 @@ func (r *Repository) scanAdmin(rows scanner) (*models.Admin, error) {
 }
 
 func (r *Repository) GetAdminsByEmails(ctx context.Context, emails []string) ([]*models.Admin, error) {
-	q := fmt.Sprintf(`
+	q := `
     select
       id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
       countries, languages, password_hash
     from public.admins
-    where emails && array[%s]::text[];`, "'"+strings.Join(emails, "', '")+"'")
-	rows, err := r.driver.QueryRows(ctx, q)
+    where emails && $1::text[];`
+	rows, err := r.driver.QueryRows(ctx, q, emails)
 	if err != nil {
 		return nil, err
 	}
@@ -865,13 +2588,13 @@ func (r *Repository) GetAdminsByEmails(ctx context.Context, emails []string) ([]
 }
 
 func (r *Repository) GetAdminsByPhoneNumbers(ctx context.Context, phoneNumbers []string) ([]*models.Admin, error) {
-	q := fmt.Sprintf(`
+	q := `
     select
       id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
       countries, languages, password_hash
     from public.admins
-    where phone_numbers && array[%s]::text[];`, "'"+strings.Join(phoneNumbers, "', '")+"'")
-	rows, err := r.driver.QueryRows(ctx, q)
+    where phone_numbers && $1::text[];`
+	rows, err := r.driver.QueryRows(ctx, q, phoneNumbers)
 	if err != nil {
 		return nil, err
 	}
@@ -892,13 +2615,13 @@ func (r *Repository) GetAdminsByPhoneNumbers(ctx context.Context, phoneNumbers [
 }
 
 func (r *Repository) GetAdminByEmail(ctx context.Context, email string) (*models.Admin, error) {
-	q := fmt.Sprintf(`
+	q := `
     select
       id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
       countries, languages, password_hash
     from public.admins
-    where emails && array['%s']::text[];`, email)
-	row, err := r.driver.QueryRow(ctx, q)
+    where emails && array[$1::text];`
+	row, err := r.driver.QueryRow(ctx, q, email)
 	if err != nil {
 		return nil, err
 	}
@@ -916,13 +2639,13 @@ func (r *Repository) GetAdminByEmail(ctx context.Context, email string) (*models
 }
 
 func (r *Repository) GetAdminByPhoneNumber(ctx context.Context, phoneNumber string) (*models.Admin, error) {
-	q := fmt.Sprintf(`
+	q := `
     select
       id, created_at, last_modified_at, emails, phone_numbers, avatar_image_url,
       countries, languages, password_hash
     from public.admins
-    where phone_numbers && array['%s']::text[];`, phoneNumber)
-	row, err := r.driver.QueryRow(ctx, q)
+    where phone_numbers && array[$1::text];`
+	row, err := r.driver.QueryRow(ctx, q, phoneNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -991,12 +2714,12 @@ func (r *Repository) ModifyAdmin(ctx context.Context, id int64, admin *models.Ad
 
 func (r *Repository) InsertAdminSession(ctx context.Context, adminSession *models.AdminSession) error {
 	query := `insert into public.admin_sessions
-  (id, created_at, last_modified_at, admin_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address)
-  values ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+  (id, created_at, last_modified_at, admin_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key)
+  values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);`
 	params := []interface{}{
 		adminSession.ID, adminSession.CreatedAt, adminSession.LastModifiedAt,
 		adminSession.AdminID, adminSession.Token, adminSession.RefreshToken, adminSession.ExpiredAt,
-		adminSession.RemoteIPAddress, adminSession.RemoteMACAddress,
+		adminSession.RemoteIPAddress, adminSession.RemoteMACAddress, adminSession.LastUsedAt, adminSession.DeviceKey,
 	}
 	err := r.driver.ExecuteQuery(ctx, query, params...)
 	return err
@@ -1014,6 +2737,8 @@ func (r *Repository) scanAdminSession(rows scanner) (*models.AdminSession, error
 		&hs.ExpiredAt,
 		&hs.RemoteIPAddress,
 		&hs.RemoteMACAddress,
+		&hs.LastUsedAt,
+		&hs.DeviceKey,
 	)
 	return &hs, err
 }
@@ -1021,7 +2746,7 @@ func (r *Repository) scanAdminSession(rows scanner) (*models.AdminSession, error
 func (r *Repository) GetAdminSessionByRefreshToken(ctx context.Context, refToken string) (*models.AdminSession, error) {
 	q := `
   select
-  id, created_at, last_modified_at, admin_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address
+  id, created_at, last_modified_at, admin_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
   from public.admin_sessions
   where refresh_token=$1;`
 	row, err := r.driver.QueryRow(ctx, q, refToken)
@@ -1044,7 +2769,7 @@ func (r *Repository) GetAdminSessionByRefreshToken(ctx context.Context, refToken
 func (r *Repository) GetAdminSessionByToken(ctx context.Context, token string) (*models.AdminSession, error) {
 	q := `
   select
-  id, created_at, last_modified_at, admin_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address
+  id, created_at, last_modified_at, admin_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
   from public.admin_sessions
   where token=$1;`
 	row, err := r.driver.QueryRow(ctx, q, token)
@@ -1066,13 +2791,135 @@ func (r *Repository) GetAdminSessionByToken(ctx context.Context, token string) (
 
 func (r *Repository) ModifyAdminSession(ctx context.Context, id int64, adminSession *models.AdminSession) error {
 	query := `update public.admin_sessions
-  set created_at=$2, last_modified_at=$3, admin_id=$4, token=$5, refresh_token=$6, expired_at=$7, remote_ip_address=$8, remote_mac_address=$9
+  set created_at=$2, last_modified_at=$3, admin_id=$4, token=$5, refresh_token=$6, expired_at=$7, remote_ip_address=$8, remote_mac_address=$9, last_used_at=$10, device_key=$11
   where id=$1;`
 	params := []interface{}{
 		adminSession.ID, adminSession.CreatedAt, adminSession.LastModifiedAt,
 		adminSession.AdminID, adminSession.Token, adminSession.RefreshToken, adminSession.ExpiredAt,
-		adminSession.RemoteIPAddress, adminSession.RemoteMACAddress,
+		adminSession.RemoteIPAddress, adminSession.RemoteMACAddress, adminSession.LastUsedAt, adminSession.DeviceKey,
 	}
 	err := r.driver.ExecuteQuery(ctx, query, params...)
 	return err
 }
+
+// SwapAdminSessionTokens replaces the token pair of the session only if its
+// refresh token is still oldRefreshToken. It returns false if another request
+// rotated the refresh token first.
+func (r *Repository) SwapAdminSessionTokens(ctx context.Context, id int64, oldRefreshToken string, adminSession *models.AdminSession) (bool, error) {
+	q := `
+  update public.admin_sessions
+  set last_modified_at=$3, token=$4, refresh_token=$5, expired_at=$6
+  where id=$1 and refresh_token=$2
+  returning id;`
+	row, err := r.driver.QueryRow(ctx, q, id, oldRefreshToken, adminSession.LastModifiedAt, adminSession.Token, adminSession.RefreshToken, adminSession.ExpiredAt)
+	if err != nil {
+		return false, err
+	}
+
+	var swappedID int64
+	err = row.Scan(&swappedID)
+	if err == nil {
+		return true, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+func (r *Repository) GetAdminSessionByID(ctx context.Context, id int64) (*models.AdminSession, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, admin_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
+  from public.admin_sessions
+  where id=$1;`
+	row, err := r.driver.QueryRow(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hs, err := r.scanAdminSession(row)
+	if err == nil {
+		return hs, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func (r *Repository) GetActiveAdminSessionsByAdminID(ctx context.Context, adminID int64, now time.Time) ([]*models.AdminSession, error) {
+	q := `
+  select
+  id, created_at, last_modified_at, admin_id, token, refresh_token, expired_at, remote_ip_address, remote_mac_address, last_used_at, device_key
+  from public.admin_sessions
+  where admin_id=$1 and (expired_at is null or expired_at > $2)
+  order by created_at desc;`
+	rows, err := r.driver.QueryRows(ctx, q, adminID, now)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.AdminSession
+
+	for rows.Next() {
+		hs, err := r.scanAdminSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, hs)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *Repository) ExpireAdminSessionsByAdminIDExcept(ctx context.Context, adminID, exceptID int64, expiredAt time.Time) error {
+	query := `update public.admin_sessions
+  set last_modified_at=$3, expired_at=$3
+  where admin_id=$1 and id<>$2 and (expired_at is null or expired_at > $3);`
+	err := r.driver.ExecuteQuery(ctx, query, adminID, exceptID, expiredAt)
+	return err
+}
+
+func (r *Repository) MarkAdminSessionUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	query := "update public.admin_sessions set last_used_at=$2 where id=$1;"
+	err := r.driver.ExecuteQuery(ctx, query, id, usedAt)
+	return err
+}
+
+func (r *Repository) InsertAdminSessionRotatedRefreshToken(ctx context.Context, rt *models.AdminSessionRotatedRefreshToken) error {
+	query := `insert into public.admin_session_rotated_refresh_tokens
+  (refresh_token_hash, created_at, admin_session_id)
+  values ($1, $2, $3);`
+	err := r.driver.ExecuteQuery(ctx, query, rt.RefreshTokenHash, rt.CreatedAt, rt.AdminSessionID)
+	return err
+}
+
+func (r *Repository) GetAdminSessionRotatedRefreshToken(ctx context.Context, refreshTokenHash string) (*models.AdminSessionRotatedRefreshToken, error) {
+	q := `
+  select refresh_token_hash, created_at, admin_session_id
+  from public.admin_session_rotated_refresh_tokens
+  where refresh_token_hash=$1;`
+	row, err := r.driver.QueryRow(ctx, q, refreshTokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var rt models.AdminSessionRotatedRefreshToken
+	err = row.Scan(&rt.RefreshTokenHash, &rt.CreatedAt, &rt.AdminSessionID)
+	if err == nil {
+		return &rt, nil
+	}
+
+	if primitives.IsSameError(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return nil, err
+}
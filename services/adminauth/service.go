@@ -3,15 +3,21 @@ package adminauth
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	errorwrapper "github.com/ecumenos-social/error-wrapper"
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
 	"github.com/ecumenos-social/network-warden/services/jwt"
+	"github.com/ecumenos-social/toolkit/hash"
 	"go.uber.org/zap"
 )
 
+// ErrRefreshTokenReused is returned when refresh token which was already
+// rotated is presented, the session it belonged to is revoked.
+var ErrRefreshTokenReused = errors.New("refresh token was already used")
+
 type Config struct {
 	SessionAge time.Duration
 }
@@ -21,6 +27,13 @@ type Repository interface {
 	GetAdminSessionByRefreshToken(ctx context.Context, refToken string) (*models.AdminSession, error)
 	GetAdminSessionByToken(ctx context.Context, token string) (*models.AdminSession, error)
 	ModifyAdminSession(ctx context.Context, id int64, adminSession *models.AdminSession) error
+	SwapAdminSessionTokens(ctx context.Context, id int64, oldRefreshToken string, adminSession *models.AdminSession) (bool, error)
+	GetAdminSessionByID(ctx context.Context, id int64) (*models.AdminSession, error)
+	GetActiveAdminSessionsByAdminID(ctx context.Context, adminID int64, now time.Time) ([]*models.AdminSession, error)
+	ExpireAdminSessionsByAdminIDExcept(ctx context.Context, adminID, exceptID int64, expiredAt time.Time) error
+	MarkAdminSessionUsed(ctx context.Context, id int64, usedAt time.Time) error
+	InsertAdminSessionRotatedRefreshToken(ctx context.Context, rt *models.AdminSessionRotatedRefreshToken) error
+	GetAdminSessionRotatedRefreshToken(ctx context.Context, refreshTokenHash string) (*models.AdminSessionRotatedRefreshToken, error)
 }
 
 type Service interface {
@@ -29,6 +42,11 @@ type Service interface {
 	GetExpiredAtForAdminSession() time.Time
 	ModifyAdminSession(ctx context.Context, logger *zap.Logger, id int64, adminSession *models.AdminSession) error
 	MakeAdminSessionExpired(ctx context.Context, logger *zap.Logger, id int64, adminSession *models.AdminSession) error
+	ListAdminSessions(ctx context.Context, logger *zap.Logger, adminID int64) ([]*models.AdminSession, error)
+	RevokeAdminSession(ctx context.Context, logger *zap.Logger, adminID, sessionID int64) error
+	RevokeOtherAdminSessions(ctx context.Context, logger *zap.Logger, adminID, currentSessionID int64) error
+	MarkAdminSessionUsed(ctx context.Context, logger *zap.Logger, adminSession *models.AdminSession) error
+	RotateAdminSessionTokens(ctx context.Context, logger *zap.Logger, adminSession *models.AdminSession, token, refreshToken string) error
 }
 
 type service struct {
@@ -51,6 +69,8 @@ type InsertParams struct {
 	RefreshToken     string
 	RemoteIPAddress  string
 	RemoteMACAddress *string
+	// DeviceKey is set if session is bound to device key.
+	DeviceKey *string
 }
 
 func (s *service) Insert(ctx context.Context, logger *zap.Logger, params *InsertParams) (*models.AdminSession, error) {
@@ -64,6 +84,11 @@ func (s *service) Insert(ctx context.Context, logger *zap.Logger, params *Insert
 		remoteMACAddress.String = *params.RemoteMACAddress
 		remoteMACAddress.Valid = true
 	}
+	deviceKey := sql.NullString{}
+	if params.DeviceKey != nil {
+		deviceKey.String = *params.DeviceKey
+		deviceKey.Valid = true
+	}
 	hs := &models.AdminSession{
 		ID:             id,
 		CreatedAt:      time.Now(),
@@ -80,6 +105,7 @@ func (s *service) Insert(ctx context.Context, logger *zap.Logger, params *Insert
 			Valid:  true,
 		},
 		RemoteMACAddress: remoteMACAddress,
+		DeviceKey:        deviceKey,
 	}
 
 	if err := s.repo.InsertAdminSession(ctx, hs); err != nil {
@@ -104,6 +130,11 @@ func (s *service) GetAdminSessionByToken(ctx context.Context, logger *zap.Logger
 		return nil, errorwrapper.WrapMessage(err, "failed to get session")
 	}
 	if as == nil {
+		if scope == jwt.TokenScopeRefresh {
+			if err := s.detectAdminRefreshTokenReuse(ctx, logger, token); err != nil {
+				return nil, err
+			}
+		}
 		logger.Info("session is not found")
 		return nil, errorwrapper.New("session is not found")
 	}
@@ -143,3 +174,146 @@ func (s *service) MakeAdminSessionExpired(ctx context.Context, logger *zap.Logge
 
 	return s.ModifyAdminSession(ctx, logger, id, adminSession)
 }
+
+func (s *service) ListAdminSessions(ctx context.Context, logger *zap.Logger, adminID int64) ([]*models.AdminSession, error) {
+	sessions, err := s.repo.GetActiveAdminSessionsByAdminID(ctx, adminID, time.Now())
+	if err != nil {
+		logger.Error("failed to get active admin sessions", zap.Error(err), zap.Int64("admin-id", adminID))
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *service) RevokeAdminSession(ctx context.Context, logger *zap.Logger, adminID, sessionID int64) error {
+	logger = logger.With(
+		zap.Int64("admin-session-id", sessionID),
+		zap.Int64("admin-id", adminID),
+	)
+	session, err := s.repo.GetAdminSessionByID(ctx, sessionID)
+	if err != nil {
+		logger.Error("failed to get admin session", zap.Error(err))
+		return err
+	}
+	if session == nil || session.AdminID != adminID {
+		logger.Error("session is not found")
+		return errorwrapper.New("session is not found")
+	}
+	if session.ExpiredAt.Valid && session.ExpiredAt.Time.Before(time.Now()) {
+		return nil
+	}
+
+	return s.MakeAdminSessionExpired(ctx, logger, session.ID, session)
+}
+
+func (s *service) RevokeOtherAdminSessions(ctx context.Context, logger *zap.Logger, adminID, currentSessionID int64) error {
+	logger = logger.With(
+		zap.Int64("admin-session-id", currentSessionID),
+		zap.Int64("admin-id", adminID),
+	)
+	if err := s.repo.ExpireAdminSessionsByAdminIDExcept(ctx, adminID, currentSessionID, time.Now()); err != nil {
+		logger.Error("failed to expire other admin sessions", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) MarkAdminSessionUsed(ctx context.Context, logger *zap.Logger, adminSession *models.AdminSession) error {
+	now := time.Now()
+	if err := s.repo.MarkAdminSessionUsed(ctx, adminSession.ID, now); err != nil {
+		logger.Error("failed to mark admin session as used", zap.Error(err), zap.Int64("admin-session-id", adminSession.ID))
+		return err
+	}
+	adminSession.LastUsedAt = sql.NullTime{
+		Time:  now,
+		Valid: true,
+	}
+
+	return nil
+}
+
+// RotateAdminSessionTokens replaces the token pair of the session and remembers
+// the previous refresh token, so that presenting it again can be detected.
+// The pair is replaced only if the refresh token wasn't rotated by concurrent
+// request, otherwise the session is revoked as on reuse.
+func (s *service) RotateAdminSessionTokens(ctx context.Context, logger *zap.Logger, adminSession *models.AdminSession, token, refreshToken string) error {
+	logger = logger.With(
+		zap.Int64("admin-session-id", adminSession.ID),
+		zap.Int64("admin-id", adminSession.AdminID),
+	)
+	oldRefreshToken := adminSession.RefreshToken
+	adminSession.LastModifiedAt = time.Now()
+	adminSession.Token = token
+	adminSession.RefreshToken = refreshToken
+	adminSession.ExpiredAt = sql.NullTime{
+		Time:  s.GetExpiredAtForAdminSession(),
+		Valid: true,
+	}
+	swapped, err := s.repo.SwapAdminSessionTokens(ctx, adminSession.ID, oldRefreshToken, adminSession)
+	if err != nil {
+		logger.Error("failed to swap admin session tokens", zap.Error(err))
+		return err
+	}
+	if !swapped {
+		// concurrent request rotated the same refresh token, one of them
+		// presents a token which is already used
+		logger.Warn("security event: refresh token was rotated concurrently, revoking refresh token family",
+			zap.String("security-event", "refresh-token-reuse"),
+		)
+		session, err := s.repo.GetAdminSessionByID(ctx, adminSession.ID)
+		if err != nil {
+			logger.Error("failed to get admin session", zap.Error(err))
+			return errorwrapper.WrapMessage(err, "failed to get session")
+		}
+		if session != nil {
+			if err := s.MakeAdminSessionExpired(ctx, logger, session.ID, session); err != nil {
+				return errorwrapper.WrapMessage(err, "failed to revoke session")
+			}
+		}
+		return ErrRefreshTokenReused
+	}
+
+	rt := &models.AdminSessionRotatedRefreshToken{
+		RefreshTokenHash: hash.SHA256(oldRefreshToken),
+		CreatedAt:        time.Now(),
+		AdminSessionID:   adminSession.ID,
+	}
+	if err := s.repo.InsertAdminSessionRotatedRefreshToken(ctx, rt); err != nil {
+		logger.Error("failed to insert rotated refresh token", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// detectAdminRefreshTokenReuse revokes the whole refresh token family (the
+// session) if refToken was already rotated.
+func (s *service) detectAdminRefreshTokenReuse(ctx context.Context, logger *zap.Logger, refToken string) error {
+	rt, err := s.repo.GetAdminSessionRotatedRefreshToken(ctx, hash.SHA256(refToken))
+	if err != nil {
+		logger.Error("failed to get rotated refresh token", zap.Error(err))
+		return errorwrapper.WrapMessage(err, "failed to get session")
+	}
+	if rt == nil {
+		return nil
+	}
+
+	logger = logger.With(zap.Int64("admin-session-id", rt.AdminSessionID))
+	logger.Warn("security event: rotated refresh token was reused, revoking refresh token family",
+		zap.String("security-event", "refresh-token-reuse"),
+		zap.Time("rotated-at", rt.CreatedAt),
+	)
+	session, err := s.repo.GetAdminSessionByID(ctx, rt.AdminSessionID)
+	if err != nil {
+		logger.Error("failed to get admin session", zap.Error(err))
+		return errorwrapper.WrapMessage(err, "failed to get session")
+	}
+	if session != nil {
+		if err := s.MakeAdminSessionExpired(ctx, logger, session.ID, session); err != nil {
+			return errorwrapper.WrapMessage(err, "failed to revoke session")
+		}
+	}
+
+	return ErrRefreshTokenReused
+}
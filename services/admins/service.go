@@ -6,7 +6,7 @@ import (
 	errorwrapper "github.com/ecumenos-social/error-wrapper"
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
-	"github.com/ecumenos-social/toolkit/hash"
+	"github.com/ecumenos-social/network-warden/services/passwords"
 	"go.uber.org/zap"
 )
 
@@ -28,14 +28,16 @@ type Service interface {
 }
 
 type service struct {
-	repo        Repository
-	idgenerator idgenerators.AdminsIDGenerator
+	passwordHasher passwords.Hasher
+	repo           Repository
+	idgenerator    idgenerators.AdminsIDGenerator
 }
 
-func New(repo Repository, idgenerator idgenerators.AdminsIDGenerator) Service {
+func New(passwordHasher passwords.Hasher, repo Repository, idgenerator idgenerators.AdminsIDGenerator) Service {
 	return &service{
-		repo:        repo,
-		idgenerator: idgenerator,
+		passwordHasher: passwordHasher,
+		repo:           repo,
+		idgenerator:    idgenerator,
 	}
 }
 
@@ -63,17 +65,29 @@ func (s *service) GetAdminByEmailOrPhoneNumber(ctx context.Context, logger *zap.
 	return a, nil
 }
 
-func HashPassword(password string) (string, error) {
-	return hash.Hash(password)
-}
+func (s *service) ValidatePassword(ctx context.Context, logger *zap.Logger, a *models.Admin, password string) error {
+	ok, needsRehash := s.passwordHasher.Verify(password, a.PasswordHash)
+	if !ok {
+		logger.Error("invalid password")
+		return errorwrapper.New("invalid password")
+	}
+	if !needsRehash {
+		return nil
+	}
 
-func (s *service) ValidatePassword(_ context.Context, logger *zap.Logger, a *models.Admin, password string) error {
-	if hash.CompareHash(password, a.PasswordHash) {
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		logger.Warn("failed to rehash password", zap.Error(err))
+		return nil
+	}
+	a.PasswordHash = passwordHash
+	if err := s.repo.ModifyAdmin(ctx, a.ID, a); err != nil {
+		logger.Warn("failed to modify admin to rehash password", zap.Error(err))
 		return nil
 	}
-	logger.Error("invalid password")
+	logger.Info("password hash was upgraded")
 
-	return errorwrapper.New("invalid password")
+	return nil
 }
 
 func (s *service) GetAdminByID(ctx context.Context, logger *zap.Logger, id int64) (*models.Admin, error) {
@@ -87,7 +101,7 @@ func (s *service) GetAdminByID(ctx context.Context, logger *zap.Logger, id int64
 }
 
 func (s *service) ChangePassword(ctx context.Context, logger *zap.Logger, admin *models.Admin, password string) error {
-	passwordHash, err := hash.Hash(password)
+	passwordHash, err := s.passwordHasher.Hash(password)
 	if err != nil {
 		logger.Error("failed to hash password", zap.Error(err))
 		return err
@@ -3,15 +3,21 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	errorwrapper "github.com/ecumenos-social/error-wrapper"
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
 	"github.com/ecumenos-social/network-warden/services/jwt"
+	"github.com/ecumenos-social/toolkit/hash"
 	"go.uber.org/zap"
 )
 
+// ErrRefreshTokenReused is returned when refresh token which was already
+// rotated is presented, the session it belonged to is revoked.
+var ErrRefreshTokenReused = errors.New("refresh token was already used")
+
 type Config struct {
 	SessionAge time.Duration
 }
@@ -21,6 +27,14 @@ type Repository interface {
 	GetHolderSessionByRefreshToken(ctx context.Context, refToken string) (*models.HolderSession, error)
 	GetHolderSessionByToken(ctx context.Context, token string) (*models.HolderSession, error)
 	ModifyHolderSession(ctx context.Context, id int64, holderSession *models.HolderSession) error
+	SwapHolderSessionTokens(ctx context.Context, id int64, oldRefreshToken string, holderSession *models.HolderSession) (bool, error)
+	GetHolderSessionByID(ctx context.Context, id int64) (*models.HolderSession, error)
+	GetActiveHolderSessionsByHolderID(ctx context.Context, holderID int64, now time.Time) ([]*models.HolderSession, error)
+	ExpireHolderSessionsByHolderIDExcept(ctx context.Context, holderID, exceptID int64, expiredAt time.Time) error
+	MarkHolderSessionUsed(ctx context.Context, id int64, usedAt time.Time) error
+	InsertHolderSessionRotatedRefreshToken(ctx context.Context, rt *models.HolderSessionRotatedRefreshToken) error
+	GetHolderSessionRotatedRefreshToken(ctx context.Context, refreshTokenHash string) (*models.HolderSessionRotatedRefreshToken, error)
+	ExpireHolderSessionsByHolderID(ctx context.Context, holderID int64, expiredAt time.Time) error
 }
 
 type Service interface {
@@ -29,6 +43,12 @@ type Service interface {
 	GetExpiredAtForHolderSession() time.Time
 	ModifyHolderSession(ctx context.Context, logger *zap.Logger, id int64, holderSession *models.HolderSession) error
 	MakeHolderSessionExpired(ctx context.Context, logger *zap.Logger, id int64, holderSession *models.HolderSession) error
+	MakeHolderSessionsExpired(ctx context.Context, logger *zap.Logger, holderID int64) error
+	ListHolderSessions(ctx context.Context, logger *zap.Logger, holderID int64) ([]*models.HolderSession, error)
+	RevokeHolderSession(ctx context.Context, logger *zap.Logger, holderID, sessionID int64) error
+	RevokeOtherHolderSessions(ctx context.Context, logger *zap.Logger, holderID, currentSessionID int64) error
+	MarkHolderSessionUsed(ctx context.Context, logger *zap.Logger, holderSession *models.HolderSession) error
+	RotateHolderSessionTokens(ctx context.Context, logger *zap.Logger, holderSession *models.HolderSession, token, refreshToken string) error
 }
 
 type service struct {
@@ -51,6 +71,8 @@ type InsertParams struct {
 	RefreshToken     string
 	RemoteIPAddress  string
 	RemoteMACAddress *string
+	// DeviceKey is set if session is bound to device key.
+	DeviceKey *string
 }
 
 func (s *service) Insert(ctx context.Context, logger *zap.Logger, params *InsertParams) (*models.HolderSession, error) {
@@ -64,6 +86,11 @@ func (s *service) Insert(ctx context.Context, logger *zap.Logger, params *Insert
 		remoteMACAddress.String = *params.RemoteMACAddress
 		remoteMACAddress.Valid = true
 	}
+	deviceKey := sql.NullString{}
+	if params.DeviceKey != nil {
+		deviceKey.String = *params.DeviceKey
+		deviceKey.Valid = true
+	}
 	hs := &models.HolderSession{
 		ID:             id,
 		CreatedAt:      time.Now(),
@@ -80,6 +107,7 @@ func (s *service) Insert(ctx context.Context, logger *zap.Logger, params *Insert
 			Valid:  true,
 		},
 		RemoteMACAddress: remoteMACAddress,
+		DeviceKey:        deviceKey,
 	}
 
 	if err := s.repo.InsertHolderSession(ctx, hs); err != nil {
@@ -104,6 +132,11 @@ func (s *service) GetHolderSessionByToken(ctx context.Context, logger *zap.Logge
 		return nil, errorwrapper.WrapMessage(err, "failed to get session")
 	}
 	if hs == nil {
+		if scope == jwt.TokenScopeRefresh {
+			if err := s.detectHolderRefreshTokenReuse(ctx, logger, token); err != nil {
+				return nil, err
+			}
+		}
 		logger.Info("session is not found")
 		return nil, errorwrapper.New("session is not found")
 	}
@@ -143,3 +176,156 @@ func (s *service) MakeHolderSessionExpired(ctx context.Context, logger *zap.Logg
 
 	return s.ModifyHolderSession(ctx, logger, id, holderSession)
 }
+
+func (s *service) MakeHolderSessionsExpired(ctx context.Context, logger *zap.Logger, holderID int64) error {
+	logger = logger.With(zap.Int64("holder-id", holderID))
+	if err := s.repo.ExpireHolderSessionsByHolderID(ctx, holderID, time.Now()); err != nil {
+		logger.Error("failed to expire holder sessions", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) ListHolderSessions(ctx context.Context, logger *zap.Logger, holderID int64) ([]*models.HolderSession, error) {
+	sessions, err := s.repo.GetActiveHolderSessionsByHolderID(ctx, holderID, time.Now())
+	if err != nil {
+		logger.Error("failed to get active holder sessions", zap.Error(err), zap.Int64("holder-id", holderID))
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *service) RevokeHolderSession(ctx context.Context, logger *zap.Logger, holderID, sessionID int64) error {
+	logger = logger.With(
+		zap.Int64("holder-session-id", sessionID),
+		zap.Int64("holder-id", holderID),
+	)
+	session, err := s.repo.GetHolderSessionByID(ctx, sessionID)
+	if err != nil {
+		logger.Error("failed to get holder session", zap.Error(err))
+		return err
+	}
+	if session == nil || session.HolderID != holderID {
+		logger.Error("session is not found")
+		return errorwrapper.New("session is not found")
+	}
+	if session.ExpiredAt.Valid && session.ExpiredAt.Time.Before(time.Now()) {
+		return nil
+	}
+
+	return s.MakeHolderSessionExpired(ctx, logger, session.ID, session)
+}
+
+func (s *service) RevokeOtherHolderSessions(ctx context.Context, logger *zap.Logger, holderID, currentSessionID int64) error {
+	logger = logger.With(
+		zap.Int64("holder-session-id", currentSessionID),
+		zap.Int64("holder-id", holderID),
+	)
+	if err := s.repo.ExpireHolderSessionsByHolderIDExcept(ctx, holderID, currentSessionID, time.Now()); err != nil {
+		logger.Error("failed to expire other holder sessions", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) MarkHolderSessionUsed(ctx context.Context, logger *zap.Logger, holderSession *models.HolderSession) error {
+	now := time.Now()
+	if err := s.repo.MarkHolderSessionUsed(ctx, holderSession.ID, now); err != nil {
+		logger.Error("failed to mark holder session as used", zap.Error(err), zap.Int64("holder-session-id", holderSession.ID))
+		return err
+	}
+	holderSession.LastUsedAt = sql.NullTime{
+		Time:  now,
+		Valid: true,
+	}
+
+	return nil
+}
+
+// RotateHolderSessionTokens replaces the token pair of the session and remembers
+// the previous refresh token, so that presenting it again can be detected.
+// The pair is replaced only if the refresh token wasn't rotated by concurrent
+// request, otherwise the session is revoked as on reuse.
+func (s *service) RotateHolderSessionTokens(ctx context.Context, logger *zap.Logger, holderSession *models.HolderSession, token, refreshToken string) error {
+	logger = logger.With(
+		zap.Int64("holder-session-id", holderSession.ID),
+		zap.Int64("holder-id", holderSession.HolderID),
+	)
+	oldRefreshToken := holderSession.RefreshToken
+	holderSession.LastModifiedAt = time.Now()
+	holderSession.Token = token
+	holderSession.RefreshToken = refreshToken
+	holderSession.ExpiredAt = sql.NullTime{
+		Time:  s.GetExpiredAtForHolderSession(),
+		Valid: true,
+	}
+	swapped, err := s.repo.SwapHolderSessionTokens(ctx, holderSession.ID, oldRefreshToken, holderSession)
+	if err != nil {
+		logger.Error("failed to swap holder session tokens", zap.Error(err))
+		return err
+	}
+	if !swapped {
+		// concurrent request rotated the same refresh token, one of them
+		// presents a token which is already used
+		logger.Warn("security event: refresh token was rotated concurrently, revoking refresh token family",
+			zap.String("security-event", "refresh-token-reuse"),
+		)
+		session, err := s.repo.GetHolderSessionByID(ctx, holderSession.ID)
+		if err != nil {
+			logger.Error("failed to get holder session", zap.Error(err))
+			return errorwrapper.WrapMessage(err, "failed to get session")
+		}
+		if session != nil {
+			if err := s.MakeHolderSessionExpired(ctx, logger, session.ID, session); err != nil {
+				return errorwrapper.WrapMessage(err, "failed to revoke session")
+			}
+		}
+		return ErrRefreshTokenReused
+	}
+
+	rt := &models.HolderSessionRotatedRefreshToken{
+		RefreshTokenHash: hash.SHA256(oldRefreshToken),
+		CreatedAt:        time.Now(),
+		HolderSessionID:  holderSession.ID,
+	}
+	if err := s.repo.InsertHolderSessionRotatedRefreshToken(ctx, rt); err != nil {
+		logger.Error("failed to insert rotated refresh token", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// detectHolderRefreshTokenReuse revokes the whole refresh token family (the
+// session) if refToken was already rotated.
+func (s *service) detectHolderRefreshTokenReuse(ctx context.Context, logger *zap.Logger, refToken string) error {
+	rt, err := s.repo.GetHolderSessionRotatedRefreshToken(ctx, hash.SHA256(refToken))
+	if err != nil {
+		logger.Error("failed to get rotated refresh token", zap.Error(err))
+		return errorwrapper.WrapMessage(err, "failed to get session")
+	}
+	if rt == nil {
+		return nil
+	}
+
+	logger = logger.With(zap.Int64("holder-session-id", rt.HolderSessionID))
+	logger.Warn("security event: rotated refresh token was reused, revoking refresh token family",
+		zap.String("security-event", "refresh-token-reuse"),
+		zap.Time("rotated-at", rt.CreatedAt),
+	)
+	session, err := s.repo.GetHolderSessionByID(ctx, rt.HolderSessionID)
+	if err != nil {
+		logger.Error("failed to get holder session", zap.Error(err))
+		return errorwrapper.WrapMessage(err, "failed to get session")
+	}
+	if session != nil {
+		if err := s.MakeHolderSessionExpired(ctx, logger, session.ID, session); err != nil {
+			return errorwrapper.WrapMessage(err, "failed to revoke session")
+		}
+	}
+
+	return ErrRefreshTokenReused
+}
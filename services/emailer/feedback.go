@@ -0,0 +1,96 @@
+package emailer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+)
+
+var (
+	// ErrSuppressedEmail is returned when all receivers of email are in the
+	// suppression list.
+	ErrSuppressedEmail         = errors.New("email address is suppressed")
+	ErrFeedbackDisabled        = errors.New("email feedback webhook is disabled")
+	ErrFeedbackUnauthorized    = errors.New("invalid email feedback webhook secret")
+	ErrUnknownFeedbackProvider = errors.New("unknown email feedback provider")
+	ErrInvalidFeedback         = errors.New("invalid email feedback")
+)
+
+// FeedbackEvent is bounce or complaint about email which was sent to Email.
+type FeedbackEvent struct {
+	Reason models.EmailSuppressionReason
+	Email  string
+	// Permanent is false for soft bounces which are expected to recover,
+	// such addresses are not suppressed.
+	Permanent bool
+	Details   string
+}
+
+// FeedbackAdapter parses bounce and complaint notifications which email
+// provider posts to webhook.
+type FeedbackAdapter interface {
+	ParseFeedback(header http.Header, body []byte) ([]*FeedbackEvent, error)
+}
+
+// FeedbackProviderGeneric is provider of notifications in the generic JSON
+// format, providers which can't be configured to post it need own adapter.
+const FeedbackProviderGeneric = "generic"
+
+type genericFeedback struct {
+	Events []struct {
+		Type       string `json:"type"`
+		BounceType string `json:"bounce_type"`
+		Email      string `json:"email"`
+		Details    string `json:"details"`
+	} `json:"events"`
+}
+
+type genericFeedbackAdapter struct{}
+
+// ParseFeedback parses notification like
+// {"events":[{"type":"bounce","bounce_type":"hard","email":"...","details":"..."}]}.
+// Type is bounce or complaint, bounce type is hard or soft.
+func (genericFeedbackAdapter) ParseFeedback(_ http.Header, body []byte) ([]*FeedbackEvent, error) {
+	var feedback genericFeedback
+	if err := json.Unmarshal(body, &feedback); err != nil {
+		return nil, errorwrapper.WrapMessage(err, "invalid email feedback")
+	}
+
+	out := make([]*FeedbackEvent, 0, len(feedback.Events))
+	for _, e := range feedback.Events {
+		if strings.TrimSpace(e.Email) == "" {
+			return nil, errorwrapper.New("email feedback event doesn't have email")
+		}
+		event := &FeedbackEvent{Email: e.Email, Details: e.Details}
+		switch e.Type {
+		case "bounce":
+			event.Reason = models.EmailSuppressionReasonBounce
+			switch e.BounceType {
+			case "hard":
+				event.Permanent = true
+			case "soft":
+			default:
+				return nil, errorwrapper.New(fmt.Sprintf("unknown bounce type, bounce type = %v", e.BounceType))
+			}
+		case "complaint":
+			event.Reason = models.EmailSuppressionReasonComplaint
+			event.Permanent = true
+		default:
+			return nil, errorwrapper.New(fmt.Sprintf("unknown email feedback event type, type = %v", e.Type))
+		}
+		out = append(out, event)
+	}
+
+	return out, nil
+}
+
+// normalizeEmail brings email to the form in which it is kept in the
+// suppression list.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
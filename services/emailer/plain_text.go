@@ -0,0 +1,101 @@
+package emailer
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spacesRegexp     = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText converts HTML body of email to plain text alternative. Block
+// elements are separated by blank lines and links are followed by their URLs.
+func htmlToText(body string) string {
+	var (
+		b         strings.Builder
+		z         = html.NewTokenizer(strings.NewReader(body))
+		skipDepth int
+		href      string
+		linkStart int
+	)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return tidyText(b.String())
+		case html.TextToken:
+			if skipDepth == 0 {
+				b.WriteString(spacesRegexp.ReplaceAllString(string(z.Text()), " "))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch a := atom.Lookup(name); a {
+			case atom.Head, atom.Title, atom.Style, atom.Script:
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+			case atom.Br:
+				b.WriteString("\n")
+			case atom.Li:
+				b.WriteString("\n- ")
+			case atom.A:
+				href = ""
+				for hasAttr {
+					var key, value []byte
+					key, value, hasAttr = z.TagAttr()
+					if string(key) == "href" {
+						href = string(value)
+					}
+				}
+				linkStart = b.Len()
+			default:
+				if isBlockElement(a) {
+					b.WriteString("\n\n")
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch a := atom.Lookup(name); a {
+			case atom.Head, atom.Title, atom.Style, atom.Script:
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			case atom.A:
+				text := strings.TrimSpace(b.String()[linkStart:])
+				if href != "" && text != href {
+					b.WriteString(" (" + href + ")")
+				}
+				href = ""
+			default:
+				if isBlockElement(a) {
+					b.WriteString("\n\n")
+				}
+			}
+		}
+	}
+}
+
+func isBlockElement(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Table, atom.Tr, atom.Blockquote, atom.Pre, atom.Hr:
+		return true
+	}
+
+	return false
+}
+
+// tidyText trims lines and collapses runs of blank lines.
+func tidyText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+
+	return strings.TrimSpace(blankLinesRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")) + "\n"
+}
@@ -1,29 +1,27 @@
 package emailer
 
 import (
+	"fmt"
 	"time"
-
-	"github.com/ecumenos-social/network-warden/models"
 )
 
+// RateLimit allows at most MaxRequests emails per Interval. Limit with zero
+// MaxRequests doesn't restrict anything.
 type RateLimit struct {
 	MaxRequests int64
 	Interval    time.Duration
 }
 
-func (rl *RateLimit) Exceed(ms []*models.SentEmail) bool {
-	var (
-		startTime = time.Now().Add(-rl.Interval)
-		count     int64
-	)
-	for _, m := range ms {
-		if m == nil {
-			continue
-		}
-		if m.CreatedAt.After(startTime) {
-			count++
-		}
-	}
+func (rl *RateLimit) enabled() bool {
+	return rl != nil && rl.MaxRequests > 0 && rl.Interval > 0
+}
+
+// RateLimitedError is returned when email can't be sent because rate limit is
+// exceeded, it can be sent after RetryAfter.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
 
-	return count > rl.MaxRequests
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("too many send email requests, retry after %v", e.RetryAfter)
 }
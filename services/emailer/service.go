@@ -1,115 +1,276 @@
 package emailer
 
 import (
-	"bytes"
 	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
 	"fmt"
-	"net"
-	"net/smtp"
+	"html"
+	"net/http"
 	"strings"
 	"time"
 
 	errorwrapper "github.com/ecumenos-social/error-wrapper"
 	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/holders"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
 	"github.com/ecumenos-social/toolkit/slices"
+	"github.com/ecumenos-social/toolkit/types"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 )
 
 type Config struct {
-	SMTPHost           string
-	SMTPPort           string
-	SenderUsername     string
+	Transport          string
 	SenderEmailAddress string
-	SenderPassword     string
-
-	ConfirmationOfRegistration *RateLimit
+	SMTP               *SMTPTransportConfig
+	HTTP               *HTTPTransportConfig
+	Maildir            *MaildirTransportConfig
+	// MaxDeliveryAttempts is amount of failed delivery attempts after which
+	// email is moved to dead state.
+	MaxDeliveryAttempts int64
+	// RetryBaseDelay is delay after the first failed delivery attempt, it
+	// doubles after every next one up to RetryMaxDelay.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// WebhookSecret is bearer token which email provider sends to bounce and
+	// complaint webhook, the webhook is disabled if it is empty.
+	WebhookSecret string
+	// RateLimits limit emails of template which are sent to the same
+	// receiver, templates without rate limit are restricted by
+	// SenderRateLimit only.
+	RateLimits map[TemplateName]*RateLimit
+	// SenderRateLimit limits all emails which are sent from
+	// SenderEmailAddress.
+	SenderRateLimit *RateLimit
 }
 
 type Repository interface {
-	InsertSentEmail(ctx context.Context, se *models.SentEmail) error
+	InsertSentEmailWithinRateLimits(ctx context.Context, se *models.SentEmail, receiverLimit, senderLimit *models.SentEmailRateLimit) (bool, error)
 	ModifySentEmail(ctx context.Context, id int64, se *models.SentEmail) error
-	GetSentEmails(ctx context.Context, sender, receiver, templateName string) ([]*models.SentEmail, error)
+	CountSentEmails(ctx context.Context, filter *models.SentEmailsFilter) (int64, error)
+	GetSentEmailCreatedAt(ctx context.Context, filter *models.SentEmailsFilter, offset int64) (*time.Time, error)
+	ClaimDueSentEmails(ctx context.Context, now, leaseUntil time.Time, limit int64) ([]*models.SentEmail, error)
+	UpsertEmailSuppression(ctx context.Context, es *models.EmailSuppression) error
+	GetEmailSuppressionsByEmails(ctx context.Context, emails []string) ([]*models.EmailSuppression, error)
+	GetEmailSuppressions(ctx context.Context, pagination *types.Pagination) ([]*models.EmailSuppression, error)
+	DeleteEmailSuppression(ctx context.Context, email string) error
+}
+
+// Recipient is holder which email is sent to. Name and Location personalize
+// the template, it is written in the first of Languages which it is translated
+// to.
+type Recipient struct {
+	Email     string
+	Name      string
+	Languages []string
+	Location  *time.Location
+}
+
+// HolderRecipient addresses email to holder's email address.
+func HolderRecipient(holder *models.Holder, email string) *Recipient {
+	return &Recipient{
+		Email:     email,
+		Name:      holders.DisplayName(holder),
+		Languages: holders.Languages(holder),
+		Location:  holders.Location(holder),
+	}
+}
+
+// expiresAt formats moment which is expiresIn from now in recipient's time
+// zone.
+func (r *Recipient) expiresAt(expiresIn time.Duration) string {
+	loc := r.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	return time.Now().Add(expiresIn).In(loc).Format("2006-01-02 15:04 MST")
 }
 
 type Service interface {
-	SendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, email, fullName, code string) error
-	CanSendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, email string) (bool, error)
+	SendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string) error
+	CanSendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, email string) error
+	SendResetHolderPassword(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string, expiresIn time.Duration) error
+	CanSendResetHolderPassword(ctx context.Context, logger *zap.Logger, email string) error
+	SendConfirmationOfContact(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string, expiresIn time.Duration) error
+	CanSendConfirmationOfContact(ctx context.Context, logger *zap.Logger, email string) error
+	SendHolderDeleted(ctx context.Context, logger *zap.Logger, recipient *Recipient) error
+	SendHolderLoginLink(ctx context.Context, logger *zap.Logger, recipient *Recipient, link, token string, expiresIn time.Duration) error
+	CanSendHolderLoginLink(ctx context.Context, logger *zap.Logger, email string) error
+	DeliverQueued(ctx context.Context, logger *zap.Logger, limit int64) (int, error)
+	HandleFeedback(ctx context.Context, logger *zap.Logger, provider, secret string, header http.Header, body []byte) (int, error)
+	GetSuppressions(ctx context.Context, logger *zap.Logger, pagination *types.Pagination) ([]*models.EmailSuppression, error)
+	Unsuppress(ctx context.Context, logger *zap.Logger, email string) error
 }
 
 type service struct {
-	smtpAddr           string
-	smtpAuth           smtp.Auth
-	senderEmailAddress string
-	rateLimits         map[TemplateName]*RateLimit
+	transport           Transport
+	senderEmailAddress  string
+	rateLimits          map[TemplateName]*RateLimit
+	senderRateLimit     *RateLimit
+	maxDeliveryAttempts int64
+	retryBaseDelay      time.Duration
+	retryMaxDelay       time.Duration
+	webhookSecret       string
+	feedbackAdapters    map[string]FeedbackAdapter
 
-	repo        Repository
-	idgenerator idgenerators.SentEmailsIDGenerator
+	repo                   Repository
+	idgenerator            idgenerators.SentEmailsIDGenerator
+	suppressionIDGenerator idgenerators.EmailSuppressionsIDGenerator
 }
 
-func New(config *Config, repo Repository, g idgenerators.SentEmailsIDGenerator) Service {
+func New(config *Config, repo Repository, g idgenerators.SentEmailsIDGenerator, sg idgenerators.EmailSuppressionsIDGenerator) (Service, error) {
+	transport, err := NewTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	for name := range config.RateLimits {
+		if err := name.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &service{
-		smtpAddr: net.JoinHostPort(config.SMTPHost, config.SMTPPort),
-		smtpAuth: smtp.PlainAuth(
-			"",
-			config.SenderUsername,
-			config.SenderPassword,
-			config.SMTPHost,
-		),
-		senderEmailAddress: config.SenderEmailAddress,
-		rateLimits: map[TemplateName]*RateLimit{
-			TemplateNameConfirmHolderRegistration: config.ConfirmationOfRegistration,
+		transport:           transport,
+		senderEmailAddress:  config.SenderEmailAddress,
+		rateLimits:          config.RateLimits,
+		senderRateLimit:     config.SenderRateLimit,
+		maxDeliveryAttempts: config.MaxDeliveryAttempts,
+		retryBaseDelay:      config.RetryBaseDelay,
+		retryMaxDelay:       config.RetryMaxDelay,
+		webhookSecret:       config.WebhookSecret,
+		feedbackAdapters: map[string]FeedbackAdapter{
+			FeedbackProviderGeneric: genericFeedbackAdapter{},
 		},
 
-		repo:        repo,
-		idgenerator: g,
-	}
+		repo:                   repo,
+		idgenerator:            g,
+		suppressionIDGenerator: sg,
+	}, nil
 }
 
-func (s *service) SendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, email, fullName, code string) error {
+func (s *service) SendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string) error {
 	return s.sendTemplate(
 		ctx,
 		logger,
 		TemplateNameConfirmHolderRegistration,
-		[]string{email},
+		recipient.Languages,
+		[]string{recipient.Email},
 		[]string{},
 		[]string{},
 		struct{ FullName, ConfirmationCode, CurrentYear string }{
-			FullName:         fullName,
+			FullName:         recipient.Name,
+			ConfirmationCode: code,
+			CurrentYear:      fmt.Sprint(time.Now().Year()),
+		},
+	)
+}
+
+func (s *service) SendResetHolderPassword(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string, expiresIn time.Duration) error {
+	return s.sendTemplate(
+		ctx,
+		logger,
+		TemplateNameResetHolderPassword,
+		recipient.Languages,
+		[]string{recipient.Email},
+		[]string{},
+		[]string{},
+		struct{ FullName, ResetCode, ExpiresIn, ExpiresAt, CurrentYear string }{
+			FullName:    recipient.Name,
+			ResetCode:   code,
+			ExpiresIn:   expiresIn.String(),
+			ExpiresAt:   recipient.expiresAt(expiresIn),
+			CurrentYear: fmt.Sprint(time.Now().Year()),
+		},
+	)
+}
+
+func (s *service) SendConfirmationOfContact(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string, expiresIn time.Duration) error {
+	return s.sendTemplate(
+		ctx,
+		logger,
+		TemplateNameConfirmHolderContact,
+		recipient.Languages,
+		[]string{recipient.Email},
+		[]string{},
+		[]string{},
+		struct{ FullName, ConfirmationCode, ExpiresIn, ExpiresAt, CurrentYear string }{
+			FullName:         recipient.Name,
 			ConfirmationCode: code,
+			ExpiresIn:        expiresIn.String(),
+			ExpiresAt:        recipient.expiresAt(expiresIn),
 			CurrentYear:      fmt.Sprint(time.Now().Year()),
 		},
-		s.rateLimits[TemplateNameConfirmHolderRegistration],
 	)
 }
 
-func (s *service) sendTemplate(ctx context.Context, logger *zap.Logger, name TemplateName, to, cc, files []string, data interface{}, rl *RateLimit) error {
+func (s *service) SendHolderDeleted(ctx context.Context, logger *zap.Logger, recipient *Recipient) error {
+	return s.sendTemplate(
+		ctx,
+		logger,
+		TemplateNameHolderDeleted,
+		recipient.Languages,
+		[]string{recipient.Email},
+		[]string{},
+		[]string{},
+		struct{ FullName, CurrentYear string }{
+			FullName:    recipient.Name,
+			CurrentYear: fmt.Sprint(time.Now().Year()),
+		},
+	)
+}
+
+func (s *service) SendHolderLoginLink(ctx context.Context, logger *zap.Logger, recipient *Recipient, link, token string, expiresIn time.Duration) error {
+	return s.sendTemplate(
+		ctx,
+		logger,
+		TemplateNameHolderLoginLink,
+		recipient.Languages,
+		[]string{recipient.Email},
+		[]string{},
+		[]string{},
+		struct{ FullName, LoginLink, LoginToken, ExpiresIn, ExpiresAt, CurrentYear string }{
+			FullName:    recipient.Name,
+			LoginLink:   link,
+			LoginToken:  token,
+			ExpiresIn:   expiresIn.String(),
+			ExpiresAt:   recipient.expiresAt(expiresIn),
+			CurrentYear: fmt.Sprint(time.Now().Year()),
+		},
+	)
+}
+
+// sendTemplate queues email for every receiver, the emails are delivered by
+// DeliverQueued. Rate limits are checked before the message is composed and
+// again by the insert of every email, so concurrent requests can't exceed
+// them.
+func (s *service) sendTemplate(ctx context.Context, logger *zap.Logger, name TemplateName, languages, to, cc, files []string, data interface{}) error {
 	logger = logger.With(
 		zap.Strings("to", to),
 		zap.Strings("cc", cc),
 		zap.String("template-name", string(name)),
 	)
-	canSend, err := s.canSendTemplate(ctx, logger, name, to, cc, rl)
+	if err := s.canSendTemplate(ctx, logger, name, slices.Merge(to, cc)); err != nil {
+		return err
+	}
+	to, cc, err := s.withoutSuppressed(ctx, logger, to, cc)
 	if err != nil {
 		return err
 	}
-	if !canSend {
-		logger.Error("too many send email requests", zap.Int64("max-requests", rl.MaxRequests), zap.Duration("interval", rl.Interval))
-		return errorwrapper.New("too many send email requests")
+	if len(to)+len(cc) == 0 {
+		logger.Warn("email is not sent, all receivers are suppressed")
+		return ErrSuppressedEmail
 	}
 
-	message, err := s.formMessage(name, s.senderEmailAddress, to, cc, data)
+	message, err := s.formMessage(name, languages, s.senderEmailAddress, to, cc, data)
 	if err != nil {
 		logger.Info("failed to compose email message", zap.Error(err))
 		return err
 	}
-	if err := smtp.SendMail(s.smtpAddr, s.smtpAuth, s.senderEmailAddress, to, message); err != nil {
-		logger.Info("failed to send email", zap.Error(err))
-		return err
-	}
-	logger.Info("email was sent successfully")
 
-	for _, receiverEmail := range slices.Merge(to, cc) {
+	for _, receiverEmail := range message.Recipients() {
 		m := &models.SentEmail{
 			ID:             s.idgenerator.Generate().Int64(),
 			CreatedAt:      time.Now(),
@@ -117,57 +278,331 @@ func (s *service) sendTemplate(ctx context.Context, logger *zap.Logger, name Tem
 			SenderEmail:    s.senderEmailAddress,
 			ReceiverEmail:  receiverEmail,
 			TemplateName:   name.String(),
+			Status:         models.SentEmailStatusPending,
+			Subject:        message.Subject,
+			Body:           message.HTMLBody,
+			NextAttemptAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
 		}
-		if err := s.repo.InsertSentEmail(ctx, m); err != nil {
+		receiverLimit, senderLimit := s.sentEmailRateLimits(name, m.CreatedAt)
+		inserted, err := s.repo.InsertSentEmailWithinRateLimits(ctx, m, receiverLimit, senderLimit)
+		if err != nil {
 			logger.Info("failed to insert sent email entity database", zap.Error(err), zap.String("receiver-email", receiverEmail))
 			return err
 		}
+		if !inserted {
+			// concurrent requests took the rest of the limit after it was
+			// checked
+			if err := s.canSendTemplate(ctx, logger, name, []string{receiverEmail}); err != nil {
+				return err
+			}
+			logger.Warn("too many send email requests", zap.String("receiver-email", receiverEmail))
+			return &RateLimitedError{RetryAfter: time.Second}
+		}
 	}
+	logger.Info("email was queued successfully")
 
 	return nil
 }
 
-func (s *service) CanSendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, email string) (bool, error) {
-	return s.canSendTemplate(ctx, logger, TemplateNameConfirmHolderRegistration, []string{email}, []string{}, s.rateLimits[TemplateNameConfirmHolderRegistration])
+// deliveryLease is time for which queued email is claimed by worker, it must
+// be longer than any transport takes to send message.
+const deliveryLease = 5 * time.Minute
+
+// DeliverQueued sends up to limit queued emails which are due and returns how
+// many of them were taken. Failed emails are retried with exponential backoff.
+func (s *service) DeliverQueued(ctx context.Context, logger *zap.Logger, limit int64) (int, error) {
+	now := time.Now()
+	ses, err := s.repo.ClaimDueSentEmails(ctx, now, now.Add(deliveryLease), limit)
+	if err != nil {
+		logger.Error("failed to claim queued emails", zap.Error(err))
+		return 0, err
+	}
+	for _, se := range ses {
+		// failed email is claimed again once its lease is over, so the rest
+		// of the batch is not held back by it
+		l := logger.With(zap.Int64("sent-email-id", se.ID), zap.String("template-name", se.TemplateName))
+		if err := s.deliver(ctx, l, se); err != nil {
+			l.Error("failed to deliver queued email", zap.Error(err))
+		}
+	}
+
+	return len(ses), nil
 }
 
-func (s *service) canSendTemplate(ctx context.Context, logger *zap.Logger, name TemplateName, to, cc []string, rl *RateLimit) (bool, error) {
-	receivers := slices.Merge(to, cc)
-	ses := make([]*models.SentEmail, 0, len(receivers))
-	for _, r := range receivers {
-		sentEmails, err := s.repo.GetSentEmails(ctx, s.senderEmailAddress, r, name.String())
+func (s *service) deliver(ctx context.Context, logger *zap.Logger, se *models.SentEmail) error {
+	// receiver could be suppressed while email was waiting in the queue
+	suppressed, err := s.repo.GetEmailSuppressionsByEmails(ctx, []string{normalizeEmail(se.ReceiverEmail)})
+	if err != nil {
+		logger.Error("failed to get email suppressions", zap.Error(err))
+		return err
+	}
+	if len(suppressed) > 0 {
+		err = ErrSuppressedEmail
+	} else {
+		err = s.transport.Send(ctx, logger, newMessage(se.SenderEmail, []string{se.ReceiverEmail}, nil, se.Subject, se.Body))
+	}
+	se.Attempts++
+	se.LastModifiedAt = time.Now()
+	switch {
+	case err == nil:
+		se.Status = models.SentEmailStatusSent
+		se.SentAt = sql.NullTime{Time: time.Now(), Valid: true}
+		se.NextAttemptAt = sql.NullTime{}
+		se.LastError = sql.NullString{}
+		logger.Info("email was sent successfully")
+	case errors.Is(err, ErrSuppressedEmail) || se.Attempts >= s.maxDeliveryAttempts:
+		se.Status = models.SentEmailStatusDead
+		se.NextAttemptAt = sql.NullTime{}
+		se.LastError = sql.NullString{String: err.Error(), Valid: true}
+		logger.Error("email delivery is given up", zap.Error(err), zap.Int64("attempts", se.Attempts))
+	default:
+		delay := s.retryDelay(se.Attempts)
+		se.NextAttemptAt = sql.NullTime{Time: time.Now().Add(delay), Valid: true}
+		se.LastError = sql.NullString{String: err.Error(), Valid: true}
+		logger.Warn("failed to send email, it will be retried", zap.Error(err), zap.Int64("attempts", se.Attempts), zap.Duration("retry-delay", delay))
+	}
+	if se.Status != models.SentEmailStatusPending {
+		// message may carry codes and links, it isn't kept once it is not
+		// going to be sent anymore
+		se.Subject = ""
+		se.Body = ""
+	}
+	if err := s.repo.ModifySentEmail(ctx, se.ID, se); err != nil {
+		logger.Error("failed to modify sent email", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// retryDelay returns delay after attempts failed delivery attempts.
+func (s *service) retryDelay(attempts int64) time.Duration {
+	delay := s.retryBaseDelay
+	for i := int64(1); i < attempts && delay < s.retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.retryMaxDelay {
+		delay = s.retryMaxDelay
+	}
+
+	return delay
+}
+
+func (s *service) CanSendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, email string) error {
+	return s.canSendTemplate(ctx, logger, TemplateNameConfirmHolderRegistration, []string{email})
+}
+
+func (s *service) CanSendResetHolderPassword(ctx context.Context, logger *zap.Logger, email string) error {
+	return s.canSendTemplate(ctx, logger, TemplateNameResetHolderPassword, []string{email})
+}
+
+func (s *service) CanSendConfirmationOfContact(ctx context.Context, logger *zap.Logger, email string) error {
+	return s.canSendTemplate(ctx, logger, TemplateNameConfirmHolderContact, []string{email})
+}
+
+func (s *service) CanSendHolderLoginLink(ctx context.Context, logger *zap.Logger, email string) error {
+	return s.canSendTemplate(ctx, logger, TemplateNameHolderLoginLink, []string{email})
+}
+
+// canSendTemplate returns RateLimitedError if template's rate limit of any
+// receiver or rate limit of sender is exceeded.
+func (s *service) canSendTemplate(ctx context.Context, logger *zap.Logger, name TemplateName, receivers []string) error {
+	var retryAfter time.Duration
+	if rl := s.rateLimits[name]; rl.enabled() {
+		for _, r := range receivers {
+			ra, err := s.retryAfter(ctx, &models.SentEmailsFilter{
+				SenderEmail:   s.senderEmailAddress,
+				ReceiverEmail: r,
+				TemplateName:  name.String(),
+			}, rl)
+			if err != nil {
+				logger.Error("can not count sent emails", zap.Error(err), zap.String("receiver-email", r))
+				return errorwrapper.WrapMessage(err, "can not count sent emails")
+			}
+			retryAfter = max(retryAfter, ra)
+		}
+		if retryAfter > 0 {
+			logger.Warn("too many send email requests", zap.Int64("max-requests", rl.MaxRequests), zap.Duration("interval", rl.Interval), zap.Duration("retry-after", retryAfter))
+			return &RateLimitedError{RetryAfter: retryAfter}
+		}
+	}
+	if rl := s.senderRateLimit; rl.enabled() {
+		ra, err := s.retryAfter(ctx, &models.SentEmailsFilter{SenderEmail: s.senderEmailAddress}, rl)
 		if err != nil {
-			logger.Error("can not get sent emails", zap.Error(err))
-			return false, errorwrapper.WrapMessage(err, "can not get sent emails")
+			logger.Error("can not count sent emails of sender", zap.Error(err))
+			return errorwrapper.WrapMessage(err, "can not count sent emails of sender")
+		}
+		if ra > 0 {
+			logger.Error("too many emails are sent by sender", zap.Int64("max-requests", rl.MaxRequests), zap.Duration("interval", rl.Interval), zap.Duration("retry-after", ra))
+			return &RateLimitedError{RetryAfter: ra}
 		}
-		ses = append(ses, sentEmails...)
 	}
 
-	return !rl.Exceed(ses), nil
+	return nil
 }
 
-func (s *service) formMessage(name TemplateName, from string, to, cc []string, data interface{}) ([]byte, error) {
-	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf("From: %s\r\n", from))
-	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(slices.Merge(to, cc), ",")))
-	if len(cc) > 0 {
-		buf.WriteString(fmt.Sprintf("Cc: %s\r\n", strings.Join(cc, ",")))
+// sentEmailRateLimits returns limits of email of template which is created at
+// now, limit is nil if it is disabled.
+func (s *service) sentEmailRateLimits(name TemplateName, now time.Time) (receiverLimit, senderLimit *models.SentEmailRateLimit) {
+	if rl := s.rateLimits[name]; rl.enabled() {
+		receiverLimit = &models.SentEmailRateLimit{CreatedAfter: now.Add(-rl.Interval), MaxCount: rl.MaxRequests}
+	}
+	if rl := s.senderRateLimit; rl.enabled() {
+		senderLimit = &models.SentEmailRateLimit{CreatedAfter: now.Add(-rl.Interval), MaxCount: rl.MaxRequests}
 	}
-	subject, err := takeSubject(TemplateNameConfirmHolderRegistration)
+
+	return receiverLimit, senderLimit
+}
+
+// retryAfter returns how long emails which match filter can't be sent
+// because of rl, it is zero if they can be sent now. The next email can be
+// sent when the MaxRequests-th newest email in the interval leaves it.
+func (s *service) retryAfter(ctx context.Context, filter *models.SentEmailsFilter, rl *RateLimit) (time.Duration, error) {
+	now := time.Now()
+	filter.CreatedAfter = lo.ToPtr(now.Add(-rl.Interval))
+	count, err := s.repo.CountSentEmails(ctx, filter)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if count < rl.MaxRequests {
+		return 0, nil
+	}
+	createdAt, err := s.repo.GetSentEmailCreatedAt(ctx, filter, rl.MaxRequests-1)
+	if err != nil {
+		return 0, err
+	}
+	if createdAt == nil {
+		// the email left the interval between the queries
+		return 0, nil
 	}
-	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
-	buf.WriteString("MIME-Version: 1.0\r\n")
-	buf.WriteString("Content-Type: text/html; charset=\"UTF-8\";\r\n")
 
-	t, err := takeTemplate(name)
+	// retry delay is rounded up to seconds, created_at doesn't keep fraction
+	// of second anyway
+	retryAfter := (createdAt.Add(rl.Interval).Sub(now) + time.Second - 1).Truncate(time.Second)
+
+	return max(retryAfter, time.Second), nil
+}
+
+func (s *service) formMessage(name TemplateName, languages []string, from string, to, cc []string, data interface{}) (*Message, error) {
+	t, err := takeTemplate(name, languages)
 	if err != nil {
 		return nil, err
 	}
-	if err = t.Execute(&buf, data); err != nil {
+	var subject, body strings.Builder
+	if err = t.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err = t.Execute(&body, data); err != nil {
+		return nil, err
+	}
+
+	// subject is rendered by HTML template, so it is unescaped back to text
+	return newMessage(from, to, cc, strings.TrimSpace(html.UnescapeString(subject.String())), body.String()), nil
+}
+
+// withoutSuppressed drops receivers which are in the suppression list.
+func (s *service) withoutSuppressed(ctx context.Context, logger *zap.Logger, to, cc []string) ([]string, []string, error) {
+	receivers := slices.Merge(to, cc)
+	emails := make([]string, 0, len(receivers))
+	for _, r := range receivers {
+		emails = append(emails, normalizeEmail(r))
+	}
+	ess, err := s.repo.GetEmailSuppressionsByEmails(ctx, emails)
+	if err != nil {
+		logger.Error("failed to get email suppressions", zap.Error(err))
+		return nil, nil, errorwrapper.WrapMessage(err, "can not get email suppressions")
+	}
+	if len(ess) == 0 {
+		return to, cc, nil
+	}
+	suppressed := make(map[string]struct{}, len(ess))
+	for _, es := range ess {
+		suppressed[es.Email] = struct{}{}
+		logger.Warn("receiver email is suppressed", zap.String("receiver-email", es.Email), zap.String("reason", string(es.Reason)))
+	}
+	filter := func(emails []string) []string {
+		out := make([]string, 0, len(emails))
+		for _, email := range emails {
+			if _, ok := suppressed[normalizeEmail(email)]; !ok {
+				out = append(out, email)
+			}
+		}
+		return out
+	}
+
+	return filter(to), filter(cc), nil
+}
+
+// HandleFeedback suppresses addresses from bounce and complaint notification
+// of provider and returns amount of suppressed addresses. Soft bounces are
+// ignored.
+func (s *service) HandleFeedback(ctx context.Context, logger *zap.Logger, provider, secret string, header http.Header, body []byte) (int, error) {
+	if s.webhookSecret == "" {
+		return 0, ErrFeedbackDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(s.webhookSecret)) != 1 {
+		logger.Warn("invalid email feedback webhook secret")
+		return 0, ErrFeedbackUnauthorized
+	}
+	adapter, ok := s.feedbackAdapters[provider]
+	if !ok {
+		return 0, ErrUnknownFeedbackProvider
+	}
+	events, err := adapter.ParseFeedback(header, body)
+	if err != nil {
+		logger.Info("failed to parse email feedback", zap.Error(err))
+		return 0, ErrInvalidFeedback
+	}
+
+	var count int
+	for _, e := range events {
+		if !e.Permanent {
+			logger.Info("temporary bounce is ignored", zap.String("email", e.Email))
+			continue
+		}
+		es := &models.EmailSuppression{
+			ID:             s.suppressionIDGenerator.Generate().Int64(),
+			CreatedAt:      time.Now(),
+			LastModifiedAt: time.Now(),
+			Email:          normalizeEmail(e.Email),
+			Reason:         e.Reason,
+			Provider:       provider,
+			Details: sql.NullString{
+				String: e.Details,
+				Valid:  e.Details != "",
+			},
+		}
+		if err := s.repo.UpsertEmailSuppression(ctx, es); err != nil {
+			logger.Error("failed to upsert email suppression", zap.Error(err), zap.String("email", es.Email))
+			return count, err
+		}
+		logger.Info("email address is suppressed", zap.String("email", es.Email), zap.String("reason", string(es.Reason)))
+		count++
+	}
+
+	return count, nil
+}
+
+func (s *service) GetSuppressions(ctx context.Context, logger *zap.Logger, pagination *types.Pagination) ([]*models.EmailSuppression, error) {
+	ess, err := s.repo.GetEmailSuppressions(ctx, pagination)
+	if err != nil {
+		logger.Error("failed to get email suppressions", zap.Error(err))
 		return nil, err
 	}
 
-	return buf.Bytes(), nil
+	return ess, nil
+}
+
+// Unsuppress removes email from the suppression list, so emails are sent to
+// it again.
+func (s *service) Unsuppress(ctx context.Context, logger *zap.Logger, email string) error {
+	if err := s.repo.DeleteEmailSuppression(ctx, normalizeEmail(email)); err != nil {
+		logger.Error("failed to delete email suppression", zap.Error(err))
+		return err
+	}
+
+	return nil
 }
@@ -1,9 +1,11 @@
 package emailer
 
 import (
+	"embed"
 	"fmt"
 	"html/template"
-	"path/filepath"
+	"io/fs"
+	"strings"
 
 	errorwrapper "github.com/ecumenos-social/error-wrapper"
 )
@@ -12,6 +14,10 @@ type TemplateName string
 
 const (
 	TemplateNameConfirmHolderRegistration TemplateName = "confirm-holder-registration"
+	TemplateNameResetHolderPassword       TemplateName = "reset-holder-password"
+	TemplateNameConfirmHolderContact      TemplateName = "confirm-holder-contact"
+	TemplateNameHolderDeleted             TemplateName = "holder-deleted"
+	TemplateNameHolderLoginLink           TemplateName = "holder-login-link"
 )
 
 var unknownTemplateName = func(tn TemplateName) error {
@@ -19,7 +25,7 @@ var unknownTemplateName = func(tn TemplateName) error {
 }
 
 func (tn TemplateName) Validate() error {
-	for _, n := range []TemplateName{TemplateNameConfirmHolderRegistration} {
+	for _, n := range []TemplateName{TemplateNameConfirmHolderRegistration, TemplateNameResetHolderPassword, TemplateNameConfirmHolderContact, TemplateNameHolderDeleted, TemplateNameHolderLoginLink} {
 		if n == tn {
 			return nil
 		}
@@ -31,27 +37,32 @@ func (tn TemplateName) String() string {
 	return string(tn)
 }
 
-func takeTemplate(name TemplateName) (*template.Template, error) {
+//go:embed templates
+var templatesFS embed.FS
+
+// DefaultLanguage is language of templates which are used if there is no
+// variant in any of recipient's languages.
+const DefaultLanguage = "ENG"
+
+// takeTemplate returns variant of template in the first of languages which it
+// is translated to. Every template defines "subject" template with subject of
+// email.
+func takeTemplate(name TemplateName, languages []string) (*template.Template, error) {
 	if err := name.Validate(); err != nil {
 		return nil, errorwrapper.NewWithError(err)
 	}
-	path := fmt.Sprintf("services/emailer/templates/%s.html", name)
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		return nil, err
-	}
-	t, err := template.ParseFiles(absPath)
-	if err != nil {
-		return nil, err
-	}
-	return t, nil
-}
-
-func takeSubject(name TemplateName) (string, error) {
-	switch name {
-	case TemplateNameConfirmHolderRegistration:
-		return "Confirmation of Registration", nil
+	// full slice expression makes append copy languages of caller
+	for _, lang := range append(languages[:len(languages):len(languages)], DefaultLanguage) {
+		path := fmt.Sprintf("templates/%s/%s.html", strings.ToLower(lang), name)
+		if _, err := fs.Stat(templatesFS, path); err != nil {
+			continue
+		}
+		t, err := template.ParseFS(templatesFS, path)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
 	}
 
-	return "", unknownTemplateName(name)
+	return nil, errorwrapper.New(fmt.Sprintf("template is not found, name = %v", name))
 }
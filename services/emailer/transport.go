@@ -0,0 +1,116 @@
+package emailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/toolkit/slices"
+	"go.uber.org/zap"
+)
+
+type Message struct {
+	From     string
+	To       []string
+	Cc       []string
+	Subject  string
+	HTMLBody string
+	// TextBody is plain text alternative of HTMLBody.
+	TextBody string
+}
+
+// newMessage creates message with plain text alternative which is generated
+// from HTML body.
+func newMessage(from string, to, cc []string, subject, htmlBody string) *Message {
+	return &Message{
+		From:     from,
+		To:       to,
+		Cc:       cc,
+		Subject:  subject,
+		HTMLBody: htmlBody,
+		TextBody: htmlToText(htmlBody),
+	}
+}
+
+// Recipients returns all envelope recipients of message.
+func (m *Message) Recipients() []string {
+	return slices.Merge(m.To, m.Cc)
+}
+
+// Bytes formats message as RFC 5322 message with multipart/alternative body
+// of plain text and HTML parts.
+func (m *Message) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("From: %s\r\n", m.From))
+	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(m.To, ",")))
+	if len(m.Cc) > 0 {
+		buf.WriteString(fmt.Sprintf("Cc: %s\r\n", strings.Join(m.Cc, ",")))
+	}
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", m.Subject)))
+	buf.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q\r\n", w.Boundary()))
+	buf.WriteString("\r\n")
+	// plain text goes first, clients show the last part they can render
+	writeMessagePart(w, "text/plain", m.TextBody)
+	writeMessagePart(w, "text/html", m.HTMLBody)
+	_ = w.Close()
+	buf.Write(body.Bytes())
+
+	return buf.Bytes()
+}
+
+// writeMessagePart writes quoted-printable part, writes to bytes.Buffer don't
+// fail.
+func writeMessagePart(w *multipart.Writer, contentType, content string) {
+	part, _ := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=\"UTF-8\""},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	qp := quotedprintable.NewWriter(part)
+	_, _ = qp.Write([]byte(content))
+	_ = qp.Close()
+}
+
+// Transport delivers email messages.
+type Transport interface {
+	Send(ctx context.Context, logger *zap.Logger, msg *Message) error
+}
+
+const (
+	// TransportSMTP sends messages by SMTP upgraded with STARTTLS, it is used
+	// with ports 25 and 587.
+	TransportSMTP = "smtp"
+	// TransportSMTPS sends messages by SMTP over implicit TLS, it is used
+	// with port 465.
+	TransportSMTPS   = "smtps"
+	TransportHTTP    = "http"
+	TransportMaildir = "maildir"
+)
+
+// NewTransport creates transport which is chosen in config. Maildir
+// transport doesn't deliver messages, it is meant for development and tests.
+func NewTransport(config *Config) (Transport, error) {
+	switch config.Transport {
+	case TransportSMTP:
+		return NewSMTPTransport(config.SMTP, false), nil
+	case TransportSMTPS:
+		return NewSMTPTransport(config.SMTP, true), nil
+	case TransportHTTP:
+		return NewHTTPTransport(config.HTTP)
+	case TransportMaildir:
+		return NewMaildirTransport(config.Maildir), nil
+	}
+
+	return nil, errorwrapper.New(fmt.Sprintf("unknown email transport, transport = %v", config.Transport))
+}
@@ -0,0 +1,94 @@
+package emailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"go.uber.org/zap"
+)
+
+// DefaultHTTPBodyTemplate is body of HTTP transport request if it isn't set in
+// config.
+const DefaultHTTPBodyTemplate = `{"from":{{json .From}},"to":{{json .To}},"cc":{{json .Cc}},"subject":{{json .Subject}},"html":{{json .HTMLBody}},"text":{{json .TextBody}}}`
+
+type HTTPTransportConfig struct {
+	URL string
+	// Authorization is value of Authorization header, it is not sent if it
+	// is empty.
+	Authorization string
+	ContentType   string
+	// BodyTemplate is text/template of request body which is executed with
+	// Message. Besides builtin functions it can use json function which
+	// encodes value as JSON.
+	BodyTemplate string
+	Timeout      time.Duration
+}
+
+type httpTransport struct {
+	url           string
+	authorization string
+	contentType   string
+	body          *template.Template
+	client        *http.Client
+}
+
+// NewHTTPTransport creates transport which posts messages to an HTTP API of
+// email provider, any response with 2xx status means success.
+func NewHTTPTransport(config *HTTPTransportConfig) (Transport, error) {
+	bodyTemplate := config.BodyTemplate
+	if bodyTemplate == "" {
+		bodyTemplate = DefaultHTTPBodyTemplate
+	}
+	body, err := template.New("body").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(bodyTemplate)
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "invalid HTTP email transport body template")
+	}
+
+	return &httpTransport{
+		url:           config.URL,
+		authorization: config.Authorization,
+		contentType:   config.ContentType,
+		body:          body,
+		client:        &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+func (t *httpTransport) Send(ctx context.Context, logger *zap.Logger, msg *Message) error {
+	var body bytes.Buffer
+	if err := t.body.Execute(&body, msg); err != nil {
+		return errorwrapper.WrapMessage(err, "failed to compose HTTP email transport request")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", t.contentType)
+	if t.authorization != "" {
+		req.Header.Set("Authorization", t.authorization)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		logger.Error("HTTP email provider rejected message", zap.Int("status-code", resp.StatusCode), zap.ByteString("response-body", respBody))
+		return errorwrapper.New(fmt.Sprintf("HTTP email provider responded with status %d", resp.StatusCode))
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package emailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type MaildirTransportConfig struct {
+	Path string
+}
+
+type maildirTransport struct {
+	path     string
+	hostname string
+	sequence atomic.Int64
+}
+
+// NewMaildirTransport creates transport which delivers messages to maildir
+// instead of sending them, so they can be read by mail client or inspected in
+// tests.
+func NewMaildirTransport(config *MaildirTransportConfig) Transport {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return &maildirTransport{path: config.Path, hostname: hostname}
+}
+
+func (t *maildirTransport) Send(_ context.Context, logger *zap.Logger, msg *Message) error {
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.path, dir), 0o700); err != nil {
+			return err
+		}
+	}
+
+	// message is written to tmp and moved to new, so readers never see
+	// partially written message.
+	name := fmt.Sprintf("%d.P%dQ%d.%s", time.Now().UnixNano(), os.Getpid(), t.sequence.Add(1), t.hostname)
+	tmpPath := filepath.Join(t.path, "tmp", name)
+	if err := os.WriteFile(tmpPath, msg.Bytes(), 0o600); err != nil {
+		return err
+	}
+	newPath := filepath.Join(t.path, "new", name)
+	if err := os.Rename(tmpPath, newPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	logger.Info("email message is delivered to maildir", zap.String("path", newPath))
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package emailer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"go.uber.org/zap"
+)
+
+type SMTPTransportConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+type smtpTransport struct {
+	config      *SMTPTransportConfig
+	implicitTLS bool
+}
+
+// NewSMTPTransport creates transport which sends messages to SMTP server. If
+// implicitTLS is false the connection must be upgraded with STARTTLS, so
+// credentials are never sent in plain text.
+func NewSMTPTransport(config *SMTPTransportConfig, implicitTLS bool) Transport {
+	return &smtpTransport{config: config, implicitTLS: implicitTLS}
+}
+
+func (t *smtpTransport) Send(ctx context.Context, logger *zap.Logger, msg *Message) error {
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return errorwrapper.WrapMessage(err, "failed to connect to SMTP server")
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(t.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, t.config.Host)
+	if err != nil {
+		return errorwrapper.WrapMessage(err, "failed to start SMTP session")
+	}
+	defer c.Close()
+	if !t.implicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errorwrapper.New("SMTP server doesn't support STARTTLS")
+		}
+		if err := c.StartTLS(t.tlsConfig()); err != nil {
+			return errorwrapper.WrapMessage(err, "failed to start TLS")
+		}
+	}
+	if t.config.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host)); err != nil {
+			return errorwrapper.WrapMessage(err, "failed to authenticate to SMTP server")
+		}
+	}
+	if err := c.Mail(msg.From); err != nil {
+		return err
+	}
+	for _, r := range msg.Recipients() {
+		if err := c.Rcpt(r); err != nil {
+			return errorwrapper.WrapMessage(err, "SMTP server rejected recipient")
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return errorwrapper.WrapMessage(err, "SMTP server rejected message")
+	}
+	if err := c.Quit(); err != nil {
+		logger.Warn("failed to quit SMTP session", zap.Error(err))
+	}
+
+	return nil
+}
+
+func (t *smtpTransport) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(t.config.Host, t.config.Port)
+	dialer := &net.Dialer{Timeout: t.config.Timeout}
+	if t.implicitTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: t.tlsConfig()}
+		return tlsDialer.DialContext(ctx, "tcp", addr)
+	}
+
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (t *smtpTransport) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: t.config.Host, MinVersion: tls.VersionTLS12}
+}
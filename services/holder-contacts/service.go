@@ -0,0 +1,379 @@
+package holdercontacts
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/idgenerators"
+	"github.com/ecumenos-social/toolkit/hash"
+	"github.com/ecumenos-social/toolkit/random"
+	"github.com/ecumenos-social/toolkit/validators"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+type Kind string
+
+func (k Kind) String() string {
+	return string(k)
+}
+
+func (k Kind) Validate() error {
+	switch k {
+	case KindEmail, KindPhoneNumber:
+		return nil
+	}
+	return errorwrapper.New("unknown contact kind, kind = " + k.String())
+}
+
+const (
+	KindEmail       Kind = "email"
+	KindPhoneNumber Kind = "phone-number"
+)
+
+type Config struct {
+	CodeAge     time.Duration
+	MaxAttempts int64
+}
+
+type Repository interface {
+	GetHoldersByEmails(ctx context.Context, emails []string) ([]*models.Holder, error)
+	GetHoldersByPhoneNumbers(ctx context.Context, phoneNumbers []string) ([]*models.Holder, error)
+	ModifyHolder(ctx context.Context, id int64, holder *models.Holder) error
+	InsertHolderContact(ctx context.Context, hc *models.HolderContact) error
+	ModifyHolderContact(ctx context.Context, id int64, hc *models.HolderContact) error
+	IncrementHolderContactVerificationFailedAttempts(ctx context.Context, id int64, verificationCodeHash string, maxAttempts int64, modifiedAt time.Time) (int64, error)
+	DeleteHolderContact(ctx context.Context, id int64) error
+	GetHolderContactByID(ctx context.Context, id int64) (*models.HolderContact, error)
+	GetHolderContactsByHolderID(ctx context.Context, holderID int64) ([]*models.HolderContact, error)
+	GetEmailSuppressionsByEmails(ctx context.Context, emails []string) ([]*models.EmailSuppression, error)
+}
+
+type Service interface {
+	CodeAge() time.Duration
+	GetList(ctx context.Context, logger *zap.Logger, holder *models.Holder) ([]*Contact, error)
+	Add(ctx context.Context, logger *zap.Logger, holder *models.Holder, kind Kind, value string) (hc *models.HolderContact, code string, err error)
+	RegenerateCode(ctx context.Context, logger *zap.Logger, holder *models.Holder, contactID int64) (hc *models.HolderContact, code string, err error)
+	Verify(ctx context.Context, logger *zap.Logger, holder *models.Holder, contactID int64, code string) (*models.Holder, error)
+	Remove(ctx context.Context, logger *zap.Logger, holder *models.Holder, kind Kind, value string) (*models.Holder, error)
+	MakePrimary(ctx context.Context, logger *zap.Logger, holder *models.Holder, kind Kind, value string) (*models.Holder, error)
+}
+
+type service struct {
+	codeAge     time.Duration
+	maxAttempts int64
+	repo        Repository
+	idgenerator idgenerators.HolderContactsIDGenerator
+}
+
+func New(config *Config, repo Repository, g idgenerators.HolderContactsIDGenerator) Service {
+	return &service{
+		codeAge:     config.CodeAge,
+		maxAttempts: config.MaxAttempts,
+		repo:        repo,
+		idgenerator: g,
+	}
+}
+
+func (s *service) CodeAge() time.Duration {
+	return s.codeAge
+}
+
+func generateCode() string {
+	return random.GenNumericString(8)
+}
+
+func hashCode(code string) string {
+	return hash.SHA256(code)
+}
+
+// Contact is a holder's email or phone number. Verified contacts come from
+// the holder entity, unverified ones from holder_contacts. Undeliverable
+// emails are in the suppression list, emails are not sent to them.
+type Contact struct {
+	ID            *int64
+	Kind          Kind
+	Value         string
+	Verified      bool
+	Primary       bool
+	Undeliverable bool
+}
+
+func (s *service) GetList(ctx context.Context, logger *zap.Logger, holder *models.Holder) ([]*Contact, error) {
+	pending, err := s.repo.GetHolderContactsByHolderID(ctx, holder.ID)
+	if err != nil {
+		logger.Error("failed to get holder contacts", zap.Error(err))
+		return nil, err
+	}
+
+	out := make([]*Contact, 0, len(holder.Emails)+len(holder.PhoneNumbers)+len(pending))
+	for i, email := range holder.Emails {
+		out = append(out, &Contact{Kind: KindEmail, Value: email, Verified: true, Primary: i == 0})
+	}
+	for i, phoneNumber := range holder.PhoneNumbers {
+		out = append(out, &Contact{Kind: KindPhoneNumber, Value: phoneNumber, Verified: true, Primary: i == 0})
+	}
+	for _, hc := range pending {
+		out = append(out, &Contact{ID: lo.ToPtr(hc.ID), Kind: Kind(hc.Kind), Value: hc.Value})
+	}
+	if err := s.markUndeliverable(ctx, logger, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *service) markUndeliverable(ctx context.Context, logger *zap.Logger, contacts []*Contact) error {
+	emails := make([]string, 0, len(contacts))
+	for _, c := range contacts {
+		if c.Kind == KindEmail {
+			emails = append(emails, strings.ToLower(c.Value))
+		}
+	}
+	if len(emails) == 0 {
+		return nil
+	}
+	ess, err := s.repo.GetEmailSuppressionsByEmails(ctx, emails)
+	if err != nil {
+		logger.Error("failed to get email suppressions", zap.Error(err))
+		return err
+	}
+	for _, es := range ess {
+		for _, c := range contacts {
+			if c.Kind == KindEmail && strings.EqualFold(c.Value, es.Email) {
+				c.Undeliverable = true
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *service) validateValue(ctx context.Context, logger *zap.Logger, kind Kind, value string) error {
+	var (
+		holders []*models.Holder
+		err     error
+	)
+	switch kind {
+	case KindEmail:
+		if err := validators.ValidateEmail(ctx, value); err != nil {
+			return err
+		}
+		holders, err = s.repo.GetHoldersByEmails(ctx, []string{value})
+	case KindPhoneNumber:
+		if err := validators.ValidatePhoneNumber(ctx, value); err != nil {
+			return err
+		}
+		holders, err = s.repo.GetHoldersByPhoneNumbers(ctx, []string{value})
+	default:
+		return kind.Validate()
+	}
+	if err != nil {
+		logger.Error("failed to get holders by contact", zap.Error(err))
+		return err
+	}
+	if len(holders) > 0 {
+		logger.Error("contact is in use")
+		return errorwrapper.New("contact is in use")
+	}
+
+	return nil
+}
+
+func (s *service) Add(ctx context.Context, logger *zap.Logger, holder *models.Holder, kind Kind, value string) (*models.HolderContact, string, error) {
+	logger = logger.With(zap.String("contact-kind", kind.String()))
+	if err := s.validateValue(ctx, logger, kind, value); err != nil {
+		return nil, "", err
+	}
+
+	pending, err := s.repo.GetHolderContactsByHolderID(ctx, holder.ID)
+	if err != nil {
+		logger.Error("failed to get holder contacts", zap.Error(err))
+		return nil, "", err
+	}
+	if hc, ok := lo.Find(pending, func(hc *models.HolderContact) bool {
+		return hc.Kind == kind.String() && hc.Value == value
+	}); ok {
+		return s.RegenerateCode(ctx, logger, holder, hc.ID)
+	}
+
+	code := generateCode()
+	hc := &models.HolderContact{
+		ID:                       s.idgenerator.Generate().Int64(),
+		CreatedAt:                time.Now(),
+		LastModifiedAt:           time.Now(),
+		HolderID:                 holder.ID,
+		Kind:                     kind.String(),
+		Value:                    value,
+		VerificationCodeHash:     hashCode(code),
+		VerificationCodeIssuedAt: time.Now(),
+	}
+	if err := s.repo.InsertHolderContact(ctx, hc); err != nil {
+		logger.Error("failed to insert holder contact", zap.Error(err))
+		return nil, "", err
+	}
+
+	return hc, code, nil
+}
+
+func (s *service) getPendingContact(ctx context.Context, logger *zap.Logger, holder *models.Holder, contactID int64) (*models.HolderContact, error) {
+	hc, err := s.repo.GetHolderContactByID(ctx, contactID)
+	if err != nil {
+		logger.Error("failed to get holder contact", zap.Error(err))
+		return nil, err
+	}
+	if hc == nil || hc.HolderID != holder.ID {
+		logger.Error("holder contact is not found")
+		return nil, errorwrapper.New("holder contact is not found")
+	}
+
+	return hc, nil
+}
+
+func (s *service) RegenerateCode(ctx context.Context, logger *zap.Logger, holder *models.Holder, contactID int64) (*models.HolderContact, string, error) {
+	logger = logger.With(zap.Int64("holder-contact-id", contactID))
+	hc, err := s.getPendingContact(ctx, logger, holder, contactID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	code := generateCode()
+	hc.LastModifiedAt = time.Now()
+	hc.VerificationCodeHash = hashCode(code)
+	hc.VerificationCodeIssuedAt = time.Now()
+	hc.VerificationFailedAttempts = 0
+	if err := s.repo.ModifyHolderContact(ctx, hc.ID, hc); err != nil {
+		logger.Error("failed to modify holder contact to make verification_code_hash={{new_code_hash}}", zap.Error(err))
+		return nil, "", err
+	}
+
+	return hc, code, nil
+}
+
+func (s *service) Verify(ctx context.Context, logger *zap.Logger, holder *models.Holder, contactID int64, code string) (*models.Holder, error) {
+	logger = logger.With(zap.Int64("holder-contact-id", contactID))
+	hc, err := s.getPendingContact(ctx, logger, holder, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if hc.VerificationCodeIssuedAt.Add(s.codeAge).Before(time.Now()) {
+		logger.Error("verification code was expired", zap.Time("issued-at", hc.VerificationCodeIssuedAt))
+		return nil, errorwrapper.New("verification code was expired, request a new one")
+	}
+	// the attempt is counted in the database before the code is compared and
+	// only while it is under the limit, so concurrent guesses can't exceed it;
+	// verified contact is deleted, so the attempt of valid code doesn't matter
+	attempts, err := s.repo.IncrementHolderContactVerificationFailedAttempts(ctx, hc.ID, hc.VerificationCodeHash, s.maxAttempts, time.Now())
+	if err != nil {
+		logger.Error("failed to increment verification_failed_attempts of holder contact", zap.Error(err))
+		return nil, err
+	}
+	if attempts == 0 {
+		logger.Error("too many failed verification attempts", zap.Int64("failed-attempts", hc.VerificationFailedAttempts))
+		return nil, errorwrapper.New("too many failed verification attempts, request a new code")
+	}
+	if subtle.ConstantTimeCompare([]byte(hc.VerificationCodeHash), []byte(hashCode(code))) != 1 {
+		logger.Error("invalid verification code", zap.Int64("failed-attempts", attempts))
+		return nil, errorwrapper.New("invalid verification code")
+	}
+
+	// contact could be taken by someone else since it was added
+	if err := s.validateValue(ctx, logger, Kind(hc.Kind), hc.Value); err != nil {
+		return nil, err
+	}
+	switch Kind(hc.Kind) {
+	case KindEmail:
+		holder.Emails = append(holder.Emails, hc.Value)
+	case KindPhoneNumber:
+		holder.PhoneNumbers = append(holder.PhoneNumbers, hc.Value)
+	}
+	holder.LastModifiedAt = time.Now()
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Error("failed to modify holder to add verified contact", zap.Error(err))
+		return nil, err
+	}
+	if err := s.repo.DeleteHolderContact(ctx, hc.ID); err != nil {
+		logger.Error("failed to delete verified holder contact", zap.Error(err))
+		return nil, err
+	}
+
+	return holder, nil
+}
+
+func (s *service) Remove(ctx context.Context, logger *zap.Logger, holder *models.Holder, kind Kind, value string) (*models.Holder, error) {
+	logger = logger.With(zap.String("contact-kind", kind.String()))
+	if err := kind.Validate(); err != nil {
+		return nil, err
+	}
+
+	pending, err := s.repo.GetHolderContactsByHolderID(ctx, holder.ID)
+	if err != nil {
+		logger.Error("failed to get holder contacts", zap.Error(err))
+		return nil, err
+	}
+	if hc, ok := lo.Find(pending, func(hc *models.HolderContact) bool {
+		return hc.Kind == kind.String() && hc.Value == value
+	}); ok {
+		if err := s.repo.DeleteHolderContact(ctx, hc.ID); err != nil {
+			logger.Error("failed to delete holder contact", zap.Error(err), zap.Int64("holder-contact-id", hc.ID))
+			return nil, err
+		}
+		return holder, nil
+	}
+
+	emails, phoneNumbers := holder.Emails, holder.PhoneNumbers
+	switch kind {
+	case KindEmail:
+		emails = lo.Without(emails, value)
+	case KindPhoneNumber:
+		phoneNumbers = lo.Without(phoneNumbers, value)
+	}
+	if len(emails) == len(holder.Emails) && len(phoneNumbers) == len(holder.PhoneNumbers) {
+		logger.Error("holder contact is not found")
+		return nil, errorwrapper.New("holder contact is not found")
+	}
+	if len(emails) == 0 && len(phoneNumbers) == 0 {
+		logger.Error("can not remove the last verified contact")
+		return nil, errorwrapper.New("can not remove the last verified contact")
+	}
+
+	holder.LastModifiedAt = time.Now()
+	holder.Emails = emails
+	holder.PhoneNumbers = phoneNumbers
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Error("failed to modify holder to remove contact", zap.Error(err))
+		return nil, err
+	}
+
+	return holder, nil
+}
+
+func (s *service) MakePrimary(ctx context.Context, logger *zap.Logger, holder *models.Holder, kind Kind, value string) (*models.Holder, error) {
+	logger = logger.With(zap.String("contact-kind", kind.String()))
+	var list *[]string
+	switch kind {
+	case KindEmail:
+		list = &holder.Emails
+	case KindPhoneNumber:
+		list = &holder.PhoneNumbers
+	default:
+		return nil, kind.Validate()
+	}
+	if !lo.Contains(*list, value) {
+		logger.Error("verified holder contact is not found")
+		return nil, errorwrapper.New("verified holder contact is not found")
+	}
+
+	*list = append([]string{value}, lo.Without(*list, value)...)
+	holder.LastModifiedAt = time.Now()
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Error("failed to modify holder to make contact primary", zap.Error(err))
+		return nil, err
+	}
+
+	return holder, nil
+}
@@ -0,0 +1,316 @@
+package holderexports
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	GetHolderSessionsByHolderID(ctx context.Context, holderID int64) ([]*models.HolderSession, error)
+	GetSentEmailsByReceivers(ctx context.Context, receivers []string) ([]*models.SentEmail, error)
+	GetSentSMSByReceivers(ctx context.Context, receivers []string) ([]*models.SentSMS, error)
+	GetNetworkNodesByHolderID(ctx context.Context, holderID int64) ([]*models.NetworkNode, error)
+	GetPersonalDataNodesByHolderID(ctx context.Context, holderID int64) ([]*models.PersonalDataNode, error)
+}
+
+type Service interface {
+	// Export writes zip archive with JSON files of all holder's data to w.
+	// Secrets (password hash, confirmation code, tokens, API key hashes) and
+	// bodies of sent messages, which carry codes and links, are left out.
+	Export(ctx context.Context, logger *zap.Logger, holder *models.Holder, w io.Writer) error
+}
+
+type service struct {
+	repo Repository
+}
+
+func New(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) Export(ctx context.Context, logger *zap.Logger, holder *models.Holder, w io.Writer) error {
+	sessions, err := s.repo.GetHolderSessionsByHolderID(ctx, holder.ID)
+	if err != nil {
+		logger.Error("failed to get holder sessions", zap.Error(err))
+		return err
+	}
+	sentEmails, err := s.repo.GetSentEmailsByReceivers(ctx, holder.Emails)
+	if err != nil {
+		logger.Error("failed to get sent emails", zap.Error(err))
+		return err
+	}
+	sentSMS, err := s.repo.GetSentSMSByReceivers(ctx, holder.PhoneNumbers)
+	if err != nil {
+		logger.Error("failed to get sent SMS", zap.Error(err))
+		return err
+	}
+	networkNodes, err := s.repo.GetNetworkNodesByHolderID(ctx, holder.ID)
+	if err != nil {
+		logger.Error("failed to get network nodes", zap.Error(err))
+		return err
+	}
+	personalDataNodes, err := s.repo.GetPersonalDataNodesByHolderID(ctx, holder.ID)
+	if err != nil {
+		logger.Error("failed to get personal data nodes", zap.Error(err))
+		return err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{name: "holder.json", data: newHolderExport(holder)},
+		{name: "sessions.json", data: mapSlice(sessions, newSessionExport)},
+		{name: "sent_emails.json", data: mapSlice(sentEmails, newSentEmailExport)},
+		{name: "sent_sms.json", data: mapSlice(sentSMS, newSentSMSExport)},
+		{name: "network_nodes.json", data: mapSlice(networkNodes, newNetworkNodeExport)},
+		{name: "personal_data_nodes.json", data: mapSlice(personalDataNodes, newPersonalDataNodeExport)},
+	}
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			logger.Error("failed to create archive entry", zap.Error(err), zap.String("file-name", f.name))
+			return errorwrapper.WrapMessage(err, "failed to create archive entry")
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			logger.Error("failed to write archive entry", zap.Error(err), zap.String("file-name", f.name))
+			return errorwrapper.WrapMessage(err, "failed to write archive entry")
+		}
+	}
+	if err := zw.Close(); err != nil {
+		logger.Error("failed to close archive", zap.Error(err))
+		return errorwrapper.WrapMessage(err, "failed to close archive")
+	}
+
+	return nil
+}
+
+func mapSlice[T, R any](in []T, fn func(T) R) []R {
+	out := make([]R, 0, len(in))
+	for _, item := range in {
+		out = append(out, fn(item))
+	}
+
+	return out
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+type holderExport struct {
+	ID                int64      `json:"id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastModifiedAt    time.Time  `json:"last_modified_at"`
+	Emails            []string   `json:"emails"`
+	PhoneNumbers      []string   `json:"phone_numbers"`
+	AvatarImageURL    *string    `json:"avatar_image_url"`
+	Countries         []string   `json:"countries"`
+	Languages         []string   `json:"languages"`
+	Confirmed         bool       `json:"confirmed"`
+	DeletionDueAt     *time.Time `json:"deletion_due_at"`
+	DisplayName       string     `json:"display_name"`
+	LegalName         *string    `json:"legal_name"`
+	PreferredLanguage *string    `json:"preferred_language"`
+	Timezone          *string    `json:"timezone"`
+}
+
+func newHolderExport(h *models.Holder) *holderExport {
+	return &holderExport{
+		ID:                h.ID,
+		CreatedAt:         h.CreatedAt,
+		LastModifiedAt:    h.LastModifiedAt,
+		Emails:            h.Emails,
+		PhoneNumbers:      h.PhoneNumbers,
+		AvatarImageURL:    nullString(h.AvatarImageURL),
+		Countries:         h.Countries,
+		Languages:         h.Languages,
+		Confirmed:         h.Confirmed,
+		DeletionDueAt:     nullTime(h.DeletionDueAt),
+		DisplayName:       h.DisplayName,
+		LegalName:         nullString(h.LegalName),
+		PreferredLanguage: nullString(h.PreferredLanguage),
+		Timezone:          nullString(h.Timezone),
+	}
+}
+
+type sessionExport struct {
+	ID               int64      `json:"id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	LastModifiedAt   time.Time  `json:"last_modified_at"`
+	ExpiredAt        *time.Time `json:"expired_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	RemoteIPAddress  *string    `json:"remote_ip_address"`
+	RemoteMACAddress *string    `json:"remote_mac_address"`
+}
+
+func newSessionExport(hs *models.HolderSession) *sessionExport {
+	return &sessionExport{
+		ID:               hs.ID,
+		CreatedAt:        hs.CreatedAt,
+		LastModifiedAt:   hs.LastModifiedAt,
+		ExpiredAt:        nullTime(hs.ExpiredAt),
+		LastUsedAt:       nullTime(hs.LastUsedAt),
+		RemoteIPAddress:  nullString(hs.RemoteIPAddress),
+		RemoteMACAddress: nullString(hs.RemoteMACAddress),
+	}
+}
+
+type sentEmailExport struct {
+	ID           int64                  `json:"id"`
+	CreatedAt    time.Time              `json:"created_at"`
+	TemplateName string                 `json:"template_name"`
+	Receiver     string                 `json:"receiver"`
+	Status       models.SentEmailStatus `json:"status"`
+	SentAt       *time.Time             `json:"sent_at"`
+}
+
+func newSentEmailExport(se *models.SentEmail) *sentEmailExport {
+	return &sentEmailExport{
+		ID:           se.ID,
+		CreatedAt:    se.CreatedAt,
+		TemplateName: se.TemplateName,
+		Receiver:     se.ReceiverEmail,
+		Status:       se.Status,
+		SentAt:       nullTime(se.SentAt),
+	}
+}
+
+type sentSMSExport struct {
+	ID           int64     `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	TemplateName string    `json:"template_name"`
+	Receiver     string    `json:"receiver"`
+}
+
+func newSentSMSExport(ss *models.SentSMS) *sentSMSExport {
+	return &sentSMSExport{
+		ID:           ss.ID,
+		CreatedAt:    ss.CreatedAt,
+		TemplateName: ss.TemplateName,
+		Receiver:     ss.ReceiverPhoneNumber,
+	}
+}
+
+type networkNodeExport struct {
+	ID                        int64                    `json:"id"`
+	CreatedAt                 time.Time                `json:"created_at"`
+	LastModifiedAt            time.Time                `json:"last_modified_at"`
+	NetworkWardenID           int64                    `json:"network_warden_id"`
+	Name                      string                   `json:"name"`
+	Description               string                   `json:"description"`
+	DomainName                string                   `json:"domain_name"`
+	Location                  *models.Location         `json:"location"`
+	AccountsCapacity          int64                    `json:"accounts_capacity"`
+	Alive                     bool                     `json:"alive"`
+	LastPingedAt              *time.Time               `json:"last_pinged_at"`
+	IsOpen                    bool                     `json:"is_open"`
+	IsInviteCodeRequired      bool                     `json:"is_invite_code_required"`
+	URL                       string                   `json:"url"`
+	Version                   string                   `json:"version"`
+	RateLimitMaxRequests      int64                    `json:"rate_limit_max_requests"`
+	RateLimitInterval         string                   `json:"rate_limit_interval"`
+	CrawlRateLimitMaxRequests int64                    `json:"crawl_rate_limit_max_requests"`
+	CrawlRateLimitInterval    string                   `json:"crawl_rate_limit_interval"`
+	Status                    models.NetworkNodeStatus `json:"status"`
+}
+
+func newNetworkNodeExport(nn *models.NetworkNode) *networkNodeExport {
+	return &networkNodeExport{
+		ID:                        nn.ID,
+		CreatedAt:                 nn.CreatedAt,
+		LastModifiedAt:            nn.LastModifiedAt,
+		NetworkWardenID:           nn.NetworkWardenID,
+		Name:                      nn.Name,
+		Description:               nn.Description,
+		DomainName:                nn.DomainName,
+		Location:                  nn.Location,
+		AccountsCapacity:          nn.AccountsCapacity,
+		Alive:                     nn.Alive,
+		LastPingedAt:              nullTime(nn.LastPingedAt),
+		IsOpen:                    nn.IsOpen,
+		IsInviteCodeRequired:      nn.IsInviteCodeRequired,
+		URL:                       nn.URL,
+		Version:                   nn.Version,
+		RateLimitMaxRequests:      nn.RateLimitMaxRequests,
+		RateLimitInterval:         nn.RateLimitInterval.String(),
+		CrawlRateLimitMaxRequests: nn.CrawlRateLimitMaxRequests,
+		CrawlRateLimitInterval:    nn.CrawlRateLimitInterval.String(),
+		Status:                    nn.Status,
+	}
+}
+
+type personalDataNodeExport struct {
+	ID                        int64                         `json:"id"`
+	CreatedAt                 time.Time                     `json:"created_at"`
+	LastModifiedAt            time.Time                     `json:"last_modified_at"`
+	NetworkWardenID           int64                         `json:"network_warden_id"`
+	Label                     string                        `json:"label"`
+	Address                   string                        `json:"address"`
+	Name                      string                        `json:"name"`
+	Description               string                        `json:"description"`
+	Location                  *models.Location              `json:"location"`
+	AccountsCapacity          int64                         `json:"accounts_capacity"`
+	Alive                     bool                          `json:"alive"`
+	LastPingedAt              *time.Time                    `json:"last_pinged_at"`
+	IsOpen                    bool                          `json:"is_open"`
+	IsInviteCodeRequired      bool                          `json:"is_invite_code_required"`
+	URL                       string                        `json:"url"`
+	Version                   string                        `json:"version"`
+	RateLimitMaxRequests      int64                         `json:"rate_limit_max_requests"`
+	RateLimitInterval         string                        `json:"rate_limit_interval"`
+	CrawlRateLimitMaxRequests int64                         `json:"crawl_rate_limit_max_requests"`
+	CrawlRateLimitInterval    string                        `json:"crawl_rate_limit_interval"`
+	Status                    models.PersonalDataNodeStatus `json:"status"`
+}
+
+func newPersonalDataNodeExport(pdn *models.PersonalDataNode) *personalDataNodeExport {
+	return &personalDataNodeExport{
+		ID:                        pdn.ID,
+		CreatedAt:                 pdn.CreatedAt,
+		LastModifiedAt:            pdn.LastModifiedAt,
+		NetworkWardenID:           pdn.NetworkWardenID,
+		Label:                     pdn.Label,
+		Address:                   pdn.Address,
+		Name:                      pdn.Name,
+		Description:               pdn.Description,
+		Location:                  pdn.Location,
+		AccountsCapacity:          pdn.AccountsCapacity,
+		Alive:                     pdn.Alive,
+		LastPingedAt:              nullTime(pdn.LastPingedAt),
+		IsOpen:                    pdn.IsOpen,
+		IsInviteCodeRequired:      pdn.IsInviteCodeRequired,
+		URL:                       pdn.URL,
+		Version:                   pdn.Version,
+		RateLimitMaxRequests:      pdn.RateLimitMaxRequests,
+		RateLimitInterval:         pdn.RateLimitInterval.String(),
+		CrawlRateLimitMaxRequests: pdn.CrawlRateLimitMaxRequests,
+		CrawlRateLimitInterval:    pdn.CrawlRateLimitInterval.String(),
+		Status:                    pdn.Status,
+	}
+}
@@ -2,6 +2,7 @@ package holders
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -10,11 +11,21 @@ import (
 	errorwrapper "github.com/ecumenos-social/error-wrapper"
 	"github.com/ecumenos-social/network-warden/models"
 	"github.com/ecumenos-social/network-warden/services/idgenerators"
-	"github.com/ecumenos-social/toolkit/hash"
+	"github.com/ecumenos-social/network-warden/services/passwords"
 	"github.com/ecumenos-social/toolkit/random"
+	"github.com/ecumenos-social/toolkit/slices"
+	"github.com/ecumenos-social/toolkit/types"
 	"go.uber.org/zap"
 )
 
+type Config struct {
+	ConfirmationCodeAge     time.Duration
+	MaxConfirmationAttempts int64
+	// DeletionGracePeriod is period after deletion request when holder can
+	// still cancel it. The holder is purged once it is over.
+	DeletionGracePeriod time.Duration
+}
+
 type Repository interface {
 	GetHoldersByEmails(ctx context.Context, emails []string) ([]*models.Holder, error)
 	GetHoldersByPhoneNumbers(ctx context.Context, phoneNumbers []string) ([]*models.Holder, error)
@@ -23,7 +34,11 @@ type Repository interface {
 	GetHolderByPhoneNumber(ctx context.Context, phoneNumber string) (*models.Holder, error)
 	GetHolderByID(ctx context.Context, id int64) (*models.Holder, error)
 	ModifyHolder(ctx context.Context, id int64, holder *models.Holder) error
+	IncrementHolderConfirmationFailedAttempts(ctx context.Context, id int64, confirmationCode string, maxAttempts int64, modifiedAt time.Time) (int64, error)
 	DeleteHolder(ctx context.Context, id int64) error
+	ClaimHoldersDueForDeletion(ctx context.Context, now, leaseUntil time.Time, limit int64) ([]*models.Holder, error)
+	GetHolders(ctx context.Context, filter *models.HoldersFilter, pagination *types.Pagination) ([]*models.Holder, error)
+	RevokeOIDCAccessTokensByHolderID(ctx context.Context, holderID int64, revokedAt time.Time) error
 }
 
 type Service interface {
@@ -35,20 +50,38 @@ type Service interface {
 	GetHolderByID(ctx context.Context, logger *zap.Logger, id int64) (*models.Holder, error)
 	Confirm(ctx context.Context, logger *zap.Logger, id int64, confirmationCode string) (*models.Holder, error)
 	RegenerateConfirmationCode(ctx context.Context, logger *zap.Logger, id int64) (*models.Holder, error)
+	ValidatePasswordPolicy(ctx context.Context, logger *zap.Logger, holder *models.Holder, password string) error
 	ChangePassword(ctx context.Context, logger *zap.Logger, holder *models.Holder, password string) error
 	Modify(ctx context.Context, logger *zap.Logger, holder *models.Holder, params *ModifyParams) (*models.Holder, error)
+	ScheduleDeletion(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error)
+	CancelDeletion(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error)
+	ClaimHoldersDueForDeletion(ctx context.Context, logger *zap.Logger, limit int64) ([]*models.Holder, error)
 	Delete(ctx context.Context, logger *zap.Logger, id int64) error
+	GetList(ctx context.Context, logger *zap.Logger, filter *models.HoldersFilter, pagination *types.Pagination) ([]*models.Holder, error)
+	ForceConfirm(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error)
+	Suspend(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error)
+	Unsuspend(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error)
 }
 
 type service struct {
-	repo        Repository
-	idgenerator idgenerators.HoldersIDGenerator
+	confirmationCodeAge     time.Duration
+	maxConfirmationAttempts int64
+	deletionGracePeriod     time.Duration
+	passwordPolicy          passwords.Policy
+	passwordHasher          passwords.Hasher
+	repo                    Repository
+	idgenerator             idgenerators.HoldersIDGenerator
 }
 
-func New(repo Repository, g idgenerators.HoldersIDGenerator) Service {
+func New(config *Config, passwordPolicy passwords.Policy, passwordHasher passwords.Hasher, repo Repository, g idgenerators.HoldersIDGenerator) Service {
 	return &service{
-		repo:        repo,
-		idgenerator: g,
+		confirmationCodeAge:     config.ConfirmationCodeAge,
+		maxConfirmationAttempts: config.MaxConfirmationAttempts,
+		deletionGracePeriod:     config.DeletionGracePeriod,
+		passwordPolicy:          passwordPolicy,
+		passwordHasher:          passwordHasher,
+		repo:                    repo,
+		idgenerator:             g,
 	}
 }
 
@@ -87,14 +120,20 @@ type InsertParams struct {
 	Countries      []string
 	Languages      []string
 	Password       string
-}
-
-func HashPassword(password string) (string, error) {
-	return hash.Hash(password)
+	// DisplayName is name which holder is addressed by, primary contact is
+	// used if it is empty.
+	DisplayName       string
+	LegalName         *string
+	PreferredLanguage *string
+	Timezone          *string
 }
 
 func (s *service) Insert(ctx context.Context, logger *zap.Logger, params *InsertParams) (*models.Holder, error) {
-	passwordHash, err := HashPassword(params.Password)
+	if err := s.passwordPolicy.Validate(params.Password, slices.Merge(params.Emails, params.PhoneNumbers)); err != nil {
+		logger.Error("password violates policy", zap.Error(err))
+		return nil, err
+	}
+	passwordHash, err := s.passwordHasher.Hash(params.Password)
 	if err != nil {
 		logger.Error("failed to hash password", zap.Error(err))
 		return nil, err
@@ -102,16 +141,21 @@ func (s *service) Insert(ctx context.Context, logger *zap.Logger, params *Insert
 
 	id := s.idgenerator.Generate().Int64()
 	h := &models.Holder{
-		ID:               id,
-		CreatedAt:        time.Now(),
-		LastModifiedAt:   time.Now(),
-		Emails:           params.Emails,
-		PhoneNumbers:     params.PhoneNumbers,
-		Countries:        params.Countries,
-		Languages:        params.Languages,
-		PasswordHash:     passwordHash,
-		Confirmed:        false,
-		ConfirmationCode: generateConfirmationCode(),
+		ID:                       id,
+		CreatedAt:                time.Now(),
+		LastModifiedAt:           time.Now(),
+		Emails:                   params.Emails,
+		PhoneNumbers:             params.PhoneNumbers,
+		Countries:                params.Countries,
+		Languages:                params.Languages,
+		PasswordHash:             passwordHash,
+		Confirmed:                false,
+		ConfirmationCode:         generateConfirmationCode(),
+		ConfirmationCodeIssuedAt: time.Now(),
+		DisplayName:              params.DisplayName,
+		LegalName:                optionalString(params.LegalName),
+		PreferredLanguage:        optionalString(params.PreferredLanguage),
+		Timezone:                 optionalString(params.Timezone),
 	}
 	if params.AvatarImageURL != nil {
 		h.AvatarImageURL = sql.NullString{
@@ -164,13 +208,31 @@ func (s *service) GetHolderByEmailOrPhoneNumber(ctx context.Context, logger *zap
 	return h, nil
 }
 
-func (s *service) ValidatePassword(_ context.Context, logger *zap.Logger, holder *models.Holder, password string) error {
-	if hash.CompareHash(password, holder.PasswordHash) {
+func (s *service) ValidatePassword(ctx context.Context, logger *zap.Logger, holder *models.Holder, password string) error {
+	ok, needsRehash := s.passwordHasher.Verify(password, holder.PasswordHash)
+	if !ok {
+		logger.Error("invalid password")
+		return errorwrapper.New("invalid password")
+	}
+	if !needsRehash {
+		return nil
+	}
+
+	// password is known only here, so outdated hash is upgraded on successful
+	// validation; failure to upgrade doesn't fail validation
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		logger.Warn("failed to rehash password", zap.Error(err))
+		return nil
+	}
+	holder.PasswordHash = passwordHash
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Warn("failed to modify holder to rehash password", zap.Error(err))
 		return nil
 	}
-	logger.Error("invalid password")
+	logger.Info("password hash was upgraded")
 
-	return errorwrapper.New("invalid password")
+	return nil
 }
 
 func (s *service) GetHolderByID(ctx context.Context, logger *zap.Logger, id int64) (*models.Holder, error) {
@@ -193,10 +255,31 @@ func (s *service) Confirm(ctx context.Context, logger *zap.Logger, id int64, con
 		logger.Error("holder is not found")
 		return nil, errorwrapper.New("holder is not found")
 	}
-	if holder.ConfirmationCode != confirmationCode {
-		logger.Error("invalid confirmation code")
+	if holder.ConfirmationCode == "" {
+		logger.Error("confirmation code was invalidated")
+		return nil, errorwrapper.New("confirmation code was invalidated, request a new one")
+	}
+	if holder.ConfirmationCodeIssuedAt.Add(s.confirmationCodeAge).Before(time.Now()) {
+		logger.Error("confirmation code was expired", zap.Time("issued-at", holder.ConfirmationCodeIssuedAt))
+		return nil, errorwrapper.New("confirmation code was expired, request a new one")
+	}
+	if subtle.ConstantTimeCompare([]byte(holder.ConfirmationCode), []byte(confirmationCode)) != 1 {
+		// incremented in database, so concurrent guesses can't exceed the
+		// limit; the code is invalidated there once the limit is reached and
+		// ResendConfirmationCode issues a new one
+		failedAttempts, err := s.repo.IncrementHolderConfirmationFailedAttempts(ctx, id, holder.ConfirmationCode, s.maxConfirmationAttempts, time.Now())
+		if err != nil {
+			logger.Error("failed to increment confirmation_failed_attempts", zap.Error(err))
+			return nil, err
+		}
+		if failedAttempts >= s.maxConfirmationAttempts {
+			logger.Error("too many failed confirmation attempts, confirmation code was invalidated", zap.Int64("failed-attempts", failedAttempts))
+			return nil, errorwrapper.New("invalid confirmation code, too many failed attempts, request a new one")
+		}
+		logger.Error("invalid confirmation code", zap.Int64("failed-attempts", failedAttempts))
 		return nil, errorwrapper.New("invalid confirmation code")
 	}
+	holder.LastModifiedAt = time.Now()
 	holder.Confirmed = true
 	if err := s.repo.ModifyHolder(ctx, id, holder); err != nil {
 		logger.Error("failed to modify holder to make confirm=true", zap.Error(err))
@@ -213,7 +296,10 @@ func (s *service) RegenerateConfirmationCode(ctx context.Context, logger *zap.Lo
 		return nil, err
 	}
 
+	holder.LastModifiedAt = time.Now()
 	holder.ConfirmationCode = generateConfirmationCode()
+	holder.ConfirmationCodeIssuedAt = time.Now()
+	holder.ConfirmationFailedAttempts = 0
 	if err := s.repo.ModifyHolder(ctx, id, holder); err != nil {
 		logger.Error("failed to modify holder to make confirmation_code={{new_confirmation_code}}", zap.Error(err))
 		return nil, err
@@ -222,8 +308,22 @@ func (s *service) RegenerateConfirmationCode(ctx context.Context, logger *zap.Lo
 	return holder, nil
 }
 
+// ValidatePasswordPolicy checks password against the policy without changing
+// it, so single-use codes are not spent on passwords which would be rejected.
+func (s *service) ValidatePasswordPolicy(ctx context.Context, logger *zap.Logger, holder *models.Holder, password string) error {
+	if err := s.passwordPolicy.Validate(password, slices.Merge(holder.Emails, holder.PhoneNumbers)); err != nil {
+		logger.Error("password violates policy", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 func (s *service) ChangePassword(ctx context.Context, logger *zap.Logger, holder *models.Holder, password string) error {
-	passwordHash, err := hash.Hash(password)
+	if err := s.ValidatePasswordPolicy(ctx, logger, holder, password); err != nil {
+		return err
+	}
+	passwordHash, err := s.passwordHasher.Hash(password)
 	if err != nil {
 		logger.Error("failed to hash password", zap.Error(err))
 		return err
@@ -242,6 +342,12 @@ type ModifyParams struct {
 	AvatarImageURL *string
 	Countries      []string
 	Languages      []string
+	DisplayName    *string
+	// LegalName, PreferredLanguage and Timezone are cleared if they are set
+	// to empty string.
+	LegalName         *string
+	PreferredLanguage *string
+	Timezone          *string
 }
 
 func (s *service) Modify(ctx context.Context, logger *zap.Logger, holder *models.Holder, params *ModifyParams) (*models.Holder, error) {
@@ -257,6 +363,18 @@ func (s *service) Modify(ctx context.Context, logger *zap.Logger, holder *models
 	if params.Languages != nil {
 		holder.Languages = params.Languages
 	}
+	if params.DisplayName != nil {
+		holder.DisplayName = *params.DisplayName
+	}
+	if params.LegalName != nil {
+		holder.LegalName = optionalString(params.LegalName)
+	}
+	if params.PreferredLanguage != nil {
+		holder.PreferredLanguage = optionalString(params.PreferredLanguage)
+	}
+	if params.Timezone != nil {
+		holder.Timezone = optionalString(params.Timezone)
+	}
 	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
 		logger.Error("failed to modify holder", zap.Error(err))
 		return nil, err
@@ -265,6 +383,130 @@ func (s *service) Modify(ctx context.Context, logger *zap.Logger, holder *models
 	return holder, nil
 }
 
+func (s *service) ScheduleDeletion(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error) {
+	if holder.DeletionDueAt.Valid {
+		logger.Error("holder deletion is already scheduled", zap.Time("deletion-due-at", holder.DeletionDueAt.Time))
+		return nil, errorwrapper.New("holder deletion is already scheduled")
+	}
+
+	holder.LastModifiedAt = time.Now()
+	holder.DeletionDueAt = sql.NullTime{
+		Time:  time.Now().Add(s.deletionGracePeriod),
+		Valid: true,
+	}
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Error("failed to modify holder to schedule deletion", zap.Error(err))
+		return nil, err
+	}
+
+	return holder, nil
+}
+
+func (s *service) CancelDeletion(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error) {
+	if !holder.DeletionDueAt.Valid {
+		logger.Error("holder deletion is not scheduled")
+		return nil, errorwrapper.New("holder deletion is not scheduled")
+	}
+
+	holder.LastModifiedAt = time.Now()
+	holder.DeletionDueAt = sql.NullTime{}
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Error("failed to modify holder to cancel deletion", zap.Error(err))
+		return nil, err
+	}
+
+	return holder, nil
+}
+
+func (s *service) GetList(ctx context.Context, logger *zap.Logger, filter *models.HoldersFilter, pagination *types.Pagination) ([]*models.Holder, error) {
+	hs, err := s.repo.GetHolders(ctx, filter, pagination)
+	if err != nil {
+		logger.Error("failed to get holders", zap.Error(err))
+		return nil, err
+	}
+
+	return hs, nil
+}
+
+// ForceConfirm confirms holder without confirmation code, the code is
+// invalidated.
+func (s *service) ForceConfirm(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error) {
+	if holder.Confirmed {
+		logger.Error("holder is already confirmed")
+		return nil, errorwrapper.New("holder is already confirmed")
+	}
+
+	holder.LastModifiedAt = time.Now()
+	holder.Confirmed = true
+	holder.ConfirmationCode = ""
+	holder.ConfirmationFailedAttempts = 0
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Error("failed to modify holder to force confirmation", zap.Error(err))
+		return nil, err
+	}
+
+	return holder, nil
+}
+
+// Suspend suspends holder and revokes access tokens which were granted to
+// OIDC clients, so the clients lose access to holder's claims right away.
+func (s *service) Suspend(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error) {
+	if holder.SuspendedAt.Valid {
+		logger.Error("holder is already suspended", zap.Time("suspended-at", holder.SuspendedAt.Time))
+		return nil, errorwrapper.New("holder is already suspended")
+	}
+
+	holder.LastModifiedAt = time.Now()
+	holder.SuspendedAt = sql.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Error("failed to modify holder to suspend", zap.Error(err))
+		return nil, err
+	}
+	if err := s.repo.RevokeOIDCAccessTokensByHolderID(ctx, holder.ID, holder.SuspendedAt.Time); err != nil {
+		logger.Error("failed to revoke OIDC access tokens of suspended holder", zap.Error(err))
+		return nil, err
+	}
+
+	return holder, nil
+}
+
+func (s *service) Unsuspend(ctx context.Context, logger *zap.Logger, holder *models.Holder) (*models.Holder, error) {
+	if !holder.SuspendedAt.Valid {
+		logger.Error("holder is not suspended")
+		return nil, errorwrapper.New("holder is not suspended")
+	}
+
+	holder.LastModifiedAt = time.Now()
+	holder.SuspendedAt = sql.NullTime{}
+	if err := s.repo.ModifyHolder(ctx, holder.ID, holder); err != nil {
+		logger.Error("failed to modify holder to unsuspend", zap.Error(err))
+		return nil, err
+	}
+
+	return holder, nil
+}
+
+// deletionLease is time which claimed holder is hidden from other purgers
+// for, holder which failed to be deleted is claimed again after it.
+const deletionLease = 10 * time.Minute
+
+// ClaimHoldersDueForDeletion returns at most limit holders whose deletion
+// grace period is over, they are not returned again until deletionLease
+// passes.
+func (s *service) ClaimHoldersDueForDeletion(ctx context.Context, logger *zap.Logger, limit int64) ([]*models.Holder, error) {
+	now := time.Now()
+	hs, err := s.repo.ClaimHoldersDueForDeletion(ctx, now, now.Add(deletionLease), limit)
+	if err != nil {
+		logger.Error("failed to claim holders due for deletion", zap.Error(err))
+		return nil, err
+	}
+
+	return hs, nil
+}
+
 func (s *service) Delete(ctx context.Context, logger *zap.Logger, id int64) error {
 	if err := s.repo.DeleteHolder(ctx, id); err != nil {
 		logger.Error("failed to delete holder", zap.Error(err))
@@ -273,3 +515,65 @@ func (s *service) Delete(ctx context.Context, logger *zap.Logger, id int64) erro
 
 	return nil
 }
+
+func optionalString(value *string) sql.NullString {
+	if value == nil || *value == "" {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *value, Valid: true}
+}
+
+// DisplayName returns name which holder is addressed by in messages. Holders
+// without display name are addressed by their primary contact.
+func DisplayName(holder *models.Holder) string {
+	if holder.DisplayName != "" {
+		return holder.DisplayName
+	}
+	if len(holder.Emails) > 0 {
+		return holder.Emails[0]
+	}
+	if len(holder.PhoneNumbers) > 0 {
+		return holder.PhoneNumbers[0]
+	}
+
+	return ""
+}
+
+// Language returns language which messages to holder are written in, it is
+// preferred language or the first of holder's languages.
+func Language(holder *models.Holder) string {
+	if holder.PreferredLanguage.Valid {
+		return holder.PreferredLanguage.String
+	}
+	if len(holder.Languages) > 0 {
+		return holder.Languages[0]
+	}
+
+	return ""
+}
+
+// Languages returns languages which messages to holder can be written in,
+// in order of preference: preferred language and then holder's languages.
+func Languages(holder *models.Holder) []string {
+	languages := make([]string, 0, len(holder.Languages)+1)
+	if holder.PreferredLanguage.Valid {
+		languages = append(languages, holder.PreferredLanguage.String)
+	}
+
+	return append(languages, holder.Languages...)
+}
+
+// Location returns time zone which times in messages to holder are shown
+// in, it is UTC if holder hasn't set time zone.
+func Location(holder *models.Holder) *time.Location {
+	if !holder.Timezone.Valid {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(holder.Timezone.String)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
@@ -27,6 +27,50 @@ func NewHolderSessionsIDGenerator(config *HolderSessionsIDGeneratorConfig) (Hold
 	})
 }
 
+type HolderPasswordResetsIDGeneratorConfig fxidgenerator.Config
+
+type HolderPasswordResetsIDGenerator idgenerator.Generator
+
+func NewHolderPasswordResetsIDGenerator(config *HolderPasswordResetsIDGeneratorConfig) (HolderPasswordResetsIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type HolderTOTPSecretsIDGeneratorConfig fxidgenerator.Config
+
+type HolderTOTPSecretsIDGenerator idgenerator.Generator
+
+func NewHolderTOTPSecretsIDGenerator(config *HolderTOTPSecretsIDGeneratorConfig) (HolderTOTPSecretsIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type HolderRecoveryCodesIDGeneratorConfig fxidgenerator.Config
+
+type HolderRecoveryCodesIDGenerator idgenerator.Generator
+
+func NewHolderRecoveryCodesIDGenerator(config *HolderRecoveryCodesIDGeneratorConfig) (HolderRecoveryCodesIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type HolderLoginChallengesIDGeneratorConfig fxidgenerator.Config
+
+type HolderLoginChallengesIDGenerator idgenerator.Generator
+
+func NewHolderLoginChallengesIDGenerator(config *HolderLoginChallengesIDGeneratorConfig) (HolderLoginChallengesIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
 type SentEmailsIDGeneratorConfig fxidgenerator.Config
 
 type SentEmailsIDGenerator idgenerator.Generator
@@ -92,3 +136,102 @@ func NewAdminSessionsIDGenerator(config *AdminSessionsIDGeneratorConfig) (AdminS
 		Low: config.LowNodeID,
 	})
 }
+
+type LoginThrottlesIDGeneratorConfig fxidgenerator.Config
+
+type LoginThrottlesIDGenerator idgenerator.Generator
+
+func NewLoginThrottlesIDGenerator(config *LoginThrottlesIDGeneratorConfig) (LoginThrottlesIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type HolderContactsIDGeneratorConfig fxidgenerator.Config
+
+type HolderContactsIDGenerator idgenerator.Generator
+
+func NewHolderContactsIDGenerator(config *HolderContactsIDGeneratorConfig) (HolderContactsIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type OIDCAuthorizationCodesIDGeneratorConfig fxidgenerator.Config
+
+type OIDCAuthorizationCodesIDGenerator idgenerator.Generator
+
+func NewOIDCAuthorizationCodesIDGenerator(config *OIDCAuthorizationCodesIDGeneratorConfig) (OIDCAuthorizationCodesIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type OIDCAccessTokensIDGeneratorConfig fxidgenerator.Config
+
+type OIDCAccessTokensIDGenerator idgenerator.Generator
+
+func NewOIDCAccessTokensIDGenerator(config *OIDCAccessTokensIDGeneratorConfig) (OIDCAccessTokensIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type HolderPasskeysIDGeneratorConfig fxidgenerator.Config
+
+type HolderPasskeysIDGenerator idgenerator.Generator
+
+func NewHolderPasskeysIDGenerator(config *HolderPasskeysIDGeneratorConfig) (HolderPasskeysIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type WebAuthnChallengesIDGeneratorConfig fxidgenerator.Config
+
+type WebAuthnChallengesIDGenerator idgenerator.Generator
+
+func NewWebAuthnChallengesIDGenerator(config *WebAuthnChallengesIDGeneratorConfig) (WebAuthnChallengesIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type HolderLoginLinksIDGeneratorConfig fxidgenerator.Config
+
+type HolderLoginLinksIDGenerator idgenerator.Generator
+
+func NewHolderLoginLinksIDGenerator(config *HolderLoginLinksIDGeneratorConfig) (HolderLoginLinksIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type SentSMSIDGeneratorConfig fxidgenerator.Config
+
+type SentSMSIDGenerator idgenerator.Generator
+
+func NewSentSMSIDGenerator(config *SentSMSIDGeneratorConfig) (SentSMSIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
+
+type EmailSuppressionsIDGeneratorConfig fxidgenerator.Config
+
+type EmailSuppressionsIDGenerator idgenerator.Generator
+
+func NewEmailSuppressionsIDGenerator(config *EmailSuppressionsIDGeneratorConfig) (EmailSuppressionsIDGenerator, error) {
+	return idgenerator.New(&idgenerator.NodeID{
+		Top: config.TopNodeID,
+		Low: config.LowNodeID,
+	})
+}
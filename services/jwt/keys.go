@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"sort"
+	"strings"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// Key is a single entry of the JWT key set. Every configured key is accepted
+// for verification, the signing key is the latest one which is already
+// activated.
+type Key struct {
+	ID          string
+	Secret      []byte
+	ActivatedAt time.Time
+}
+
+// ParseKey parses key in format "<kid>;<activated-at in RFC 3339>;<secret>".
+func ParseKey(value string) (*Key, error) {
+	parts := strings.SplitN(value, ";", 3)
+	if len(parts) != 3 {
+		return nil, errorwrapper.New("JWT key must be formatted as <kid>;<activated-at>;<secret>")
+	}
+	if parts[0] == "" {
+		return nil, errorwrapper.New("JWT key ID is empty")
+	}
+	activatedAt, err := time.Parse(time.RFC3339, parts[1])
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "invalid JWT key activation date")
+	}
+	if parts[2] == "" {
+		return nil, errorwrapper.New("JWT key secret is empty")
+	}
+
+	return &Key{
+		ID:          parts[0],
+		Secret:      []byte(parts[2]),
+		ActivatedAt: activatedAt,
+	}, nil
+}
+
+type keySet struct {
+	algorithm jwa.SignatureAlgorithm
+	keys      []*Key
+	// verification contains keys which verify tokens, for asymmetric
+	// algorithms these are public keys only.
+	verification jwk.Set
+	// public is published as JWKS, it is empty for HS256.
+	public jwk.Set
+	byID   map[string]jwk.Key
+}
+
+func newKeySet(keys []*Key, algorithm jwa.SignatureAlgorithm) (*keySet, error) {
+	sorted := make([]*Key, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivatedAt.Before(sorted[j].ActivatedAt)
+	})
+
+	ks := &keySet{
+		algorithm:    algorithm,
+		keys:         sorted,
+		verification: jwk.NewSet(),
+		public:       jwk.NewSet(),
+		byID:         make(map[string]jwk.Key, len(sorted)),
+	}
+	for _, k := range sorted {
+		if _, ok := ks.byID[k.ID]; ok {
+			return nil, errorwrapper.New("duplicated JWT key ID " + k.ID)
+		}
+		raw, err := rawSigningKey(k.Secret, algorithm)
+		if err != nil {
+			return nil, errorwrapper.WrapMessage(err, "invalid JWT key "+k.ID)
+		}
+		key, err := jwk.FromRaw(raw)
+		if err != nil {
+			return nil, errorwrapper.WrapMessage(err, "invalid JWT key "+k.ID)
+		}
+		if err := setKeyHeaders(key, k.ID, algorithm); err != nil {
+			return nil, err
+		}
+		ks.byID[k.ID] = key
+
+		if algorithm == jwa.HS256 {
+			if err := ks.verification.AddKey(key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		pub, err := jwk.PublicKeyOf(key)
+		if err != nil {
+			return nil, errorwrapper.WrapMessage(err, "invalid JWT key "+k.ID)
+		}
+		if err := setKeyHeaders(pub, k.ID, algorithm); err != nil {
+			return nil, err
+		}
+		if err := ks.verification.AddKey(pub); err != nil {
+			return nil, err
+		}
+		if err := ks.public.AddKey(pub); err != nil {
+			return nil, err
+		}
+	}
+	if len(sorted) == 0 {
+		return nil, errorwrapper.New("no JWT keys configured")
+	}
+
+	return ks, nil
+}
+
+func setKeyHeaders(key jwk.Key, id string, algorithm jwa.SignatureAlgorithm) error {
+	if err := key.Set(jwk.KeyIDKey, id); err != nil {
+		return err
+	}
+	if err := key.Set(jwk.AlgorithmKey, algorithm); err != nil {
+		return err
+	}
+
+	return key.Set(jwk.KeyUsageKey, jwk.ForSignature)
+}
+
+// rawSigningKey converts configured key to the value expected by algorithm.
+// HS256 uses secret as is, EdDSA and ES256 expect base64 encoded PKCS #8
+// private key (Ed25519 and P-256 accordingly).
+func rawSigningKey(secret []byte, algorithm jwa.SignatureAlgorithm) (interface{}, error) {
+	if algorithm == jwa.HS256 {
+		return secret, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(string(secret))
+	if err != nil {
+		return nil, err
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	switch algorithm {
+	case jwa.EdDSA:
+		if key, ok := priv.(ed25519.PrivateKey); ok {
+			return key, nil
+		}
+		return nil, errorwrapper.New("EdDSA requires Ed25519 private key")
+	case jwa.ES256:
+		if key, ok := priv.(*ecdsa.PrivateKey); ok && key.Curve == elliptic.P256() {
+			return key, nil
+		}
+		return nil, errorwrapper.New("ES256 requires P-256 private key")
+	}
+
+	return nil, errorwrapper.New("unsupported JWT algorithm " + algorithm.String())
+}
+
+// signingKey returns the latest activated key. Keys which are activated in
+// the future are already accepted for verification, so other replicas can
+// start signing with them as soon as their activation date comes.
+func (ks *keySet) signingKey(now time.Time) (jwk.Key, error) {
+	for i := len(ks.keys) - 1; i >= 0; i-- {
+		if !ks.keys[i].ActivatedAt.After(now) {
+			return ks.byID[ks.keys[i].ID], nil
+		}
+	}
+
+	return nil, errorwrapper.New("no activated JWT signing key")
+}
@@ -7,13 +7,23 @@ import (
 	"time"
 
 	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/toolkitfx"
 	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 )
 
 type Config struct {
-	SigningKey      string
+	// SigningKey is a key which is used when Keys are not configured.
+	SigningKey string
+	// SigningKeyID is kid of SigningKey.
+	SigningKeyID string
+	Keys         []*Key
+	// Algorithm is one of HS256, EdDSA or ES256.
+	Algorithm       string
 	TokenAge        time.Duration
 	RefreshTokenAge time.Duration
 }
@@ -21,24 +31,59 @@ type Config struct {
 type Service interface {
 	CreateTokens(ctx context.Context, logger *zap.Logger, subj string) (string, string, error)
 	DecodeToken(logger *zap.Logger, token string) (jwt.Token, error)
+	// CreateIDToken signs OpenID Connect ID token with the same key set as
+	// holder tokens, so nodes verify it with the published JWKS.
+	CreateIDToken(ctx context.Context, logger *zap.Logger, params *IDTokenParams) (string, error)
+	PublicKeySet() jwk.Set
+	Algorithm() string
 }
 
 type service struct {
-	jwtSigningKey   []byte
+	keys            *keySet
+	issuer          string
+	audience        string
 	tokenAge        time.Duration
 	refreshTokenAge time.Duration
 }
 
-func New(config *Config) Service {
+func New(config *Config, appConfig *toolkitfx.GenericAppConfig) (Service, error) {
+	var algorithm jwa.SignatureAlgorithm
+	switch config.Algorithm {
+	case "", jwa.HS256.String():
+		algorithm = jwa.HS256
+	case jwa.EdDSA.String():
+		algorithm = jwa.EdDSA
+	case jwa.ES256.String():
+		algorithm = jwa.ES256
+	default:
+		return nil, errorwrapper.New("unsupported JWT algorithm " + config.Algorithm)
+	}
+
+	keys := config.Keys
+	if len(keys) == 0 {
+		keys = []*Key{{
+			ID:     config.SigningKeyID,
+			Secret: []byte(config.SigningKey),
+		}}
+	}
+	ks, err := newKeySet(keys, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
 	return &service{
-		jwtSigningKey:   []byte(config.SigningKey),
+		keys:            ks,
+		issuer:          appConfig.Name,
+		audience:        appConfig.Name,
 		tokenAge:        config.TokenAge,
 		refreshTokenAge: config.RefreshTokenAge,
-	}
+	}, nil
 }
 
-func makeToken(subject string, scope TokenScope, exp time.Time) jwt.Token {
+func (s *service) makeToken(subject string, scope TokenScope, exp time.Time) jwt.Token {
 	tok := jwt.New()
+	tok.Set("iss", s.issuer)
+	tok.Set("aud", s.audience)
 	tok.Set("scope", scope)
 	tok.Set("sub", subject)
 	tok.Set("iat", time.Now().Unix())
@@ -59,20 +104,26 @@ const TokenScopeRefresh TokenScope = "refresh"
 func (s *service) CreateTokens(ctx context.Context, logger *zap.Logger, subj string) (string, string, error) {
 	tokExp := time.Now().Add(s.tokenAge)
 	refTokExp := time.Now().Add(s.refreshTokenAge)
-	accessTok := makeToken(subj, TokenScopeAccess, tokExp)
-	refreshTok := makeToken(subj, TokenScopeRefresh, refTokExp)
+	accessTok := s.makeToken(subj, TokenScopeAccess, tokExp)
+	refreshTok := s.makeToken(subj, TokenScopeRefresh, refTokExp)
 
 	rVal := make([]byte, 10)
 	rand.Read(rVal)
 	refreshTok.Set("jti", base64.StdEncoding.EncodeToString(rVal))
 
-	accSig, err := jwt.Sign(accessTok, jwt.WithKey(jwa.HS256, s.jwtSigningKey))
+	key, err := s.keys.signingKey(time.Now())
+	if err != nil {
+		logger.Error("taking signing key error", zap.Error(err))
+		return "", "", err
+	}
+
+	accSig, err := jwt.Sign(accessTok, jwt.WithKey(s.keys.algorithm, key))
 	if err != nil {
 		logger.Error("signing access token error", zap.Error(err))
 		return "", "", errorwrapper.WrapMessage(err, "signing access token")
 	}
 
-	refSig, err := jwt.Sign(refreshTok, jwt.WithKey(jwa.HS256, s.jwtSigningKey))
+	refSig, err := jwt.Sign(refreshTok, jwt.WithKey(s.keys.algorithm, key))
 	if err != nil {
 		logger.Error("signing refresh token error", zap.Error(err))
 		return "", "", errorwrapper.WrapMessage(err, "signing refresh token")
@@ -81,12 +132,72 @@ func (s *service) CreateTokens(ctx context.Context, logger *zap.Logger, subj str
 	return string(accSig), string(refSig), nil
 }
 
+type IDTokenParams struct {
+	Issuer    string
+	Subject   string
+	Audience  string
+	Nonce     string
+	AuthTime  time.Time
+	ExpiresAt time.Time
+}
+
+func (s *service) CreateIDToken(ctx context.Context, logger *zap.Logger, params *IDTokenParams) (string, error) {
+	tok := jwt.New()
+	tok.Set("iss", params.Issuer)
+	tok.Set("sub", params.Subject)
+	tok.Set("aud", params.Audience)
+	tok.Set("azp", params.Audience)
+	tok.Set("iat", time.Now().Unix())
+	tok.Set("exp", params.ExpiresAt.Unix())
+	tok.Set("auth_time", params.AuthTime.Unix())
+	if params.Nonce != "" {
+		tok.Set("nonce", params.Nonce)
+	}
+
+	key, err := s.keys.signingKey(time.Now())
+	if err != nil {
+		logger.Error("taking signing key error", zap.Error(err))
+		return "", err
+	}
+	sig, err := jwt.Sign(tok, jwt.WithKey(s.keys.algorithm, key))
+	if err != nil {
+		logger.Error("signing ID token error", zap.Error(err))
+		return "", errorwrapper.WrapMessage(err, "signing ID token")
+	}
+
+	return string(sig), nil
+}
+
 func (s *service) DecodeToken(logger *zap.Logger, token string) (jwt.Token, error) {
-	t, err := jwt.ParseString(token, jwt.WithVerify(false), jwt.WithValidate(true))
+	// tokens issued before key IDs were introduced don't have kid header,
+	// they are verified against every key of the set.
+	t, err := jwt.ParseString(
+		token,
+		jwt.WithKeySet(s.keys.verification, jws.WithRequireKid(false)),
+		jwt.WithValidate(true),
+	)
 	if err != nil {
 		logger.Error("decode token error", zap.Error(err))
 		return nil, errorwrapper.WrapMessage(err, "decode token error")
 	}
+	// iss and aud are optional for tokens issued before they were introduced,
+	// but if they are present they must name this warden.
+	if iss := t.Issuer(); iss != "" && iss != s.issuer {
+		logger.Error("decode token error, unexpected issuer", zap.String("issuer", iss))
+		return nil, errorwrapper.New("decode token error, unexpected issuer")
+	}
+	if aud := t.Audience(); len(aud) > 0 && !lo.Contains(aud, s.audience) {
+		logger.Error("decode token error, unexpected audience", zap.Strings("audience", aud))
+		return nil, errorwrapper.New("decode token error, unexpected audience")
+	}
 
 	return t, nil
 }
+
+func (s *service) PublicKeySet() jwk.Set {
+	return s.keys.public
+}
+
+func (s *service) Algorithm() string {
+	return s.keys.algorithm.String()
+}
@@ -0,0 +1,148 @@
+package loginlinks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/idgenerators"
+	"github.com/ecumenos-social/toolkit/hash"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	// Enabled switches passwordless login with emailed links for the warden.
+	Enabled bool
+	// LinkURL is URL of holders' client page which redeems login link, the
+	// token is passed in "token" query parameter.
+	LinkURL  string
+	TokenAge time.Duration
+}
+
+type Repository interface {
+	InsertHolderLoginLink(ctx context.Context, ll *models.HolderLoginLink) error
+	RedeemHolderLoginLink(ctx context.Context, tokenHash string, usedAt time.Time) (*models.HolderLoginLink, error)
+	GetHolderLoginLinkByTokenHash(ctx context.Context, tokenHash string) (*models.HolderLoginLink, error)
+}
+
+type Service interface {
+	Enabled() bool
+	TokenAge() time.Duration
+	// Issue creates login link of holder. The token is also shown in the
+	// email, so it can be pasted if the link can't be opened on the device.
+	Issue(ctx context.Context, logger *zap.Logger, holderID int64) (link, token string, err error)
+	// Redeem marks login link as used and returns its holder ID.
+	Redeem(ctx context.Context, logger *zap.Logger, token string) (int64, error)
+}
+
+type service struct {
+	enabled     bool
+	linkURL     string
+	tokenAge    time.Duration
+	repo        Repository
+	idgenerator idgenerators.HolderLoginLinksIDGenerator
+}
+
+func New(config *Config, repo Repository, g idgenerators.HolderLoginLinksIDGenerator) Service {
+	return &service{
+		enabled:     config.Enabled,
+		linkURL:     config.LinkURL,
+		tokenAge:    config.TokenAge,
+		repo:        repo,
+		idgenerator: g,
+	}
+}
+
+const tokenLength = 32
+
+func generateToken() (string, error) {
+	b := make([]byte, tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	return hash.SHA256(token)
+}
+
+func (s *service) Enabled() bool {
+	return s.enabled
+}
+
+func (s *service) TokenAge() time.Duration {
+	return s.tokenAge
+}
+
+func (s *service) Issue(ctx context.Context, logger *zap.Logger, holderID int64) (string, string, error) {
+	id := s.idgenerator.Generate().Int64()
+	logger = logger.With(
+		zap.Int64("holder-login-link-id", id),
+		zap.Int64("holder-id", holderID),
+	)
+	link, err := url.Parse(s.linkURL)
+	if err != nil {
+		logger.Error("invalid login link URL", zap.Error(err))
+		return "", "", err
+	}
+	token, err := generateToken()
+	if err != nil {
+		logger.Error("failed to generate login link token", zap.Error(err))
+		return "", "", err
+	}
+	ll := &models.HolderLoginLink{
+		ID:             id,
+		CreatedAt:      time.Now(),
+		LastModifiedAt: time.Now(),
+		HolderID:       holderID,
+		TokenHash:      hashToken(token),
+		ExpiredAt:      time.Now().Add(s.tokenAge),
+	}
+	if err := s.repo.InsertHolderLoginLink(ctx, ll); err != nil {
+		logger.Error("failed to insert holder login link", zap.Error(err))
+		return "", "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), token, nil
+}
+
+// Redeem checks login link and marks it as used by one statement, so one link
+// can't create two sessions.
+func (s *service) Redeem(ctx context.Context, logger *zap.Logger, token string) (int64, error) {
+	ll, err := s.repo.RedeemHolderLoginLink(ctx, hashToken(token), time.Now())
+	if err != nil {
+		logger.Error("failed to redeem holder login link", zap.Error(err))
+		return 0, err
+	}
+	if ll != nil {
+		return ll.HolderID, nil
+	}
+
+	// the lookup only tells why the link was rejected
+	ll, err = s.repo.GetHolderLoginLinkByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		logger.Error("failed to get holder login link", zap.Error(err))
+		return 0, err
+	}
+	if ll == nil {
+		logger.Error("invalid login link token")
+		return 0, errorwrapper.New("invalid login link")
+	}
+	logger = logger.With(zap.Int64("holder-login-link-id", ll.ID), zap.Int64("holder-id", ll.HolderID))
+	if ll.UsedAt.Valid {
+		logger.Error("login link was already used")
+		return 0, errorwrapper.New("login link was already used")
+	}
+	logger.Error("login link was expired", zap.Time("expired-at", ll.ExpiredAt))
+	return 0, errorwrapper.New("login link was expired")
+}
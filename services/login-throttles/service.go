@@ -0,0 +1,214 @@
+package loginthrottles
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/idgenerators"
+	"github.com/ecumenos-social/toolkit/types"
+	"go.uber.org/zap"
+)
+
+type Kind string
+
+func (k Kind) String() string {
+	return string(k)
+}
+
+const (
+	KindHolder          Kind = "holder"
+	KindAdmin           Kind = "admin"
+	KindRemoteIPAddress Kind = "remote-ip-address"
+)
+
+// Key identifies what failed login attempts are counted for: an account or
+// a remote IP address.
+type Key struct {
+	Kind    Kind
+	Subject string
+}
+
+func HolderKey(holderID int64) Key {
+	return Key{Kind: KindHolder, Subject: fmt.Sprint(holderID)}
+}
+
+func AdminKey(adminID int64) Key {
+	return Key{Kind: KindAdmin, Subject: fmt.Sprint(adminID)}
+}
+
+func RemoteIPAddressKey(ip string) Key {
+	return Key{Kind: KindRemoteIPAddress, Subject: ip}
+}
+
+type Config struct {
+	// DelayThreshold is amount of failed attempts after which every next
+	// attempt has to wait BaseDelay, doubled for every further failure.
+	DelayThreshold int64
+	BaseDelay      time.Duration
+	// MaxAttempts is amount of failed attempts which locks key for
+	// LockoutDuration.
+	MaxAttempts     int64
+	LockoutDuration time.Duration
+	// AttemptsWindow is period after the latest failure when failed
+	// attempts are forgotten.
+	AttemptsWindow time.Duration
+}
+
+type Repository interface {
+	GetLoginThrottle(ctx context.Context, kind, subject string) (*models.LoginThrottle, error)
+	IncrementLoginThrottle(ctx context.Context, id int64, kind, subject string, failedAt, resetBefore time.Time) (*models.LoginThrottle, error)
+	ModifyLoginThrottle(ctx context.Context, id int64, lt *models.LoginThrottle) error
+	DeleteLoginThrottle(ctx context.Context, kind, subject string) error
+	DeleteLoginThrottleByID(ctx context.Context, id int64) error
+	GetLoginThrottles(ctx context.Context, now time.Time, pagination *types.Pagination) ([]*models.LoginThrottle, error)
+}
+
+type Service interface {
+	Check(ctx context.Context, logger *zap.Logger, keys ...Key) error
+	RegisterFailure(ctx context.Context, logger *zap.Logger, keys ...Key) error
+	RegisterSuccess(ctx context.Context, logger *zap.Logger, key Key) error
+	GetList(ctx context.Context, logger *zap.Logger, pagination *types.Pagination) ([]*models.LoginThrottle, error)
+	Clear(ctx context.Context, logger *zap.Logger, id int64) error
+}
+
+type service struct {
+	delayThreshold  int64
+	baseDelay       time.Duration
+	maxAttempts     int64
+	lockoutDuration time.Duration
+	attemptsWindow  time.Duration
+	repo            Repository
+	idgenerator     idgenerators.LoginThrottlesIDGenerator
+}
+
+func New(config *Config, repo Repository, g idgenerators.LoginThrottlesIDGenerator) Service {
+	return &service{
+		delayThreshold:  config.DelayThreshold,
+		baseDelay:       config.BaseDelay,
+		maxAttempts:     config.MaxAttempts,
+		lockoutDuration: config.LockoutDuration,
+		attemptsWindow:  config.AttemptsWindow,
+		repo:            repo,
+		idgenerator:     g,
+	}
+}
+
+// ThrottledError is returned by Check when login is not allowed yet.
+type ThrottledError struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("too many failed login attempts, login is locked, retry after %v", e.RetryAfter)
+	}
+	return fmt.Sprintf("too many failed login attempts, retry after %v", e.RetryAfter)
+}
+
+func (s *service) retryAfter(lt *models.LoginThrottle, now time.Time) (time.Duration, bool) {
+	if lt.LockedUntil.Valid && lt.LockedUntil.Time.After(now) {
+		return lt.LockedUntil.Time.Sub(now), true
+	}
+	if lt.LastFailedAt.Before(now.Add(-s.attemptsWindow)) || lt.FailedAttempts < s.delayThreshold {
+		return 0, false
+	}
+
+	delay := s.baseDelay
+	for i := s.delayThreshold; i < lt.FailedAttempts && delay < s.lockoutDuration; i++ {
+		delay *= 2
+	}
+	if delay > s.lockoutDuration {
+		delay = s.lockoutDuration
+	}
+	if next := lt.LastFailedAt.Add(delay); next.After(now) {
+		return next.Sub(now), false
+	}
+
+	return 0, false
+}
+
+func (s *service) Check(ctx context.Context, logger *zap.Logger, keys ...Key) error {
+	now := time.Now()
+	for _, key := range keys {
+		lt, err := s.repo.GetLoginThrottle(ctx, key.Kind.String(), key.Subject)
+		if err != nil {
+			logger.Error("failed to get login throttle", zap.Error(err), zap.String("kind", key.Kind.String()))
+			return err
+		}
+		if lt == nil {
+			continue
+		}
+		if retryAfter, locked := s.retryAfter(lt, now); retryAfter > 0 {
+			logger.Info("login is throttled",
+				zap.String("kind", key.Kind.String()),
+				zap.Bool("locked", locked),
+				zap.Duration("retry-after", retryAfter),
+			)
+			return &ThrottledError{Locked: locked, RetryAfter: retryAfter}
+		}
+	}
+
+	return nil
+}
+
+func (s *service) RegisterFailure(ctx context.Context, logger *zap.Logger, keys ...Key) error {
+	now := time.Now()
+	for _, key := range keys {
+		l := logger.With(zap.String("kind", key.Kind.String()))
+		lt, err := s.repo.IncrementLoginThrottle(ctx, s.idgenerator.Generate().Int64(), key.Kind.String(), key.Subject, now, now.Add(-s.attemptsWindow))
+		if err != nil {
+			l.Error("failed to increment login throttle", zap.Error(err))
+			return err
+		}
+		if lt.FailedAttempts < s.maxAttempts {
+			continue
+		}
+
+		lt.LastModifiedAt = now
+		lt.FailedAttempts = 0
+		lt.LockedUntil = sql.NullTime{
+			Time:  now.Add(s.lockoutDuration),
+			Valid: true,
+		}
+		if err := s.repo.ModifyLoginThrottle(ctx, lt.ID, lt); err != nil {
+			l.Error("failed to modify login throttle to lock it", zap.Error(err))
+			return err
+		}
+		l.Warn("login is locked after too many failed attempts", zap.Time("locked-until", lt.LockedUntil.Time))
+	}
+
+	return nil
+}
+
+func (s *service) RegisterSuccess(ctx context.Context, logger *zap.Logger, key Key) error {
+	if err := s.repo.DeleteLoginThrottle(ctx, key.Kind.String(), key.Subject); err != nil {
+		logger.Error("failed to delete login throttle", zap.Error(err), zap.String("kind", key.Kind.String()))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetList(ctx context.Context, logger *zap.Logger, pagination *types.Pagination) ([]*models.LoginThrottle, error) {
+	list, err := s.repo.GetLoginThrottles(ctx, time.Now(), pagination)
+	if err != nil {
+		logger.Error("failed to get login throttles", zap.Error(err))
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *service) Clear(ctx context.Context, logger *zap.Logger, id int64) error {
+	if err := s.repo.DeleteLoginThrottleByID(ctx, id); err != nil {
+		logger.Error("failed to delete login throttle", zap.Error(err), zap.Int64("login-throttle-id", id))
+		return errorwrapper.WrapMessage(err, "failed to clear login throttle")
+	}
+
+	return nil
+}
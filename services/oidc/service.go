@@ -0,0 +1,474 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/idgenerators"
+	"github.com/ecumenos-social/network-warden/services/jwt"
+	networknodes "github.com/ecumenos-social/network-warden/services/network-nodes"
+	personaldatanodes "github.com/ecumenos-social/network-warden/services/personal-data-nodes"
+	"github.com/ecumenos-social/toolkit/hash"
+	"github.com/ecumenos-social/toolkit/random"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	// Issuer is public URL of the warden. It is "iss" claim of ID tokens and
+	// base URL of endpoints announced in discovery document.
+	Issuer         string
+	CodeAge        time.Duration
+	AccessTokenAge time.Duration
+	IDTokenAge     time.Duration
+}
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	ScopePhone   = "phone"
+)
+
+var SupportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopePhone}
+
+const (
+	ResponseTypeCode             = "code"
+	GrantTypeAuthorizationCode   = "authorization_code"
+	CodeChallengeMethodS256      = "S256"
+	TokenTypeBearer              = "Bearer"
+	personalDataNodeClientPrefix = "pdn-"
+	networkNodeClientPrefix      = "nn-"
+)
+
+// Error codes of OAuth 2.0 (RFC 6749) error responses.
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorAccessDenied            = "access_denied"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorServerError             = "server_error"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorInvalidToken            = "invalid_token"
+)
+
+// Error is OAuth 2.0 error which is returned to client as is.
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
+
+// Client is activated personal data node or network node. Its client ID is
+// "pdn-<ID>" or "nn-<ID>", its client secret is the node's API key and
+// redirect URIs have to be under the node's URL.
+type Client struct {
+	ID   string
+	Name string
+	URL  string
+
+	apiKeyHash string
+	hashAPIKey func(apiKey string) string
+}
+
+// ValidRedirectURI reports whether uri has the same origin as client's URL and
+// its path is under path of client's URL.
+func (c *Client) ValidRedirectURI(uri string) bool {
+	base, err := url.Parse(c.URL)
+	if err != nil || base.Host == "" {
+		return false
+	}
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.User != nil || u.Fragment != "" {
+		return false
+	}
+	if !strings.EqualFold(u.Scheme, base.Scheme) || !strings.EqualFold(u.Host, base.Host) {
+		return false
+	}
+	basePath := strings.TrimSuffix(base.Path, "/")
+
+	return u.Path == basePath || strings.HasPrefix(u.Path, basePath+"/")
+}
+
+func (c *Client) authenticate(secret string) bool {
+	if secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.hashAPIKey(secret)), []byte(c.apiKeyHash)) == 1
+}
+
+type Repository interface {
+	GetPersonalDataNodeByID(ctx context.Context, id int64) (*models.PersonalDataNode, error)
+	GetNetworkNodeByID(ctx context.Context, id int64) (*models.NetworkNode, error)
+	GetHolderByID(ctx context.Context, id int64) (*models.Holder, error)
+	InsertOIDCAuthorizationCode(ctx context.Context, ac *models.OIDCAuthorizationCode) error
+	RedeemOIDCAuthorizationCode(ctx context.Context, id int64, usedAt time.Time) (*models.OIDCAuthorizationCode, error)
+	GetOIDCAuthorizationCodeByCodeHash(ctx context.Context, codeHash string) (*models.OIDCAuthorizationCode, error)
+	InsertOIDCAccessToken(ctx context.Context, at *models.OIDCAccessToken) error
+	GetOIDCAccessTokenByTokenHash(ctx context.Context, tokenHash string) (*models.OIDCAccessToken, error)
+	RevokeOIDCAccessTokensByAuthorizationCodeID(ctx context.Context, authorizationCodeID int64, revokedAt time.Time) error
+}
+
+type Service interface {
+	Issuer() string
+	// ValidateAuthorizationRequest checks authorization request. If client or
+	// redirect URI is invalid the returned client is nil and the error must be
+	// shown to holder, otherwise the error is sent to redirect URI.
+	ValidateAuthorizationRequest(ctx context.Context, logger *zap.Logger, req *AuthorizationRequest) (*Client, error)
+	IssueCode(ctx context.Context, logger *zap.Logger, holderID int64, req *AuthorizationRequest) (string, error)
+	Exchange(ctx context.Context, logger *zap.Logger, req *TokenRequest) (*TokenResponse, error)
+	GetAccessToken(ctx context.Context, logger *zap.Logger, token string) (*models.OIDCAccessToken, error)
+}
+
+type service struct {
+	issuer         string
+	codeAge        time.Duration
+	accessTokenAge time.Duration
+	idTokenAge     time.Duration
+
+	repo                          Repository
+	jwt                           jwt.Service
+	authorizationCodesIDGenerator idgenerators.OIDCAuthorizationCodesIDGenerator
+	accessTokensIDGenerator       idgenerators.OIDCAccessTokensIDGenerator
+}
+
+func New(
+	config *Config,
+	repo Repository,
+	jwtService jwt.Service,
+	acg idgenerators.OIDCAuthorizationCodesIDGenerator,
+	atg idgenerators.OIDCAccessTokensIDGenerator,
+) Service {
+	return &service{
+		issuer:         strings.TrimSuffix(config.Issuer, "/"),
+		codeAge:        config.CodeAge,
+		accessTokenAge: config.AccessTokenAge,
+		idTokenAge:     config.IDTokenAge,
+
+		repo:                          repo,
+		jwt:                           jwtService,
+		authorizationCodesIDGenerator: acg,
+		accessTokensIDGenerator:       atg,
+	}
+}
+
+func hashSecretValue(value string) string {
+	return hash.SHA256(value)
+}
+
+func (s *service) Issuer() string {
+	return s.issuer
+}
+
+func (s *service) getClient(ctx context.Context, logger *zap.Logger, clientID string) (*Client, error) {
+	var (
+		prefix string
+		ok     bool
+	)
+	for _, p := range []string{personalDataNodeClientPrefix, networkNodeClientPrefix} {
+		if strings.HasPrefix(clientID, p) {
+			prefix, ok = p, true
+			break
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(clientID, prefix), 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	if prefix == personalDataNodeClientPrefix {
+		pdn, err := s.repo.GetPersonalDataNodeByID(ctx, id)
+		if err != nil {
+			logger.Error("failed to get personal data node by id", zap.Error(err))
+			return nil, err
+		}
+		if pdn == nil || pdn.Status != models.PersonalDataNodeStatusApproved || pdn.APIKeyHash == "" {
+			return nil, nil
+		}
+		return &Client{
+			ID:         clientID,
+			Name:       pdn.Name,
+			URL:        pdn.URL,
+			apiKeyHash: pdn.APIKeyHash,
+			hashAPIKey: personaldatanodes.HashAPIKey,
+		}, nil
+	}
+
+	nn, err := s.repo.GetNetworkNodeByID(ctx, id)
+	if err != nil {
+		logger.Error("failed to get network node by id", zap.Error(err))
+		return nil, err
+	}
+	if nn == nil || nn.Status != models.NetworkNodeStatusApproved || nn.APIKeyHash == "" {
+		return nil, nil
+	}
+	return &Client{
+		ID:         clientID,
+		Name:       nn.Name,
+		URL:        nn.URL,
+		apiKeyHash: nn.APIKeyHash,
+		hashAPIKey: networknodes.HashAPIKey,
+	}, nil
+}
+
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Scopes returns requested scopes which are supported, unknown scopes are
+// ignored.
+func (r *AuthorizationRequest) Scopes() []string {
+	return lo.Uniq(lo.Filter(strings.Fields(r.Scope), func(scope string, _ int) bool {
+		return lo.Contains(SupportedScopes, scope)
+	}))
+}
+
+func (s *service) ValidateAuthorizationRequest(ctx context.Context, logger *zap.Logger, req *AuthorizationRequest) (*Client, error) {
+	logger = logger.With(zap.String("client-id", req.ClientID))
+	client, err := s.getClient(ctx, logger, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		logger.Error("unknown client")
+		return nil, newError(ErrorInvalidClient, "client is unknown or is not activated")
+	}
+	if !client.ValidRedirectURI(req.RedirectURI) {
+		logger.Error("invalid redirect URI", zap.String("redirect-uri", req.RedirectURI))
+		return nil, newError(ErrorInvalidRequest, "redirect_uri doesn't match client's URL")
+	}
+
+	if req.ResponseType != ResponseTypeCode {
+		return client, newError(ErrorUnsupportedResponseType, "only code response type is supported")
+	}
+	if !lo.Contains(req.Scopes(), ScopeOpenID) {
+		return client, newError(ErrorInvalidScope, "openid scope is required")
+	}
+	if req.CodeChallengeMethod != CodeChallengeMethodS256 {
+		return client, newError(ErrorInvalidRequest, "code_challenge_method must be S256")
+	}
+	if l := len(req.CodeChallenge); l < 43 || l > 128 {
+		return client, newError(ErrorInvalidRequest, "code_challenge is required")
+	}
+
+	return client, nil
+}
+
+func (s *service) IssueCode(ctx context.Context, logger *zap.Logger, holderID int64, req *AuthorizationRequest) (string, error) {
+	id := s.authorizationCodesIDGenerator.Generate().Int64()
+	logger = logger.With(
+		zap.Int64("oidc-authorization-code-id", id),
+		zap.Int64("holder-id", holderID),
+		zap.String("client-id", req.ClientID),
+	)
+	code, err := random.GenNanoString(32)
+	if err != nil {
+		logger.Error("failed to generate authorization code", zap.Error(err))
+		return "", err
+	}
+	ac := &models.OIDCAuthorizationCode{
+		ID:                  id,
+		CreatedAt:           time.Now(),
+		LastModifiedAt:      time.Now(),
+		HolderID:            holderID,
+		ClientID:            req.ClientID,
+		CodeHash:            hashSecretValue(code),
+		RedirectURI:         req.RedirectURI,
+		Scopes:              req.Scopes(),
+		Nonce:               sql.NullString{String: req.Nonce, Valid: req.Nonce != ""},
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		ExpiredAt:           time.Now().Add(s.codeAge),
+	}
+	if err := s.repo.InsertOIDCAuthorizationCode(ctx, ac); err != nil {
+		logger.Error("failed to insert OIDC authorization code", zap.Error(err))
+		return "", err
+	}
+
+	return code, nil
+}
+
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+func (s *service) Exchange(ctx context.Context, logger *zap.Logger, req *TokenRequest) (*TokenResponse, error) {
+	logger = logger.With(zap.String("client-id", req.ClientID))
+	if req.GrantType != GrantTypeAuthorizationCode {
+		return nil, newError(ErrorUnsupportedGrantType, "only authorization_code grant type is supported")
+	}
+	client, err := s.getClient(ctx, logger, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || !client.authenticate(req.ClientSecret) {
+		logger.Error("client authentication failed")
+		return nil, newError(ErrorInvalidClient, "client authentication failed")
+	}
+
+	ac, err := s.repo.GetOIDCAuthorizationCodeByCodeHash(ctx, hashSecretValue(req.Code))
+	if err != nil {
+		logger.Error("failed to get OIDC authorization code", zap.Error(err))
+		return nil, err
+	}
+	if ac == nil || ac.ClientID != client.ID {
+		logger.Error("invalid authorization code")
+		return nil, newError(ErrorInvalidGrant, "invalid authorization code")
+	}
+	logger = logger.With(zap.Int64("oidc-authorization-code-id", ac.ID), zap.Int64("holder-id", ac.HolderID))
+	// the code is checked and marked as used by one statement, so parallel
+	// token requests with one code can't both get tokens
+	redeemed, err := s.repo.RedeemOIDCAuthorizationCode(ctx, ac.ID, time.Now())
+	if err != nil {
+		logger.Error("failed to redeem OIDC authorization code", zap.Error(err))
+		return nil, err
+	}
+	if redeemed == nil {
+		if !ac.UsedAt.Valid && !ac.ExpiredAt.After(time.Now()) {
+			logger.Error("authorization code was expired", zap.Time("expired-at", ac.ExpiredAt))
+			return nil, newError(ErrorInvalidGrant, "authorization code was expired")
+		}
+		// the code could be stolen, so tokens issued for it are revoked
+		// (RFC 6749, section 4.1.2)
+		logger.Error("authorization code was already used, revoking issued access tokens")
+		if err := s.repo.RevokeOIDCAccessTokensByAuthorizationCodeID(ctx, ac.ID, time.Now()); err != nil {
+			logger.Error("failed to revoke OIDC access tokens", zap.Error(err))
+			return nil, err
+		}
+		return nil, newError(ErrorInvalidGrant, "authorization code was already used")
+	}
+	if ac.RedirectURI != req.RedirectURI {
+		logger.Error("redirect URI doesn't match", zap.String("redirect-uri", req.RedirectURI))
+		return nil, newError(ErrorInvalidGrant, "redirect_uri doesn't match authorization request")
+	}
+	if !verifyCodeChallenge(ac.CodeChallenge, req.CodeVerifier) {
+		logger.Error("code verifier doesn't match code challenge")
+		return nil, newError(ErrorInvalidGrant, "invalid code_verifier")
+	}
+	// holder could be suspended after the code was issued
+	holder, err := s.repo.GetHolderByID(ctx, ac.HolderID)
+	if err != nil {
+		logger.Error("failed to get holder", zap.Error(err))
+		return nil, err
+	}
+	if holder == nil || holder.SuspendedAt.Valid {
+		logger.Error("holder is not found or suspended")
+		return nil, newError(ErrorInvalidGrant, "holder is not active")
+	}
+
+	accessToken, err := random.GenNanoString(32)
+	if err != nil {
+		logger.Error("failed to generate access token", zap.Error(err))
+		return nil, err
+	}
+	at := &models.OIDCAccessToken{
+		ID:                  s.accessTokensIDGenerator.Generate().Int64(),
+		CreatedAt:           time.Now(),
+		LastModifiedAt:      time.Now(),
+		HolderID:            ac.HolderID,
+		ClientID:            ac.ClientID,
+		AuthorizationCodeID: ac.ID,
+		TokenHash:           hashSecretValue(accessToken),
+		Scopes:              ac.Scopes,
+		ExpiredAt:           time.Now().Add(s.accessTokenAge),
+	}
+	if err := s.repo.InsertOIDCAccessToken(ctx, at); err != nil {
+		logger.Error("failed to insert OIDC access token", zap.Error(err))
+		return nil, err
+	}
+
+	idToken, err := s.jwt.CreateIDToken(ctx, logger, &jwt.IDTokenParams{
+		Issuer:    s.issuer,
+		Subject:   fmt.Sprint(ac.HolderID),
+		Audience:  ac.ClientID,
+		Nonce:     ac.Nonce.String,
+		AuthTime:  ac.CreatedAt,
+		ExpiresAt: time.Now().Add(s.idTokenAge),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   TokenTypeBearer,
+		ExpiresIn:   int64(s.accessTokenAge.Seconds()),
+		IDToken:     idToken,
+		Scope:       strings.Join(ac.Scopes, " "),
+	}, nil
+}
+
+// verifyCodeChallenge checks PKCE (RFC 7636) code verifier with S256 method.
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if l := len(verifier); l < 43 || l > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func (s *service) GetAccessToken(ctx context.Context, logger *zap.Logger, token string) (*models.OIDCAccessToken, error) {
+	at, err := s.repo.GetOIDCAccessTokenByTokenHash(ctx, hashSecretValue(token))
+	if err != nil {
+		logger.Error("failed to get OIDC access token", zap.Error(err))
+		return nil, err
+	}
+	if at == nil {
+		logger.Error("unknown access token")
+		return nil, newError(ErrorInvalidToken, "invalid access token")
+	}
+	logger = logger.With(zap.Int64("oidc-access-token-id", at.ID), zap.String("client-id", at.ClientID))
+	if at.RevokedAt.Valid {
+		logger.Error("access token was revoked", zap.Time("revoked-at", at.RevokedAt.Time))
+		return nil, newError(ErrorInvalidToken, "access token was revoked")
+	}
+	if at.ExpiredAt.Before(time.Now()) {
+		logger.Error("access token was expired", zap.Time("expired-at", at.ExpiredAt))
+		return nil, newError(ErrorInvalidToken, "access token was expired")
+	}
+
+	return at, nil
+}
@@ -0,0 +1,130 @@
+package passkeys
+
+import (
+	"encoding/binary"
+	"math"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+)
+
+// maxCBORDepth limits nesting of decoded CBOR items, authenticator data never
+// needs more than a few levels.
+const maxCBORDepth = 16
+
+// decodeCBOR decodes the first CBOR item of data and returns the rest. It
+// supports the subset of CBOR used by WebAuthn attestation objects and COSE
+// keys: integers, byte and text strings, arrays, maps, booleans and null.
+// Integers are decoded as int64, maps as map[interface{}]interface{}.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errorwrapper.New("CBOR item is nested too deep")
+	}
+	if len(data) == 0 {
+		return nil, nil, errorwrapper.New("unexpected end of CBOR data")
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22:
+			return nil, data, nil
+		}
+		return nil, nil, errorwrapper.New("unsupported CBOR simple value")
+	}
+
+	arg, data, err := decodeCBORArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, nil, errorwrapper.New("CBOR integer overflows int64")
+		}
+		return int64(arg), data, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, nil, errorwrapper.New("CBOR integer overflows int64")
+		}
+		return -1 - int64(arg), data, nil
+	case 2, 3:
+		if arg > uint64(len(data)) {
+			return nil, nil, errorwrapper.New("unexpected end of CBOR data")
+		}
+		value := data[:arg]
+		if major == 3 {
+			return string(value), data[arg:], nil
+		}
+		return value, data[arg:], nil
+	case 4:
+		// every item takes at least one byte, so longer lengths are invalid
+		if arg > uint64(len(data)) {
+			return nil, nil, errorwrapper.New("unexpected end of CBOR data")
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item interface{}
+			item, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+	case 5:
+		if arg > uint64(len(data)) {
+			return nil, nil, errorwrapper.New("unexpected end of CBOR data")
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value interface{}
+			key, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errorwrapper.New("unsupported CBOR map key")
+			}
+			value, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, data, nil
+	}
+
+	return nil, nil, errorwrapper.New("unsupported CBOR major type")
+}
+
+// decodeCBORArgument decodes argument of CBOR item head. Indefinite lengths
+// are not supported, WebAuthn requires canonical CBOR.
+func decodeCBORArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	case info > 27:
+		return 0, nil, errorwrapper.New("unsupported CBOR additional information")
+	}
+
+	return 0, nil, errorwrapper.New("unexpected end of CBOR data")
+}
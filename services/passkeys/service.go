@@ -0,0 +1,388 @@
+package passkeys
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/idgenerators"
+	"github.com/ecumenos-social/toolkit/hash"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	// RPID is WebAuthn relying party ID, it is domain of the holders' client.
+	RPID   string
+	RPName string
+	// Origins are origins of holders' clients which are allowed to run
+	// ceremonies.
+	Origins      []string
+	ChallengeAge time.Duration
+}
+
+type Repository interface {
+	InsertHolderPasskey(ctx context.Context, pk *models.HolderPasskey) error
+	ModifyHolderPasskey(ctx context.Context, id int64, pk *models.HolderPasskey) error
+	DeleteHolderPasskey(ctx context.Context, id int64) error
+	GetHolderPasskeyByID(ctx context.Context, id int64) (*models.HolderPasskey, error)
+	GetHolderPasskeyByCredentialID(ctx context.Context, credentialID string) (*models.HolderPasskey, error)
+	GetHolderPasskeysByHolderID(ctx context.Context, holderID int64) ([]*models.HolderPasskey, error)
+	InsertWebAuthnChallenge(ctx context.Context, wc *models.WebAuthnChallenge) error
+	RedeemWebAuthnChallenge(ctx context.Context, challengeHash, ceremony string, usedAt time.Time) (*models.WebAuthnChallenge, error)
+	GetWebAuthnChallengeByChallengeHash(ctx context.Context, challengeHash string) (*models.WebAuthnChallenge, error)
+}
+
+type Service interface {
+	// BeginRegistration returns options of registration ceremony of a new
+	// passkey of holder. name and displayName are shown by authenticator.
+	BeginRegistration(ctx context.Context, logger *zap.Logger, holderID int64, name, displayName string) (*CreationOptions, error)
+	FinishRegistration(ctx context.Context, logger *zap.Logger, holderID int64, name string, credential *RegistrationCredential) (*models.HolderPasskey, error)
+	// BeginLogin returns options of assertion ceremony. Authenticator offers
+	// any discoverable passkey of the relying party, options don't depend on
+	// holder, so they can't tell whether the holder exists or which passkeys
+	// it has.
+	BeginLogin(ctx context.Context, logger *zap.Logger) (*RequestOptions, error)
+	// FinishLogin verifies assertion and returns the passkey which was used,
+	// its sign counter is updated.
+	FinishLogin(ctx context.Context, logger *zap.Logger, credential *AssertionCredential) (*models.HolderPasskey, error)
+	GetList(ctx context.Context, logger *zap.Logger, holderID int64) ([]*models.HolderPasskey, error)
+	Remove(ctx context.Context, logger *zap.Logger, holderID, id int64) error
+}
+
+type service struct {
+	rpID         string
+	rpName       string
+	origins      []string
+	challengeAge time.Duration
+
+	repo                  Repository
+	passkeysIDGenerator   idgenerators.HolderPasskeysIDGenerator
+	challengesIDGenerator idgenerators.WebAuthnChallengesIDGenerator
+}
+
+func New(
+	config *Config,
+	repo Repository,
+	pg idgenerators.HolderPasskeysIDGenerator,
+	cg idgenerators.WebAuthnChallengesIDGenerator,
+) Service {
+	return &service{
+		rpID:         config.RPID,
+		rpName:       config.RPName,
+		origins:      config.Origins,
+		challengeAge: config.ChallengeAge,
+
+		repo:                  repo,
+		passkeysIDGenerator:   pg,
+		challengesIDGenerator: cg,
+	}
+}
+
+func hashSecretValue(value string) string {
+	return hash.SHA256(value)
+}
+
+const challengeLength = 32
+
+func (s *service) createChallenge(ctx context.Context, logger *zap.Logger, ceremony string, holderID *int64) ([]byte, error) {
+	id := s.challengesIDGenerator.Generate().Int64()
+	logger = logger.With(zap.Int64("webauthn-challenge-id", id))
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		logger.Error("failed to generate challenge", zap.Error(err))
+		return nil, err
+	}
+	wc := &models.WebAuthnChallenge{
+		ID:             id,
+		CreatedAt:      time.Now(),
+		LastModifiedAt: time.Now(),
+		HolderID:       sql.NullInt64{Int64: lo.FromPtr(holderID), Valid: holderID != nil},
+		Ceremony:       ceremony,
+		ChallengeHash:  hashSecretValue(base64.RawURLEncoding.EncodeToString(challenge)),
+		ExpiredAt:      time.Now().Add(s.challengeAge),
+	}
+	if err := s.repo.InsertWebAuthnChallenge(ctx, wc); err != nil {
+		logger.Error("failed to insert WebAuthn challenge", zap.Error(err))
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// redeemChallenge marks challenge from client data as used. Challenge is
+// single-use even if the ceremony fails afterwards.
+func (s *service) redeemChallenge(ctx context.Context, logger *zap.Logger, ceremony, challenge string) (*models.WebAuthnChallenge, error) {
+	wc, err := s.repo.RedeemWebAuthnChallenge(ctx, hashSecretValue(challenge), ceremony, time.Now())
+	if err != nil {
+		logger.Error("failed to redeem WebAuthn challenge", zap.Error(err))
+		return nil, err
+	}
+	if wc != nil {
+		return wc, nil
+	}
+
+	// challenge is looked up only to tell why it can't be redeemed
+	wc, err = s.repo.GetWebAuthnChallengeByChallengeHash(ctx, hashSecretValue(challenge))
+	if err != nil {
+		logger.Error("failed to get WebAuthn challenge", zap.Error(err))
+		return nil, err
+	}
+	if wc == nil || wc.Ceremony != ceremony {
+		logger.Error("invalid WebAuthn challenge")
+		return nil, errorwrapper.New("invalid challenge")
+	}
+	logger = logger.With(zap.Int64("webauthn-challenge-id", wc.ID))
+	if wc.UsedAt.Valid {
+		logger.Error("WebAuthn challenge was already used")
+		return nil, errorwrapper.New("challenge was already used")
+	}
+	logger.Error("WebAuthn challenge was expired", zap.Time("expired-at", wc.ExpiredAt))
+
+	return nil, errorwrapper.New("challenge was expired")
+}
+
+func (s *service) credentialDescriptors(ctx context.Context, logger *zap.Logger, holderID int64) ([]*CredentialDescriptor, error) {
+	passkeys, err := s.repo.GetHolderPasskeysByHolderID(ctx, holderID)
+	if err != nil {
+		logger.Error("failed to get holder passkeys", zap.Error(err))
+		return nil, err
+	}
+	descriptors := make([]*CredentialDescriptor, 0, len(passkeys))
+	for _, pk := range passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(pk.CredentialID)
+		if err != nil {
+			logger.Error("invalid passkey credential ID", zap.Error(err), zap.Int64("holder-passkey-id", pk.ID))
+			return nil, err
+		}
+		descriptors = append(descriptors, &CredentialDescriptor{Type: credentialTypePublicKey, ID: id})
+	}
+
+	return descriptors, nil
+}
+
+// userHandle is WebAuthn user ID of holder, it is big-endian holder ID.
+func userHandle(holderID int64) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(holderID))
+	return handle
+}
+
+func (s *service) BeginRegistration(ctx context.Context, logger *zap.Logger, holderID int64, name, displayName string) (*CreationOptions, error) {
+	logger = logger.With(zap.Int64("holder-id", holderID))
+	exclude, err := s.credentialDescriptors(ctx, logger, holderID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.createChallenge(ctx, logger, ceremonyCreate, &holderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreationOptions{
+		Challenge: challenge,
+		RP:        &RelyingParty{ID: s.rpID, Name: s.rpName},
+		User: &User{
+			ID:          userHandle(holderID),
+			Name:        name,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams: []*CredentialParameter{
+			{Type: credentialTypePublicKey, Alg: AlgorithmES256},
+			{Type: credentialTypePublicKey, Alg: AlgorithmEdDSA},
+			{Type: credentialTypePublicKey, Alg: AlgorithmRS256},
+		},
+		Timeout:            s.challengeAge.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: &AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+func (s *service) FinishRegistration(ctx context.Context, logger *zap.Logger, holderID int64, name string, credential *RegistrationCredential) (*models.HolderPasskey, error) {
+	logger = logger.With(zap.Int64("holder-id", holderID))
+	if credential.Type != credentialTypePublicKey {
+		logger.Error("unexpected credential type", zap.String("credential-type", credential.Type))
+		return nil, errorwrapper.New("unexpected credential type")
+	}
+	challenge, err := verifyClientData(credential.Response.ClientDataJSON, ceremonyCreate, s.origins)
+	if err != nil {
+		logger.Error("invalid client data", zap.Error(err))
+		return nil, err
+	}
+	wc, err := s.redeemChallenge(ctx, logger, ceremonyCreate, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if wc.HolderID.Int64 != holderID {
+		logger.Error("challenge was issued for another holder", zap.Int64("challenge-holder-id", wc.HolderID.Int64))
+		return nil, errorwrapper.New("invalid challenge")
+	}
+
+	ad, err := parseAttestationObject(credential.Response.AttestationObject)
+	if err != nil {
+		logger.Error("invalid attestation object", zap.Error(err))
+		return nil, err
+	}
+	if err := ad.verify(s.rpID); err != nil {
+		logger.Error("invalid authenticator data", zap.Error(err))
+		return nil, err
+	}
+	if ad.credentialID == nil {
+		logger.Error("authenticator data has no attested credential")
+		return nil, errorwrapper.New("authenticator data has no attested credential")
+	}
+	credentialID := base64.RawURLEncoding.EncodeToString(ad.credentialID)
+	if credentialID != credential.ID || credentialID != base64.RawURLEncoding.EncodeToString(credential.RawID) {
+		logger.Error("credential ID doesn't match attested credential ID")
+		return nil, errorwrapper.New("credential ID doesn't match attested credential ID")
+	}
+	if _, _, err := parseCOSEKey(ad.publicKey); err != nil {
+		logger.Error("invalid credential public key", zap.Error(err))
+		return nil, err
+	}
+	existing, err := s.repo.GetHolderPasskeyByCredentialID(ctx, credentialID)
+	if err != nil {
+		logger.Error("failed to get holder passkey by credential ID", zap.Error(err))
+		return nil, err
+	}
+	if existing != nil {
+		logger.Error("passkey is already registered", zap.Int64("holder-passkey-id", existing.ID))
+		return nil, errorwrapper.New("passkey is already registered")
+	}
+
+	pk := &models.HolderPasskey{
+		ID:             s.passkeysIDGenerator.Generate().Int64(),
+		CreatedAt:      time.Now(),
+		LastModifiedAt: time.Now(),
+		HolderID:       holderID,
+		Name:           name,
+		CredentialID:   credentialID,
+		PublicKey:      ad.publicKey,
+		SignCount:      int64(ad.signCount),
+	}
+	if err := s.repo.InsertHolderPasskey(ctx, pk); err != nil {
+		logger.Error("failed to insert holder passkey", zap.Error(err), zap.Int64("holder-passkey-id", pk.ID))
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+func (s *service) BeginLogin(ctx context.Context, logger *zap.Logger) (*RequestOptions, error) {
+	challenge, err := s.createChallenge(ctx, logger, ceremonyGet, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          s.challengeAge.Milliseconds(),
+		RPID:             s.rpID,
+		AllowCredentials: []*CredentialDescriptor{},
+		UserVerification: "required",
+	}, nil
+}
+
+func (s *service) FinishLogin(ctx context.Context, logger *zap.Logger, credential *AssertionCredential) (*models.HolderPasskey, error) {
+	if credential.Type != credentialTypePublicKey {
+		logger.Error("unexpected credential type", zap.String("credential-type", credential.Type))
+		return nil, errorwrapper.New("unexpected credential type")
+	}
+	challenge, err := verifyClientData(credential.Response.ClientDataJSON, ceremonyGet, s.origins)
+	if err != nil {
+		logger.Error("invalid client data", zap.Error(err))
+		return nil, err
+	}
+	if _, err := s.redeemChallenge(ctx, logger, ceremonyGet, challenge); err != nil {
+		return nil, err
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.RawID)
+	pk, err := s.repo.GetHolderPasskeyByCredentialID(ctx, credentialID)
+	if err != nil {
+		logger.Error("failed to get holder passkey by credential ID", zap.Error(err))
+		return nil, err
+	}
+	if pk == nil {
+		logger.Error("unknown passkey", zap.String("credential-id", credentialID))
+		return nil, errorwrapper.New("unknown passkey")
+	}
+	logger = logger.With(zap.Int64("holder-passkey-id", pk.ID), zap.Int64("holder-id", pk.HolderID))
+	if len(credential.Response.UserHandle) > 0 && string(credential.Response.UserHandle) != string(userHandle(pk.HolderID)) {
+		logger.Error("user handle doesn't match passkey holder")
+		return nil, errorwrapper.New("user handle doesn't match passkey holder")
+	}
+
+	ad, err := parseAuthenticatorData(credential.Response.AuthenticatorData)
+	if err != nil {
+		logger.Error("invalid authenticator data", zap.Error(err))
+		return nil, err
+	}
+	if err := ad.verify(s.rpID); err != nil {
+		logger.Error("invalid authenticator data", zap.Error(err))
+		return nil, err
+	}
+	if err := verifyAssertionSignature(pk.PublicKey, credential.Response.AuthenticatorData, credential.Response.ClientDataJSON, credential.Response.Signature); err != nil {
+		logger.Error("invalid assertion signature", zap.Error(err))
+		return nil, err
+	}
+	// authenticators which don't support counters always send 0, otherwise
+	// counter which didn't grow means that the passkey could be cloned
+	signCount := int64(ad.signCount)
+	if (signCount != 0 || pk.SignCount != 0) && signCount <= pk.SignCount {
+		logger.Error("sign counter didn't grow, passkey could be cloned", zap.Int64("sign-count", signCount), zap.Int64("stored-sign-count", pk.SignCount))
+		return nil, errorwrapper.New("sign counter didn't grow")
+	}
+
+	pk.LastModifiedAt = time.Now()
+	pk.SignCount = signCount
+	pk.LastUsedAt = sql.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	if err := s.repo.ModifyHolderPasskey(ctx, pk.ID, pk); err != nil {
+		logger.Error("failed to modify holder passkey", zap.Error(err))
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+func (s *service) GetList(ctx context.Context, logger *zap.Logger, holderID int64) ([]*models.HolderPasskey, error) {
+	passkeys, err := s.repo.GetHolderPasskeysByHolderID(ctx, holderID)
+	if err != nil {
+		logger.Error("failed to get holder passkeys", zap.Error(err), zap.Int64("holder-id", holderID))
+		return nil, err
+	}
+
+	return passkeys, nil
+}
+
+func (s *service) Remove(ctx context.Context, logger *zap.Logger, holderID, id int64) error {
+	logger = logger.With(zap.Int64("holder-id", holderID), zap.Int64("holder-passkey-id", id))
+	pk, err := s.repo.GetHolderPasskeyByID(ctx, id)
+	if err != nil {
+		logger.Error("failed to get holder passkey", zap.Error(err))
+		return err
+	}
+	if pk == nil || pk.HolderID != holderID {
+		logger.Error("holder passkey is not found")
+		return errorwrapper.New("passkey is not found")
+	}
+	if err := s.repo.DeleteHolderPasskey(ctx, id); err != nil {
+		logger.Error("failed to delete holder passkey", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package passkeys
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/samber/lo"
+)
+
+// URLEncodedBase64 is binary value which is encoded as unpadded base64url
+// string in WebAuthn JSON messages.
+type URLEncodedBase64 []byte
+
+func (b URLEncodedBase64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBase64) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	// some clients keep the padding
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+
+	return nil
+}
+
+const (
+	credentialTypePublicKey = "public-key"
+
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+// COSE algorithm identifiers of supported credential public keys.
+const (
+	AlgorithmES256 int64 = -7
+	AlgorithmEdDSA int64 = -8
+	AlgorithmRS256 int64 = -257
+)
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type User struct {
+	ID          URLEncodedBase64 `json:"id"`
+	Name        string           `json:"name"`
+	DisplayName string           `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string           `json:"type"`
+	ID   URLEncodedBase64 `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions is passed to navigator.credentials.create() as publicKey.
+type CreationOptions struct {
+	Challenge              URLEncodedBase64        `json:"challenge"`
+	RP                     *RelyingParty           `json:"rp"`
+	User                   *User                   `json:"user"`
+	PubKeyCredParams       []*CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                   `json:"timeout"`
+	ExcludeCredentials     []*CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection *AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                  `json:"attestation"`
+}
+
+// RequestOptions is passed to navigator.credentials.get() as publicKey.
+type RequestOptions struct {
+	Challenge        URLEncodedBase64        `json:"challenge"`
+	Timeout          int64                   `json:"timeout"`
+	RPID             string                  `json:"rpId"`
+	AllowCredentials []*CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                  `json:"userVerification"`
+}
+
+// RegistrationCredential is PublicKeyCredential returned by
+// navigator.credentials.create().
+type RegistrationCredential struct {
+	ID       string           `json:"id"`
+	RawID    URLEncodedBase64 `json:"rawId"`
+	Type     string           `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+		AttestationObject URLEncodedBase64 `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionCredential is PublicKeyCredential returned by
+// navigator.credentials.get().
+type AssertionCredential struct {
+	ID       string           `json:"id"`
+	RawID    URLEncodedBase64 `json:"rawId"`
+	Type     string           `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+		AuthenticatorData URLEncodedBase64 `json:"authenticatorData"`
+		Signature         URLEncodedBase64 `json:"signature"`
+		UserHandle        URLEncodedBase64 `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// verifyClientData checks type and origin of collected client data and
+// returns the challenge, it is still base64url encoded.
+func verifyClientData(raw []byte, ceremony string, origins []string) (string, error) {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return "", errorwrapper.WrapMessage(err, "invalid client data")
+	}
+	if cd.Type != ceremony {
+		return "", errorwrapper.New("unexpected client data type " + cd.Type)
+	}
+	if !lo.Contains(origins, cd.Origin) {
+		return "", errorwrapper.New("unexpected origin " + cd.Origin)
+	}
+	if cd.Challenge == "" {
+		return "", errorwrapper.New("client data has no challenge")
+	}
+
+	return cd.Challenge, nil
+}
+
+// Authenticator data flags.
+const (
+	flagUserPresent            byte = 0x01
+	flagUserVerified           byte = 0x04
+	flagAttestedCredentialData byte = 0x40
+)
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// credentialID and publicKey are set only if attested credential data is
+	// included, i.e. on registration.
+	credentialID []byte
+	publicKey    []byte
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, errorwrapper.New("authenticator data is too short")
+	}
+	ad := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if ad.flags&flagAttestedCredentialData == 0 {
+		return ad, nil
+	}
+
+	// aaguid (16 bytes) and credential ID length (2 bytes)
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, errorwrapper.New("attested credential data is too short")
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return nil, errorwrapper.New("attested credential data is too short")
+	}
+	ad.credentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// credential public key is followed by extensions, so its length is only
+	// known after decoding
+	_, after, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "invalid credential public key")
+	}
+	ad.publicKey = rest[:len(rest)-len(after)]
+
+	return ad, nil
+}
+
+// verify checks relying party ID hash and user presence and verification
+// flags.
+func (ad *authenticatorData) verify(rpID string) error {
+	expected := sha256.Sum256([]byte(rpID))
+	if !bytes.Equal(ad.rpIDHash, expected[:]) {
+		return errorwrapper.New("relying party ID hash doesn't match")
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return errorwrapper.New("user is not present")
+	}
+	if ad.flags&flagUserVerified == 0 {
+		return errorwrapper.New("user is not verified")
+	}
+
+	return nil
+}
+
+// parseAttestationObject returns authenticator data of attestation object.
+// Attestation statement is not verified, passkeys are requested with "none"
+// attestation conveyance, so authenticator model is not trusted anyway.
+func parseAttestationObject(raw []byte) (*authenticatorData, error) {
+	decoded, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "invalid attestation object")
+	}
+	m, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errorwrapper.New("attestation object is not a map")
+	}
+	authData, ok := m["authData"].([]byte)
+	if !ok {
+		return nil, errorwrapper.New("attestation object has no authenticator data")
+	}
+
+	return parseAuthenticatorData(authData)
+}
+
+// COSE key parameters (RFC 9052, RFC 9053).
+const (
+	coseKeyType      int64 = 1
+	coseKeyAlgorithm int64 = 3
+	coseKeyCurve     int64 = -1
+	coseKeyX         int64 = -2
+	coseKeyY         int64 = -3
+	coseKeyRSAN      int64 = -1
+	coseKeyRSAE      int64 = -2
+
+	coseKeyTypeOKP int64 = 1
+	coseKeyTypeEC2 int64 = 2
+	coseKeyTypeRSA int64 = 3
+
+	coseCurveP256    int64 = 1
+	coseCurveEd25519 int64 = 6
+)
+
+// parseCOSEKey converts COSE encoded credential public key to crypto
+// public key and returns its algorithm.
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int64, error) {
+	decoded, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, 0, errorwrapper.WrapMessage(err, "invalid COSE key")
+	}
+	m, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errorwrapper.New("COSE key is not a map")
+	}
+	kty, _ := m[coseKeyType].(int64)
+	alg, _ := m[coseKeyAlgorithm].(int64)
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == AlgorithmES256:
+		crv, _ := m[coseKeyCurve].(int64)
+		x, _ := m[coseKeyX].([]byte)
+		y, _ := m[coseKeyY].([]byte)
+		if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errorwrapper.New("invalid ES256 COSE key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, errorwrapper.New("invalid ES256 COSE key, point is not on curve")
+		}
+		return pub, alg, nil
+	case kty == coseKeyTypeOKP && alg == AlgorithmEdDSA:
+		crv, _ := m[coseKeyCurve].(int64)
+		x, _ := m[coseKeyX].([]byte)
+		if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errorwrapper.New("invalid EdDSA COSE key")
+		}
+		return ed25519.PublicKey(x), alg, nil
+	case kty == coseKeyTypeRSA && alg == AlgorithmRS256:
+		n, _ := m[coseKeyRSAN].([]byte)
+		e, _ := m[coseKeyRSAE].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errorwrapper.New("invalid RS256 COSE key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, alg, nil
+	}
+
+	return nil, 0, errorwrapper.New("unsupported COSE key")
+}
+
+// verifyAssertionSignature checks signature over authenticator data and hash
+// of client data.
+func verifyAssertionSignature(publicKey []byte, authData, clientDataJSON, signature []byte) error {
+	pub, alg, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	digest := sha256.Sum256(signed)
+
+	var ok bool
+	switch alg {
+	case AlgorithmES256:
+		ok = ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], signature)
+	case AlgorithmEdDSA:
+		ok = ed25519.Verify(pub.(ed25519.PublicKey), signed, signature)
+	case AlgorithmRS256:
+		ok = rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+	if !ok {
+		return errorwrapper.New("invalid assertion signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,574 @@
+package passkeys
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	idgenerator "github.com/ecumenos-social/id-generator"
+	"github.com/ecumenos-social/network-warden/models"
+	"go.uber.org/zap"
+)
+
+const (
+	testRPID   = "ecumenos.example"
+	testOrigin = "https://ecumenos.example"
+)
+
+// fakeRepository keeps rows in memory, challenges are redeemed under lock
+// the same way the database redeems them in a single update.
+type fakeRepository struct {
+	mu         sync.Mutex
+	passkeys   map[int64]models.HolderPasskey
+	challenges map[int64]models.WebAuthnChallenge
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		passkeys:   make(map[int64]models.HolderPasskey),
+		challenges: make(map[int64]models.WebAuthnChallenge),
+	}
+}
+
+func (r *fakeRepository) InsertHolderPasskey(_ context.Context, pk *models.HolderPasskey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.passkeys[pk.ID] = *pk
+	return nil
+}
+
+func (r *fakeRepository) ModifyHolderPasskey(_ context.Context, id int64, pk *models.HolderPasskey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.passkeys[id] = *pk
+	return nil
+}
+
+func (r *fakeRepository) DeleteHolderPasskey(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.passkeys, id)
+	return nil
+}
+
+func (r *fakeRepository) GetHolderPasskeyByID(_ context.Context, id int64) (*models.HolderPasskey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pk, ok := r.passkeys[id]
+	if !ok {
+		return nil, nil
+	}
+	return &pk, nil
+}
+
+func (r *fakeRepository) GetHolderPasskeyByCredentialID(_ context.Context, credentialID string) (*models.HolderPasskey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, pk := range r.passkeys {
+		if pk.CredentialID == credentialID {
+			return &pk, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) GetHolderPasskeysByHolderID(_ context.Context, holderID int64) ([]*models.HolderPasskey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*models.HolderPasskey
+	for _, pk := range r.passkeys {
+		if pk.HolderID == holderID {
+			pk := pk
+			out = append(out, &pk)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRepository) InsertWebAuthnChallenge(_ context.Context, wc *models.WebAuthnChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.challenges[wc.ID] = *wc
+	return nil
+}
+
+func (r *fakeRepository) RedeemWebAuthnChallenge(_ context.Context, challengeHash, ceremony string, usedAt time.Time) (*models.WebAuthnChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, wc := range r.challenges {
+		if wc.ChallengeHash != challengeHash || wc.Ceremony != ceremony || wc.UsedAt.Valid || !wc.ExpiredAt.After(usedAt) {
+			continue
+		}
+		wc.LastModifiedAt = usedAt
+		wc.UsedAt.Time, wc.UsedAt.Valid = usedAt, true
+		r.challenges[id] = wc
+		return &wc, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) GetWebAuthnChallengeByChallengeHash(_ context.Context, challengeHash string) (*models.WebAuthnChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, wc := range r.challenges {
+		if wc.ChallengeHash == challengeHash {
+			return &wc, nil
+		}
+	}
+	return nil, nil
+}
+
+// expireChallenges moves expiration of every issued challenge to the past.
+func (r *fakeRepository) expireChallenges() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, wc := range r.challenges {
+		wc.ExpiredAt = time.Now().Add(-time.Second)
+		r.challenges[id] = wc
+	}
+}
+
+func newTestService(t *testing.T) (Service, *fakeRepository) {
+	t.Helper()
+	g, err := idgenerator.New(&idgenerator.NodeID{Top: 1, Low: 1})
+	if err != nil {
+		t.Fatalf("failed to create ID generator: %v", err)
+	}
+	repo := newFakeRepository()
+	s := New(&Config{
+		RPID:         testRPID,
+		RPName:       "Ecumenos",
+		Origins:      []string{testOrigin},
+		ChallengeAge: time.Minute,
+	}, repo, g, g)
+
+	return s, repo
+}
+
+// cborPair is entry of CBOR map, maps are encoded in the order of entries.
+type cborPair struct {
+	key   interface{}
+	value interface{}
+}
+
+func encodeCBORHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+}
+
+// encodeCBOR encodes the subset of CBOR which authenticators produce.
+func encodeCBOR(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return encodeCBORHead(1, uint64(-1-v))
+		}
+		return encodeCBORHead(0, uint64(v))
+	case []byte:
+		return append(encodeCBORHead(2, uint64(len(v))), v...)
+	case string:
+		return append(encodeCBORHead(3, uint64(len(v))), v...)
+	case []cborPair:
+		out := encodeCBORHead(5, uint64(len(v)))
+		for _, p := range v {
+			out = append(out, encodeCBOR(t, p.key)...)
+			out = append(out, encodeCBOR(t, p.value)...)
+		}
+		return out
+	}
+	t.Fatalf("unsupported CBOR value %T", value)
+	return nil
+}
+
+// testAuthenticator is a software authenticator with ES256 or EdDSA key.
+type testAuthenticator struct {
+	t            *testing.T
+	credentialID []byte
+	ecdsaKey     *ecdsa.PrivateKey
+	ed25519Key   ed25519.PrivateKey
+	signCount    uint32
+	// counterless authenticator reports 0 sign count on every ceremony
+	counterless bool
+	origin      string
+	rpID        string
+}
+
+func newTestAuthenticator(t *testing.T, alg int64) *testAuthenticator {
+	t.Helper()
+	a := &testAuthenticator{t: t, credentialID: make([]byte, 16), origin: testOrigin, rpID: testRPID}
+	if _, err := rand.Read(a.credentialID); err != nil {
+		t.Fatalf("failed to generate credential ID: %v", err)
+	}
+	var err error
+	switch alg {
+	case AlgorithmES256:
+		a.ecdsaKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, a.ed25519Key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("unsupported algorithm %d", alg)
+	}
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	return a
+}
+
+func (a *testAuthenticator) coseKey() []byte {
+	if a.ecdsaKey != nil {
+		return encodeCBOR(a.t, []cborPair{
+			{coseKeyType, coseKeyTypeEC2},
+			{coseKeyAlgorithm, AlgorithmES256},
+			{coseKeyCurve, coseCurveP256},
+			{coseKeyX, a.ecdsaKey.X.FillBytes(make([]byte, 32))},
+			{coseKeyY, a.ecdsaKey.Y.FillBytes(make([]byte, 32))},
+		})
+	}
+	return encodeCBOR(a.t, []cborPair{
+		{coseKeyType, coseKeyTypeOKP},
+		{coseKeyAlgorithm, AlgorithmEdDSA},
+		{coseKeyCurve, coseCurveEd25519},
+		{coseKeyX, []byte(a.ed25519Key.Public().(ed25519.PublicKey))},
+	})
+}
+
+func (a *testAuthenticator) authenticatorData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	flags := flagUserPresent | flagUserVerified
+	if attested {
+		flags |= flagAttestedCredentialData
+	}
+	out := append(rpIDHash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, a.signCount)
+	if attested {
+		out = append(out, make([]byte, 16)...)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(a.credentialID)))
+		out = append(out, a.credentialID...)
+		out = append(out, a.coseKey()...)
+	}
+
+	return out
+}
+
+func (a *testAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	raw, err := json.Marshal(&clientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    a.origin,
+	})
+	if err != nil {
+		a.t.Fatalf("failed to marshal client data: %v", err)
+	}
+
+	return raw
+}
+
+func (a *testAuthenticator) count() {
+	if !a.counterless {
+		a.signCount++
+	}
+}
+
+func (a *testAuthenticator) register(options *CreationOptions) *RegistrationCredential {
+	a.count()
+	credential := &RegistrationCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  credentialTypePublicKey,
+	}
+	credential.Response.ClientDataJSON = a.clientData(ceremonyCreate, options.Challenge)
+	credential.Response.AttestationObject = encodeCBOR(a.t, []cborPair{
+		{"fmt", "none"},
+		{"attStmt", []cborPair{}},
+		{"authData", a.authenticatorData(true)},
+	})
+
+	return credential
+}
+
+func (a *testAuthenticator) assert(options *RequestOptions, holderID int64) *AssertionCredential {
+	a.count()
+	credential := &AssertionCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  credentialTypePublicKey,
+	}
+	authData := a.authenticatorData(false)
+	clientDataJSON := a.clientData(ceremonyGet, options.Challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	var signature []byte
+	if a.ecdsaKey != nil {
+		digest := sha256.Sum256(signed)
+		var err error
+		signature, err = ecdsa.SignASN1(rand.Reader, a.ecdsaKey, digest[:])
+		if err != nil {
+			a.t.Fatalf("failed to sign assertion: %v", err)
+		}
+	} else {
+		signature = ed25519.Sign(a.ed25519Key, signed)
+	}
+	credential.Response.ClientDataJSON = clientDataJSON
+	credential.Response.AuthenticatorData = authData
+	credential.Response.Signature = signature
+	credential.Response.UserHandle = userHandle(holderID)
+
+	return credential
+}
+
+const testHolderID int64 = 42
+
+// registerPasskey runs registration ceremony of authenticator for
+// testHolderID.
+func registerPasskey(t *testing.T, s Service, a *testAuthenticator) *models.HolderPasskey {
+	t.Helper()
+	ctx, logger := context.Background(), zap.NewNop()
+	options, err := s.BeginRegistration(ctx, logger, testHolderID, "holder", "Holder")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	pk, err := s.FinishRegistration(ctx, logger, testHolderID, "laptop", a.register(options))
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+
+	return pk
+}
+
+func beginLogin(t *testing.T, s Service) *RequestOptions {
+	t.Helper()
+	options, err := s.BeginLogin(context.Background(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+
+	return options
+}
+
+func assertErrorContains(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("error = nil, want error containing %q", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("error = %v, want error containing %q", err, want)
+	}
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	algorithms := []struct {
+		name string
+		alg  int64
+	}{
+		{name: "ES256", alg: AlgorithmES256},
+		{name: "EdDSA", alg: AlgorithmEdDSA},
+	}
+	for _, tt := range algorithms {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t)
+			a := newTestAuthenticator(t, tt.alg)
+			registered := registerPasskey(t, s, a)
+			if registered.HolderID != testHolderID || registered.CredentialID != base64.RawURLEncoding.EncodeToString(a.credentialID) {
+				t.Fatalf("FinishRegistration() = %+v, unexpected holder or credential ID", registered)
+			}
+
+			options := beginLogin(t, s)
+			if len(options.AllowCredentials) != 0 {
+				t.Fatalf("BeginLogin() allows credentials %v, want discoverable credentials only", options.AllowCredentials)
+			}
+			pk, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(options, testHolderID))
+			if err != nil {
+				t.Fatalf("FinishLogin() error = %v", err)
+			}
+			if pk.ID != registered.ID || pk.SignCount != int64(a.signCount) || !pk.LastUsedAt.Valid {
+				t.Fatalf("FinishLogin() = %+v, want passkey %d with sign count %d", pk, registered.ID, a.signCount)
+			}
+		})
+	}
+}
+
+func TestRegistrationRejectsWrongOrigin(t *testing.T) {
+	s, _ := newTestService(t)
+	a := newTestAuthenticator(t, AlgorithmES256)
+	ctx, logger := context.Background(), zap.NewNop()
+	options, err := s.BeginRegistration(ctx, logger, testHolderID, "holder", "Holder")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+
+	a.origin = "https://evil.example"
+	_, err = s.FinishRegistration(ctx, logger, testHolderID, "laptop", a.register(options))
+	assertErrorContains(t, err, "unexpected origin")
+
+	// challenge is not spent by ceremony from foreign origin
+	a.origin = testOrigin
+	if _, err := s.FinishRegistration(ctx, logger, testHolderID, "laptop", a.register(options)); err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+}
+
+func TestAssertionRejections(t *testing.T) {
+	tests := []struct {
+		name string
+		// run finishes login of registered authenticator and returns the
+		// error of the last ceremony
+		run  func(t *testing.T, s Service, repo *fakeRepository, a *testAuthenticator) error
+		want string
+	}{
+		{
+			name: "wrong origin",
+			run: func(t *testing.T, s Service, _ *fakeRepository, a *testAuthenticator) error {
+				a.origin = "https://evil.example"
+				_, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(beginLogin(t, s), testHolderID))
+				return err
+			},
+			want: "unexpected origin",
+		},
+		{
+			name: "wrong relying party",
+			run: func(t *testing.T, s Service, _ *fakeRepository, a *testAuthenticator) error {
+				a.rpID = "evil.example"
+				_, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(beginLogin(t, s), testHolderID))
+				return err
+			},
+			want: "relying party ID hash doesn't match",
+		},
+		{
+			name: "reused challenge",
+			run: func(t *testing.T, s Service, _ *fakeRepository, a *testAuthenticator) error {
+				options := beginLogin(t, s)
+				if _, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(options, testHolderID)); err != nil {
+					t.Fatalf("FinishLogin() error = %v", err)
+				}
+				_, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(options, testHolderID))
+				return err
+			},
+			want: "challenge was already used",
+		},
+		{
+			name: "registration challenge",
+			run: func(t *testing.T, s Service, _ *fakeRepository, a *testAuthenticator) error {
+				creation, err := s.BeginRegistration(context.Background(), zap.NewNop(), testHolderID, "holder", "Holder")
+				if err != nil {
+					t.Fatalf("BeginRegistration() error = %v", err)
+				}
+				_, err = s.FinishLogin(context.Background(), zap.NewNop(), a.assert(&RequestOptions{Challenge: creation.Challenge}, testHolderID))
+				return err
+			},
+			want: "invalid challenge",
+		},
+		{
+			name: "expired challenge",
+			run: func(t *testing.T, s Service, repo *fakeRepository, a *testAuthenticator) error {
+				options := beginLogin(t, s)
+				repo.expireChallenges()
+				_, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(options, testHolderID))
+				return err
+			},
+			want: "challenge was expired",
+		},
+		{
+			name: "same sign counter",
+			run: func(t *testing.T, s Service, _ *fakeRepository, a *testAuthenticator) error {
+				a.signCount--
+				_, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(beginLogin(t, s), testHolderID))
+				return err
+			},
+			want: "sign counter didn't grow",
+		},
+		{
+			name: "decreased sign counter",
+			run: func(t *testing.T, s Service, _ *fakeRepository, a *testAuthenticator) error {
+				if _, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(beginLogin(t, s), testHolderID)); err != nil {
+					t.Fatalf("FinishLogin() error = %v", err)
+				}
+				// clone keeps counter of the moment it was copied
+				a.signCount -= 2
+				_, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(beginLogin(t, s), testHolderID))
+				return err
+			},
+			want: "sign counter didn't grow",
+		},
+		{
+			name: "foreign signature",
+			run: func(t *testing.T, s Service, _ *fakeRepository, a *testAuthenticator) error {
+				other := newTestAuthenticator(t, AlgorithmES256)
+				other.credentialID = a.credentialID
+				_, err := s.FinishLogin(context.Background(), zap.NewNop(), other.assert(beginLogin(t, s), testHolderID))
+				return err
+			},
+			want: "invalid assertion signature",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newTestService(t)
+			a := newTestAuthenticator(t, AlgorithmES256)
+			registerPasskey(t, s, a)
+			assertErrorContains(t, tt.run(t, s, repo, a), tt.want)
+		})
+	}
+}
+
+func TestAssertionAcceptsZeroSignCounter(t *testing.T) {
+	s, _ := newTestService(t)
+	a := newTestAuthenticator(t, AlgorithmEdDSA)
+	a.counterless = true
+	registerPasskey(t, s, a)
+	for i := 0; i < 2; i++ {
+		if _, err := s.FinishLogin(context.Background(), zap.NewNop(), a.assert(beginLogin(t, s), testHolderID)); err != nil {
+			t.Fatalf("FinishLogin() error = %v", err)
+		}
+	}
+}
+
+func TestConcurrentAssertionsRedeemChallengeOnce(t *testing.T) {
+	s, _ := newTestService(t)
+	a := newTestAuthenticator(t, AlgorithmES256)
+	registerPasskey(t, s, a)
+	credential := a.assert(beginLogin(t, s), testHolderID)
+
+	const attempts = 8
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.FinishLogin(context.Background(), zap.NewNop(), credential); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("%d of %d concurrent assertions succeeded, want 1", succeeded, attempts)
+	}
+}
@@ -0,0 +1,114 @@
+package passwordresets
+
+import (
+	"context"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/idgenerators"
+	"github.com/ecumenos-social/toolkit/hash"
+	"github.com/ecumenos-social/toolkit/random"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	CodeAge time.Duration
+}
+
+type Repository interface {
+	InsertHolderPasswordReset(ctx context.Context, pr *models.HolderPasswordReset) error
+	RedeemHolderPasswordReset(ctx context.Context, holderID int64, codeHash string, usedAt time.Time) (*models.HolderPasswordReset, error)
+	GetUnusedHolderPasswordReset(ctx context.Context, holderID int64, codeHash string) (*models.HolderPasswordReset, error)
+}
+
+type Service interface {
+	Issue(ctx context.Context, logger *zap.Logger, holderID int64) (pr *models.HolderPasswordReset, code string, err error)
+	// Verify checks password reset code without using it up.
+	Verify(ctx context.Context, logger *zap.Logger, holderID int64, code string) error
+	Redeem(ctx context.Context, logger *zap.Logger, holderID int64, code string) error
+}
+
+type service struct {
+	codeAge     time.Duration
+	repo        Repository
+	idgenerator idgenerators.HolderPasswordResetsIDGenerator
+}
+
+func New(config *Config, repo Repository, g idgenerators.HolderPasswordResetsIDGenerator) Service {
+	return &service{
+		codeAge:     config.CodeAge,
+		repo:        repo,
+		idgenerator: g,
+	}
+}
+
+func generateCode() string {
+	return random.GenNumericString(10)
+}
+
+func hashCode(code string) string {
+	return hash.SHA256(code)
+}
+
+func (s *service) Issue(ctx context.Context, logger *zap.Logger, holderID int64) (*models.HolderPasswordReset, string, error) {
+	id := s.idgenerator.Generate().Int64()
+	logger = logger.With(
+		zap.Int64("holder-password-reset-id", id),
+		zap.Int64("holder-id", holderID),
+	)
+	code := generateCode()
+	pr := &models.HolderPasswordReset{
+		ID:             id,
+		CreatedAt:      time.Now(),
+		LastModifiedAt: time.Now(),
+		HolderID:       holderID,
+		CodeHash:       hashCode(code),
+		ExpiredAt:      time.Now().Add(s.codeAge),
+	}
+	if err := s.repo.InsertHolderPasswordReset(ctx, pr); err != nil {
+		logger.Error("failed to insert holder password reset", zap.Error(err))
+		return nil, "", err
+	}
+
+	return pr, code, nil
+}
+
+func (s *service) Verify(ctx context.Context, logger *zap.Logger, holderID int64, code string) error {
+	logger = logger.With(zap.Int64("holder-id", holderID))
+	pr, err := s.repo.GetUnusedHolderPasswordReset(ctx, holderID, hashCode(code))
+	if err != nil {
+		logger.Error("failed to get holder password reset", zap.Error(err))
+		return err
+	}
+	if pr == nil {
+		logger.Error("invalid password reset code")
+		return errorwrapper.New("invalid password reset code")
+	}
+	if pr.ExpiredAt.Before(time.Now()) {
+		logger.Error("password reset code was expired", zap.Int64("holder-password-reset-id", pr.ID), zap.Time("expired-at", pr.ExpiredAt))
+		return errorwrapper.New("password reset code was expired")
+	}
+
+	return nil
+}
+
+// Redeem uses password reset code up. The code is checked and marked as used
+// by one statement, so concurrent requests can't redeem the same code twice.
+func (s *service) Redeem(ctx context.Context, logger *zap.Logger, holderID int64, code string) error {
+	pr, err := s.repo.RedeemHolderPasswordReset(ctx, holderID, hashCode(code), time.Now())
+	if err != nil {
+		logger.Error("failed to redeem holder password reset", zap.Int64("holder-id", holderID), zap.Error(err))
+		return err
+	}
+	if pr == nil {
+		// the lookup only tells why the code was rejected
+		if err := s.Verify(ctx, logger, holderID, code); err != nil {
+			return err
+		}
+		logger.Error("password reset code was already used", zap.Int64("holder-id", holderID))
+		return errorwrapper.New("password reset code was already used")
+	}
+
+	return nil
+}
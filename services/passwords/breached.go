@@ -0,0 +1,196 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+)
+
+const (
+	sha1HexLength   = 40
+	rangePrefixSize = 5
+)
+
+// BreachedPasswords is a corpus of SHA-1 hashes of breached passwords. It is
+// grouped by 5 character hash prefix the same way k-anonymity range API of
+// Have I Been Pwned is, so the corpus is a plain concatenation of range
+// responses.
+//
+// The corpus is too big to be kept in memory, it stays on disk and every
+// check binary searches the file for the range of password's hash prefix and
+// reads only that range.
+type BreachedPasswords struct {
+	f    *os.File
+	size int64
+}
+
+// OpenBreachedPasswords opens corpus file. Every line is either
+// "<SHA-1>:<count>" or, after a "<prefix>" header line, "<suffix>:<count>"
+// as it is returned by range API. Lines must be sorted by hash, prefixes of
+// range headers included. Hashes are case-insensitive, empty lines and lines
+// starting with "#" are skipped.
+func OpenBreachedPasswords(path string) (*BreachedPasswords, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "failed to open breached passwords file")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errorwrapper.WrapMessage(err, "failed to stat breached passwords file")
+	}
+	bp := &BreachedPasswords{f: f, size: info.Size()}
+	// the first entry is read to reject files of other formats at start
+	if _, err := bp.firstPrefixFrom(0); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return bp, nil
+}
+
+// Close closes corpus file.
+func (bp *BreachedPasswords) Close() error {
+	return bp.f.Close()
+}
+
+// Contains reports whether password is in the corpus.
+func (bp *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	return bp.containsHash(strings.ToUpper(hex.EncodeToString(sum[:])))
+}
+
+// containsHash reports whether upper case SHA-1 hex hash is in the corpus.
+func (bp *BreachedPasswords) containsHash(h string) (bool, error) {
+	prefix, suffix := h[:rangePrefixSize], h[rangePrefixSize:]
+
+	// the smallest offset from which the first prefixed line has prefix which
+	// is not less than the searched one
+	lo, hi := int64(0), bp.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		p, err := bp.firstPrefixFrom(mid)
+		if err != nil {
+			return false, err
+		}
+		if p == "" || p >= prefix {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	r := bp.linesFrom(lo)
+	var inRange bool
+	for {
+		value, err := r.next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch len(value) {
+		case rangePrefixSize:
+			if value > prefix {
+				return false, nil
+			}
+			inRange = value == prefix
+		case sha1HexLength:
+			if value[:rangePrefixSize] > prefix {
+				return false, nil
+			}
+			if value == h {
+				return true, nil
+			}
+		case sha1HexLength - rangePrefixSize:
+			if inRange && value == suffix {
+				return true, nil
+			}
+		}
+	}
+}
+
+// firstPrefixFrom returns hash prefix of the first range header or full hash
+// line which starts at offset or after it, suffix lines are skipped. It
+// returns empty string if there is no such line.
+func (bp *BreachedPasswords) firstPrefixFrom(offset int64) (string, error) {
+	r := bp.linesFrom(offset)
+	for {
+		value, err := r.next()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(value) == rangePrefixSize || len(value) == sha1HexLength {
+			return value[:rangePrefixSize], nil
+		}
+	}
+}
+
+// linesFrom reads lines which start at offset or after it, the line which
+// offset points into the middle of is skipped.
+func (bp *BreachedPasswords) linesFrom(offset int64) *breachedLines {
+	r := &breachedLines{}
+	if offset == 0 {
+		r.reader = bufio.NewReader(io.NewSectionReader(bp.f, 0, bp.size))
+		return r
+	}
+	r.reader = bufio.NewReader(io.NewSectionReader(bp.f, offset-1, bp.size-offset+1))
+	r.skipLine = true
+
+	return r
+}
+
+type breachedLines struct {
+	reader   *bufio.Reader
+	skipLine bool
+}
+
+// next returns the next hash, prefix or suffix in upper case without count.
+func (r *breachedLines) next() (string, error) {
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return "", io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return "", errorwrapper.WrapMessage(err, "failed to read breached passwords file")
+		}
+		if r.skipLine {
+			r.skipLine = false
+			continue
+		}
+		value := strings.TrimSpace(line)
+		if value == "" || strings.HasPrefix(value, "#") {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ":")
+		value = strings.ToUpper(value)
+		if _, err := hex.DecodeString(padHex(value)); err != nil {
+			return "", errorwrapper.New(fmt.Sprintf("invalid hash in breached passwords file, hash = %v", value))
+		}
+		switch len(value) {
+		case rangePrefixSize, sha1HexLength, sha1HexLength - rangePrefixSize:
+			return value, nil
+		}
+		return "", errorwrapper.New(fmt.Sprintf("invalid hash in breached passwords file, hash = %v", value))
+	}
+}
+
+// padHex makes odd length prefixes decodable, so they can be validated with
+// hex.DecodeString.
+func padHex(value string) string {
+	if len(value)%2 == 1 {
+		return value + "0"
+	}
+	return value
+}
@@ -0,0 +1,229 @@
+package passwords
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// hashOf fills hash prefix up to full SHA-1 hex length, so hashes next to
+// range boundaries can be written by hand.
+func hashOf(prefix string, fill string) string {
+	return prefix + strings.Repeat(fill, sha1HexLength-len(prefix))
+}
+
+func writeCorpus(t *testing.T, lines []string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatalf("failed to write corpus: %v", err)
+	}
+
+	return path
+}
+
+func openCorpus(t *testing.T, lines []string) *BreachedPasswords {
+	t.Helper()
+	bp, err := OpenBreachedPasswords(writeCorpus(t, lines))
+	if err != nil {
+		t.Fatalf("failed to open corpus: %v", err)
+	}
+	t.Cleanup(func() { bp.Close() })
+
+	return bp
+}
+
+// testCorpus mixes range responses with full hash lines, lower case hashes,
+// comments, empty lines and CRLF line endings. The first and the last
+// possible prefixes are ranges.
+var testCorpus = []string{
+	"# breached passwords",
+	"00000",
+	strings.Repeat("A", 35) + ":1",
+	strings.Repeat("C", 35) + ":2\r",
+	hashOf("00001", "B") + ":3",
+	"00002",
+	strings.Repeat("1", 35) + ":4",
+	strings.Repeat("f", 35) + ":5",
+	"",
+	strings.ToLower(hashOf("3A3A3", "0")) + ":6",
+	"5BAA6\r",
+	"1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r",
+	"# full hashes and ranges interleave",
+	"7FFFF",
+	strings.Repeat("0", 35) + ":1",
+	strings.Repeat("9", 35) + ":1",
+	hashOf("80000", "D") + ":7",
+	"FFFFF",
+	strings.Repeat("0", 35) + ":1",
+	strings.Repeat("F", 35) + ":1",
+}
+
+func TestBreachedPasswordsContainsHash(t *testing.T) {
+	bp := openCorpus(t, testCorpus)
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "first prefix, first suffix", hash: hashOf("00000", "A"), want: true},
+		{name: "first prefix, last suffix", hash: hashOf("00000", "C"), want: true},
+		{name: "first prefix, below first suffix", hash: hashOf("00000", "0")},
+		{name: "first prefix, between suffixes", hash: hashOf("00000", "B")},
+		{name: "first prefix, above last suffix", hash: hashOf("00000", "D")},
+		{name: "full hash after range", hash: hashOf("00001", "B"), want: true},
+		{name: "full hash prefix, other hash", hash: hashOf("00001", "C")},
+		{name: "lower case suffix", hash: hashOf("00002", "F"), want: true},
+		{name: "prefix between ranges", hash: hashOf("00003", "1")},
+		{name: "lower case full hash", hash: hashOf("3A3A3", "0"), want: true},
+		{name: "CRLF range", hash: "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", want: true},
+		{name: "range before full hash, first suffix", hash: hashOf("7FFFF", "0"), want: true},
+		{name: "range before full hash, last suffix", hash: hashOf("7FFFF", "9"), want: true},
+		{name: "range before full hash, miss", hash: hashOf("7FFFF", "5")},
+		{name: "full hash after range of previous prefix", hash: hashOf("80000", "D"), want: true},
+		{name: "suffix of previous range with other prefix", hash: hashOf("80000", "9")},
+		{name: "last prefix, first suffix", hash: hashOf("FFFFF", "0"), want: true},
+		{name: "last prefix, last suffix", hash: hashOf("FFFFF", "F"), want: true},
+		{name: "last prefix, miss", hash: hashOf("FFFFF", "8")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bp.containsHash(tt.hash)
+			if err != nil {
+				t.Fatalf("containsHash(%s) error = %v", tt.hash, err)
+			}
+			if got != tt.want {
+				t.Fatalf("containsHash(%s) = %v, want %v", tt.hash, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreachedPasswordsContains(t *testing.T) {
+	bp := openCorpus(t, testCorpus)
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{password: "password", want: true},
+		{password: "correct horse battery staple"},
+	}
+	for _, tt := range tests {
+		got, err := bp.Contains(tt.password)
+		if err != nil {
+			t.Fatalf("Contains(%q) error = %v", tt.password, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+// TestBreachedPasswordsContainsHashRandomCorpus checks the search against
+// bigger corpus, so it lands in the middle of lines of every kind.
+func TestBreachedPasswordsContainsHashRandomCorpus(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	// only 256 prefixes are used, so ranges have several suffixes and misses
+	// mostly fall into existing ranges
+	randomHash := func() string {
+		const digits = "0123456789ABCDEF"
+		b := make([]byte, sha1HexLength)
+		for i := range b {
+			b[i] = digits[rnd.Intn(len(digits))]
+		}
+		copy(b[2:rangePrefixSize], "000")
+		return string(b)
+	}
+
+	byPrefix := make(map[string][]string)
+	present := make(map[string]bool)
+	for i := 0; i < 3000; i++ {
+		h := randomHash()
+		byPrefix[h[:rangePrefixSize]] = append(byPrefix[h[:rangePrefixSize]], h)
+		present[h] = true
+	}
+	prefixes := make([]string, 0, len(byPrefix))
+	for p := range byPrefix {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+
+	var lines []string
+	for i, p := range prefixes {
+		hashes := byPrefix[p]
+		sort.Strings(hashes)
+		if i%2 == 0 {
+			lines = append(lines, p)
+			for _, h := range hashes {
+				lines = append(lines, fmt.Sprintf("%s:%d", h[rangePrefixSize:], rnd.Intn(1000)+1))
+			}
+			continue
+		}
+		for _, h := range hashes {
+			lines = append(lines, fmt.Sprintf("%s:%d", h, rnd.Intn(1000)+1))
+		}
+	}
+	bp := openCorpus(t, lines)
+
+	for h := range present {
+		got, err := bp.containsHash(h)
+		if err != nil {
+			t.Fatalf("containsHash(%s) error = %v", h, err)
+		}
+		if !got {
+			t.Fatalf("containsHash(%s) = false, want true", h)
+		}
+	}
+	for i := 0; i < 3000; i++ {
+		h := randomHash()
+		got, err := bp.containsHash(h)
+		if err != nil {
+			t.Fatalf("containsHash(%s) error = %v", h, err)
+		}
+		if got != present[h] {
+			t.Fatalf("containsHash(%s) = %v, want %v", h, got, present[h])
+		}
+	}
+}
+
+func TestOpenBreachedPasswordsRejectsMalformedFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{name: "plain text passwords", lines: []string{"password", "123456"}},
+		{name: "not hex", lines: []string{"ZZZZZ", strings.Repeat("A", 35) + ":1"}},
+		{name: "hash of other length", lines: []string{"0123456789:1"}},
+		{name: "SHA-256 hash", lines: []string{strings.Repeat("A", 64) + ":1"}},
+		{name: "invalid line after comment", lines: []string{"# header", "", "password:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bp, err := OpenBreachedPasswords(writeCorpus(t, tt.lines))
+			if err == nil {
+				bp.Close()
+				t.Fatal("OpenBreachedPasswords() error = nil, want error")
+			}
+		})
+	}
+
+	if _, err := OpenBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("OpenBreachedPasswords() of missing file error = nil, want error")
+	}
+}
+
+func TestBreachedPasswordsContainsHashMalformedRange(t *testing.T) {
+	bp := openCorpus(t, []string{
+		"00000",
+		strings.Repeat("A", 35) + ":1",
+		"7FFFF",
+		"not a hash",
+	})
+	if _, err := bp.containsHash(hashOf("7FFFF", "0")); err == nil {
+		t.Fatal("containsHash() error = nil, want error")
+	}
+}
@@ -0,0 +1,186 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+type HasherConfig struct {
+	// Algorithm is used for new hashes, hashes of every supported algorithm
+	// are still verified.
+	Algorithm string
+	// Argon2idMemory is memory cost in KiB.
+	Argon2idMemory      uint32
+	Argon2idIterations  uint32
+	Argon2idParallelism uint8
+	BcryptCost          int
+}
+
+// Hasher hashes passwords with configured strategy. Holders and admins share
+// it, so both are upgraded the same way once configuration changes.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded hash. needsRehash is
+	// true if hash was produced by another algorithm or with other cost
+	// parameters than configured, so it should be replaced by Hash(password).
+	Verify(password, encoded string) (ok, needsRehash bool)
+}
+
+// strategy is a single hashing algorithm.
+type strategy interface {
+	hash(password string) (string, error)
+	recognizes(encoded string) bool
+	verify(password, encoded string) (ok, outdated bool)
+}
+
+type hasher struct {
+	primary    strategy
+	strategies []strategy
+}
+
+func NewHasher(config *HasherConfig) (Hasher, error) {
+	a := &argon2idStrategy{
+		memory:      config.Argon2idMemory,
+		iterations:  config.Argon2idIterations,
+		parallelism: config.Argon2idParallelism,
+	}
+	b := &bcryptStrategy{cost: config.BcryptCost}
+
+	switch config.Algorithm {
+	case AlgorithmArgon2id:
+		if a.memory == 0 || a.iterations == 0 || a.parallelism == 0 {
+			return nil, errorwrapper.New("argon2id cost parameters must be positive")
+		}
+		return &hasher{primary: a, strategies: []strategy{a, b}}, nil
+	case AlgorithmBcrypt:
+		if b.cost < bcrypt.MinCost || b.cost > bcrypt.MaxCost {
+			return nil, errorwrapper.New(fmt.Sprintf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+		}
+		return &hasher{primary: b, strategies: []strategy{b, a}}, nil
+	}
+
+	return nil, errorwrapper.New("unsupported password hashing algorithm " + config.Algorithm)
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	return h.primary.hash(password)
+}
+
+func (h *hasher) Verify(password, encoded string) (bool, bool) {
+	for _, s := range h.strategies {
+		if !s.recognizes(encoded) {
+			continue
+		}
+		ok, outdated := s.verify(password, encoded)
+		if !ok {
+			return false, false
+		}
+		return true, outdated || s != h.primary
+	}
+
+	return false, false
+}
+
+const (
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+type argon2idStrategy struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// hash returns PHC string like "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>".
+func (s *argon2idStrategy) hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, s.iterations, s.memory, s.parallelism, argon2idKeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		s.memory,
+		s.iterations,
+		s.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (s *argon2idStrategy) recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (s *argon2idStrategy) verify(password, encoded string) (bool, bool) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	var (
+		memory, iterations uint32
+		parallelism        uint8
+	)
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(actual, key) != 1 {
+		return false, false
+	}
+	outdated := memory != s.memory || iterations != s.iterations || parallelism != s.parallelism ||
+		len(salt) != argon2idSaltLength || len(key) != argon2idKeyLength
+
+	return true, outdated
+}
+
+// bcryptStrategy keeps hashes produced by toolkit/hash.Hash valid.
+type bcryptStrategy struct {
+	cost int
+}
+
+func (s *bcryptStrategy) hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	return string(bytes), err
+}
+
+func (s *bcryptStrategy) recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (s *bcryptStrategy) verify(password, encoded string) (bool, bool) {
+	if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+
+	return true, err != nil || cost != s.cost
+}
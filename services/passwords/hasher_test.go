@@ -0,0 +1,201 @@
+package passwords
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters keep the tests fast, they are never used outside of them
+const (
+	testArgon2idMemory      uint32 = 64
+	testArgon2idIterations  uint32 = 1
+	testArgon2idParallelism uint8  = 1
+)
+
+func newTestHasher(t *testing.T, algorithm string, change func(*HasherConfig)) Hasher {
+	t.Helper()
+	config := &HasherConfig{
+		Algorithm:           algorithm,
+		Argon2idMemory:      testArgon2idMemory,
+		Argon2idIterations:  testArgon2idIterations,
+		Argon2idParallelism: testArgon2idParallelism,
+		BcryptCost:          bcrypt.MinCost,
+	}
+	if change != nil {
+		change(config)
+	}
+	h, err := NewHasher(config)
+	if err != nil {
+		t.Fatalf("failed to create hasher: %v", err)
+	}
+
+	return h
+}
+
+func hashWith(t *testing.T, h Hasher, password string) string {
+	t.Helper()
+	encoded, err := h.Hash(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	return encoded
+}
+
+// argon2idPHC builds PHC string by hand, so malformed variants of valid hash
+// can be produced.
+func argon2idPHC(version int, params string, salt, key []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$%s$%s$%s",
+		version,
+		params,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func TestArgon2idPHCParsing(t *testing.T) {
+	const password = "correct horse battery staple"
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, testArgon2idIterations, testArgon2idMemory, testArgon2idParallelism, argon2idKeyLength)
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", testArgon2idMemory, testArgon2idIterations, testArgon2idParallelism)
+	valid := argon2idPHC(argon2.Version, params, salt, key)
+	h := newTestHasher(t, AlgorithmArgon2id, nil)
+
+	tests := []struct {
+		name            string
+		password        string
+		encoded         string
+		wantOK          bool
+		wantNeedsRehash bool
+	}{
+		{name: "valid", password: password, encoded: valid, wantOK: true},
+		{name: "wrong password", password: "wrong", encoded: valid},
+		{name: "unknown algorithm", password: password, encoded: strings.Replace(valid, "$argon2id$", "$argon2i$", 1)},
+		{name: "empty", password: password, encoded: ""},
+		{name: "missing key", password: password, encoded: valid[:strings.LastIndex(valid, "$")]},
+		{name: "extra segment", password: password, encoded: valid + "$extra"},
+		{name: "unsupported version", password: password, encoded: argon2idPHC(0x10, params, salt, key)},
+		{name: "malformed version", password: password, encoded: strings.Replace(valid, "$v=19$", "$version=19$", 1)},
+		{name: "malformed params", password: password, encoded: argon2idPHC(argon2.Version, "m=64;t=1;p=1", salt, key)},
+		{name: "invalid salt encoding", password: password, encoded: strings.Replace(valid, base64.RawStdEncoding.EncodeToString(salt), "!!!", 1)},
+		{name: "invalid key encoding", password: password, encoded: strings.Replace(valid, base64.RawStdEncoding.EncodeToString(key), "!!!", 1)},
+		{
+			name:            "short salt",
+			password:        password,
+			encoded:         argon2idPHC(argon2.Version, params, salt[:8], argon2.IDKey([]byte(password), salt[:8], testArgon2idIterations, testArgon2idMemory, testArgon2idParallelism, argon2idKeyLength)),
+			wantOK:          true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:            "short key",
+			password:        password,
+			encoded:         argon2idPHC(argon2.Version, params, salt, argon2.IDKey([]byte(password), salt, testArgon2idIterations, testArgon2idMemory, testArgon2idParallelism, 16)),
+			wantOK:          true,
+			wantNeedsRehash: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash := h.Verify(tt.password, tt.encoded)
+			if ok != tt.wantOK || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("Verify() = (%v, %v), want (%v, %v)", ok, needsRehash, tt.wantOK, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestHasherAlgorithmMigration(t *testing.T) {
+	const password = "correct horse battery staple"
+	argon2idHasher := newTestHasher(t, AlgorithmArgon2id, nil)
+	bcryptHasher := newTestHasher(t, AlgorithmBcrypt, nil)
+	argon2idHash := hashWith(t, argon2idHasher, password)
+	bcryptHash := hashWith(t, bcryptHasher, password)
+
+	tests := []struct {
+		name            string
+		hasher          Hasher
+		password        string
+		encoded         string
+		wantOK          bool
+		wantNeedsRehash bool
+	}{
+		{name: "argon2id hash with argon2id primary", hasher: argon2idHasher, password: password, encoded: argon2idHash, wantOK: true},
+		{name: "bcrypt hash with argon2id primary", hasher: argon2idHasher, password: password, encoded: bcryptHash, wantOK: true, wantNeedsRehash: true},
+		{name: "wrong password of bcrypt hash with argon2id primary", hasher: argon2idHasher, password: "wrong", encoded: bcryptHash},
+		{name: "bcrypt hash with bcrypt primary", hasher: bcryptHasher, password: password, encoded: bcryptHash, wantOK: true},
+		{name: "argon2id hash with bcrypt primary", hasher: bcryptHasher, password: password, encoded: argon2idHash, wantOK: true, wantNeedsRehash: true},
+		{name: "$2y$ bcrypt hash with argon2id primary", hasher: argon2idHasher, password: password, encoded: "$2y$" + strings.TrimPrefix(bcryptHash, "$2a$"), wantOK: true, wantNeedsRehash: true},
+		{name: "unknown hash format", hasher: argon2idHasher, password: password, encoded: "plaintext"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash := tt.hasher.Verify(tt.password, tt.encoded)
+			if ok != tt.wantOK || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("Verify() = (%v, %v), want (%v, %v)", ok, needsRehash, tt.wantOK, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestHasherCostChange(t *testing.T) {
+	const password = "correct horse battery staple"
+	argon2idHash := hashWith(t, newTestHasher(t, AlgorithmArgon2id, nil), password)
+	bcryptHash := hashWith(t, newTestHasher(t, AlgorithmBcrypt, nil), password)
+
+	tests := []struct {
+		name            string
+		algorithm       string
+		change          func(*HasherConfig)
+		encoded         string
+		wantNeedsRehash bool
+	}{
+		{name: "argon2id same cost", algorithm: AlgorithmArgon2id, encoded: argon2idHash},
+		{name: "argon2id memory changed", algorithm: AlgorithmArgon2id, change: func(c *HasherConfig) { c.Argon2idMemory *= 2 }, encoded: argon2idHash, wantNeedsRehash: true},
+		{name: "argon2id iterations changed", algorithm: AlgorithmArgon2id, change: func(c *HasherConfig) { c.Argon2idIterations++ }, encoded: argon2idHash, wantNeedsRehash: true},
+		{name: "argon2id parallelism changed", algorithm: AlgorithmArgon2id, change: func(c *HasherConfig) { c.Argon2idParallelism++ }, encoded: argon2idHash, wantNeedsRehash: true},
+		{name: "argon2id bcrypt cost changed", algorithm: AlgorithmArgon2id, change: func(c *HasherConfig) { c.BcryptCost++ }, encoded: argon2idHash},
+		{name: "bcrypt same cost", algorithm: AlgorithmBcrypt, encoded: bcryptHash},
+		{name: "bcrypt cost changed", algorithm: AlgorithmBcrypt, change: func(c *HasherConfig) { c.BcryptCost++ }, encoded: bcryptHash, wantNeedsRehash: true},
+		{name: "bcrypt argon2id cost changed", algorithm: AlgorithmBcrypt, change: func(c *HasherConfig) { c.Argon2idMemory *= 2 }, encoded: bcryptHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHasher(t, tt.algorithm, tt.change)
+			ok, needsRehash := h.Verify(password, tt.encoded)
+			if !ok || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("Verify() = (%v, %v), want (true, %v)", ok, needsRehash, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestNewHasherValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *HasherConfig
+		wantErr bool
+	}{
+		{name: "argon2id", config: &HasherConfig{Algorithm: AlgorithmArgon2id, Argon2idMemory: 64, Argon2idIterations: 1, Argon2idParallelism: 1}},
+		{name: "argon2id zero memory", config: &HasherConfig{Algorithm: AlgorithmArgon2id, Argon2idIterations: 1, Argon2idParallelism: 1}, wantErr: true},
+		{name: "argon2id zero iterations", config: &HasherConfig{Algorithm: AlgorithmArgon2id, Argon2idMemory: 64, Argon2idParallelism: 1}, wantErr: true},
+		{name: "argon2id zero parallelism", config: &HasherConfig{Algorithm: AlgorithmArgon2id, Argon2idMemory: 64, Argon2idIterations: 1}, wantErr: true},
+		{name: "bcrypt", config: &HasherConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}},
+		{name: "bcrypt cost too low", config: &HasherConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost - 1}, wantErr: true},
+		{name: "bcrypt cost too high", config: &HasherConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MaxCost + 1}, wantErr: true},
+		{name: "unknown algorithm", config: &HasherConfig{Algorithm: "scrypt"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHasher(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewHasher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
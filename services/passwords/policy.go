@@ -0,0 +1,172 @@
+package passwords
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type PolicyConfig struct {
+	MinLength int
+	// MinCharacterClasses is amount of character classes (lowercase letters,
+	// uppercase letters, digits, other symbols) password has to contain.
+	MinCharacterClasses int
+	// BreachedPasswordsFile is path to the breached passwords corpus, see
+	// OpenBreachedPasswords. Screening is disabled if it is empty.
+	BreachedPasswordsFile string
+}
+
+type Policy interface {
+	// Validate checks password against the policy. identifiers are emails and
+	// phone numbers of the holder which must not be reused in the password.
+	// It returns *PolicyError if password violates the policy, other errors
+	// mean that password couldn't be checked.
+	Validate(password string, identifiers []string) error
+}
+
+const (
+	ViolationTooShort               = "TOO_SHORT"
+	ViolationTooFewCharacterClasses = "TOO_FEW_CHARACTER_CLASSES"
+	ViolationContainsIdentifier     = "CONTAINS_IDENTIFIER"
+	ViolationBreached               = "BREACHED"
+)
+
+type Violation struct {
+	Reason      string
+	Description string
+}
+
+// PolicyError lists every rule password violates, so client can show them all
+// at once.
+type PolicyError struct {
+	Violations []*Violation
+}
+
+func (e *PolicyError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, v.Description)
+	}
+
+	return "password violates policy: " + strings.Join(descriptions, "; ")
+}
+
+type policy struct {
+	minLength           int
+	minCharacterClasses int
+	breached            *BreachedPasswords
+}
+
+func NewPolicy(config *PolicyConfig) (Policy, error) {
+	p := &policy{
+		minLength:           config.MinLength,
+		minCharacterClasses: config.MinCharacterClasses,
+	}
+	if config.BreachedPasswordsFile != "" {
+		breached, err := OpenBreachedPasswords(config.BreachedPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+		p.breached = breached
+	}
+
+	return p, nil
+}
+
+func (p *policy) Validate(password string, identifiers []string) error {
+	var violations []*Violation
+	if length := len([]rune(password)); length < p.minLength {
+		violations = append(violations, &Violation{
+			Reason:      ViolationTooShort,
+			Description: fmt.Sprintf("password must be at least %d characters long", p.minLength),
+		})
+	}
+	if classes := countCharacterClasses(password); classes < p.minCharacterClasses {
+		violations = append(violations, &Violation{
+			Reason:      ViolationTooFewCharacterClasses,
+			Description: fmt.Sprintf("password must contain at least %d of lowercase letters, uppercase letters, digits and other symbols", p.minCharacterClasses),
+		})
+	}
+	if containsIdentifier(password, identifiers) {
+		violations = append(violations, &Violation{
+			Reason:      ViolationContainsIdentifier,
+			Description: "password must not contain your email address or phone number",
+		})
+	}
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, &Violation{
+				Reason:      ViolationBreached,
+				Description: "password appeared in a data breach, choose another one",
+			})
+		}
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+func countCharacterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	var count int
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			count++
+		}
+	}
+	return count
+}
+
+// minIdentifierPartLength keeps short email local parts (e.g. "al") from
+// rejecting unrelated passwords.
+const minIdentifierPartLength = 4
+
+func containsIdentifier(password string, identifiers []string) bool {
+	lower := strings.ToLower(password)
+	digits := onlyDigits(password)
+	for _, identifier := range identifiers {
+		identifier = strings.ToLower(strings.TrimSpace(identifier))
+		if identifier == "" {
+			continue
+		}
+		if local, _, ok := strings.Cut(identifier, "@"); ok {
+			if strings.Contains(lower, identifier) || (len(local) >= minIdentifierPartLength && strings.Contains(lower, local)) {
+				return true
+			}
+			continue
+		}
+		if phoneDigits := onlyDigits(identifier); len(phoneDigits) >= minIdentifierPartLength && strings.Contains(digits, phoneDigits) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func onlyDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
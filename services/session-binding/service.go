@@ -0,0 +1,271 @@
+package sessionbinding
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"go.uber.org/zap"
+)
+
+// Policy defines what session is bound to, i.e. what request has to match
+// to use session token.
+type Policy string
+
+const (
+	// PolicyNone doesn't bind session, token is enough.
+	PolicyNone Policy = "none"
+	// PolicyIPSubnet binds session to subnet of remote IP address, so
+	// clients can change address inside of the network.
+	PolicyIPSubnet Policy = "ip-subnet"
+	// PolicyStrictIP binds session to remote IP address.
+	PolicyStrictIP Policy = "strict-ip"
+	// PolicyDeviceKey binds session to device key which client presents on
+	// login, every request has to carry proof of possession of the key.
+	PolicyDeviceKey Policy = "device-key"
+)
+
+// MismatchAction defines how request which doesn't match session binding is
+// handled.
+type MismatchAction string
+
+const (
+	// MismatchActionDeny rejects request, session stays valid.
+	MismatchActionDeny MismatchAction = "deny"
+	// MismatchActionReauthenticate expires session, client has to log in
+	// again.
+	MismatchActionReauthenticate MismatchAction = "reauthenticate"
+	// MismatchActionStepUp expires session and asks for second factor and
+	// password if second factor is enabled, otherwise it works as
+	// MismatchActionReauthenticate. The new session is bound to the request
+	// which redeems the challenge.
+	MismatchActionStepUp MismatchAction = "step-up"
+)
+
+// Metadata keys of device key and its proof. HTTP gateway clients send them
+// as Grpc-Metadata-Device-Key and Grpc-Metadata-Device-Key-Proof headers.
+const (
+	DeviceKeyMetadataKey      = "device-key"
+	DeviceKeyProofMetadataKey = "device-key-proof"
+)
+
+type Config struct {
+	Policy Policy
+	// IPv4PrefixLength and IPv6PrefixLength are lengths of subnet prefixes
+	// which are compared by PolicyIPSubnet.
+	IPv4PrefixLength int
+	IPv6PrefixLength int
+	// ProofAge is how long device key proof is accepted after it was made.
+	ProofAge       time.Duration
+	MismatchAction MismatchAction
+}
+
+// Binding is what session was bound to on login.
+type Binding struct {
+	RemoteIPAddress sql.NullString
+	DeviceKey       sql.NullString
+}
+
+// Request is what incoming request presents.
+type Request struct {
+	RemoteIPAddress string
+	Token           string
+	DeviceKeyProof  string
+}
+
+// MismatchError is returned if request doesn't match session binding.
+type MismatchError struct {
+	Reason string
+	Action MismatchAction
+}
+
+func (e *MismatchError) Error() string {
+	return "session binding mismatch: " + e.Reason
+}
+
+type Service interface {
+	// DeviceKey validates device key presented on login. It returns nil if
+	// policy doesn't bind sessions to device keys.
+	DeviceKey(logger *zap.Logger, value string) (*string, error)
+	// Check returns *MismatchError if request doesn't match session binding.
+	Check(logger *zap.Logger, binding *Binding, req *Request) error
+}
+
+type service struct {
+	policy           Policy
+	ipv4PrefixLength int
+	ipv6PrefixLength int
+	proofAge         time.Duration
+	mismatchAction   MismatchAction
+}
+
+func New(config *Config) (Service, error) {
+	switch config.Policy {
+	case PolicyNone, PolicyIPSubnet, PolicyStrictIP, PolicyDeviceKey:
+	default:
+		return nil, errorwrapper.New(fmt.Sprintf("unknown session binding policy %q", config.Policy))
+	}
+	switch config.MismatchAction {
+	case MismatchActionDeny, MismatchActionReauthenticate, MismatchActionStepUp:
+	default:
+		return nil, errorwrapper.New(fmt.Sprintf("unknown session binding mismatch action %q", config.MismatchAction))
+	}
+	if config.IPv4PrefixLength < 0 || config.IPv4PrefixLength > 32 {
+		return nil, errorwrapper.New("IPv4 prefix length must be between 0 and 32")
+	}
+	if config.IPv6PrefixLength < 0 || config.IPv6PrefixLength > 128 {
+		return nil, errorwrapper.New("IPv6 prefix length must be between 0 and 128")
+	}
+
+	return &service{
+		policy:           config.Policy,
+		ipv4PrefixLength: config.IPv4PrefixLength,
+		ipv6PrefixLength: config.IPv6PrefixLength,
+		proofAge:         config.ProofAge,
+		mismatchAction:   config.MismatchAction,
+	}, nil
+}
+
+func (s *service) DeviceKey(logger *zap.Logger, value string) (*string, error) {
+	if s.policy != PolicyDeviceKey {
+		return nil, nil
+	}
+	if value == "" {
+		logger.Error("device key is missing")
+		return nil, errorwrapper.New("device key is required")
+	}
+	if _, err := parseDeviceKey(value); err != nil {
+		logger.Error("invalid device key", zap.Error(err))
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+func (s *service) Check(logger *zap.Logger, binding *Binding, req *Request) error {
+	switch s.policy {
+	case PolicyIPSubnet:
+		if !binding.RemoteIPAddress.Valid || s.sameSubnet(binding.RemoteIPAddress.String, req.RemoteIPAddress) {
+			return nil
+		}
+		logger.Error("remote IP address is out of session's subnet", zap.String("session-remote-ip-address", binding.RemoteIPAddress.String))
+		return s.mismatch("remote IP address is out of session's subnet")
+	case PolicyStrictIP:
+		if !binding.RemoteIPAddress.Valid || binding.RemoteIPAddress.String == req.RemoteIPAddress {
+			return nil
+		}
+		logger.Error("remote IP address doesn't match with session's remote IP address", zap.String("session-remote-ip-address", binding.RemoteIPAddress.String))
+		return s.mismatch("remote IP address doesn't match with session's remote IP address")
+	case PolicyDeviceKey:
+		// sessions which were created before the policy was enabled have no
+		// device key, they can't be proven
+		if !binding.DeviceKey.Valid {
+			logger.Error("session is not bound to device key")
+			return s.mismatch("session is not bound to device key")
+		}
+		if err := s.verifyProof(binding.DeviceKey.String, req.Token, req.DeviceKeyProof); err != nil {
+			logger.Error("invalid device key proof", zap.Error(err))
+			return s.mismatch(err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (s *service) mismatch(reason string) error {
+	return &MismatchError{Reason: reason, Action: s.mismatchAction}
+}
+
+func (s *service) sameSubnet(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return a == b
+	}
+	if v4A, v4B := ipA.To4(), ipB.To4(); v4A != nil || v4B != nil {
+		if v4A == nil || v4B == nil {
+			return false
+		}
+		mask := net.CIDRMask(s.ipv4PrefixLength, 32)
+		return v4A.Mask(mask).Equal(v4B.Mask(mask))
+	}
+	mask := net.CIDRMask(s.ipv6PrefixLength, 128)
+
+	return ipA.Mask(mask).Equal(ipB.Mask(mask))
+}
+
+// parseDeviceKey decodes device key, it is unpadded base64url encoded PKIX
+// public key. ECDSA P-256 and Ed25519 keys are supported.
+func parseDeviceKey(value string) (interface{}, error) {
+	der, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "device key is not base64url encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "invalid device key")
+	}
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errorwrapper.New("unsupported device key curve")
+		}
+	case ed25519.PublicKey:
+	default:
+		return nil, errorwrapper.New("unsupported device key type")
+	}
+
+	return key, nil
+}
+
+// verifyProof checks device key proof, it is formatted as
+// <unix-timestamp>.<signature> where signature is unpadded base64url encoded
+// signature of <unix-timestamp>.<token>. ECDSA signatures are ASN.1 encoded.
+func (s *service) verifyProof(deviceKey, token, proof string) error {
+	if proof == "" {
+		return errorwrapper.New("device key proof is missing")
+	}
+	timestamp, encodedSignature, ok := strings.Cut(proof, ".")
+	if !ok {
+		return errorwrapper.New("device key proof is formatted incorrectly")
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errorwrapper.New("device key proof timestamp is formatted incorrectly")
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > s.proofAge || age < -s.proofAge {
+		return errorwrapper.New("device key proof was expired")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return errorwrapper.New("device key proof signature is not base64url encoded")
+	}
+	key, err := parseDeviceKey(deviceKey)
+	if err != nil {
+		return err
+	}
+
+	signed := []byte(timestamp + "." + token)
+	var valid bool
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		valid = ecdsa.VerifyASN1(k, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(k, signed, signature)
+	}
+	if !valid {
+		return errorwrapper.New("device key proof signature is invalid")
+	}
+
+	return nil
+}
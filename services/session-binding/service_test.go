@@ -0,0 +1,299 @@
+package sessionbinding
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	testToken    = "session-token"
+	testProofAge = time.Minute
+)
+
+func newTestService(t *testing.T, policy Policy) *service {
+	t.Helper()
+	s, err := New(&Config{
+		Policy:           policy,
+		IPv4PrefixLength: 24,
+		IPv6PrefixLength: 64,
+		ProofAge:         testProofAge,
+		MismatchAction:   MismatchActionStepUp,
+	})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	return s.(*service)
+}
+
+func encodeKey(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(der)
+}
+
+// signer makes device key proofs the way clients make them.
+type signer func(signed []byte) []byte
+
+func newECDSAKey(t *testing.T) (string, signer) {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+
+	return encodeKey(t, &k.PublicKey), func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := ecdsa.SignASN1(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return signature
+	}
+}
+
+func newEd25519Key(t *testing.T) (string, signer) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+
+	return encodeKey(t, public), func(signed []byte) []byte {
+		return ed25519.Sign(private, signed)
+	}
+}
+
+func makeProof(sign signer, at time.Time, token string) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return timestamp + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(timestamp+"."+token)))
+}
+
+func TestNew(t *testing.T) {
+	valid := Config{Policy: PolicyIPSubnet, IPv4PrefixLength: 24, IPv6PrefixLength: 64, MismatchAction: MismatchActionDeny}
+	tests := []struct {
+		name    string
+		change  func(*Config)
+		wantErr bool
+	}{
+		{name: "valid", change: func(*Config) {}},
+		{name: "zero prefix lengths", change: func(c *Config) { c.IPv4PrefixLength, c.IPv6PrefixLength = 0, 0 }},
+		{name: "full prefix lengths", change: func(c *Config) { c.IPv4PrefixLength, c.IPv6PrefixLength = 32, 128 }},
+		{name: "unknown policy", change: func(c *Config) { c.Policy = "cookie" }, wantErr: true},
+		{name: "empty policy", change: func(c *Config) { c.Policy = "" }, wantErr: true},
+		{name: "unknown mismatch action", change: func(c *Config) { c.MismatchAction = "ignore" }, wantErr: true},
+		{name: "negative IPv4 prefix length", change: func(c *Config) { c.IPv4PrefixLength = -1 }, wantErr: true},
+		{name: "too long IPv4 prefix length", change: func(c *Config) { c.IPv4PrefixLength = 33 }, wantErr: true},
+		{name: "negative IPv6 prefix length", change: func(c *Config) { c.IPv6PrefixLength = -1 }, wantErr: true},
+		{name: "too long IPv6 prefix length", change: func(c *Config) { c.IPv6PrefixLength = 129 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.change(&config)
+			_, err := New(&config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSameSubnet(t *testing.T) {
+	s := newTestService(t, PolicyIPSubnet)
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{name: "same IPv4 address", a: "192.0.2.10", b: "192.0.2.10", want: true},
+		{name: "IPv4 inside prefix", a: "192.0.2.10", b: "192.0.2.250", want: true},
+		{name: "IPv4 outside prefix", a: "192.0.2.10", b: "192.0.3.10"},
+		{name: "IPv4-mapped IPv6 and IPv4", a: "::ffff:192.0.2.10", b: "192.0.2.20", want: true},
+		{name: "IPv6 inside prefix", a: "2001:db8:1:2::1", b: "2001:db8:1:2:ffff::1", want: true},
+		{name: "IPv6 outside prefix", a: "2001:db8:1:2::1", b: "2001:db8:1:3::1"},
+		{name: "IPv4 and IPv6", a: "192.0.2.10", b: "2001:db8::1"},
+		{name: "IPv6 and IPv4", a: "2001:db8::1", b: "192.0.2.10"},
+		{name: "unparsable equal addresses", a: "unknown", b: "unknown", want: true},
+		{name: "unparsable and IPv4", a: "unknown", b: "192.0.2.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.sameSubnet(tt.a, tt.b); got != tt.want {
+				t.Fatalf("sameSubnet(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeviceKey(t *testing.T) {
+	ecdsaKey, _ := newECDSAKey(t)
+	ed25519Key, _ := newEd25519Key(t)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate P-384 key: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		value   string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "ECDSA P-256", policy: PolicyDeviceKey, value: ecdsaKey},
+		{name: "Ed25519", policy: PolicyDeviceKey, value: ed25519Key},
+		{name: "ECDSA P-384", policy: PolicyDeviceKey, value: encodeKey(t, &p384.PublicKey), wantErr: true},
+		{name: "RSA", policy: PolicyDeviceKey, value: encodeKey(t, &rsaKey.PublicKey), wantErr: true},
+		{name: "missing", policy: PolicyDeviceKey, value: "", wantErr: true},
+		{name: "not base64url", policy: PolicyDeviceKey, value: "not base64url!", wantErr: true},
+		{name: "not PKIX", policy: PolicyDeviceKey, value: base64.RawURLEncoding.EncodeToString([]byte("key")), wantErr: true},
+		{name: "other policy ignores key", policy: PolicyStrictIP, value: "not base64url!", wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestService(t, tt.policy).DeviceKey(zap.NewNop(), tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeviceKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("DeviceKey() = %q, want nil", *got)
+				}
+				return
+			}
+			if got == nil || *got != tt.value {
+				t.Fatalf("DeviceKey() = %v, want %q", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestVerifyProof(t *testing.T) {
+	s := newTestService(t, PolicyDeviceKey)
+	ecdsaKey, ecdsaSign := newECDSAKey(t)
+	ed25519Key, ed25519Sign := newEd25519Key(t)
+	_, otherSign := newEd25519Key(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		key     string
+		proof   string
+		wantErr bool
+	}{
+		{name: "ECDSA", key: ecdsaKey, proof: makeProof(ecdsaSign, now, testToken)},
+		{name: "Ed25519", key: ed25519Key, proof: makeProof(ed25519Sign, now, testToken)},
+		{name: "slightly old", key: ed25519Key, proof: makeProof(ed25519Sign, now.Add(-testProofAge/2), testToken)},
+		{name: "expired", key: ed25519Key, proof: makeProof(ed25519Sign, now.Add(-2*testProofAge), testToken), wantErr: true},
+		{name: "from the future", key: ed25519Key, proof: makeProof(ed25519Sign, now.Add(2*testProofAge), testToken), wantErr: true},
+		{name: "other token", key: ed25519Key, proof: makeProof(ed25519Sign, now, "other-token"), wantErr: true},
+		{name: "other key", key: ed25519Key, proof: makeProof(otherSign, now, testToken), wantErr: true},
+		{name: "ECDSA signature for Ed25519 key", key: ed25519Key, proof: makeProof(ecdsaSign, now, testToken), wantErr: true},
+		{name: "missing", key: ed25519Key, proof: "", wantErr: true},
+		{name: "no separator", key: ed25519Key, proof: strconv.FormatInt(now.Unix(), 10), wantErr: true},
+		{name: "timestamp is not a number", key: ed25519Key, proof: "now.c2lnbmF0dXJl", wantErr: true},
+		{name: "signature is not base64url", key: ed25519Key, proof: strconv.FormatInt(now.Unix(), 10) + ".!!!", wantErr: true},
+		{name: "invalid bound key", key: "not base64url!", proof: makeProof(ed25519Sign, now, testToken), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.verifyProof(tt.key, testToken, tt.proof)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyProof() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	deviceKey, sign := newEd25519Key(t)
+	bound := func(ip, key string) *Binding {
+		b := &Binding{}
+		if ip != "" {
+			b.RemoteIPAddress = sql.NullString{String: ip, Valid: true}
+		}
+		if key != "" {
+			b.DeviceKey = sql.NullString{String: key, Valid: true}
+		}
+		return b
+	}
+
+	tests := []struct {
+		name         string
+		policy       Policy
+		binding      *Binding
+		req          *Request
+		wantMismatch bool
+	}{
+		{name: "none", policy: PolicyNone, binding: bound("192.0.2.10", ""), req: &Request{RemoteIPAddress: "198.51.100.1"}},
+		{name: "strict IP same address", policy: PolicyStrictIP, binding: bound("192.0.2.10", ""), req: &Request{RemoteIPAddress: "192.0.2.10"}},
+		{name: "strict IP same subnet", policy: PolicyStrictIP, binding: bound("192.0.2.10", ""), req: &Request{RemoteIPAddress: "192.0.2.11"}, wantMismatch: true},
+		{name: "strict IP unbound session", policy: PolicyStrictIP, binding: bound("", ""), req: &Request{RemoteIPAddress: "192.0.2.11"}},
+		{name: "IP subnet same subnet", policy: PolicyIPSubnet, binding: bound("192.0.2.10", ""), req: &Request{RemoteIPAddress: "192.0.2.11"}},
+		{name: "IP subnet other subnet", policy: PolicyIPSubnet, binding: bound("192.0.2.10", ""), req: &Request{RemoteIPAddress: "198.51.100.1"}, wantMismatch: true},
+		{name: "IP subnet unbound session", policy: PolicyIPSubnet, binding: bound("", ""), req: &Request{RemoteIPAddress: "198.51.100.1"}},
+		{
+			name:    "device key valid proof",
+			policy:  PolicyDeviceKey,
+			binding: bound("192.0.2.10", deviceKey),
+			req:     &Request{RemoteIPAddress: "198.51.100.1", Token: testToken, DeviceKeyProof: makeProof(sign, time.Now(), testToken)},
+		},
+		{
+			name:         "device key missing proof",
+			policy:       PolicyDeviceKey,
+			binding:      bound("192.0.2.10", deviceKey),
+			req:          &Request{RemoteIPAddress: "192.0.2.10", Token: testToken},
+			wantMismatch: true,
+		},
+		{
+			name:         "device key unbound session",
+			policy:       PolicyDeviceKey,
+			binding:      bound("192.0.2.10", ""),
+			req:          &Request{RemoteIPAddress: "192.0.2.10", Token: testToken, DeviceKeyProof: makeProof(sign, time.Now(), testToken)},
+			wantMismatch: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestService(t, tt.policy).Check(zap.NewNop(), tt.binding, tt.req)
+			if !tt.wantMismatch {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			var mismatch *MismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("Check() error = %v, want *MismatchError", err)
+			}
+			if mismatch.Action != MismatchActionStepUp {
+				t.Fatalf("Check() action = %q, want %q", mismatch.Action, MismatchActionStepUp)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package smssender
+
+import (
+	"context"
+	"fmt"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"go.uber.org/zap"
+)
+
+type Message struct {
+	From string
+	To   string
+	Text string
+}
+
+// Provider delivers SMS messages.
+type Provider interface {
+	Send(ctx context.Context, logger *zap.Logger, msg *Message) error
+}
+
+const (
+	ProviderHTTP = "http"
+	ProviderSMPP = "smpp"
+	ProviderFile = "file"
+	ProviderLog  = "log"
+)
+
+// NewProvider creates provider which is chosen in config. File and log
+// providers don't deliver messages, they are meant for development. There is
+// no default provider, so the warden doesn't start without delivery by
+// mistake.
+func NewProvider(config *Config) (Provider, error) {
+	switch config.Provider {
+	case "":
+		return nil, errorwrapper.New("SMS provider is not set")
+	case ProviderHTTP:
+		return NewHTTPProvider(config.HTTP)
+	case ProviderSMPP:
+		return NewSMPPProvider(config.SMPP), nil
+	case ProviderFile:
+		return NewFileProvider(config.File), nil
+	case ProviderLog:
+		return NewLogProvider(), nil
+	}
+
+	return nil, errorwrapper.New(fmt.Sprintf("unknown SMS provider, provider = %v", config.Provider))
+}
@@ -0,0 +1,70 @@
+package smssender
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type FileProviderConfig struct {
+	Path string
+}
+
+type fileProvider struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileProvider creates provider which appends messages to file as JSON
+// lines instead of sending them.
+func NewFileProvider(config *FileProviderConfig) Provider {
+	return &fileProvider{path: config.Path}
+}
+
+func (p *fileProvider) Send(_ context.Context, _ *zap.Logger, msg *Message) error {
+	line, err := json.Marshal(struct {
+		SentAt time.Time `json:"sent_at"`
+		From   string    `json:"from"`
+		To     string    `json:"to"`
+		Text   string    `json:"text"`
+	}{
+		SentAt: time.Now(),
+		From:   msg.From,
+		To:     msg.To,
+		Text:   msg.Text,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+type logProvider struct{}
+
+// NewLogProvider creates provider which logs messages instead of sending them.
+// Text is not logged, messages carry confirmation and reset codes; file
+// provider keeps the text when it is needed.
+func NewLogProvider() Provider {
+	return &logProvider{}
+}
+
+func (p *logProvider) Send(_ context.Context, logger *zap.Logger, msg *Message) error {
+	logger.Info("SMS message", zap.String("from", msg.From), zap.String("to", msg.To), zap.Int("text-length", len(msg.Text)))
+	return nil
+}
@@ -0,0 +1,94 @@
+package smssender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"go.uber.org/zap"
+)
+
+// DefaultHTTPBodyTemplate is body of HTTP provider request if it isn't set in
+// config.
+const DefaultHTTPBodyTemplate = `{"from":{{json .From}},"to":{{json .To}},"text":{{json .Text}}}`
+
+type HTTPProviderConfig struct {
+	URL string
+	// Authorization is value of Authorization header, it is not sent if it
+	// is empty.
+	Authorization string
+	ContentType   string
+	// BodyTemplate is text/template of request body which is executed with
+	// Message. Besides builtin functions it can use json function which
+	// encodes value as JSON.
+	BodyTemplate string
+	Timeout      time.Duration
+}
+
+type httpProvider struct {
+	url           string
+	authorization string
+	contentType   string
+	body          *template.Template
+	client        *http.Client
+}
+
+// NewHTTPProvider creates provider which posts messages to an HTTP API of SMS
+// gateway, any response with 2xx status means success.
+func NewHTTPProvider(config *HTTPProviderConfig) (Provider, error) {
+	bodyTemplate := config.BodyTemplate
+	if bodyTemplate == "" {
+		bodyTemplate = DefaultHTTPBodyTemplate
+	}
+	body, err := template.New("body").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(bodyTemplate)
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "invalid HTTP SMS provider body template")
+	}
+
+	return &httpProvider{
+		url:           config.URL,
+		authorization: config.Authorization,
+		contentType:   config.ContentType,
+		body:          body,
+		client:        &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+func (p *httpProvider) Send(ctx context.Context, logger *zap.Logger, msg *Message) error {
+	var body bytes.Buffer
+	if err := p.body.Execute(&body, msg); err != nil {
+		return errorwrapper.WrapMessage(err, "failed to compose HTTP SMS provider request")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", p.contentType)
+	if p.authorization != "" {
+		req.Header.Set("Authorization", p.authorization)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		logger.Error("HTTP SMS provider rejected message", zap.Int("status-code", resp.StatusCode), zap.ByteString("response-body", respBody))
+		return errorwrapper.New(fmt.Sprintf("HTTP SMS provider responded with status %d", resp.StatusCode))
+	}
+
+	return nil
+}
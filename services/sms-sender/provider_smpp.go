@@ -0,0 +1,272 @@
+package smssender
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"go.uber.org/zap"
+)
+
+type SMPPProviderConfig struct {
+	Addr       string
+	SystemID   string
+	Password   string
+	SystemType string
+	TLS        bool
+	Timeout    time.Duration
+}
+
+// SMPP 3.4 command IDs.
+const (
+	smppGenericNack         uint32 = 0x80000000
+	smppBindTransmitter     uint32 = 0x00000002
+	smppBindTransmitterResp uint32 = 0x80000002
+	smppSubmitSM            uint32 = 0x00000004
+	smppSubmitSMResp        uint32 = 0x80000004
+	smppUnbind              uint32 = 0x00000006
+	smppUnbindResp          uint32 = 0x80000006
+	smppEnquireLink         uint32 = 0x00000015
+	smppEnquireLinkResp     uint32 = 0x80000015
+)
+
+const (
+	smppHeaderLength = 16
+	// smppMaxPDULength limits length of PDUs which are read from SMSC.
+	smppMaxPDULength = 64 * 1024
+	// smppMaxShortMessageLength is the longest message which fits in
+	// short_message field, longer ones are sent in message_payload.
+	smppMaxShortMessageLength = 140
+	smppInterfaceVersion      = 0x34
+	smppTagMessagePayload     = 0x0424
+
+	smppDataCodingIA5  = 0x01
+	smppDataCodingUCS2 = 0x08
+
+	smppTONUnknown       = 0x00
+	smppTONInternational = 0x01
+	smppTONAlphanumeric  = 0x05
+	smppNPIUnknown       = 0x00
+	smppNPIISDN          = 0x01
+)
+
+type smppProvider struct {
+	config *SMPPProviderConfig
+}
+
+// NewSMPPProvider creates provider which submits messages to SMSC by SMPP 3.4.
+// It binds as transmitter for every message, so it suits low volume of
+// messages like confirmations.
+func NewSMPPProvider(config *SMPPProviderConfig) Provider {
+	return &smppProvider{config: config}
+}
+
+func (p *smppProvider) Send(ctx context.Context, logger *zap.Logger, msg *Message) error {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return errorwrapper.WrapMessage(err, "failed to connect to SMSC")
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(p.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	s := &smppSession{conn: conn}
+	if _, err := s.call(smppBindTransmitter, smppBindTransmitterResp, p.bindBody()); err != nil {
+		return errorwrapper.WrapMessage(err, "failed to bind to SMSC")
+	}
+	resp, err := s.call(smppSubmitSM, smppSubmitSMResp, submitSMBody(msg))
+	if err != nil {
+		return errorwrapper.WrapMessage(err, "failed to submit SMS")
+	}
+	logger.Info("SMSC accepted message", zap.String("message-id", readCString(resp)))
+	if _, err := s.call(smppUnbind, smppUnbindResp, nil); err != nil {
+		logger.Warn("failed to unbind from SMSC", zap.Error(err))
+	}
+
+	return nil
+}
+
+func (p *smppProvider) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: p.config.Timeout}
+	if p.config.TLS {
+		host, _, err := net.SplitHostPort(p.config.Addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}
+		return tlsDialer.DialContext(ctx, "tcp", p.config.Addr)
+	}
+
+	return dialer.DialContext(ctx, "tcp", p.config.Addr)
+}
+
+func (p *smppProvider) bindBody() []byte {
+	var body []byte
+	body = appendCString(body, p.config.SystemID)
+	body = appendCString(body, p.config.Password)
+	body = appendCString(body, p.config.SystemType)
+	body = append(body, smppInterfaceVersion, smppTONUnknown, smppNPIUnknown)
+	body = appendCString(body, "")
+
+	return body
+}
+
+func submitSMBody(msg *Message) []byte {
+	dataCoding, text := encodeSMSText(msg.Text)
+	sourceTON, sourceNPI := byte(smppTONInternational), byte(smppNPIISDN)
+	source := strings.TrimPrefix(msg.From, "+")
+	if !isDigits(source) {
+		sourceTON, sourceNPI = smppTONAlphanumeric, smppNPIUnknown
+		source = msg.From
+	}
+
+	var body []byte
+	body = appendCString(body, "") // service_type
+	body = append(body, sourceTON, sourceNPI)
+	body = appendCString(body, source)
+	body = append(body, smppTONInternational, smppNPIISDN)
+	body = appendCString(body, strings.TrimPrefix(msg.To, "+"))
+	// esm_class, protocol_id, priority_flag
+	body = append(body, 0, 0, 0)
+	body = appendCString(body, "") // schedule_delivery_time
+	body = appendCString(body, "") // validity_period
+	// registered_delivery, replace_if_present_flag, data_coding, sm_default_msg_id
+	body = append(body, 0, 0, dataCoding, 0)
+	if len(text) <= smppMaxShortMessageLength {
+		body = append(body, byte(len(text)))
+		return append(body, text...)
+	}
+	body = append(body, 0)
+	body = binary.BigEndian.AppendUint16(body, smppTagMessagePayload)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(text)))
+
+	return append(body, text...)
+}
+
+// encodeSMSText returns data coding and encoded text. ASCII text is sent as
+// IA5, other text as UCS2.
+func encodeSMSText(text string) (byte, []byte) {
+	ascii := true
+	for _, r := range text {
+		if r > 0x7f {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return smppDataCodingIA5, []byte(text)
+	}
+
+	encoded := utf16.Encode([]rune(text))
+	out := make([]byte, 0, len(encoded)*2)
+	for _, u := range encoded {
+		out = binary.BigEndian.AppendUint16(out, u)
+	}
+
+	return smppDataCodingUCS2, out
+}
+
+type smppSession struct {
+	conn     net.Conn
+	sequence uint32
+}
+
+// call sends request PDU and waits for its response. Enquire links which SMSC
+// sends meanwhile are answered.
+func (s *smppSession) call(commandID, respCommandID uint32, body []byte) ([]byte, error) {
+	s.sequence++
+	if err := s.write(commandID, 0, s.sequence, body); err != nil {
+		return nil, err
+	}
+	for {
+		id, status, sequence, respBody, err := s.read()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case id == smppEnquireLink:
+			if err := s.write(smppEnquireLinkResp, 0, sequence, nil); err != nil {
+				return nil, err
+			}
+			continue
+		case sequence != s.sequence:
+			continue
+		case id == smppGenericNack:
+			return nil, errorwrapper.New(fmt.Sprintf("SMSC rejected PDU, status = 0x%08x", status))
+		case id != respCommandID:
+			return nil, errorwrapper.New(fmt.Sprintf("unexpected SMPP response, command id = 0x%08x", id))
+		case status != 0:
+			return nil, errorwrapper.New(fmt.Sprintf("SMSC responded with error, status = 0x%08x", status))
+		}
+		return respBody, nil
+	}
+}
+
+func (s *smppSession) write(commandID, status, sequence uint32, body []byte) error {
+	pdu := make([]byte, 0, smppHeaderLength+len(body))
+	pdu = binary.BigEndian.AppendUint32(pdu, uint32(smppHeaderLength+len(body)))
+	pdu = binary.BigEndian.AppendUint32(pdu, commandID)
+	pdu = binary.BigEndian.AppendUint32(pdu, status)
+	pdu = binary.BigEndian.AppendUint32(pdu, sequence)
+	_, err := s.conn.Write(append(pdu, body...))
+
+	return err
+}
+
+func (s *smppSession) read() (commandID, status, sequence uint32, body []byte, err error) {
+	header := make([]byte, smppHeaderLength)
+	if _, err = io.ReadFull(s.conn, header); err != nil {
+		return
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length < smppHeaderLength || length > smppMaxPDULength {
+		err = errorwrapper.New(fmt.Sprintf("invalid SMPP PDU length, length = %d", length))
+		return
+	}
+	commandID = binary.BigEndian.Uint32(header[4:8])
+	status = binary.BigEndian.Uint32(header[8:12])
+	sequence = binary.BigEndian.Uint32(header[12:16])
+	body = make([]byte, length-smppHeaderLength)
+	_, err = io.ReadFull(s.conn, body)
+
+	return
+}
+
+func appendCString(b []byte, s string) []byte {
+	return append(append(b, s...), 0)
+}
+
+func readCString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+
+	return string(b)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,29 @@
+package smssender
+
+import (
+	"time"
+
+	"github.com/ecumenos-social/network-warden/models"
+)
+
+type RateLimit struct {
+	MaxRequests int64
+	Interval    time.Duration
+}
+
+func (rl *RateLimit) Exceed(ms []*models.SentSMS) bool {
+	var (
+		startTime = time.Now().Add(-rl.Interval)
+		count     int64
+	)
+	for _, m := range ms {
+		if m == nil {
+			continue
+		}
+		if m.CreatedAt.After(startTime) {
+			count++
+		}
+	}
+
+	return count > rl.MaxRequests
+}
@@ -1,22 +1,200 @@
 package smssender
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/holders"
+	"github.com/ecumenos-social/network-warden/services/idgenerators"
+	"go.uber.org/zap"
+)
 
 type Config struct {
+	// Provider is name of provider which delivers messages, see NewProvider.
+	Provider string
+	// Sender is phone number or alphanumeric sender ID of messages.
+	Sender string
+	HTTP   *HTTPProviderConfig
+	SMPP   *SMPPProviderConfig
+	File   *FileProviderConfig
+
+	ConfirmationOfRegistration *RateLimit
+	ResetHolderPassword        *RateLimit
+	ConfirmationOfContact      *RateLimit
+}
+
+type Repository interface {
+	InsertSentSMS(ctx context.Context, ss *models.SentSMS) error
+	GetSentSMS(ctx context.Context, sender, receiver, templateName string) ([]*models.SentSMS, error)
+}
+
+// Recipient is holder which SMS is sent to. Name and Language personalize the
+// template.
+type Recipient struct {
+	PhoneNumber string
+	Name        string
+	Language    string
+}
+
+// HolderRecipient addresses SMS to holder's phone number.
+func HolderRecipient(holder *models.Holder, phoneNumber string) *Recipient {
+	return &Recipient{
+		PhoneNumber: phoneNumber,
+		Name:        holders.DisplayName(holder),
+		Language:    holders.Language(holder),
+	}
 }
 
 type Service interface {
-	Send(ctx context.Context) error
+	SendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string) error
+	CanSendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, phoneNumber string) (bool, error)
+	SendResetHolderPassword(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string) error
+	CanSendResetHolderPassword(ctx context.Context, logger *zap.Logger, phoneNumber string) (bool, error)
+	SendConfirmationOfContact(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string) error
+	CanSendConfirmationOfContact(ctx context.Context, logger *zap.Logger, phoneNumber string) (bool, error)
 }
 
 type service struct {
+	provider   Provider
+	sender     string
+	rateLimits map[TemplateName]*RateLimit
+
+	repo        Repository
+	idgenerator idgenerators.SentSMSIDGenerator
+}
+
+func New(config *Config, repo Repository, g idgenerators.SentSMSIDGenerator) (Service, error) {
+	provider, err := NewProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service{
+		provider: provider,
+		sender:   config.Sender,
+		rateLimits: map[TemplateName]*RateLimit{
+			TemplateNameConfirmHolderRegistration: config.ConfirmationOfRegistration,
+			TemplateNameResetHolderPassword:       config.ResetHolderPassword,
+			TemplateNameConfirmHolderContact:      config.ConfirmationOfContact,
+		},
+
+		repo:        repo,
+		idgenerator: g,
+	}, nil
+}
+
+func (s *service) SendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string) error {
+	return s.sendTemplate(
+		ctx,
+		logger,
+		TemplateNameConfirmHolderRegistration,
+		recipient.PhoneNumber,
+		struct{ FullName, Language, ConfirmationCode string }{
+			FullName:         recipient.Name,
+			Language:         recipient.Language,
+			ConfirmationCode: code,
+		},
+		s.rateLimits[TemplateNameConfirmHolderRegistration],
+	)
 }
 
-func New(*Config) Service {
-	return &service{}
+func (s *service) SendResetHolderPassword(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string) error {
+	return s.sendTemplate(
+		ctx,
+		logger,
+		TemplateNameResetHolderPassword,
+		recipient.PhoneNumber,
+		struct{ FullName, Language, ResetCode string }{
+			FullName:  recipient.Name,
+			Language:  recipient.Language,
+			ResetCode: code,
+		},
+		s.rateLimits[TemplateNameResetHolderPassword],
+	)
 }
 
-func (s *service) Send(ctx context.Context) error {
-	// TODO: add functionality here: https://ecumenos.atlassian.net/browse/NNW-27
+func (s *service) SendConfirmationOfContact(ctx context.Context, logger *zap.Logger, recipient *Recipient, code string) error {
+	return s.sendTemplate(
+		ctx,
+		logger,
+		TemplateNameConfirmHolderContact,
+		recipient.PhoneNumber,
+		struct{ FullName, Language, ConfirmationCode string }{
+			FullName:         recipient.Name,
+			Language:         recipient.Language,
+			ConfirmationCode: code,
+		},
+		s.rateLimits[TemplateNameConfirmHolderContact],
+	)
+}
+
+func (s *service) sendTemplate(ctx context.Context, logger *zap.Logger, name TemplateName, to string, data interface{}, rl *RateLimit) error {
+	logger = logger.With(
+		zap.String("to", to),
+		zap.String("template-name", string(name)),
+	)
+	canSend, err := s.canSendTemplate(ctx, logger, name, to, rl)
+	if err != nil {
+		return err
+	}
+	if !canSend {
+		logger.Error("too many send SMS requests", zap.Int64("max-requests", rl.MaxRequests), zap.Duration("interval", rl.Interval))
+		return errorwrapper.New("too many send SMS requests")
+	}
+
+	t, err := takeTemplate(name)
+	if err != nil {
+		logger.Info("failed to take SMS template", zap.Error(err))
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		logger.Info("failed to compose SMS message", zap.Error(err))
+		return err
+	}
+	if err := s.provider.Send(ctx, logger, &Message{From: s.sender, To: to, Text: buf.String()}); err != nil {
+		logger.Info("failed to send SMS", zap.Error(err))
+		return err
+	}
+	logger.Info("SMS was sent successfully")
+
+	m := &models.SentSMS{
+		ID:                  s.idgenerator.Generate().Int64(),
+		CreatedAt:           time.Now(),
+		LastModifiedAt:      time.Now(),
+		Sender:              s.sender,
+		ReceiverPhoneNumber: to,
+		TemplateName:        name.String(),
+	}
+	if err := s.repo.InsertSentSMS(ctx, m); err != nil {
+		logger.Info("failed to insert sent SMS entity database", zap.Error(err))
+		return err
+	}
+
 	return nil
 }
+
+func (s *service) CanSendConfirmationOfRegistration(ctx context.Context, logger *zap.Logger, phoneNumber string) (bool, error) {
+	return s.canSendTemplate(ctx, logger, TemplateNameConfirmHolderRegistration, phoneNumber, s.rateLimits[TemplateNameConfirmHolderRegistration])
+}
+
+func (s *service) CanSendResetHolderPassword(ctx context.Context, logger *zap.Logger, phoneNumber string) (bool, error) {
+	return s.canSendTemplate(ctx, logger, TemplateNameResetHolderPassword, phoneNumber, s.rateLimits[TemplateNameResetHolderPassword])
+}
+
+func (s *service) CanSendConfirmationOfContact(ctx context.Context, logger *zap.Logger, phoneNumber string) (bool, error) {
+	return s.canSendTemplate(ctx, logger, TemplateNameConfirmHolderContact, phoneNumber, s.rateLimits[TemplateNameConfirmHolderContact])
+}
+
+func (s *service) canSendTemplate(ctx context.Context, logger *zap.Logger, name TemplateName, to string, rl *RateLimit) (bool, error) {
+	sentSMS, err := s.repo.GetSentSMS(ctx, s.sender, to, name.String())
+	if err != nil {
+		logger.Error("can not get sent SMS", zap.Error(err))
+		return false, errorwrapper.WrapMessage(err, "can not get sent SMS")
+	}
+
+	return !rl.Exceed(sentSMS), nil
+}
@@ -0,0 +1,50 @@
+package smssender
+
+import (
+	"fmt"
+	"path/filepath"
+	"text/template"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+)
+
+type TemplateName string
+
+const (
+	TemplateNameConfirmHolderRegistration TemplateName = "confirm-holder-registration"
+	TemplateNameResetHolderPassword       TemplateName = "reset-holder-password"
+	TemplateNameConfirmHolderContact      TemplateName = "confirm-holder-contact"
+)
+
+var unknownTemplateName = func(tn TemplateName) error {
+	return errorwrapper.New(fmt.Sprintf("unknown template name, name = %v", tn))
+}
+
+func (tn TemplateName) Validate() error {
+	for _, n := range []TemplateName{TemplateNameConfirmHolderRegistration, TemplateNameResetHolderPassword, TemplateNameConfirmHolderContact} {
+		if n == tn {
+			return nil
+		}
+	}
+	return unknownTemplateName(tn)
+}
+
+func (tn TemplateName) String() string {
+	return string(tn)
+}
+
+func takeTemplate(name TemplateName) (*template.Template, error) {
+	if err := name.Validate(); err != nil {
+		return nil, errorwrapper.NewWithError(err)
+	}
+	path := fmt.Sprintf("services/sms-sender/templates/%s.txt", name)
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := template.ParseFiles(absPath)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
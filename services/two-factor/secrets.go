@@ -0,0 +1,79 @@
+package twofactor
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+)
+
+// sealedSecretPrefix marks TOTP secrets which are encrypted, every stored
+// secret must have it.
+const sealedSecretPrefix = "aes256gcm:"
+
+// secretBox encrypts TOTP secrets with AES-256-GCM, holder ID is additional
+// data, so sealed secret can't be moved to another holder.
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+// newSecretBox takes base64 encoded 32 bytes key.
+func newSecretBox(key string) (*secretBox, error) {
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errorwrapper.WrapMessage(err, "invalid TOTP secret key, it must be base64 encoded")
+	}
+	if len(rawKey) != 32 {
+		return nil, errorwrapper.New(fmt.Sprintf("invalid TOTP secret key, it must be 32 bytes long, got %v bytes", len(rawKey)))
+	}
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretBox{aead: aead}, nil
+}
+
+func secretAdditionalData(holderID int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(holderID))
+}
+
+func (b *secretBox) seal(holderID int64, secret string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), secretAdditionalData(holderID))
+
+	return sealedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *secretBox) open(holderID int64, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, sealedSecretPrefix)
+	if !ok {
+		return "", errorwrapper.New("TOTP secret is not sealed")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errorwrapper.WrapMessage(err, "invalid sealed TOTP secret")
+	}
+	if len(sealed) < b.aead.NonceSize() {
+		return "", errorwrapper.New("invalid sealed TOTP secret")
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, ciphertext, secretAdditionalData(holderID))
+	if err != nil {
+		return "", errorwrapper.WrapMessage(err, "failed to decrypt TOTP secret")
+	}
+
+	return string(secret), nil
+}
@@ -0,0 +1,408 @@
+package twofactor
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	errorwrapper "github.com/ecumenos-social/error-wrapper"
+	"github.com/ecumenos-social/network-warden/models"
+	"github.com/ecumenos-social/network-warden/services/idgenerators"
+	"github.com/ecumenos-social/toolkit/hash"
+	"github.com/ecumenos-social/toolkit/random"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	Issuer               string
+	Skew                 int64
+	RecoveryCodesCount   int
+	ChallengeAge         time.Duration
+	MaxChallengeAttempts int64
+	// SecretKey is base64 encoded 32 bytes key which TOTP secrets are
+	// encrypted with.
+	SecretKey string
+}
+
+type Repository interface {
+	GetHolderTOTPSecretByHolderID(ctx context.Context, holderID int64) (*models.HolderTOTPSecret, error)
+	InsertHolderTOTPSecret(ctx context.Context, ts *models.HolderTOTPSecret) error
+	ModifyHolderTOTPSecret(ctx context.Context, id int64, ts *models.HolderTOTPSecret) error
+	DeleteHolderTOTPSecretByHolderID(ctx context.Context, holderID int64) error
+	InsertHolderRecoveryCode(ctx context.Context, rc *models.HolderRecoveryCode) error
+	UseHolderTOTPStep(ctx context.Context, id, step int64, modifiedAt time.Time) (bool, error)
+	DeleteHolderRecoveryCodesByHolderID(ctx context.Context, holderID int64) error
+	RedeemHolderRecoveryCode(ctx context.Context, holderID int64, codeHash string, usedAt time.Time) (*models.HolderRecoveryCode, error)
+	InsertHolderLoginChallenge(ctx context.Context, lc *models.HolderLoginChallenge) error
+	IncrementHolderLoginChallengeFailedAttempts(ctx context.Context, id, maxAttempts int64, modifiedAt time.Time) (int64, error)
+	RedeemHolderLoginChallenge(ctx context.Context, id int64, usedAt time.Time) (*models.HolderLoginChallenge, error)
+	GetHolderLoginChallengeByChallengeHash(ctx context.Context, challengeHash string) (*models.HolderLoginChallenge, error)
+}
+
+type Service interface {
+	Enroll(ctx context.Context, logger *zap.Logger, holderID int64, account string) (*Enrollment, error)
+	Verify(ctx context.Context, logger *zap.Logger, holderID int64, code string) error
+	Disable(ctx context.Context, logger *zap.Logger, holderID int64, code string) error
+	IsEnabled(ctx context.Context, logger *zap.Logger, holderID int64) (bool, error)
+	ValidateCode(ctx context.Context, logger *zap.Logger, holderID int64, code string) error
+	CreateLoginChallenge(ctx context.Context, logger *zap.Logger, params *CreateLoginChallengeParams) (string, error)
+	RedeemLoginChallenge(ctx context.Context, logger *zap.Logger, challenge, code string) (*models.HolderLoginChallenge, error)
+}
+
+type service struct {
+	issuer               string
+	skew                 int64
+	recoveryCodesCount   int
+	challengeAge         time.Duration
+	maxChallengeAttempts int64
+	secretBox            *secretBox
+
+	repo                       Repository
+	totpSecretsIDGenerator     idgenerators.HolderTOTPSecretsIDGenerator
+	recoveryCodesIDGenerator   idgenerators.HolderRecoveryCodesIDGenerator
+	loginChallengesIDGenerator idgenerators.HolderLoginChallengesIDGenerator
+}
+
+func New(
+	config *Config,
+	repo Repository,
+	tsg idgenerators.HolderTOTPSecretsIDGenerator,
+	rcg idgenerators.HolderRecoveryCodesIDGenerator,
+	lcg idgenerators.HolderLoginChallengesIDGenerator,
+) (Service, error) {
+	box, err := newSecretBox(config.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service{
+		issuer:               config.Issuer,
+		skew:                 config.Skew,
+		recoveryCodesCount:   config.RecoveryCodesCount,
+		challengeAge:         config.ChallengeAge,
+		maxChallengeAttempts: config.MaxChallengeAttempts,
+		secretBox:            box,
+
+		repo:                       repo,
+		totpSecretsIDGenerator:     tsg,
+		recoveryCodesIDGenerator:   rcg,
+		loginChallengesIDGenerator: lcg,
+	}, nil
+}
+
+func hashSecretValue(value string) string {
+	return hash.SHA256(value)
+}
+
+type Enrollment struct {
+	Secret          string
+	ProvisioningURI string
+	RecoveryCodes   []string
+}
+
+func (s *service) Enroll(ctx context.Context, logger *zap.Logger, holderID int64, account string) (*Enrollment, error) {
+	logger = logger.With(zap.Int64("holder-id", holderID))
+	ts, err := s.repo.GetHolderTOTPSecretByHolderID(ctx, holderID)
+	if err != nil {
+		logger.Error("failed to get holder TOTP secret", zap.Error(err))
+		return nil, err
+	}
+	if ts != nil && ts.Enabled {
+		logger.Error("two-factor authentication is already enabled")
+		return nil, errorwrapper.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		logger.Error("failed to generate TOTP secret", zap.Error(err))
+		return nil, err
+	}
+	sealedSecret, err := s.secretBox.seal(holderID, secret)
+	if err != nil {
+		logger.Error("failed to encrypt TOTP secret", zap.Error(err))
+		return nil, err
+	}
+	if ts == nil {
+		ts = &models.HolderTOTPSecret{
+			ID:             s.totpSecretsIDGenerator.Generate().Int64(),
+			CreatedAt:      time.Now(),
+			LastModifiedAt: time.Now(),
+			HolderID:       holderID,
+			Secret:         sealedSecret,
+			Enabled:        false,
+			LastUsedStep:   0,
+		}
+		if err := s.repo.InsertHolderTOTPSecret(ctx, ts); err != nil {
+			logger.Error("failed to insert holder TOTP secret", zap.Error(err))
+			return nil, err
+		}
+	} else {
+		ts.LastModifiedAt = time.Now()
+		ts.Secret = sealedSecret
+		ts.LastUsedStep = 0
+		if err := s.repo.ModifyHolderTOTPSecret(ctx, ts.ID, ts); err != nil {
+			logger.Error("failed to modify holder TOTP secret", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	recoveryCodes, err := s.regenerateRecoveryCodes(ctx, logger, holderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(s.issuer, account, secret),
+		RecoveryCodes:   recoveryCodes,
+	}, nil
+}
+
+func (s *service) regenerateRecoveryCodes(ctx context.Context, logger *zap.Logger, holderID int64) ([]string, error) {
+	if err := s.repo.DeleteHolderRecoveryCodesByHolderID(ctx, holderID); err != nil {
+		logger.Error("failed to delete holder recovery codes", zap.Error(err))
+		return nil, err
+	}
+
+	codes := make([]string, 0, s.recoveryCodesCount)
+	for i := 0; i < s.recoveryCodesCount; i++ {
+		code, err := random.GenNanoString(12)
+		if err != nil {
+			logger.Error("failed to generate recovery code", zap.Error(err))
+			return nil, err
+		}
+		rc := &models.HolderRecoveryCode{
+			ID:             s.recoveryCodesIDGenerator.Generate().Int64(),
+			CreatedAt:      time.Now(),
+			LastModifiedAt: time.Now(),
+			HolderID:       holderID,
+			CodeHash:       hashSecretValue(code),
+		}
+		if err := s.repo.InsertHolderRecoveryCode(ctx, rc); err != nil {
+			logger.Error("failed to insert holder recovery code", zap.Error(err))
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+func (s *service) Verify(ctx context.Context, logger *zap.Logger, holderID int64, code string) error {
+	logger = logger.With(zap.Int64("holder-id", holderID))
+	ts, err := s.repo.GetHolderTOTPSecretByHolderID(ctx, holderID)
+	if err != nil {
+		logger.Error("failed to get holder TOTP secret", zap.Error(err))
+		return err
+	}
+	if ts == nil {
+		logger.Error("two-factor authentication enrollment is not found")
+		return errorwrapper.New("two-factor authentication enrollment is not found")
+	}
+	if ts.Enabled {
+		logger.Error("two-factor authentication is already enabled")
+		return errorwrapper.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := s.secretBox.open(ts.HolderID, ts.Secret)
+	if err != nil {
+		logger.Error("failed to decrypt TOTP secret", zap.Error(err))
+		return err
+	}
+	step, err := matchTOTPStep(secret, code, time.Now(), s.skew)
+	if err != nil {
+		logger.Error("failed to match TOTP code", zap.Error(err))
+		return err
+	}
+	if step < 0 {
+		logger.Error("invalid TOTP code")
+		return errorwrapper.New("invalid TOTP code")
+	}
+
+	ts.LastModifiedAt = time.Now()
+	ts.Enabled = true
+	ts.LastUsedStep = step
+	if err := s.repo.ModifyHolderTOTPSecret(ctx, ts.ID, ts); err != nil {
+		logger.Error("failed to modify holder TOTP secret to make enabled=true", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) Disable(ctx context.Context, logger *zap.Logger, holderID int64, code string) error {
+	logger = logger.With(zap.Int64("holder-id", holderID))
+	if err := s.ValidateCode(ctx, logger, holderID, code); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteHolderRecoveryCodesByHolderID(ctx, holderID); err != nil {
+		logger.Error("failed to delete holder recovery codes", zap.Error(err))
+		return err
+	}
+	if err := s.repo.DeleteHolderTOTPSecretByHolderID(ctx, holderID); err != nil {
+		logger.Error("failed to delete holder TOTP secret", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) IsEnabled(ctx context.Context, logger *zap.Logger, holderID int64) (bool, error) {
+	ts, err := s.repo.GetHolderTOTPSecretByHolderID(ctx, holderID)
+	if err != nil {
+		logger.Error("failed to get holder TOTP secret", zap.Error(err), zap.Int64("holder-id", holderID))
+		return false, err
+	}
+
+	return ts != nil && ts.Enabled, nil
+}
+
+// ValidateCode accepts either a TOTP code or an unused recovery code. Both
+// are single-use: a TOTP time step can't be replayed and a recovery code is
+// marked as used. The use is recorded by conditional update, so one code
+// can't be accepted twice by concurrent requests.
+func (s *service) ValidateCode(ctx context.Context, logger *zap.Logger, holderID int64, code string) error {
+	ts, err := s.repo.GetHolderTOTPSecretByHolderID(ctx, holderID)
+	if err != nil {
+		logger.Error("failed to get holder TOTP secret", zap.Error(err))
+		return err
+	}
+	if ts == nil || !ts.Enabled {
+		logger.Error("two-factor authentication is not enabled")
+		return errorwrapper.New("two-factor authentication is not enabled")
+	}
+
+	secret, err := s.secretBox.open(ts.HolderID, ts.Secret)
+	if err != nil {
+		logger.Error("failed to decrypt TOTP secret", zap.Error(err))
+		return err
+	}
+	step, err := matchTOTPStep(secret, code, time.Now(), s.skew)
+	if err != nil {
+		logger.Error("failed to match TOTP code", zap.Error(err))
+		return err
+	}
+	if step >= 0 {
+		used, err := s.repo.UseHolderTOTPStep(ctx, ts.ID, step, time.Now())
+		if err != nil {
+			logger.Error("failed to modify holder TOTP secret to make last_used_step={{step}}", zap.Error(err))
+			return err
+		}
+		if !used {
+			logger.Error("TOTP code was already used", zap.Int64("step", step))
+			return errorwrapper.New("TOTP code was already used")
+		}
+		return nil
+	}
+
+	rc, err := s.repo.RedeemHolderRecoveryCode(ctx, holderID, hashSecretValue(code), time.Now())
+	if err != nil {
+		logger.Error("failed to redeem holder recovery code", zap.Error(err))
+		return err
+	}
+	if rc == nil {
+		logger.Error("invalid two-factor authentication code")
+		return errorwrapper.New("invalid two-factor authentication code")
+	}
+	logger.Info("recovery code was used", zap.Int64("holder-recovery-code-id", rc.ID))
+
+	return nil
+}
+
+type CreateLoginChallengeParams struct {
+	HolderID         int64
+	RemoteIPAddress  string
+	RemoteMACAddress *string
+	// StepUp marks challenge of session binding mismatch, see
+	// models.HolderLoginChallenge.
+	StepUp bool
+}
+
+func (s *service) CreateLoginChallenge(ctx context.Context, logger *zap.Logger, params *CreateLoginChallengeParams) (string, error) {
+	id := s.loginChallengesIDGenerator.Generate().Int64()
+	logger = logger.With(
+		zap.Int64("holder-login-challenge-id", id),
+		zap.Int64("holder-id", params.HolderID),
+	)
+	challenge, err := random.GenNanoString(32)
+	if err != nil {
+		logger.Error("failed to generate login challenge", zap.Error(err))
+		return "", err
+	}
+	remoteMACAddress := sql.NullString{}
+	if params.RemoteMACAddress != nil {
+		remoteMACAddress.String = *params.RemoteMACAddress
+		remoteMACAddress.Valid = true
+	}
+	lc := &models.HolderLoginChallenge{
+		ID:             id,
+		CreatedAt:      time.Now(),
+		LastModifiedAt: time.Now(),
+		HolderID:       params.HolderID,
+		ChallengeHash:  hashSecretValue(challenge),
+		ExpiredAt:      time.Now().Add(s.challengeAge),
+		RemoteIPAddress: sql.NullString{
+			String: params.RemoteIPAddress,
+			Valid:  true,
+		},
+		RemoteMACAddress: remoteMACAddress,
+		StepUp:           params.StepUp,
+	}
+	if err := s.repo.InsertHolderLoginChallenge(ctx, lc); err != nil {
+		logger.Error("failed to insert holder login challenge", zap.Error(err))
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+func (s *service) RedeemLoginChallenge(ctx context.Context, logger *zap.Logger, challenge, code string) (*models.HolderLoginChallenge, error) {
+	lc, err := s.repo.GetHolderLoginChallengeByChallengeHash(ctx, hashSecretValue(challenge))
+	if err != nil {
+		logger.Error("failed to get holder login challenge", zap.Error(err))
+		return nil, err
+	}
+	if lc == nil {
+		logger.Error("login challenge is not found")
+		return nil, errorwrapper.New("login challenge is not found")
+	}
+	logger = logger.With(
+		zap.Int64("holder-login-challenge-id", lc.ID),
+		zap.Int64("holder-id", lc.HolderID),
+	)
+	if lc.UsedAt.Valid {
+		logger.Error("login challenge was already used")
+		return nil, errorwrapper.New("login challenge was already used")
+	}
+	if lc.ExpiredAt.Before(time.Now()) {
+		logger.Error("login challenge was expired", zap.Time("expired-at", lc.ExpiredAt))
+		return nil, errorwrapper.New("login challenge was expired")
+	}
+	// the attempt is counted in database before the code is checked, so
+	// concurrent guesses can't exceed the limit; it is not taken back when the
+	// code is valid, the challenge is used up then anyway
+	attempts, err := s.repo.IncrementHolderLoginChallengeFailedAttempts(ctx, lc.ID, s.maxChallengeAttempts, time.Now())
+	if err != nil {
+		logger.Error("failed to increment failed_attempts of holder login challenge", zap.Error(err))
+		return nil, err
+	}
+	if attempts == 0 {
+		logger.Error("too many failed attempts for login challenge", zap.Int64("failed-attempts", lc.FailedAttempts))
+		return nil, errorwrapper.New("too many failed attempts for login challenge")
+	}
+
+	if err := s.ValidateCode(ctx, logger, lc.HolderID, code); err != nil {
+		return nil, err
+	}
+	redeemed, err := s.repo.RedeemHolderLoginChallenge(ctx, lc.ID, time.Now())
+	if err != nil {
+		logger.Error("failed to modify holder login challenge to make used_at={{now}}", zap.Error(err))
+		return nil, err
+	}
+	if redeemed == nil {
+		logger.Error("login challenge was already used")
+		return nil, errorwrapper.New("login challenge was already used")
+	}
+
+	return redeemed, nil
+}